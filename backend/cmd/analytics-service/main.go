@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	analyticsgrpc "zipit/internal/analytics/grpc"
+	"zipit/internal/analytics/repository"
+	"zipit/internal/analytics/service"
+	"zipit/pkg/config"
+	"zipit/pkg/database"
+	"zipit/pkg/logger"
+
+	"github.com/joho/godotenv"
+	grpc "google.golang.org/grpc"
+
+	pb "zipit/gen/analytics"
+)
+
+func main() {
+	// 1. Initialize Infrastructure: Logger, Env vars
+	logger.SetLogger()
+	_ = godotenv.Load() // Optional: only used in local dev, Railway injects env vars directly
+
+	// 2. Database Setup
+	dbConfig, err := config.NewDBConfig()
+	if err != nil {
+		slog.Error("failed to load database config file", "error", err)
+		os.Exit(1)
+	}
+	db, err := database.NewDatabase(dbConfig)
+	if err != nil {
+		slog.Error("failed to establish connection to database", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// 3. Initialize Layers: Repository -> Service -> gRPC Handler
+	repo := repository.NewPostgresRepository(db)
+	analyticsSvc := service.NewAnalyticsSvc(repo)
+	handler := analyticsgrpc.NewAnalyticsHandler(analyticsSvc)
+
+	// 4. Start Network Listener
+	port := os.Getenv("ANALYTICS_SERVICE_PORT")
+	if port == "" {
+		port = "5052"
+	}
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		slog.Error("failed to listen", "error", err)
+		os.Exit(1)
+	}
+
+	// sig chan to catch interrupts
+	stopChan := make(chan os.Signal, 1)
+	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
+
+	// 5. Setup and Start gRPC Server
+	server := grpc.NewServer()
+	pb.RegisterAnalyticsServiceServer(server, handler)
+	errChan := make(chan error, 1)
+	go func() {
+		if err := server.Serve(lis); (err != nil) && (err != grpc.ErrServerStopped) {
+			errChan <- err
+		}
+	}()
+	slog.Info("analytics service up & ready!", "port", port)
+
+	select {
+	case <-stopChan:
+		slog.Info("shutting down gracefully...")
+	case err := <-errChan:
+		slog.Error("failed to serve", "error", err)
+	}
+	server.GracefulStop()
+}
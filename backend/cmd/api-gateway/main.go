@@ -6,17 +6,23 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"zipit/internal/gateway/handler"
 	"zipit/internal/gateway/router"
+	"zipit/pkg/config"
 	"zipit/pkg/logger"
+	"zipit/pkg/metrics"
+	"zipit/pkg/ratelimit"
 
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	analyticspb "zipit/gen/analytics"
+	authpb "zipit/gen/auth"
 	pb "zipit/gen/url"
 )
 
@@ -35,9 +41,73 @@ func main() {
 	}
 	defer conn.Close()
 
+	analyticsServiceHost := getEnvOrDefault("ANALYTICS_SERVICE_HOST", "localhost")
+	analyticsServicePort := getEnvOrDefault("ANALYTICS_SERVICE_PORT", "5052")
+	analyticsServiceAddr := analyticsServiceHost + ":" + analyticsServicePort
+
+	analyticsConn, err := grpc.NewClient(analyticsServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Error("failed to connect to analytics service", "error", err)
+		os.Exit(1)
+	}
+	defer analyticsConn.Close()
+
 	urlSvc := pb.NewURLServiceClient(conn)
-	gatewayHandler := handler.NewGatewayHandler(urlSvc)
-	apiRouter := router.New(gatewayHandler)
+	analyticsSvc := analyticspb.NewAnalyticsServiceClient(analyticsConn)
+	gatewayHandler := handler.NewGatewayHandler(urlSvc, analyticsSvc)
+
+	// API keys are checked by url-service, over the same connection
+	routerConfig := router.Config{
+		AllowAnonymousShorten: getEnvOrDefault("ALLOW_ANONYMOUS_SHORTEN", "false") == "true",
+		AllowedOrigins:        splitList(getEnvOrDefault("CORS_ALLOWED_ORIGINS", "*")),
+	}
+	if getEnvOrDefault("AUTH_ENABLED", "true") == "true" {
+		routerConfig.Auth = authpb.NewAuthServiceClient(conn)
+	} else {
+		slog.Warn("api key authentication is disabled, links can be shortened and followed but not managed")
+	}
+
+	rateLimitConfig, err := config.NewRateLimitConfig()
+	if err != nil {
+		slog.Error("failed to load rate limit config", "error", err)
+		os.Exit(1)
+	}
+	if routerConfig.ShortenLimiter, err = ratelimit.NewLimiter(rateLimitConfig, "shorten", ratelimit.Limit{Rate: rateLimitConfig.ShortenRate, Burst: rateLimitConfig.ShortenBurst}); err != nil {
+		slog.Error("failed to initialize rate limiter", "error", err)
+		os.Exit(1)
+	}
+	if routerConfig.ResolveLimiter, err = ratelimit.NewLimiter(rateLimitConfig, "resolve", ratelimit.Limit{Rate: rateLimitConfig.ResolveRate, Burst: rateLimitConfig.ResolveBurst}); err != nil {
+		slog.Error("failed to initialize rate limiter", "error", err)
+		os.Exit(1)
+	}
+	if routerConfig.AuthLimiter, err = ratelimit.NewLimiter(rateLimitConfig, "auth", ratelimit.Limit{Rate: rateLimitConfig.AuthRate, Burst: rateLimitConfig.AuthBurst}); err != nil {
+		slog.Error("failed to initialize rate limiter", "error", err)
+		os.Exit(1)
+	}
+	proxyConfig, err := config.NewProxyConfig()
+	if err != nil {
+		slog.Error("failed to load proxy config", "error", err)
+		os.Exit(1)
+	}
+	routerConfig.TrustedProxies = proxyConfig.TrustedProxies
+	metricsConfig, err := config.NewMetricsConfig()
+	if err != nil {
+		slog.Error("failed to load metrics config", "error", err)
+		os.Exit(1)
+	}
+	// Metrics are served on a separate admin port, away from the public API
+	var adminServer *http.Server
+	if metricsConfig.Enabled {
+		routerConfig.Metrics = metrics.NewRegistry()
+		adminMux := http.NewServeMux()
+		adminMux.Handle("GET /metrics", metrics.Handler(routerConfig.Metrics))
+		adminServer = &http.Server{
+			Addr:              ":" + metricsConfig.GatewayAdminPort,
+			Handler:           adminMux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+	}
+	apiRouter := router.New(gatewayHandler, routerConfig)
 
 	port := getEnvOrDefault("GATEWAY_PORT", "8080")
 	server := &http.Server{
@@ -49,14 +119,21 @@ func main() {
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
 	go func() {
 		if err := server.ListenAndServe(); (err != nil) && (err != http.ErrServerClosed) {
 			errChan <- err
 		}
 	}()
+	if adminServer != nil {
+		go func() {
+			if err := adminServer.ListenAndServe(); (err != nil) && (err != http.ErrServerClosed) {
+				errChan <- err
+			}
+		}()
+	}
 
-	slog.Info("api gateway up & ready", "port", port, "url_service", urlServiceAddr)
+	slog.Info("api gateway up & ready", "port", port, "admin_port", metricsConfig.GatewayAdminPort, "url_service", urlServiceAddr, "analytics_service", analyticsServiceAddr, "rate_limit", rateLimitConfig.Store, "metrics", metricsConfig.Enabled)
 
 	select {
 	case <-stopChan:
@@ -69,6 +146,11 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		slog.Error("failed to shutdown http server", "error", err)
 	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			slog.Error("failed to shutdown admin server", "error", err)
+		}
+	}
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -77,3 +159,14 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitList splits a comma separated setting, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
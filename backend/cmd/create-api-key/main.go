@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"zipit/internal/auth/repository"
+	"zipit/internal/auth/service"
+	"zipit/pkg/config"
+	"zipit/pkg/database"
+	"zipit/pkg/logger"
+
+	"github.com/joho/godotenv"
+)
+
+// create-api-key issues an API key for an owner and prints it. The key is
+// only stored as a digest, so this is the one chance to copy it.
+//
+// Usage: create-api-key -owner acme [-name ci]
+func main() {
+	logger.SetLogger()
+	_ = godotenv.Load() // Optional: only used in local dev
+
+	owner := flag.String("owner", "", "owner the key authenticates as (required)")
+	name := flag.String("name", "", "label to tell the owner's keys apart")
+	flag.Parse()
+
+	if *owner == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dbConfig, err := config.NewDBConfig()
+	if err != nil {
+		slog.Error("failed to load database config file", "error", err)
+		os.Exit(1)
+	}
+	db, err := database.NewDatabase(dbConfig)
+	if err != nil {
+		slog.Error("failed to establish connection to database", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authSvc := service.NewAuthSvc(repository.NewPostgresRepository(db))
+	key, err := authSvc.CreateAPIKey(ctx, *owner, *name)
+	if err != nil {
+		slog.Error("failed to create api key", "error", err)
+		os.Exit(1)
+	}
+	fmt.Println(key)
+}
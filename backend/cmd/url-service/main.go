@@ -1,23 +1,37 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	authgrpc "zipit/internal/auth/grpc"
+	authrepository "zipit/internal/auth/repository"
+	authservice "zipit/internal/auth/service"
 	urlgrpc "zipit/internal/url/grpc"
+	"zipit/internal/url/healthcheck"
+	"zipit/internal/url/pagefetch"
+	"zipit/internal/url/policy"
 	"zipit/internal/url/repository"
+	"zipit/internal/url/rules"
 	"zipit/internal/url/service"
+	"zipit/pkg/cache"
 	"zipit/pkg/config"
 	"zipit/pkg/database"
+	"zipit/pkg/geoip"
 	"zipit/pkg/logger"
+	"zipit/pkg/metrics"
 	"zipit/pkg/shortener"
 
 	"github.com/joho/godotenv"
 	grpc "google.golang.org/grpc"
 
+	authpb "zipit/gen/auth"
 	pb "zipit/gen/url"
 )
 
@@ -39,13 +53,170 @@ func main() {
 	}
 	defer db.Close()
 
-	// 3. Initialize Layers: Repository -> Service -> gRPC Handler
+	// 3. Short code generator: base62 (default), feistel or random
+	shortenerConfig, err := config.NewShortenerConfig()
+	if err != nil {
+		slog.Error("failed to load shortener config", "error", err)
+		os.Exit(1)
+	}
+	codeShortener, err := shortener.NewShortener(shortenerConfig)
+	if err != nil {
+		slog.Error("failed to create shortener", "error", err)
+		os.Exit(1)
+	}
+
+	// 4. Link cache: in-process LRU (default), redis or none
+	cacheConfig, err := config.NewCacheConfig()
+	if err != nil {
+		slog.Error("failed to load cache config", "error", err)
+		os.Exit(1)
+	}
+	linkCache, err := cache.NewCache(cacheConfig)
+	if err != nil {
+		slog.Error("failed to create cache", "error", err)
+		os.Exit(1)
+	}
+
+	// 5. Destination rules: normalization and URL policy
+	normalizeConfig, err := config.NewNormalizeConfig()
+	if err != nil {
+		slog.Error("failed to load url normalization config", "error", err)
+		os.Exit(1)
+	}
+	normalizer := service.Normalizer{SortQuery: normalizeConfig.SortQuery, StripTracking: normalizeConfig.StripTracking}
+
+	policyConfig, err := config.NewPolicyConfig()
+	if err != nil {
+		slog.Error("failed to load url policy config", "error", err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var urlPolicy policy.Chain
+	if !policyConfig.AllowPrivateHosts {
+		urlPolicy = append(urlPolicy, policy.InternalHosts{})
+	}
+	if len(policyConfig.ShortDomains) > 0 {
+		urlPolicy = append(urlPolicy, policy.NewSelfReference(policyConfig.ShortDomains))
+	}
+	if policyConfig.BlocklistFile != "" || policyConfig.AllowlistFile != "" {
+		lists, err := policy.NewDomainLists(policyConfig.BlocklistFile, policyConfig.AllowlistFile)
+		if err != nil {
+			slog.Error("failed to load url domain lists", "error", err)
+			os.Exit(1)
+		}
+		go lists.Watch(ctx, policyConfig.ReloadInterval)
+		urlPolicy = append(urlPolicy, lists)
+	}
+
+	// Links can route visitors by country, from an optional offline IP database
+	geoIPConfig, err := config.NewGeoIPConfig()
+	if err != nil {
+		slog.Error("failed to load geoip config", "error", err)
+		os.Exit(1)
+	}
+	var locator geoip.Locator
+	if geoIPConfig.DatabaseFile != "" {
+		ipDatabase, err := geoip.Open(geoIPConfig.DatabaseFile)
+		if err != nil {
+			slog.Error("failed to load ip database", "error", err)
+			os.Exit(1)
+		}
+		go ipDatabase.Watch(ctx, geoIPConfig.ReloadInterval)
+		locator = ipDatabase
+	}
+	router := rules.NewEngine(locator)
+
+	// 6. Initialize Layers: Repository -> Service -> gRPC Handler
 	repo := repository.NewPostgresRepository(db)
-	shortener := shortener.NewBase62Shortener()
-	urlSvc := service.NewUrlSvc(repo, shortener)
-	handler := urlgrpc.NewURLHandler(urlSvc)
+	if linkCache != nil {
+		repo = repository.NewCachedRepository(repo, linkCache, cacheConfig.TTL, cacheConfig.NegativeTTL)
+	}
+	urlSvc := service.NewUrlSvc(repo, codeShortener, normalizer, urlPolicy, router)
 
-	// 4. Start Network Listener
+	// Destination pages of new links are fetched in the background, subject
+	// to the same URL policy as the links themselves
+	pageFetchConfig, err := config.NewPageFetchConfig()
+	if err != nil {
+		slog.Error("failed to load page fetch config", "error", err)
+		os.Exit(1)
+	}
+	if pageFetchConfig.Enabled {
+		fetcher := pagefetch.NewFetcher(pagefetch.Options{
+			Timeout:      pageFetchConfig.Timeout,
+			MaxBytes:     pageFetchConfig.MaxBytes,
+			UserAgent:    pageFetchConfig.UserAgent,
+			Policy:       urlPolicy,
+			AllowPrivate: policyConfig.AllowPrivateHosts,
+		})
+		pages := pagefetch.NewPool(fetcher, repo, pageFetchConfig.Workers, pageFetchConfig.QueueSize)
+		go pages.Run(ctx)
+		urlSvc = service.WithPageFetching(urlSvc, pages)
+	}
+
+	// Destinations are re-checked periodically so dead links are noticed
+	healthCheckConfig, err := config.NewHealthCheckConfig()
+	if err != nil {
+		slog.Error("failed to load health check config", "error", err)
+		os.Exit(1)
+	}
+	if healthCheckConfig.Enabled {
+		prober := pagefetch.NewFetcher(pagefetch.Options{
+			Timeout:      healthCheckConfig.Timeout,
+			UserAgent:    pageFetchConfig.UserAgent,
+			Policy:       urlPolicy,
+			AllowPrivate: policyConfig.AllowPrivateHosts,
+		})
+		checker := healthcheck.NewChecker(repo, prober, healthcheck.Options{
+			Interval:     healthCheckConfig.Interval,
+			RecheckAfter: healthCheckConfig.RecheckAfter,
+			BatchSize:    healthCheckConfig.BatchSize,
+			Workers:      healthCheckConfig.Workers,
+			DisableAfter: healthCheckConfig.DisableAfter,
+		})
+		go checker.Run(ctx)
+	}
+
+	// Metrics are served on a separate admin port, away from the gRPC API
+	metricsConfig, err := config.NewMetricsConfig()
+	if err != nil {
+		slog.Error("failed to load metrics config", "error", err)
+		os.Exit(1)
+	}
+	var serverOpts []grpc.ServerOption
+	var adminServer *http.Server
+	if metricsConfig.Enabled {
+		registry := metrics.NewRegistry()
+		registry.MustRegister(metrics.NewDBStatsCollector(db))
+		urlSvc = service.WithCounters(urlSvc, metrics.NewLinkCounters(registry))
+		grpcMetrics := metrics.NewGRPCMetrics(registry)
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(grpcMetrics.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(grpcMetrics.StreamServerInterceptor()),
+		)
+
+		adminMux := http.NewServeMux()
+		adminMux.Handle("GET /metrics", metrics.Handler(registry))
+		adminServer = &http.Server{
+			Addr:              ":" + metricsConfig.AdminPort,
+			Handler:           adminMux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+	}
+
+	accessConfig, err := config.NewAccessConfig()
+	if err != nil {
+		slog.Error("failed to load access config", "error", err)
+		os.Exit(1)
+	}
+	handler := urlgrpc.NewURLHandler(urlSvc, accessConfig.AdminOwners)
+
+	// API keys live next to the links they own, so url-service also answers
+	// the gateway's key lookups
+	authSvc := authservice.NewAuthSvc(authrepository.NewPostgresRepository(db))
+	authHandler := authgrpc.NewAuthHandler(authSvc)
+
+	// 7. Start Network Listener
 	port := os.Getenv("URL_SERVICE_PORT")
 	if port == "" {
 		port = "5051"
@@ -60,16 +231,24 @@ func main() {
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 
-	// 5. Setup and Start gRPC Server
-	server := grpc.NewServer()
+	// 8. Setup and Start gRPC Server
+	server := grpc.NewServer(serverOpts...)
 	pb.RegisterURLServiceServer(server, handler)
-	errChan := make(chan error, 1)
+	authpb.RegisterAuthServiceServer(server, authHandler)
+	errChan := make(chan error, 2)
 	go func() {
 		if err := server.Serve(lis); (err != nil) && (err != grpc.ErrServerStopped) {
 			errChan <- err
 		}
 	}()
-	slog.Info("url service up & ready!", "port", port)
+	if adminServer != nil {
+		go func() {
+			if err := adminServer.ListenAndServe(); (err != nil) && (err != http.ErrServerClosed) {
+				errChan <- err
+			}
+		}()
+	}
+	slog.Info("url service up & ready!", "port", port, "admin_port", metricsConfig.AdminPort, "metrics", metricsConfig.Enabled, "shortener", shortenerConfig.Kind, "cache", cacheConfig.Backend)
 
 	select {
 	case <-stopChan:
@@ -78,4 +257,11 @@ func main() {
 		slog.Error("failed to serve", "error", err)
 	}
 	server.GracefulStop()
+	if adminServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to shutdown admin server", "error", err)
+		}
+	}
 }
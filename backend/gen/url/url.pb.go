@@ -9,6 +9,8 @@ package url
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -23,7 +25,22 @@ const (
 
 type LongURL struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`                                        // unset by GetLongURL for links with a click limit that use up no click
+	CustomAlias   string                 `protobuf:"bytes,2,opt,name=custom_alias,json=customAlias,proto3" json:"custom_alias,omitempty"`     // optional vanity short code, only used by PostURL
+	TtlSeconds    int64                  `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`       // optional lifetime, mutually exclusive with expires_at
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`           // optional absolute expiry
+	Interstitial  bool                   `protobuf:"varint,5,opt,name=interstitial,proto3" json:"interstitial,omitempty"`                     // on PostURL, show a warning page before redirecting; on GetLongURL, whether to
+	Warning       string                 `protobuf:"bytes,6,opt,name=warning,proto3" json:"warning,omitempty"`                                // set by GetLongURL with interstitial: why the destination is flagged
+	RedirectType  int32                  `protobuf:"varint,7,opt,name=redirect_type,json=redirectType,proto3" json:"redirect_type,omitempty"` // HTTP status to redirect with: 301, 302, 307 or 308; 0 means 302 on PostURL
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`           // set by GetLongURL
+	Title         string                 `protobuf:"bytes,9,opt,name=title,proto3" json:"title,omitempty"`                                    // optional details to find the link by
+	Description   string                 `protobuf:"bytes,10,opt,name=description,proto3" json:"description,omitempty"`
+	Tags          []string               `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`                             // stored lower-case
+	Health        *LinkHealth            `protobuf:"bytes,12,opt,name=health,proto3" json:"health,omitempty"`                         // set by GetLongURL once the destination has been checked
+	Password      string                 `protobuf:"bytes,13,opt,name=password,proto3" json:"password,omitempty"`                     // optional on PostURL: visitors must supply it to follow the link
+	MaxClicks     int32                  `protobuf:"varint,14,opt,name=max_clicks,json=maxClicks,proto3" json:"max_clicks,omitempty"` // optional on PostURL: the link stops resolving after this many GetLongURL calls
+	Routed        bool                   `protobuf:"varint,15,opt,name=routed,proto3" json:"routed,omitempty"`                        // set by GetLongURL if the link has routing rules or variants, so visitors may get different urls
+	Variant       string                 `protobuf:"bytes,16,opt,name=variant,proto3" json:"variant,omitempty"`                       // set by GetLongURL: the name of the A/B variant the visitor was sent to
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -65,28 +82,1288 @@ func (x *LongURL) GetUrl() string {
 	return ""
 }
 
+func (x *LongURL) GetCustomAlias() string {
+	if x != nil {
+		return x.CustomAlias
+	}
+	return ""
+}
+
+func (x *LongURL) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *LongURL) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *LongURL) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+func (x *LongURL) GetWarning() string {
+	if x != nil {
+		return x.Warning
+	}
+	return ""
+}
+
+func (x *LongURL) GetRedirectType() int32 {
+	if x != nil {
+		return x.RedirectType
+	}
+	return 0
+}
+
+func (x *LongURL) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *LongURL) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *LongURL) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *LongURL) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *LongURL) GetHealth() *LinkHealth {
+	if x != nil {
+		return x.Health
+	}
+	return nil
+}
+
+func (x *LongURL) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *LongURL) GetMaxClicks() int32 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *LongURL) GetRouted() bool {
+	if x != nil {
+		return x.Routed
+	}
+	return false
+}
+
+func (x *LongURL) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+type LinkHealth struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	LastCheckedAt       *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=last_checked_at,json=lastCheckedAt,proto3" json:"last_checked_at,omitempty"`
+	LastStatus          int32                  `protobuf:"varint,2,opt,name=last_status,json=lastStatus,proto3" json:"last_status,omitempty"`                            // status code of the last check; 0 if the destination did not answer
+	ConsecutiveFailures int32                  `protobuf:"varint,3,opt,name=consecutive_failures,json=consecutiveFailures,proto3" json:"consecutive_failures,omitempty"` // failed checks since the last successful one
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *LinkHealth) Reset() {
+	*x = LinkHealth{}
+	mi := &file_url_url_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkHealth) ProtoMessage() {}
+
+func (x *LinkHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkHealth.ProtoReflect.Descriptor instead.
+func (*LinkHealth) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LinkHealth) GetLastCheckedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastCheckedAt
+	}
+	return nil
+}
+
+func (x *LinkHealth) GetLastStatus() int32 {
+	if x != nil {
+		return x.LastStatus
+	}
+	return 0
+}
+
+func (x *LinkHealth) GetConsecutiveFailures() int32 {
+	if x != nil {
+		return x.ConsecutiveFailures
+	}
+	return 0
+}
+
 type ShortURL struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Alias    string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Password string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"` // unlocks password-protected links on GetLongURL and PreviewURL
+	// The visitor's request, matched against the link's routing rules on GetLongURL and PreviewURL
+	UserAgent      string `protobuf:"bytes,3,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	AcceptLanguage string `protobuf:"bytes,4,opt,name=accept_language,json=acceptLanguage,proto3" json:"accept_language,omitempty"`
+	IpAddress      string `protobuf:"bytes,5,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	VisitorId      string `protobuf:"bytes,6,opt,name=visitor_id,json=visitorId,proto3" json:"visitor_id,omitempty"` // identifies the visitor across requests, so it keeps getting the same variant
+	// On GetLongURL, links with a click limit only use up a click, and only reveal their url, if the visitor is redirected
+	LookupOnly    bool `protobuf:"varint,7,opt,name=lookup_only,json=lookupOnly,proto3" json:"lookup_only,omitempty"` // the caller shows the destination instead of redirecting to it
+	Confirmed     bool `protobuf:"varint,8,opt,name=confirmed,proto3" json:"confirmed,omitempty"`                     // the visitor went past the warning page of a flagged link, which uses up no click itself
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortURL) Reset() {
+	*x = ShortURL{}
+	mi := &file_url_url_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortURL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortURL) ProtoMessage() {}
+
+func (x *ShortURL) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortURL.ProtoReflect.Descriptor instead.
+func (*ShortURL) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ShortURL) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *ShortURL) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *ShortURL) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *ShortURL) GetAcceptLanguage() string {
+	if x != nil {
+		return x.AcceptLanguage
+	}
+	return ""
+}
+
+func (x *ShortURL) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *ShortURL) GetVisitorId() string {
+	if x != nil {
+		return x.VisitorId
+	}
+	return ""
+}
+
+func (x *ShortURL) GetLookupOnly() bool {
+	if x != nil {
+		return x.LookupOnly
+	}
+	return false
+}
+
+func (x *ShortURL) GetConfirmed() bool {
+	if x != nil {
+		return x.Confirmed
+	}
+	return false
+}
+
+type BatchLongURL struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Urls          []*LongURL             `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"` // at most 1000
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchLongURL) Reset() {
+	*x = BatchLongURL{}
+	mi := &file_url_url_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchLongURL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchLongURL) ProtoMessage() {}
+
+func (x *BatchLongURL) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchLongURL.ProtoReflect.Descriptor instead.
+func (*BatchLongURL) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchLongURL) GetUrls() []*LongURL {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+type BatchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"` // set if the url was shortened
+	Code          int32                  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`  // google.rpc.Code of the failure, as PostURL would return it
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResult) Reset() {
+	*x = BatchResult{}
+	mi := &file_url_url_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResult) ProtoMessage() {}
+
+func (x *BatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResult.ProtoReflect.Descriptor instead.
+func (*BatchResult) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchResult) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *BatchResult) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *BatchResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type BatchShortURL struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*BatchResult         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"` // one per url, in request order
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchShortURL) Reset() {
+	*x = BatchShortURL{}
+	mi := &file_url_url_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchShortURL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchShortURL) ProtoMessage() {}
+
+func (x *BatchShortURL) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchShortURL.ProtoReflect.Descriptor instead.
+func (*BatchShortURL) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchShortURL) GetResults() []*BatchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type DisableURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Disabled      bool                   `protobuf:"varint,2,opt,name=disabled,proto3" json:"disabled,omitempty"` // false re-enables a disabled link
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisableURLRequest) Reset() {
+	*x = DisableURLRequest{}
+	mi := &file_url_url_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisableURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisableURLRequest) ProtoMessage() {}
+
+func (x *DisableURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisableURLRequest.ProtoReflect.Descriptor instead.
+func (*DisableURLRequest) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DisableURLRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *DisableURLRequest) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+type UpdateURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`                                              // new destination, unchanged if empty
+	Interstitial  *bool                  `protobuf:"varint,3,opt,name=interstitial,proto3,oneof" json:"interstitial,omitempty"`                     // unchanged if unset
+	RedirectType  *int32                 `protobuf:"varint,4,opt,name=redirect_type,json=redirectType,proto3,oneof" json:"redirect_type,omitempty"` // unchanged if unset
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateURLRequest) Reset() {
+	*x = UpdateURLRequest{}
+	mi := &file_url_url_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateURLRequest) ProtoMessage() {}
+
+func (x *UpdateURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateURLRequest.ProtoReflect.Descriptor instead.
+func (*UpdateURLRequest) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateURLRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *UpdateURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *UpdateURLRequest) GetInterstitial() bool {
+	if x != nil && x.Interstitial != nil {
+		return *x.Interstitial
+	}
+	return false
+}
+
+func (x *UpdateURLRequest) GetRedirectType() int32 {
+	if x != nil && x.RedirectType != nil {
+		return *x.RedirectType
+	}
+	return 0
+}
+
+type URLPreview struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"` // unset for links with a click limit
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`          // unset if the link never expires
+	Safety        string                 `protobuf:"bytes,4,opt,name=safety,proto3" json:"safety,omitempty"`                                 // "ok", "flagged" by the owner, or "blocked" by the URL policy
+	SafetyReason  string                 `protobuf:"bytes,5,opt,name=safety_reason,json=safetyReason,proto3" json:"safety_reason,omitempty"` // why the destination is flagged or blocked
+	Title         string                 `protobuf:"bytes,6,opt,name=title,proto3" json:"title,omitempty"`
+	Description   string                 `protobuf:"bytes,7,opt,name=description,proto3" json:"description,omitempty"`
+	Tags          []string               `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty"`
+	Page          *PageInfo              `protobuf:"bytes,9,opt,name=page,proto3" json:"page,omitempty"` // unset until the destination page has been fetched
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLPreview) Reset() {
+	*x = URLPreview{}
+	mi := &file_url_url_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLPreview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLPreview) ProtoMessage() {}
+
+func (x *URLPreview) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLPreview.ProtoReflect.Descriptor instead.
+func (*URLPreview) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *URLPreview) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *URLPreview) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *URLPreview) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *URLPreview) GetSafety() string {
+	if x != nil {
+		return x.Safety
+	}
+	return ""
+}
+
+func (x *URLPreview) GetSafetyReason() string {
+	if x != nil {
+		return x.SafetyReason
+	}
+	return ""
+}
+
+func (x *URLPreview) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *URLPreview) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *URLPreview) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *URLPreview) GetPage() *PageInfo {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+type PageInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"` // the page's <title>
+	OgTitle       string                 `protobuf:"bytes,2,opt,name=og_title,json=ogTitle,proto3" json:"og_title,omitempty"`
+	OgImage       string                 `protobuf:"bytes,3,opt,name=og_image,json=ogImage,proto3" json:"og_image,omitempty"`
+	StatusCode    int32                  `protobuf:"varint,4,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"` // status of the final response, after redirects
+	FetchedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=fetched_at,json=fetchedAt,proto3" json:"fetched_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageInfo) Reset() {
+	*x = PageInfo{}
+	mi := &file_url_url_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageInfo) ProtoMessage() {}
+
+func (x *PageInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageInfo.ProtoReflect.Descriptor instead.
+func (*PageInfo) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PageInfo) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *PageInfo) GetOgTitle() string {
+	if x != nil {
+		return x.OgTitle
+	}
+	return ""
+}
+
+func (x *PageInfo) GetOgImage() string {
+	if x != nil {
+		return x.OgImage
+	}
+	return ""
+}
+
+func (x *PageInfo) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *PageInfo) GetFetchedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FetchedAt
+	}
+	return nil
+}
+
+type ListURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`                          // at most 100; 0 means 50
+	Owner         *string                `protobuf:"bytes,3,opt,name=owner,proto3,oneof" json:"owner,omitempty"`                     // defaults to the caller; other owners are for admins only
+	AllOwners     bool                   `protobuf:"varint,4,opt,name=all_owners,json=allOwners,proto3" json:"all_owners,omitempty"` // admins only
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	Domain        string                 `protobuf:"bytes,7,opt,name=domain,proto3" json:"domain,omitempty"` // destination host, including its subdomains
+	Search        string                 `protobuf:"bytes,8,opt,name=search,proto3" json:"search,omitempty"` // case-insensitive substring of the destination
+	Cursor        string                 `protobuf:"bytes,9,opt,name=cursor,proto3" json:"cursor,omitempty"` // next_cursor of the previous page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListURLsRequest) Reset() {
+	*x = ListURLsRequest{}
+	mi := &file_url_url_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListURLsRequest) ProtoMessage() {}
+
+func (x *ListURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListURLsRequest.ProtoReflect.Descriptor instead.
+func (*ListURLsRequest) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListURLsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *ListURLsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListURLsRequest) GetOwner() string {
+	if x != nil && x.Owner != nil {
+		return *x.Owner
+	}
+	return ""
+}
+
+func (x *ListURLsRequest) GetAllOwners() bool {
+	if x != nil {
+		return x.AllOwners
+	}
+	return false
+}
+
+func (x *ListURLsRequest) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *ListURLsRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+func (x *ListURLsRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *ListURLsRequest) GetSearch() string {
+	if x != nil {
+		return x.Search
+	}
+	return ""
+}
+
+func (x *ListURLsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type URLInfo struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Alias             string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Url               string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Title             string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Description       string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Tags              []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ExpiresAt         *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // unset if the link never expires
+	Disabled          bool                   `protobuf:"varint,8,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	Interstitial      bool                   `protobuf:"varint,9,opt,name=interstitial,proto3" json:"interstitial,omitempty"` // whether the owner flagged the link
+	RedirectType      int32                  `protobuf:"varint,10,opt,name=redirect_type,json=redirectType,proto3" json:"redirect_type,omitempty"`
+	Owner             string                 `protobuf:"bytes,11,opt,name=owner,proto3" json:"owner,omitempty"`
+	Page              *PageInfo              `protobuf:"bytes,12,opt,name=page,proto3" json:"page,omitempty"`     // unset until the destination page has been fetched
+	Health            *LinkHealth            `protobuf:"bytes,13,opt,name=health,proto3" json:"health,omitempty"` // unset until the destination has been checked
+	PasswordProtected bool                   `protobuf:"varint,14,opt,name=password_protected,json=passwordProtected,proto3" json:"password_protected,omitempty"`
+	MaxClicks         int32                  `protobuf:"varint,15,opt,name=max_clicks,json=maxClicks,proto3" json:"max_clicks,omitempty"`                   // 0 if the link has no click limit
+	ClicksRemaining   int32                  `protobuf:"varint,16,opt,name=clicks_remaining,json=clicksRemaining,proto3" json:"clicks_remaining,omitempty"` // set if max_clicks is
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *URLInfo) Reset() {
+	*x = URLInfo{}
+	mi := &file_url_url_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLInfo) ProtoMessage() {}
+
+func (x *URLInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLInfo.ProtoReflect.Descriptor instead.
+func (*URLInfo) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *URLInfo) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *URLInfo) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *URLInfo) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *URLInfo) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *URLInfo) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *URLInfo) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *URLInfo) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *URLInfo) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+func (x *URLInfo) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+func (x *URLInfo) GetRedirectType() int32 {
+	if x != nil {
+		return x.RedirectType
+	}
+	return 0
+}
+
+func (x *URLInfo) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *URLInfo) GetPage() *PageInfo {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+func (x *URLInfo) GetHealth() *LinkHealth {
+	if x != nil {
+		return x.Health
+	}
+	return nil
+}
+
+func (x *URLInfo) GetPasswordProtected() bool {
+	if x != nil {
+		return x.PasswordProtected
+	}
+	return false
+}
+
+func (x *URLInfo) GetMaxClicks() int32 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *URLInfo) GetClicksRemaining() int32 {
+	if x != nil {
+		return x.ClicksRemaining
+	}
+	return 0
+}
+
+type URLList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Urls          []*URLInfo             `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // empty on the last page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLList) Reset() {
+	*x = URLList{}
+	mi := &file_url_url_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLList) ProtoMessage() {}
+
+func (x *URLList) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLList.ProtoReflect.Descriptor instead.
+func (*URLList) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *URLList) GetUrls() []*URLInfo {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+func (x *URLList) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type URLChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OldUrl        string                 `protobuf:"bytes,1,opt,name=old_url,json=oldUrl,proto3" json:"old_url,omitempty"`
+	NewUrl        string                 `protobuf:"bytes,2,opt,name=new_url,json=newUrl,proto3" json:"new_url,omitempty"`
+	ChangedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLChange) Reset() {
+	*x = URLChange{}
+	mi := &file_url_url_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLChange) ProtoMessage() {}
+
+func (x *URLChange) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLChange.ProtoReflect.Descriptor instead.
+func (*URLChange) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *URLChange) GetOldUrl() string {
+	if x != nil {
+		return x.OldUrl
+	}
+	return ""
+}
+
+func (x *URLChange) GetNewUrl() string {
+	if x != nil {
+		return x.NewUrl
+	}
+	return ""
+}
+
+func (x *URLChange) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+type URLHistory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Changes       []*URLChange           `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"` // newest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLHistory) Reset() {
+	*x = URLHistory{}
+	mi := &file_url_url_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLHistory) ProtoMessage() {}
+
+func (x *URLHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLHistory.ProtoReflect.Descriptor instead.
+func (*URLHistory) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *URLHistory) GetChanges() []*URLChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type RoutingRule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Platforms     []string               `protobuf:"bytes,1,rep,name=platforms,proto3" json:"platforms,omitempty"` // "ios", "android", "windows", "macos" or "linux", from the User-Agent
+	Languages     []string               `protobuf:"bytes,2,rep,name=languages,proto3" json:"languages,omitempty"` // language tags; "en" also matches the preferred language "en-GB"
+	Countries     []string               `protobuf:"bytes,3,rep,name=countries,proto3" json:"countries,omitempty"` // ISO 3166-1 alpha-2 codes, from the IP address
+	Url           string                 `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutingRule) Reset() {
+	*x = RoutingRule{}
+	mi := &file_url_url_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutingRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutingRule) ProtoMessage() {}
+
+func (x *RoutingRule) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutingRule.ProtoReflect.Descriptor instead.
+func (*RoutingRule) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RoutingRule) GetPlatforms() []string {
+	if x != nil {
+		return x.Platforms
+	}
+	return nil
+}
+
+func (x *RoutingRule) GetLanguages() []string {
+	if x != nil {
+		return x.Languages
+	}
+	return nil
+}
+
+func (x *RoutingRule) GetCountries() []string {
+	if x != nil {
+		return x.Countries
+	}
+	return nil
+}
+
+func (x *RoutingRule) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type RoutingRules struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*RoutingRule         `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"` // tried in order; the first match wins
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutingRules) Reset() {
+	*x = RoutingRules{}
+	mi := &file_url_url_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutingRules) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutingRules) ProtoMessage() {}
+
+func (x *RoutingRules) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutingRules.ProtoReflect.Descriptor instead.
+func (*RoutingRules) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RoutingRules) GetRules() []*RoutingRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type SetRulesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Rules         []*RoutingRule         `protobuf:"bytes,2,rep,name=rules,proto3" json:"rules,omitempty"` // at most 20; none removes the link's rules
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ShortURL) Reset() {
-	*x = ShortURL{}
-	mi := &file_url_url_proto_msgTypes[1]
+func (x *SetRulesRequest) Reset() {
+	*x = SetRulesRequest{}
+	mi := &file_url_url_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ShortURL) String() string {
+func (x *SetRulesRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ShortURL) ProtoMessage() {}
+func (*SetRulesRequest) ProtoMessage() {}
 
-func (x *ShortURL) ProtoReflect() protoreflect.Message {
-	mi := &file_url_url_proto_msgTypes[1]
+func (x *SetRulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -97,32 +1374,349 @@ func (x *ShortURL) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ShortURL.ProtoReflect.Descriptor instead.
-func (*ShortURL) Descriptor() ([]byte, []int) {
-	return file_url_url_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use SetRulesRequest.ProtoReflect.Descriptor instead.
+func (*SetRulesRequest) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *ShortURL) GetAlias() string {
+func (x *SetRulesRequest) GetAlias() string {
 	if x != nil {
 		return x.Alias
 	}
 	return ""
 }
 
+func (x *SetRulesRequest) GetRules() []*RoutingRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type Variant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // letters, digits, '-' and '_'; defaults to "A", "B", ... by position on SetVariants
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Weight        int32                  `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"` // share of visitors relative to the other variants' weights, 1 to 1000
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variant) Reset() {
+	*x = Variant{}
+	mi := &file_url_url_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variant) ProtoMessage() {}
+
+func (x *Variant) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variant.ProtoReflect.Descriptor instead.
+func (*Variant) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Variant) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Variant) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Variant) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+type Variants struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Variants      []*Variant             `protobuf:"bytes,1,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variants) Reset() {
+	*x = Variants{}
+	mi := &file_url_url_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variants) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variants) ProtoMessage() {}
+
+func (x *Variants) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variants.ProtoReflect.Descriptor instead.
+func (*Variants) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Variants) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+type SetVariantsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Variants      []*Variant             `protobuf:"bytes,2,rep,name=variants,proto3" json:"variants,omitempty"` // 2 to 10; none removes the link's split
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetVariantsRequest) Reset() {
+	*x = SetVariantsRequest{}
+	mi := &file_url_url_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetVariantsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetVariantsRequest) ProtoMessage() {}
+
+func (x *SetVariantsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_url_url_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetVariantsRequest.ProtoReflect.Descriptor instead.
+func (*SetVariantsRequest) Descriptor() ([]byte, []int) {
+	return file_url_url_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SetVariantsRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *SetVariantsRequest) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
 var File_url_url_proto protoreflect.FileDescriptor
 
 const file_url_url_proto_rawDesc = "" +
 	"\n" +
-	"\rurl/url.proto\x12\x03url\"\x1b\n" +
+	"\rurl/url.proto\x12\x03url\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9a\x04\n" +
 	"\aLongURL\x12\x10\n" +
-	"\x03url\x18\x01 \x01(\tR\x03url\" \n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12!\n" +
+	"\fcustom_alias\x18\x02 \x01(\tR\vcustomAlias\x12\x1f\n" +
+	"\vttl_seconds\x18\x03 \x01(\x03R\n" +
+	"ttlSeconds\x129\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\"\n" +
+	"\finterstitial\x18\x05 \x01(\bR\finterstitial\x12\x18\n" +
+	"\awarning\x18\x06 \x01(\tR\awarning\x12#\n" +
+	"\rredirect_type\x18\a \x01(\x05R\fredirectType\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x14\n" +
+	"\x05title\x18\t \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\n" +
+	" \x01(\tR\vdescription\x12\x12\n" +
+	"\x04tags\x18\v \x03(\tR\x04tags\x12'\n" +
+	"\x06health\x18\f \x01(\v2\x0f.url.LinkHealthR\x06health\x12\x1a\n" +
+	"\bpassword\x18\r \x01(\tR\bpassword\x12\x1d\n" +
+	"\n" +
+	"max_clicks\x18\x0e \x01(\x05R\tmaxClicks\x12\x16\n" +
+	"\x06routed\x18\x0f \x01(\bR\x06routed\x12\x18\n" +
+	"\avariant\x18\x10 \x01(\tR\avariant\"\xa4\x01\n" +
+	"\n" +
+	"LinkHealth\x12B\n" +
+	"\x0flast_checked_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\rlastCheckedAt\x12\x1f\n" +
+	"\vlast_status\x18\x02 \x01(\x05R\n" +
+	"lastStatus\x121\n" +
+	"\x14consecutive_failures\x18\x03 \x01(\x05R\x13consecutiveFailures\"\x81\x02\n" +
 	"\bShortURL\x12\x14\n" +
-	"\x05alias\x18\x01 \x01(\tR\x05alias2_\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\x03 \x01(\tR\tuserAgent\x12'\n" +
+	"\x0faccept_language\x18\x04 \x01(\tR\x0eacceptLanguage\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x05 \x01(\tR\tipAddress\x12\x1d\n" +
+	"\n" +
+	"visitor_id\x18\x06 \x01(\tR\tvisitorId\x12\x1f\n" +
+	"\vlookup_only\x18\a \x01(\bR\n" +
+	"lookupOnly\x12\x1c\n" +
+	"\tconfirmed\x18\b \x01(\bR\tconfirmed\"0\n" +
+	"\fBatchLongURL\x12 \n" +
+	"\x04urls\x18\x01 \x03(\v2\f.url.LongURLR\x04urls\"M\n" +
+	"\vBatchResult\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\";\n" +
+	"\rBatchShortURL\x12*\n" +
+	"\aresults\x18\x01 \x03(\v2\x10.url.BatchResultR\aresults\"E\n" +
+	"\x11DisableURLRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1a\n" +
+	"\bdisabled\x18\x02 \x01(\bR\bdisabled\"\xb0\x01\n" +
+	"\x10UpdateURLRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12'\n" +
+	"\finterstitial\x18\x03 \x01(\bH\x00R\finterstitial\x88\x01\x01\x12(\n" +
+	"\rredirect_type\x18\x04 \x01(\x05H\x01R\fredirectType\x88\x01\x01B\x0f\n" +
+	"\r_interstitialB\x10\n" +
+	"\x0e_redirect_type\"\xc0\x02\n" +
+	"\n" +
+	"URLPreview\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x129\n" +
+	"\n" +
+	"created_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x16\n" +
+	"\x06safety\x18\x04 \x01(\tR\x06safety\x12#\n" +
+	"\rsafety_reason\x18\x05 \x01(\tR\fsafetyReason\x12\x14\n" +
+	"\x05title\x18\x06 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\a \x01(\tR\vdescription\x12\x12\n" +
+	"\x04tags\x18\b \x03(\tR\x04tags\x12!\n" +
+	"\x04page\x18\t \x01(\v2\r.url.PageInfoR\x04page\"\xb2\x01\n" +
+	"\bPageInfo\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x19\n" +
+	"\bog_title\x18\x02 \x01(\tR\aogTitle\x12\x19\n" +
+	"\bog_image\x18\x03 \x01(\tR\aogImage\x12\x1f\n" +
+	"\vstatus_code\x18\x04 \x01(\x05R\n" +
+	"statusCode\x129\n" +
+	"\n" +
+	"fetched_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tfetchedAt\"\xc9\x02\n" +
+	"\x0fListURLsRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x19\n" +
+	"\x05owner\x18\x03 \x01(\tH\x00R\x05owner\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"all_owners\x18\x04 \x01(\bR\tallOwners\x12?\n" +
+	"\rcreated_after\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12A\n" +
+	"\x0ecreated_before\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBefore\x12\x16\n" +
+	"\x06domain\x18\a \x01(\tR\x06domain\x12\x16\n" +
+	"\x06search\x18\b \x01(\tR\x06search\x12\x16\n" +
+	"\x06cursor\x18\t \x01(\tR\x06cursorB\b\n" +
+	"\x06_owner\"\xb3\x04\n" +
+	"\aURLInfo\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1a\n" +
+	"\bdisabled\x18\b \x01(\bR\bdisabled\x12\"\n" +
+	"\finterstitial\x18\t \x01(\bR\finterstitial\x12#\n" +
+	"\rredirect_type\x18\n" +
+	" \x01(\x05R\fredirectType\x12\x14\n" +
+	"\x05owner\x18\v \x01(\tR\x05owner\x12!\n" +
+	"\x04page\x18\f \x01(\v2\r.url.PageInfoR\x04page\x12'\n" +
+	"\x06health\x18\r \x01(\v2\x0f.url.LinkHealthR\x06health\x12-\n" +
+	"\x12password_protected\x18\x0e \x01(\bR\x11passwordProtected\x12\x1d\n" +
+	"\n" +
+	"max_clicks\x18\x0f \x01(\x05R\tmaxClicks\x12)\n" +
+	"\x10clicks_remaining\x18\x10 \x01(\x05R\x0fclicksRemaining\"L\n" +
+	"\aURLList\x12 \n" +
+	"\x04urls\x18\x01 \x03(\v2\f.url.URLInfoR\x04urls\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"x\n" +
+	"\tURLChange\x12\x17\n" +
+	"\aold_url\x18\x01 \x01(\tR\x06oldUrl\x12\x17\n" +
+	"\anew_url\x18\x02 \x01(\tR\x06newUrl\x129\n" +
+	"\n" +
+	"changed_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\"6\n" +
+	"\n" +
+	"URLHistory\x12(\n" +
+	"\achanges\x18\x01 \x03(\v2\x0e.url.URLChangeR\achanges\"y\n" +
+	"\vRoutingRule\x12\x1c\n" +
+	"\tplatforms\x18\x01 \x03(\tR\tplatforms\x12\x1c\n" +
+	"\tlanguages\x18\x02 \x03(\tR\tlanguages\x12\x1c\n" +
+	"\tcountries\x18\x03 \x03(\tR\tcountries\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\"6\n" +
+	"\fRoutingRules\x12&\n" +
+	"\x05rules\x18\x01 \x03(\v2\x10.url.RoutingRuleR\x05rules\"O\n" +
+	"\x0fSetRulesRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12&\n" +
+	"\x05rules\x18\x02 \x03(\v2\x10.url.RoutingRuleR\x05rules\"G\n" +
+	"\aVariant\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x16\n" +
+	"\x06weight\x18\x03 \x01(\x05R\x06weight\"4\n" +
+	"\bVariants\x12(\n" +
+	"\bvariants\x18\x01 \x03(\v2\f.url.VariantR\bvariants\"T\n" +
+	"\x12SetVariantsRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12(\n" +
+	"\bvariants\x18\x02 \x03(\v2\f.url.VariantR\bvariants2\xe2\x05\n" +
 	"\n" +
 	"URLService\x12&\n" +
-	"\aPostURL\x12\f.url.LongURL\x1a\r.url.ShortURL\x12)\n" +
+	"\aPostURL\x12\f.url.LongURL\x1a\r.url.ShortURL\x125\n" +
+	"\fBatchPostURL\x12\x11.url.BatchLongURL\x1a\x12.url.BatchShortURL\x128\n" +
+	"\x12BatchPostURLStream\x12\f.url.LongURL\x1a\x12.url.BatchShortURL(\x01\x12)\n" +
+	"\n" +
+	"GetLongURL\x12\r.url.ShortURL\x1a\f.url.LongURL\x12,\n" +
+	"\n" +
+	"PreviewURL\x12\r.url.ShortURL\x1a\x0f.url.URLPreview\x122\n" +
+	"\tDeleteURL\x12\r.url.ShortURL\x1a\x16.google.protobuf.Empty\x12<\n" +
 	"\n" +
-	"GetLongURL\x12\r.url.ShortURL\x1a\f.url.LongURLB\x13Z\x11backend/proto/urlb\x06proto3"
+	"DisableURL\x12\x16.url.DisableURLRequest\x1a\x16.google.protobuf.Empty\x12:\n" +
+	"\tUpdateURL\x12\x15.url.UpdateURLRequest\x1a\x16.google.protobuf.Empty\x12/\n" +
+	"\rGetURLHistory\x12\r.url.ShortURL\x1a\x0f.url.URLHistory\x12,\n" +
+	"\bGetRules\x12\r.url.ShortURL\x1a\x11.url.RoutingRules\x128\n" +
+	"\bSetRules\x12\x14.url.SetRulesRequest\x1a\x16.google.protobuf.Empty\x12+\n" +
+	"\vGetVariants\x12\r.url.ShortURL\x1a\r.url.Variants\x12>\n" +
+	"\vSetVariants\x12\x17.url.SetVariantsRequest\x1a\x16.google.protobuf.Empty\x12.\n" +
+	"\bListURLs\x12\x14.url.ListURLsRequest\x1a\f.url.URLListB\x13Z\x11backend/proto/urlb\x06proto3"
 
 var (
 	file_url_url_proto_rawDescOnce sync.Once
@@ -136,21 +1730,89 @@ func file_url_url_proto_rawDescGZIP() []byte {
 	return file_url_url_proto_rawDescData
 }
 
-var file_url_url_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_url_url_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
 var file_url_url_proto_goTypes = []any{
-	(*LongURL)(nil),  // 0: url.LongURL
-	(*ShortURL)(nil), // 1: url.ShortURL
+	(*LongURL)(nil),               // 0: url.LongURL
+	(*LinkHealth)(nil),            // 1: url.LinkHealth
+	(*ShortURL)(nil),              // 2: url.ShortURL
+	(*BatchLongURL)(nil),          // 3: url.BatchLongURL
+	(*BatchResult)(nil),           // 4: url.BatchResult
+	(*BatchShortURL)(nil),         // 5: url.BatchShortURL
+	(*DisableURLRequest)(nil),     // 6: url.DisableURLRequest
+	(*UpdateURLRequest)(nil),      // 7: url.UpdateURLRequest
+	(*URLPreview)(nil),            // 8: url.URLPreview
+	(*PageInfo)(nil),              // 9: url.PageInfo
+	(*ListURLsRequest)(nil),       // 10: url.ListURLsRequest
+	(*URLInfo)(nil),               // 11: url.URLInfo
+	(*URLList)(nil),               // 12: url.URLList
+	(*URLChange)(nil),             // 13: url.URLChange
+	(*URLHistory)(nil),            // 14: url.URLHistory
+	(*RoutingRule)(nil),           // 15: url.RoutingRule
+	(*RoutingRules)(nil),          // 16: url.RoutingRules
+	(*SetRulesRequest)(nil),       // 17: url.SetRulesRequest
+	(*Variant)(nil),               // 18: url.Variant
+	(*Variants)(nil),              // 19: url.Variants
+	(*SetVariantsRequest)(nil),    // 20: url.SetVariantsRequest
+	(*timestamppb.Timestamp)(nil), // 21: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 22: google.protobuf.Empty
 }
 var file_url_url_proto_depIdxs = []int32{
-	0, // 0: url.URLService.PostURL:input_type -> url.LongURL
-	1, // 1: url.URLService.GetLongURL:input_type -> url.ShortURL
-	1, // 2: url.URLService.PostURL:output_type -> url.ShortURL
-	0, // 3: url.URLService.GetLongURL:output_type -> url.LongURL
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	21, // 0: url.LongURL.expires_at:type_name -> google.protobuf.Timestamp
+	21, // 1: url.LongURL.created_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: url.LongURL.health:type_name -> url.LinkHealth
+	21, // 3: url.LinkHealth.last_checked_at:type_name -> google.protobuf.Timestamp
+	0,  // 4: url.BatchLongURL.urls:type_name -> url.LongURL
+	4,  // 5: url.BatchShortURL.results:type_name -> url.BatchResult
+	21, // 6: url.URLPreview.created_at:type_name -> google.protobuf.Timestamp
+	21, // 7: url.URLPreview.expires_at:type_name -> google.protobuf.Timestamp
+	9,  // 8: url.URLPreview.page:type_name -> url.PageInfo
+	21, // 9: url.PageInfo.fetched_at:type_name -> google.protobuf.Timestamp
+	21, // 10: url.ListURLsRequest.created_after:type_name -> google.protobuf.Timestamp
+	21, // 11: url.ListURLsRequest.created_before:type_name -> google.protobuf.Timestamp
+	21, // 12: url.URLInfo.created_at:type_name -> google.protobuf.Timestamp
+	21, // 13: url.URLInfo.expires_at:type_name -> google.protobuf.Timestamp
+	9,  // 14: url.URLInfo.page:type_name -> url.PageInfo
+	1,  // 15: url.URLInfo.health:type_name -> url.LinkHealth
+	11, // 16: url.URLList.urls:type_name -> url.URLInfo
+	21, // 17: url.URLChange.changed_at:type_name -> google.protobuf.Timestamp
+	13, // 18: url.URLHistory.changes:type_name -> url.URLChange
+	15, // 19: url.RoutingRules.rules:type_name -> url.RoutingRule
+	15, // 20: url.SetRulesRequest.rules:type_name -> url.RoutingRule
+	18, // 21: url.Variants.variants:type_name -> url.Variant
+	18, // 22: url.SetVariantsRequest.variants:type_name -> url.Variant
+	0,  // 23: url.URLService.PostURL:input_type -> url.LongURL
+	3,  // 24: url.URLService.BatchPostURL:input_type -> url.BatchLongURL
+	0,  // 25: url.URLService.BatchPostURLStream:input_type -> url.LongURL
+	2,  // 26: url.URLService.GetLongURL:input_type -> url.ShortURL
+	2,  // 27: url.URLService.PreviewURL:input_type -> url.ShortURL
+	2,  // 28: url.URLService.DeleteURL:input_type -> url.ShortURL
+	6,  // 29: url.URLService.DisableURL:input_type -> url.DisableURLRequest
+	7,  // 30: url.URLService.UpdateURL:input_type -> url.UpdateURLRequest
+	2,  // 31: url.URLService.GetURLHistory:input_type -> url.ShortURL
+	2,  // 32: url.URLService.GetRules:input_type -> url.ShortURL
+	17, // 33: url.URLService.SetRules:input_type -> url.SetRulesRequest
+	2,  // 34: url.URLService.GetVariants:input_type -> url.ShortURL
+	20, // 35: url.URLService.SetVariants:input_type -> url.SetVariantsRequest
+	10, // 36: url.URLService.ListURLs:input_type -> url.ListURLsRequest
+	2,  // 37: url.URLService.PostURL:output_type -> url.ShortURL
+	5,  // 38: url.URLService.BatchPostURL:output_type -> url.BatchShortURL
+	5,  // 39: url.URLService.BatchPostURLStream:output_type -> url.BatchShortURL
+	0,  // 40: url.URLService.GetLongURL:output_type -> url.LongURL
+	8,  // 41: url.URLService.PreviewURL:output_type -> url.URLPreview
+	22, // 42: url.URLService.DeleteURL:output_type -> google.protobuf.Empty
+	22, // 43: url.URLService.DisableURL:output_type -> google.protobuf.Empty
+	22, // 44: url.URLService.UpdateURL:output_type -> google.protobuf.Empty
+	14, // 45: url.URLService.GetURLHistory:output_type -> url.URLHistory
+	16, // 46: url.URLService.GetRules:output_type -> url.RoutingRules
+	22, // 47: url.URLService.SetRules:output_type -> google.protobuf.Empty
+	19, // 48: url.URLService.GetVariants:output_type -> url.Variants
+	22, // 49: url.URLService.SetVariants:output_type -> google.protobuf.Empty
+	12, // 50: url.URLService.ListURLs:output_type -> url.URLList
+	37, // [37:51] is the sub-list for method output_type
+	23, // [23:37] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_url_url_proto_init() }
@@ -158,13 +1820,15 @@ func file_url_url_proto_init() {
 	if File_url_url_proto != nil {
 		return
 	}
+	file_url_url_proto_msgTypes[7].OneofWrappers = []any{}
+	file_url_url_proto_msgTypes[10].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_url_url_proto_rawDesc), len(file_url_url_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
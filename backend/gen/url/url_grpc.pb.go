@@ -11,6 +11,7 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -19,8 +20,20 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	URLService_PostURL_FullMethodName    = "/url.URLService/PostURL"
-	URLService_GetLongURL_FullMethodName = "/url.URLService/GetLongURL"
+	URLService_PostURL_FullMethodName            = "/url.URLService/PostURL"
+	URLService_BatchPostURL_FullMethodName       = "/url.URLService/BatchPostURL"
+	URLService_BatchPostURLStream_FullMethodName = "/url.URLService/BatchPostURLStream"
+	URLService_GetLongURL_FullMethodName         = "/url.URLService/GetLongURL"
+	URLService_PreviewURL_FullMethodName         = "/url.URLService/PreviewURL"
+	URLService_DeleteURL_FullMethodName          = "/url.URLService/DeleteURL"
+	URLService_DisableURL_FullMethodName         = "/url.URLService/DisableURL"
+	URLService_UpdateURL_FullMethodName          = "/url.URLService/UpdateURL"
+	URLService_GetURLHistory_FullMethodName      = "/url.URLService/GetURLHistory"
+	URLService_GetRules_FullMethodName           = "/url.URLService/GetRules"
+	URLService_SetRules_FullMethodName           = "/url.URLService/SetRules"
+	URLService_GetVariants_FullMethodName        = "/url.URLService/GetVariants"
+	URLService_SetVariants_FullMethodName        = "/url.URLService/SetVariants"
+	URLService_ListURLs_FullMethodName           = "/url.URLService/ListURLs"
 )
 
 // URLServiceClient is the client API for URLService service.
@@ -28,7 +41,22 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type URLServiceClient interface {
 	PostURL(ctx context.Context, in *LongURL, opts ...grpc.CallOption) (*ShortURL, error)
+	BatchPostURL(ctx context.Context, in *BatchLongURL, opts ...grpc.CallOption) (*BatchShortURL, error)
+	BatchPostURLStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[LongURL, BatchShortURL], error)
+	// storing some urls, their results come back, with one
+	// failed result per url received but not stored
 	GetLongURL(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*LongURL, error)
+	// ResourceExhausted while it is locked after wrong passwords
+	PreviewURL(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*URLPreview, error)
+	DeleteURL(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DisableURL(ctx context.Context, in *DisableURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetURLHistory(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*URLHistory, error)
+	GetRules(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*RoutingRules, error)
+	SetRules(ctx context.Context, in *SetRulesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetVariants(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*Variants, error)
+	SetVariants(ctx context.Context, in *SetVariantsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ListURLs(ctx context.Context, in *ListURLsRequest, opts ...grpc.CallOption) (*URLList, error)
 }
 
 type uRLServiceClient struct {
@@ -49,6 +77,29 @@ func (c *uRLServiceClient) PostURL(ctx context.Context, in *LongURL, opts ...grp
 	return out, nil
 }
 
+func (c *uRLServiceClient) BatchPostURL(ctx context.Context, in *BatchLongURL, opts ...grpc.CallOption) (*BatchShortURL, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchShortURL)
+	err := c.cc.Invoke(ctx, URLService_BatchPostURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) BatchPostURLStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[LongURL, BatchShortURL], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &URLService_ServiceDesc.Streams[0], URLService_BatchPostURLStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LongURL, BatchShortURL]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URLService_BatchPostURLStreamClient = grpc.ClientStreamingClient[LongURL, BatchShortURL]
+
 func (c *uRLServiceClient) GetLongURL(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*LongURL, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(LongURL)
@@ -59,12 +110,127 @@ func (c *uRLServiceClient) GetLongURL(ctx context.Context, in *ShortURL, opts ..
 	return out, nil
 }
 
+func (c *uRLServiceClient) PreviewURL(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*URLPreview, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLPreview)
+	err := c.cc.Invoke(ctx, URLService_PreviewURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) DeleteURL(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, URLService_DeleteURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) DisableURL(ctx context.Context, in *DisableURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, URLService_DisableURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, URLService_UpdateURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) GetURLHistory(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*URLHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLHistory)
+	err := c.cc.Invoke(ctx, URLService_GetURLHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) GetRules(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*RoutingRules, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RoutingRules)
+	err := c.cc.Invoke(ctx, URLService_GetRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) SetRules(ctx context.Context, in *SetRulesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, URLService_SetRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) GetVariants(ctx context.Context, in *ShortURL, opts ...grpc.CallOption) (*Variants, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Variants)
+	err := c.cc.Invoke(ctx, URLService_GetVariants_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) SetVariants(ctx context.Context, in *SetVariantsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, URLService_SetVariants_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) ListURLs(ctx context.Context, in *ListURLsRequest, opts ...grpc.CallOption) (*URLList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLList)
+	err := c.cc.Invoke(ctx, URLService_ListURLs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // URLServiceServer is the server API for URLService service.
 // All implementations must embed UnimplementedURLServiceServer
 // for forward compatibility.
 type URLServiceServer interface {
 	PostURL(context.Context, *LongURL) (*ShortURL, error)
+	BatchPostURL(context.Context, *BatchLongURL) (*BatchShortURL, error)
+	BatchPostURLStream(grpc.ClientStreamingServer[LongURL, BatchShortURL]) error
+	// storing some urls, their results come back, with one
+	// failed result per url received but not stored
 	GetLongURL(context.Context, *ShortURL) (*LongURL, error)
+	// ResourceExhausted while it is locked after wrong passwords
+	PreviewURL(context.Context, *ShortURL) (*URLPreview, error)
+	DeleteURL(context.Context, *ShortURL) (*emptypb.Empty, error)
+	DisableURL(context.Context, *DisableURLRequest) (*emptypb.Empty, error)
+	UpdateURL(context.Context, *UpdateURLRequest) (*emptypb.Empty, error)
+	GetURLHistory(context.Context, *ShortURL) (*URLHistory, error)
+	GetRules(context.Context, *ShortURL) (*RoutingRules, error)
+	SetRules(context.Context, *SetRulesRequest) (*emptypb.Empty, error)
+	GetVariants(context.Context, *ShortURL) (*Variants, error)
+	SetVariants(context.Context, *SetVariantsRequest) (*emptypb.Empty, error)
+	ListURLs(context.Context, *ListURLsRequest) (*URLList, error)
 	mustEmbedUnimplementedURLServiceServer()
 }
 
@@ -78,9 +244,45 @@ type UnimplementedURLServiceServer struct{}
 func (UnimplementedURLServiceServer) PostURL(context.Context, *LongURL) (*ShortURL, error) {
 	return nil, status.Error(codes.Unimplemented, "method PostURL not implemented")
 }
+func (UnimplementedURLServiceServer) BatchPostURL(context.Context, *BatchLongURL) (*BatchShortURL, error) {
+	return nil, status.Error(codes.Unimplemented, "method BatchPostURL not implemented")
+}
+func (UnimplementedURLServiceServer) BatchPostURLStream(grpc.ClientStreamingServer[LongURL, BatchShortURL]) error {
+	return status.Error(codes.Unimplemented, "method BatchPostURLStream not implemented")
+}
 func (UnimplementedURLServiceServer) GetLongURL(context.Context, *ShortURL) (*LongURL, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetLongURL not implemented")
 }
+func (UnimplementedURLServiceServer) PreviewURL(context.Context, *ShortURL) (*URLPreview, error) {
+	return nil, status.Error(codes.Unimplemented, "method PreviewURL not implemented")
+}
+func (UnimplementedURLServiceServer) DeleteURL(context.Context, *ShortURL) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteURL not implemented")
+}
+func (UnimplementedURLServiceServer) DisableURL(context.Context, *DisableURLRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method DisableURL not implemented")
+}
+func (UnimplementedURLServiceServer) UpdateURL(context.Context, *UpdateURLRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateURL not implemented")
+}
+func (UnimplementedURLServiceServer) GetURLHistory(context.Context, *ShortURL) (*URLHistory, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetURLHistory not implemented")
+}
+func (UnimplementedURLServiceServer) GetRules(context.Context, *ShortURL) (*RoutingRules, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRules not implemented")
+}
+func (UnimplementedURLServiceServer) SetRules(context.Context, *SetRulesRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetRules not implemented")
+}
+func (UnimplementedURLServiceServer) GetVariants(context.Context, *ShortURL) (*Variants, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetVariants not implemented")
+}
+func (UnimplementedURLServiceServer) SetVariants(context.Context, *SetVariantsRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetVariants not implemented")
+}
+func (UnimplementedURLServiceServer) ListURLs(context.Context, *ListURLsRequest) (*URLList, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListURLs not implemented")
+}
 func (UnimplementedURLServiceServer) mustEmbedUnimplementedURLServiceServer() {}
 func (UnimplementedURLServiceServer) testEmbeddedByValue()                    {}
 
@@ -120,6 +322,31 @@ func _URLService_PostURL_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _URLService_BatchPostURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchLongURL)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).BatchPostURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_BatchPostURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).BatchPostURL(ctx, req.(*BatchLongURL))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_BatchPostURLStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(URLServiceServer).BatchPostURLStream(&grpc.GenericServerStream[LongURL, BatchShortURL]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URLService_BatchPostURLStreamServer = grpc.ClientStreamingServer[LongURL, BatchShortURL]
+
 func _URLService_GetLongURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ShortURL)
 	if err := dec(in); err != nil {
@@ -138,6 +365,186 @@ func _URLService_GetLongURL_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _URLService_PreviewURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortURL)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).PreviewURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_PreviewURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).PreviewURL(ctx, req.(*ShortURL))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_DeleteURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortURL)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).DeleteURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_DeleteURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).DeleteURL(ctx, req.(*ShortURL))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_DisableURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).DisableURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_DisableURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).DisableURL(ctx, req.(*DisableURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_UpdateURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).UpdateURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_UpdateURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).UpdateURL(ctx, req.(*UpdateURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_GetURLHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortURL)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).GetURLHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_GetURLHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).GetURLHistory(ctx, req.(*ShortURL))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_GetRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortURL)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).GetRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_GetRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).GetRules(ctx, req.(*ShortURL))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_SetRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).SetRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_SetRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).SetRules(ctx, req.(*SetRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_GetVariants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortURL)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).GetVariants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_GetVariants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).GetVariants(ctx, req.(*ShortURL))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_SetVariants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetVariantsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).SetVariants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_SetVariants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).SetVariants(ctx, req.(*SetVariantsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_ListURLs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListURLsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).ListURLs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_ListURLs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).ListURLs(ctx, req.(*ListURLsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // URLService_ServiceDesc is the grpc.ServiceDesc for URLService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -149,11 +556,61 @@ var URLService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PostURL",
 			Handler:    _URLService_PostURL_Handler,
 		},
+		{
+			MethodName: "BatchPostURL",
+			Handler:    _URLService_BatchPostURL_Handler,
+		},
 		{
 			MethodName: "GetLongURL",
 			Handler:    _URLService_GetLongURL_Handler,
 		},
+		{
+			MethodName: "PreviewURL",
+			Handler:    _URLService_PreviewURL_Handler,
+		},
+		{
+			MethodName: "DeleteURL",
+			Handler:    _URLService_DeleteURL_Handler,
+		},
+		{
+			MethodName: "DisableURL",
+			Handler:    _URLService_DisableURL_Handler,
+		},
+		{
+			MethodName: "UpdateURL",
+			Handler:    _URLService_UpdateURL_Handler,
+		},
+		{
+			MethodName: "GetURLHistory",
+			Handler:    _URLService_GetURLHistory_Handler,
+		},
+		{
+			MethodName: "GetRules",
+			Handler:    _URLService_GetRules_Handler,
+		},
+		{
+			MethodName: "SetRules",
+			Handler:    _URLService_SetRules_Handler,
+		},
+		{
+			MethodName: "GetVariants",
+			Handler:    _URLService_GetVariants_Handler,
+		},
+		{
+			MethodName: "SetVariants",
+			Handler:    _URLService_SetVariants_Handler,
+		},
+		{
+			MethodName: "ListURLs",
+			Handler:    _URLService_ListURLs_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchPostURLStream",
+			Handler:       _URLService_BatchPostURLStream_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "url/url.proto",
 }
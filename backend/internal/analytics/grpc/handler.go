@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	pb "zipit/gen/analytics"
+	"zipit/internal/analytics/repository"
+	"zipit/internal/analytics/service"
+
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type AnalyticsHandler struct {
+	pb.UnimplementedAnalyticsServiceServer
+	svc service.AnalyticsService
+}
+
+func NewAnalyticsHandler(svc service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{svc: svc}
+}
+
+func (h *AnalyticsHandler) TrackClick(ctx context.Context, req *pb.ClickData) (*emptypb.Empty, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+
+	click := &repository.Click{
+		ShortCode: req.Alias,
+		IPAddress: req.IpAddress,
+		UserAgent: req.UserAgent,
+		Variant:   req.Variant,
+	}
+	if req.Timestamp != nil {
+		click.ClickedAt = req.Timestamp.AsTime()
+	}
+
+	if err := h.svc.TrackClick(ctx, click); err != nil {
+		if errors.Is(err, service.ErrInvalidClick) {
+			return nil, status.Error(codes.InvalidArgument, "invalid click")
+		}
+		return nil, status.Error(codes.Internal, "failed to track click")
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "zipit/gen/analytics"
+	"zipit/internal/analytics/repository"
+	"zipit/internal/analytics/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type mockAnalyticsService struct {
+	trackClickFunc func(ctx context.Context, click *repository.Click) error
+}
+
+func (m *mockAnalyticsService) TrackClick(ctx context.Context, click *repository.Click) error {
+	return m.trackClickFunc(ctx, click)
+}
+
+func TestTrackClick(t *testing.T) {
+	clickedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		req      *pb.ClickData
+		mockErr  error
+		wantCode codes.Code
+	}{
+		{
+			name: "Success",
+			req: &pb.ClickData{
+				Alias:     "abcde",
+				IpAddress: "203.0.113.7",
+				UserAgent: "curl/8.0",
+				Variant:   "B",
+				Timestamp: timestamppb.New(clickedAt),
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "Nil Request",
+			req:      nil,
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Empty Alias",
+			req:      &pb.ClickData{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Service Returns ErrInvalidClick",
+			req:      &pb.ClickData{Alias: "abcde"},
+			mockErr:  service.ErrInvalidClick,
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Service Returns Internal Error",
+			req:      &pb.ClickData{Alias: "abcde"},
+			mockErr:  errors.New("db down"),
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *repository.Click
+			mock := &mockAnalyticsService{
+				trackClickFunc: func(ctx context.Context, click *repository.Click) error {
+					got = click
+					return tt.mockErr
+				},
+			}
+			h := NewAnalyticsHandler(mock)
+			_, err := h.TrackClick(context.Background(), tt.req)
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("expected code %s, got %s (err=%v)", tt.wantCode, code, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+			if got.ShortCode != tt.req.Alias || got.IPAddress != tt.req.IpAddress || got.UserAgent != tt.req.UserAgent || got.Variant != tt.req.Variant {
+				t.Errorf("unexpected click passed to service: %+v", got)
+			}
+			if !got.ClickedAt.Equal(clickedAt) {
+				t.Errorf("expected clicked_at %v, got %v", clickedAt, got.ClickedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// MockRepo for testing service logic
+type MockRepo struct {
+	CreateClickFunc func(ctx context.Context, click *Click) error
+}
+
+// CreateClick implements [ClickRepository].
+func (m *MockRepo) CreateClick(ctx context.Context, click *Click) error {
+	if m.CreateClickFunc != nil {
+		return m.CreateClickFunc(ctx, click)
+	}
+	return fmt.Errorf("some error creating click") // database error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"zipit/pkg/database"
+)
+
+// postgresRepository implements the ClickRepository interface using a PostgreSQL database.
+type postgresRepository struct {
+	db *database.Database
+}
+
+// CreateClick inserts a new click event.
+func (pgRepo *postgresRepository) CreateClick(ctx context.Context, click *Click) error {
+	query := `INSERT INTO clicks (short_code, ip_address, user_agent, variant, clicked_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := pgRepo.db.Conn.ExecContext(ctx, query, click.ShortCode, click.IPAddress, click.UserAgent, click.Variant, click.ClickedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert click: %w", err)
+	}
+	return nil
+}
+
+// NewPostgresRepository constructor for creating a new repository instance.
+func NewPostgresRepository(db *database.Database) ClickRepository {
+	return &postgresRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+	"zipit/pkg/config"
+	"zipit/pkg/database"
+)
+
+var keyMap = [][]string{
+	{"DB_PORT", "5432"},
+	{"DB_USER", "test_user"},
+	{"DB_PASSWORD", "test_password"},
+	{"DB_NAME", "test_db"},
+	{"DB_HOST", "localhost"},
+}
+
+func TestPostgresRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping Postgres test in short mode")
+	}
+
+	// setup test env variables
+	os.Clearenv()
+	for _, envPair := range keyMap {
+		os.Setenv(envPair[0], envPair[1])
+	}
+	config, err := config.NewDBConfig()
+	if err != nil {
+		t.Fatalf("failed to setup test env %v", err)
+	}
+	db, err := database.NewDatabase(config)
+	if err != nil {
+		t.Fatalf("failed to setup test db %v", err)
+	}
+	defer db.Close()
+
+	setupSchema(t, db)
+
+	repo := NewPostgresRepository(db)
+
+	t.Run("Create Click", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		click := &Click{
+			ShortCode: "abc123",
+			IPAddress: "203.0.113.7",
+			UserAgent: "Mozilla/5.0",
+			Variant:   "B",
+			ClickedAt: time.Now().UTC(),
+		}
+		if err := repo.CreateClick(ctx, click); err != nil {
+			t.Fatalf("CreateClick failed: %v", err)
+		}
+
+		var count int
+		err := db.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM clicks WHERE short_code = $1 AND variant = $2", click.ShortCode, click.Variant).Scan(&count)
+		if err != nil || count != 1 {
+			t.Errorf("expected 1 stored click, got %d, err=%v", count, err)
+		}
+	})
+}
+
+// setupSchema ensures the database table exists.
+func setupSchema(t *testing.T, db *database.Database) {
+	schema := `
+	CREATE TABLE IF NOT EXISTS clicks (
+		id BIGSERIAL PRIMARY KEY,
+		short_code VARCHAR(12) NOT NULL,
+		ip_address TEXT,
+		user_agent TEXT,
+		variant TEXT NOT NULL DEFAULT '',
+		clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err := db.Conn.Exec(schema)
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+}
+
+// cleanup clears the clicks table and resets the auto-increment ID counter.
+func cleanup(t *testing.T, db *database.Database) {
+	_, err := db.Conn.Exec("TRUNCATE TABLE clicks RESTART IDENTITY")
+	if err != nil {
+		t.Fatalf("failed to cleanup database: %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Click is a single resolution of a short link. Variant names the A/B
+// variant of the link the visitor was sent to, or is "" if it has none.
+type Click struct {
+	ShortCode string
+	IPAddress string
+	UserAgent string
+	Variant   string
+	ClickedAt time.Time
+}
+
+// ClickRepository persists click events recorded by the analytics service.
+//
+// CreateClick stores the given click. It returns an error if the click could
+// not be written.
+type ClickRepository interface {
+	CreateClick(ctx context.Context, click *Click) error
+}
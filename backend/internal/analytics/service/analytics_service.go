@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"time"
+	"zipit/internal/analytics/repository"
+)
+
+type analyticsSvc struct {
+	repo repository.ClickRepository
+}
+
+// TrackClick implements [AnalyticsService].
+func (svc *analyticsSvc) TrackClick(ctx context.Context, click *repository.Click) error {
+	if click == nil || click.ShortCode == "" {
+		return ErrInvalidClick
+	}
+	if click.ClickedAt.IsZero() {
+		click.ClickedAt = time.Now()
+	}
+	click.ClickedAt = click.ClickedAt.UTC()
+
+	if err := svc.repo.CreateClick(ctx, click); err != nil {
+		return ErrDatabaseWrite
+	}
+	return nil
+}
+
+func NewAnalyticsSvc(repo repository.ClickRepository) AnalyticsService {
+	return &analyticsSvc{
+		repo: repo,
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"zipit/internal/analytics/repository"
+)
+
+func TestAnalyticsSvc_TrackClick_Success(t *testing.T) {
+	var stored *repository.Click
+	mockRepo := &repository.MockRepo{
+		CreateClickFunc: func(ctx context.Context, click *repository.Click) error {
+			stored = click
+			return nil
+		},
+	}
+	svc := NewAnalyticsSvc(mockRepo)
+
+	clickedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := svc.TrackClick(context.Background(), &repository.Click{
+		ShortCode: "abc",
+		IPAddress: "203.0.113.7",
+		UserAgent: "curl/8.0",
+		ClickedAt: clickedAt,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stored == nil || stored.ShortCode != "abc" || stored.IPAddress != "203.0.113.7" || stored.UserAgent != "curl/8.0" {
+		t.Errorf("Unexpected stored click %+v", stored)
+	}
+	if !stored.ClickedAt.Equal(clickedAt) {
+		t.Errorf("Expected clicked_at %v, got %v", clickedAt, stored.ClickedAt)
+	}
+}
+
+func TestAnalyticsSvc_TrackClick_DefaultsTimestamp(t *testing.T) {
+	var stored *repository.Click
+	mockRepo := &repository.MockRepo{
+		CreateClickFunc: func(ctx context.Context, click *repository.Click) error {
+			stored = click
+			return nil
+		},
+	}
+	svc := NewAnalyticsSvc(mockRepo)
+
+	before := time.Now()
+	if err := svc.TrackClick(context.Background(), &repository.Click{ShortCode: "abc"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stored.ClickedAt.Before(before) {
+		t.Errorf("Expected clicked_at to default to now, got %v", stored.ClickedAt)
+	}
+}
+
+func TestAnalyticsSvc_TrackClick_MissingShortCode(t *testing.T) {
+	svc := NewAnalyticsSvc(&repository.MockRepo{})
+	err := svc.TrackClick(context.Background(), &repository.Click{})
+	if !errors.Is(err, ErrInvalidClick) {
+		t.Errorf("Expected ErrInvalidClick, got %v", err)
+	}
+}
+
+func TestAnalyticsSvc_TrackClick_DatabaseError(t *testing.T) {
+	svc := NewAnalyticsSvc(&repository.MockRepo{})
+	err := svc.TrackClick(context.Background(), &repository.Click{ShortCode: "abc"})
+	if !errors.Is(err, ErrDatabaseWrite) {
+		t.Errorf("Expected ErrDatabaseWrite, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"zipit/internal/analytics/repository"
+)
+
+var (
+	ErrInvalidClick  = errors.New("invalid click")
+	ErrDatabaseWrite = errors.New("error writing to database")
+)
+
+// AnalyticsService defines the interface for recording link usage.
+//
+// TrackClick records a single resolution of a short link.
+// Parameters:
+//   - ctx: Context for request cancellation and timeouts
+//   - click: The click to record; a zero ClickedAt means now
+//
+// Returns:
+//   - error: An error if the click is invalid or could not be stored
+type AnalyticsService interface {
+	TrackClick(ctx context.Context, click *repository.Click) error
+}
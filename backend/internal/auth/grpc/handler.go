@@ -0,0 +1,35 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	pb "zipit/gen/auth"
+	"zipit/internal/auth/service"
+
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+type AuthHandler struct {
+	pb.UnimplementedAuthServiceServer
+	svc service.AuthService
+}
+
+func NewAuthHandler(svc service.AuthService) *AuthHandler {
+	return &AuthHandler{svc: svc}
+}
+
+func (h *AuthHandler) Authenticate(ctx context.Context, req *pb.APIKey) (*pb.Identity, error) {
+	if req == nil || req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	owner, err := h.svc.Authenticate(ctx, req.Key)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		return nil, status.Error(codes.Internal, "failed to authenticate")
+	}
+	return &pb.Identity{Owner: owner}, nil
+}
@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "zipit/gen/auth"
+	"zipit/internal/auth/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type mockAuthService struct {
+	authenticateFunc func(ctx context.Context, key string) (string, error)
+}
+
+func (m *mockAuthService) Authenticate(ctx context.Context, key string) (string, error) {
+	return m.authenticateFunc(ctx, key)
+}
+
+func (m *mockAuthService) CreateAPIKey(ctx context.Context, owner, name string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *pb.APIKey
+		mockOwner string
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{
+			name:      "Success",
+			req:       &pb.APIKey{Key: "zk_valid"},
+			mockOwner: "acme",
+			wantCode:  codes.OK,
+		},
+		{
+			name:     "Nil Request",
+			req:      nil,
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Empty Key",
+			req:      &pb.APIKey{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Service Returns ErrInvalidKey",
+			req:      &pb.APIKey{Key: "zk_revoked"},
+			mockErr:  service.ErrInvalidKey,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Service Returns Internal Error",
+			req:      &pb.APIKey{Key: "zk_valid"},
+			mockErr:  errors.New("db down"),
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockAuthService{
+				authenticateFunc: func(ctx context.Context, key string) (string, error) {
+					return tt.mockOwner, tt.mockErr
+				},
+			}
+			h := NewAuthHandler(mock)
+			resp, err := h.Authenticate(context.Background(), tt.req)
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("expected code %s, got %s (err=%v)", tt.wantCode, code, err)
+			}
+			if tt.wantCode == codes.OK && resp.Owner != tt.mockOwner {
+				t.Errorf("expected owner %s, got %s", tt.mockOwner, resp.Owner)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// MockRepo for testing service logic
+type MockRepo struct {
+	CreateAPIKeyFunc    func(ctx context.Context, key *APIKey) error
+	GetAPIKeyByHashFunc func(ctx context.Context, keyHash string) (*APIKey, error)
+}
+
+// CreateAPIKey implements [APIKeyRepository].
+func (m *MockRepo) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(ctx, key)
+	}
+	return fmt.Errorf("some error creating api key") // database error
+}
+
+// GetAPIKeyByHash implements [APIKeyRepository].
+func (m *MockRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	if m.GetAPIKeyByHashFunc != nil {
+		return m.GetAPIKeyByHashFunc(ctx, keyHash)
+	}
+	return nil, fmt.Errorf("some error fetching api key") // database error
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"zipit/pkg/database"
+)
+
+// postgresRepository implements the APIKeyRepository interface using a PostgreSQL database.
+type postgresRepository struct {
+	db *database.Database
+}
+
+// CreateAPIKey inserts a new API key.
+func (pgRepo *postgresRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	query := `INSERT INTO api_keys (key_hash, owner, name) VALUES ($1, $2, $3) RETURNING id, created_at`
+
+	err := pgRepo.db.Conn.QueryRowContext(ctx, query, key.KeyHash, key.Owner, key.Name).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the API key with the given digest.
+func (pgRepo *postgresRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	key := &APIKey{}
+	var revokedAt sql.NullTime
+	query := "SELECT id, key_hash, owner, name, created_at, revoked_at FROM api_keys WHERE key_hash = $1"
+
+	err := pgRepo.db.Conn.QueryRowContext(ctx, query, keyHash).Scan(&key.ID, &key.KeyHash, &key.Owner, &key.Name, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key does not exist: %w", sql.ErrNoRows)
+		}
+		return nil, fmt.Errorf("failed to retrieve api key: %w", err)
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
+
+// NewPostgresRepository constructor for creating a new repository instance.
+func NewPostgresRepository(db *database.Database) APIKeyRepository {
+	return &postgresRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"zipit/pkg/config"
+	"zipit/pkg/database"
+)
+
+var keyMap = [][]string{
+	{"DB_PORT", "5432"},
+	{"DB_USER", "test_user"},
+	{"DB_PASSWORD", "test_password"},
+	{"DB_NAME", "test_db"},
+	{"DB_HOST", "localhost"},
+}
+
+func TestPostgresRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping Postgres test in short mode")
+	}
+
+	// setup test env variables
+	os.Clearenv()
+	for _, envPair := range keyMap {
+		os.Setenv(envPair[0], envPair[1])
+	}
+	config, err := config.NewDBConfig()
+	if err != nil {
+		t.Fatalf("failed to setup test env %v", err)
+	}
+	db, err := database.NewDatabase(config)
+	if err != nil {
+		t.Fatalf("failed to setup test db %v", err)
+	}
+	defer db.Close()
+
+	setupSchema(t, db)
+
+	repo := NewPostgresRepository(db)
+
+	t.Run("Create and Get", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		key := &APIKey{KeyHash: strings.Repeat("a", 64), Owner: "acme", Name: "ci"}
+		if err := repo.CreateAPIKey(ctx, key); err != nil {
+			t.Fatalf("CreateAPIKey failed: %v", err)
+		}
+		if key.ID == 0 || key.CreatedAt.IsZero() {
+			t.Errorf("expected id and created_at to be set, got %+v", key)
+		}
+
+		got, err := repo.GetAPIKeyByHash(ctx, key.KeyHash)
+		if err != nil {
+			t.Fatalf("GetAPIKeyByHash failed: %v", err)
+		}
+		if got.Owner != "acme" || got.Name != "ci" || got.RevokedAt != nil {
+			t.Errorf("unexpected key %+v", got)
+		}
+
+		// Digests are unique
+		if err := repo.CreateAPIKey(ctx, &APIKey{KeyHash: key.KeyHash, Owner: "other"}); err == nil {
+			t.Error("expected duplicate digest to fail")
+		}
+	})
+
+	t.Run("Unknown Key", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := repo.GetAPIKeyByHash(ctx, strings.Repeat("b", 64)); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+}
+
+// setupSchema ensures the database table exists.
+func setupSchema(t *testing.T, db *database.Database) {
+	schema := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id BIGSERIAL PRIMARY KEY,
+		key_hash CHAR(64) NOT NULL UNIQUE,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		revoked_at TIMESTAMP WITH TIME ZONE
+	);`
+
+	_, err := db.Conn.Exec(schema)
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+}
+
+// cleanup clears the api_keys table and resets the auto-increment ID counter.
+func cleanup(t *testing.T, db *database.Database) {
+	_, err := db.Conn.Exec("TRUNCATE TABLE api_keys RESTART IDENTITY")
+	if err != nil {
+		t.Fatalf("failed to cleanup database: %v", err)
+	}
+}
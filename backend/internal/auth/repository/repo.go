@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// APIKey is a stored API key. Only the SHA-256 hex digest of the key is kept.
+// A non-nil RevokedAt means the key no longer authenticates.
+type APIKey struct {
+	ID        int64
+	KeyHash   string
+	Owner     string
+	Name      string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// APIKeyRepository persists API keys used to authenticate gateway callers.
+//
+// CreateAPIKey stores the given key.
+//
+// GetAPIKeyByHash returns the key with the given digest, whether or not it has
+// been revoked. If no key is found, the returned error wraps sql.ErrNoRows.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+}
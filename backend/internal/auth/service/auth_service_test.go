@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"zipit/internal/auth/repository"
+)
+
+func TestAuthSvc_CreateAndAuthenticate(t *testing.T) {
+	keys := map[string]*repository.APIKey{}
+	mockRepo := &repository.MockRepo{
+		CreateAPIKeyFunc: func(ctx context.Context, key *repository.APIKey) error {
+			keys[key.KeyHash] = key
+			return nil
+		},
+		GetAPIKeyByHashFunc: func(ctx context.Context, keyHash string) (*repository.APIKey, error) {
+			if key, ok := keys[keyHash]; ok {
+				return key, nil
+			}
+			return nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)
+		},
+	}
+	svc := NewAuthSvc(mockRepo)
+
+	key, err := svc.CreateAPIKey(context.Background(), "acme", "ci")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(key, "zk_") {
+		t.Errorf("Expected key with zk_ prefix, got %s", key)
+	}
+	for hash, stored := range keys {
+		if strings.Contains(hash, key) || stored.Owner != "acme" || stored.Name != "ci" {
+			t.Errorf("Unexpected stored key %+v", stored)
+		}
+	}
+
+	owner, err := svc.Authenticate(context.Background(), key)
+	if err != nil || owner != "acme" {
+		t.Errorf("Expected owner acme, got %q, err=%v", owner, err)
+	}
+
+	// Keys are unique
+	other, _ := svc.CreateAPIKey(context.Background(), "acme", "ci")
+	if other == key {
+		t.Error("Expected a different key on each call")
+	}
+}
+
+func TestAuthSvc_CreateAPIKey_Errors(t *testing.T) {
+	svc := NewAuthSvc(&repository.MockRepo{})
+
+	if _, err := svc.CreateAPIKey(context.Background(), " ", "ci"); !errors.Is(err, ErrInvalidOwner) {
+		t.Errorf("Expected ErrInvalidOwner, got %v", err)
+	}
+	if _, err := svc.CreateAPIKey(context.Background(), "acme", "ci"); !errors.Is(err, ErrDatabaseWrite) {
+		t.Errorf("Expected ErrDatabaseWrite, got %v", err)
+	}
+}
+
+func TestAuthSvc_Authenticate_Errors(t *testing.T) {
+	revokedAt := time.Now()
+	tests := []struct {
+		name    string
+		key     string
+		repoKey *repository.APIKey
+		repoErr error
+		wantErr error
+	}{
+		{name: "Missing prefix", key: "abc", wantErr: ErrInvalidKey},
+		{name: "Empty key", key: "", wantErr: ErrInvalidKey},
+		{name: "Unknown key", key: "zk_unknown", repoErr: fmt.Errorf("wrapped: %w", sql.ErrNoRows), wantErr: ErrInvalidKey},
+		{name: "Revoked key", key: "zk_revoked", repoKey: &repository.APIKey{Owner: "acme", RevokedAt: &revokedAt}, wantErr: ErrInvalidKey},
+		{name: "Database error", key: "zk_any", repoErr: errors.New("connection refused"), wantErr: ErrDatabaseRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &repository.MockRepo{
+				GetAPIKeyByHashFunc: func(ctx context.Context, keyHash string) (*repository.APIKey, error) {
+					if keyHash == tt.key {
+						t.Error("Expected the key to be hashed before lookup")
+					}
+					return tt.repoKey, tt.repoErr
+				},
+			}
+			svc := NewAuthSvc(mockRepo)
+			if _, err := svc.Authenticate(context.Background(), tt.key); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid api key")
+	ErrInvalidOwner  = errors.New("invalid owner")
+	ErrDatabaseRead  = errors.New("error reading from database")
+	ErrDatabaseWrite = errors.New("error writing to database")
+)
+
+// AuthService defines the interface for issuing and checking API keys.
+//
+// Authenticate resolves an API key to the owner it was issued to.
+// Parameters:
+//   - ctx: Context for request cancellation and timeouts
+//   - key: The API key presented by the caller
+//
+// Returns:
+//   - string: The owner of the key
+//   - error: ErrInvalidKey if the key is unknown or revoked, or an error if the lookup fails
+//
+// CreateAPIKey issues a new API key for owner.
+// Parameters:
+//   - ctx: Context for request cancellation and timeouts
+//   - owner: The owner the key authenticates as
+//   - name: A label to tell the owner's keys apart
+//
+// Returns:
+//   - string: The new key; only its digest is stored, so it cannot be shown again
+//   - error: An error if the owner is empty or the key could not be stored
+type AuthService interface {
+	Authenticate(ctx context.Context, key string) (string, error)
+	CreateAPIKey(ctx context.Context, owner, name string) (string, error)
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	pb "zipit/gen/url"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchSize mirrors the url service's limit on urls per batch.
+const maxBatchSize = 1000
+
+// maxBatchBody bounds the size of a batch upload.
+const maxBatchBody = 10 << 20 // 10MB
+
+// csvColumns are the columns of a CSV upload, in their default order.
+var csvColumns = []string{"long_url", "custom_alias", "ttl_seconds", "expires_at", "redirect_type", "title", "description", "tags"}
+
+// batchItem is one parsed entry of a batch upload. err is set if the entry
+// could not be parsed.
+type batchItem struct {
+	req PostURLRequest
+	err string
+}
+
+// ShortenBatch handles POST /shorten/batch. The body is either a JSON array of
+// shorten requests or CSV, sent as text/csv or as the "file" field of a
+// multipart form. CSV rows hold the columns long_url, custom_alias,
+// ttl_seconds, expires_at (RFC 3339), redirect_type, title, description and
+// tags (separated by spaces or semicolons), in that order unless the first row
+// is a header naming them, which must include long_url. Every entry gets its
+// own result, so invalid entries do not fail the rest of the batch.
+func (h *GatewayHandler) ShortenBatch(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBody)
+
+	items, message := parseBatch(r)
+	if message != "" {
+		writeJSONError(w, http.StatusBadRequest, message)
+		return
+	}
+	if len(items) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "at least one url is required")
+		return
+	}
+	if len(items) > maxBatchSize {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("at most %d urls are allowed per batch", maxBatchSize))
+		return
+	}
+
+	resp := BatchShortenResponse{Results: make([]BatchItemResponse, len(items))}
+	batchReq := &pb.BatchLongURL{}
+	var indexes []int
+	for i, item := range items {
+		resp.Results[i].LongURL = item.req.LongURL
+		if item.err != "" {
+			resp.Results[i].fail(http.StatusBadRequest, item.err)
+			continue
+		}
+		postReq, message := item.req.toProto()
+		if message != "" {
+			resp.Results[i].fail(http.StatusBadRequest, message)
+			continue
+		}
+		batchReq.Urls = append(batchReq.Urls, postReq)
+		indexes = append(indexes, i)
+	}
+
+	if len(batchReq.Urls) > 0 {
+		batchResp, err := h.urlSvc.BatchPostURL(r.Context(), batchReq)
+		if err != nil {
+			if status.Code(err) == codes.InvalidArgument {
+				writeJSONError(w, http.StatusBadRequest, "invalid batch")
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, "failed to shorten urls")
+			return
+		}
+		if len(batchResp.Results) != len(indexes) {
+			writeJSONError(w, http.StatusInternalServerError, "failed to shorten urls")
+			return
+		}
+		for n, res := range batchResp.Results {
+			result := &resp.Results[indexes[n]]
+			switch codes.Code(res.Code) {
+			case codes.OK:
+				result.ShortCode = res.Alias
+				result.Status = http.StatusCreated
+			case codes.InvalidArgument:
+				result.fail(http.StatusBadRequest, strings.ToLower(res.Error))
+			case codes.AlreadyExists:
+				result.fail(http.StatusConflict, "custom alias already in use")
+			case codes.PermissionDenied:
+				result.fail(http.StatusUnprocessableEntity, "url not allowed")
+				result.Reason = res.Error
+			default:
+				result.fail(http.StatusInternalServerError, "failed to shorten url")
+			}
+		}
+	}
+
+	for _, result := range resp.Results {
+		if result.Error == "" {
+			resp.Created++
+		} else {
+			resp.Failed++
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (res *BatchItemResponse) fail(statusCode int, message string) {
+	res.Status = statusCode
+	res.Error = message
+}
+
+// parseBatch reads the entries of a batch upload, or returns a message
+// explaining why the upload as a whole is unreadable.
+func parseBatch(r *http.Request) ([]batchItem, string) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "", "application/json":
+		var reqs []PostURLRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&reqs); err != nil {
+			return nil, "invalid JSON payload"
+		}
+		items := make([]batchItem, len(reqs))
+		for i, req := range reqs {
+			items[i].req = req
+		}
+		return items, ""
+	case "text/csv":
+		return parseCSV(r.Body)
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, "a CSV file is required in the file field"
+		}
+		defer file.Close()
+		return parseCSV(file)
+	default:
+		return nil, "unsupported content type"
+	}
+}
+
+// parseCSV reads the entries of a CSV upload. Rows are limited to one more
+// than maxBatchSize, which is enough to reject an oversized batch.
+func parseCSV(body io.Reader) ([]batchItem, string) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := csvColumns
+	var items []batchItem
+	for line := 0; len(items) <= maxBatchSize; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "invalid CSV payload"
+		}
+
+		if line == 0 && isCSVHeader(record) {
+			columns = make([]string, len(record))
+			for i, name := range record {
+				columns[i] = strings.ToLower(strings.TrimSpace(name))
+				if !isCSVColumn(columns[i]) {
+					return nil, fmt.Sprintf("unknown CSV column %q", name)
+				}
+			}
+			continue
+		}
+		if len(record) > len(columns) {
+			return nil, fmt.Sprintf("too many columns on CSV line %d", line+1)
+		}
+		items = append(items, parseCSVRecord(columns, record))
+	}
+	return items, ""
+}
+
+// isCSVHeader reports whether record names the columns, which it does if one
+// of them is long_url.
+func isCSVHeader(record []string) bool {
+	for _, name := range record {
+		if strings.EqualFold(strings.TrimSpace(name), "long_url") {
+			return true
+		}
+	}
+	return false
+}
+
+func isCSVColumn(name string) bool {
+	for _, column := range csvColumns {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCSVRecord converts one CSV row into a batch entry.
+func parseCSVRecord(columns, record []string) batchItem {
+	var item batchItem
+	for i, value := range record {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		switch columns[i] {
+		case "long_url":
+			item.req.LongURL = value
+		case "custom_alias":
+			item.req.CustomAlias = value
+		case "ttl_seconds":
+			ttl, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				item.err = "invalid ttl_seconds"
+			}
+			item.req.TTLSeconds = ttl
+		case "expires_at":
+			expiresAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				item.err = "invalid expires_at"
+			}
+			item.req.ExpiresAt = &expiresAt
+		case "redirect_type":
+			redirectType, err := strconv.Atoi(value)
+			if err != nil {
+				item.err = "invalid redirect_type"
+			}
+			item.req.RedirectType = redirectType
+		case "title":
+			item.req.Title = value
+		case "description":
+			item.req.Description = value
+		case "tags":
+			item.req.Tags = strings.FieldsFunc(value, func(r rune) bool { return r == ';' || unicode.IsSpace(r) })
+		}
+	}
+	return item
+}
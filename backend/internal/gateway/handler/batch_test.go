@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "zipit/gen/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchBackend shortens every url to its position in the batch, except for
+// those ending in "/taken", "/bad" and "/blocked", which fail as taken
+// aliases, invalid urls and urls rejected by the URL policy. It records the requests it receives.
+func batchBackend(reqs *[]*pb.BatchLongURL) *mockURLServiceClient {
+	return &mockURLServiceClient{
+		batchPostFunc: func(ctx context.Context, in *pb.BatchLongURL, opts ...grpc.CallOption) (*pb.BatchShortURL, error) {
+			*reqs = append(*reqs, in)
+			resp := &pb.BatchShortURL{}
+			for i, u := range in.Urls {
+				switch {
+				case strings.HasSuffix(u.Url, "/taken"):
+					resp.Results = append(resp.Results, &pb.BatchResult{Code: int32(codes.AlreadyExists), Error: "custom alias already in use"})
+				case strings.HasSuffix(u.Url, "/blocked"):
+					resp.Results = append(resp.Results, &pb.BatchResult{Code: int32(codes.PermissionDenied), Error: "destination domain is blocked"})
+				case strings.HasSuffix(u.Url, "/bad"):
+					resp.Results = append(resp.Results, &pb.BatchResult{Code: int32(codes.InvalidArgument), Error: "invalid URL"})
+				default:
+					resp.Results = append(resp.Results, &pb.BatchResult{Alias: fmt.Sprintf("c%d", i)})
+				}
+			}
+			return resp, nil
+		},
+	}
+}
+
+func serveBatch(h *GatewayHandler, contentType string, body *bytes.Buffer) (*httptest.ResponseRecorder, BatchShortenResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rr := httptest.NewRecorder()
+	h.ShortenBatch(rr, req)
+
+	var resp BatchShortenResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	return rr, resp
+}
+
+func TestShortenBatch_JSON(t *testing.T) {
+	var reqs []*pb.BatchLongURL
+	h := NewGatewayHandler(batchBackend(&reqs), nil)
+
+	body := bytes.NewBufferString(`[
+		{"long_url": "https://example.com/a"},
+		{"long_url": ""},
+		{"long_url": "https://example.com/taken", "custom_alias": "promo"},
+		{"long_url": "https://example.com/bad"},
+		{"long_url": "https://example.com/b", "ttl_seconds": 60},
+		{"long_url": "https://example.com/blocked"}
+	]`)
+	rr, resp := serveBatch(h, "application/json", body)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	want := []BatchItemResponse{
+		{LongURL: "https://example.com/a", ShortCode: "c0", Status: http.StatusCreated},
+		{Status: http.StatusBadRequest, Error: "long_url is required"},
+		{LongURL: "https://example.com/taken", Status: http.StatusConflict, Error: "custom alias already in use"},
+		{LongURL: "https://example.com/bad", Status: http.StatusBadRequest, Error: "invalid url"},
+		{LongURL: "https://example.com/b", ShortCode: "c3", Status: http.StatusCreated},
+		{LongURL: "https://example.com/blocked", Status: http.StatusUnprocessableEntity, Error: "url not allowed", Reason: "destination domain is blocked"},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), resp)
+	}
+	for i := range want {
+		if resp.Results[i] != want[i] {
+			t.Errorf("result %d: expected %+v, got %+v", i, want[i], resp.Results[i])
+		}
+	}
+	if resp.Created != 2 || resp.Failed != 4 {
+		t.Errorf("expected 2 created and 4 failed, got %d and %d", resp.Created, resp.Failed)
+	}
+
+	// Entries rejected by the gateway are not sent to the backend
+	if len(reqs) != 1 || len(reqs[0].Urls) != 5 || reqs[0].Urls[3].TtlSeconds != 60 {
+		t.Errorf("expected one backend call with the 5 valid urls, got %+v", reqs)
+	}
+}
+
+func TestShortenBatch_CSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []BatchItemResponse
+	}{
+		{
+			name: "Without Header",
+			csv:  "https://example.com/a\nhttps://example.com/b,launch\n",
+			want: []BatchItemResponse{
+				{LongURL: "https://example.com/a", ShortCode: "c0", Status: http.StatusCreated},
+				{LongURL: "https://example.com/b", ShortCode: "c1", Status: http.StatusCreated},
+			},
+		},
+		{
+			name: "With Header",
+			csv:  "ttl_seconds,long_url\n60,https://example.com/a\nsoon,https://example.com/b\n",
+			want: []BatchItemResponse{
+				{LongURL: "https://example.com/a", ShortCode: "c0", Status: http.StatusCreated},
+				{LongURL: "https://example.com/b", Status: http.StatusBadRequest, Error: "invalid ttl_seconds"},
+			},
+		},
+		{
+			name: "Metadata",
+			csv:  "long_url,title,tags\nhttps://example.com/a,Launch,q3-campaign;blog\nhttps://example.com/b,,two&words\n",
+			want: []BatchItemResponse{
+				{LongURL: "https://example.com/a", ShortCode: "c0", Status: http.StatusCreated},
+				{LongURL: "https://example.com/b", Status: http.StatusBadRequest, Error: `invalid tag "two&words"`},
+			},
+		},
+		{
+			name: "Redirect Type",
+			csv:  "long_url,redirect_type\nhttps://example.com/a,301\nhttps://example.com/b,moved\nhttps://example.com/c,200\n",
+			want: []BatchItemResponse{
+				{LongURL: "https://example.com/a", ShortCode: "c0", Status: http.StatusCreated},
+				{LongURL: "https://example.com/b", Status: http.StatusBadRequest, Error: "invalid redirect_type"},
+				{LongURL: "https://example.com/c", Status: http.StatusBadRequest, Error: "redirect_type must be 301, 302, 307 or 308"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reqs []*pb.BatchLongURL
+			h := NewGatewayHandler(batchBackend(&reqs), nil)
+
+			rr, resp := serveBatch(h, "text/csv", bytes.NewBufferString(tt.csv))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			if len(resp.Results) != len(tt.want) {
+				t.Fatalf("expected %d results, got %+v", len(tt.want), resp)
+			}
+			for i := range tt.want {
+				if resp.Results[i] != tt.want[i] {
+					t.Errorf("result %d: expected %+v, got %+v", i, tt.want[i], resp.Results[i])
+				}
+			}
+		})
+	}
+}
+
+func TestShortenBatch_MultipartUpload(t *testing.T) {
+	var reqs []*pb.BatchLongURL
+	h := NewGatewayHandler(batchBackend(&reqs), nil)
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	file, _ := form.CreateFormFile("file", "links.csv")
+	_, _ = file.Write([]byte("long_url,custom_alias\nhttps://example.com/a,spring\n"))
+	_ = form.Close()
+
+	rr, resp := serveBatch(h, form.FormDataContentType(), body)
+	if rr.Code != http.StatusOK || resp.Created != 1 {
+		t.Fatalf("expected one created link, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if len(reqs) != 1 || reqs[0].Urls[0].CustomAlias != "spring" {
+		t.Errorf("expected the alias to be passed on, got %+v", reqs)
+	}
+}
+
+func TestShortenBatch_Errors(t *testing.T) {
+	tooMany := &strings.Builder{}
+	for i := 0; i <= maxBatchSize; i++ {
+		fmt.Fprintf(tooMany, "https://example.com/%d\n", i)
+	}
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Invalid JSON", body: `[{"long_url": "https://example.com",}]`, expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"invalid JSON payload"}`},
+		{name: "Not An Array", body: `{"long_url": "https://example.com"}`, expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"invalid JSON payload"}`},
+		{name: "Empty Batch", body: `[]`, expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"at least one url is required"}`},
+		{name: "Too Many URLs", contentType: "text/csv", body: tooMany.String(), expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"at most 1000 urls are allowed per batch"}`},
+		{name: "Unknown CSV Column", contentType: "text/csv", body: "long_url,notes\nhttps://example.com,x\n", expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"unknown CSV column \"notes\""}`},
+		{name: "Unsupported Content Type", contentType: "text/plain", body: "https://example.com", expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"unsupported content type"}`},
+		{name: "Backend Down", body: `[{"long_url": "https://example.com"}]`, mockErr: status.Error(codes.Unavailable, "down"), expectedStatus: http.StatusInternalServerError, expectedBody: `{"error":"failed to shorten urls"}`},
+		{name: "Backend Error", body: `[{"long_url": "https://example.com"}]`, mockErr: errors.New("boom"), expectedStatus: http.StatusInternalServerError, expectedBody: `{"error":"failed to shorten urls"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				batchPostFunc: func(ctx context.Context, in *pb.BatchLongURL, opts ...grpc.CallOption) (*pb.BatchShortURL, error) {
+					return nil, tt.mockErr
+				},
+			}
+			rr, _ := serveBatch(NewGatewayHandler(mockSvc, nil), tt.contentType, bytes.NewBufferString(tt.body))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := strings.TrimSpace(rr.Body.String()); got != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, got)
+			}
+		})
+	}
+}
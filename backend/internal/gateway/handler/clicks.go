@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	analyticspb "zipit/gen/analytics"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// clickTimeout bounds how long a single click may take to reach the analytics service.
+const clickTimeout = 2 * time.Second
+
+// trackClick sends a click event for code to the analytics service in the
+// background so that the redirect is never delayed by analytics. variant is
+// the A/B variant the visitor was sent to, or "" if the link has none.
+func (h *GatewayHandler) trackClick(r *http.Request, code, variant string) {
+	if h.analyticsSvc == nil {
+		return
+	}
+
+	click := &analyticspb.ClickData{
+		Alias:     code,
+		IpAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Variant:   variant,
+		Timestamp: timestamppb.Now(),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), clickTimeout)
+		defer cancel()
+
+		if _, err := h.analyticsSvc.TrackClick(ctx, click); err != nil {
+			slog.Warn("failed to track click", "alias", click.Alias, "error", err)
+		}
+	}()
+}
+
+// clientIP returns the host part of the request's remote address. The router
+// runs RealIP, so the headers of trusted proxies are already applied.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -1,11 +1,17 @@
 package handler
 
-import pb "zipit/gen/url"
+import (
+	analyticspb "zipit/gen/analytics"
+	pb "zipit/gen/url"
+)
 
 type GatewayHandler struct {
-	urlSvc pb.URLServiceClient
+	urlSvc       pb.URLServiceClient
+	analyticsSvc analyticspb.AnalyticsServiceClient
 }
 
-func NewGatewayHandler(urlSvc pb.URLServiceClient) *GatewayHandler {
-	return &GatewayHandler{urlSvc: urlSvc}
+// NewGatewayHandler creates a handler backed by the given services.
+// analyticsSvc may be nil, in which case clicks are not tracked.
+func NewGatewayHandler(urlSvc pb.URLServiceClient, analyticsSvc analyticspb.AnalyticsServiceClient) *GatewayHandler {
+	return &GatewayHandler{urlSvc: urlSvc, analyticsSvc: analyticsSvc}
 }
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "zipit/gen/url"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxListLimit mirrors the url service's cap on links listed at once.
+const maxListLimit = 100
+
+// ListLinks handles GET /api/links. It lists the caller's links, newest first,
+// filtered by ?tag=, ?domain= (the destination host or its subdomains), ?q=
+// (a substring of the destination) and ?created_after= / ?created_before=
+// (RFC 3339). ?limit= caps the page size and ?cursor= takes the next_cursor of
+// the previous page. Admins may pass ?owner= or ?all_owners=1.
+func (h *GatewayHandler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &pb.ListURLsRequest{
+		Tag:       query.Get("tag"),
+		Domain:    query.Get("domain"),
+		Search:    query.Get("q"),
+		Cursor:    query.Get("cursor"),
+		AllOwners: isSet(r, "all_owners"),
+	}
+	if query.Has("owner") {
+		owner := query.Get("owner")
+		req.Owner = &owner
+	}
+	for param, field := range map[string]**timestamppb.Timestamp{
+		"created_after":  &req.CreatedAfter,
+		"created_before": &req.CreatedBefore,
+	} {
+		if raw := query.Get(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, param+" must be an RFC 3339 timestamp")
+				return
+			}
+			*field = timestamppb.New(t)
+		}
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+			return
+		}
+		req.Limit = int32(limit)
+	}
+
+	resp, err := h.urlSvc.ListURLs(r.Context(), req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			writeJSONError(w, http.StatusBadRequest, status.Convert(err).Message())
+			return
+		case codes.PermissionDenied:
+			writeJSONError(w, http.StatusForbidden, status.Convert(err).Message())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "failed to list links")
+		return
+	}
+
+	list := LinkListResponse{Links: make([]LinkResponse, 0, len(resp.GetUrls())), NextCursor: resp.GetNextCursor()}
+	for _, u := range resp.GetUrls() {
+		link := LinkResponse{
+			ShortCode:    u.GetAlias(),
+			LongURL:      u.GetUrl(),
+			Title:        u.GetTitle(),
+			Description:  u.GetDescription(),
+			Tags:         u.GetTags(),
+			CreatedAt:    u.GetCreatedAt().AsTime(),
+			Disabled:     u.GetDisabled(),
+			Interstitial: u.GetInterstitial(),
+			RedirectType: int(u.GetRedirectType()),
+			Owner:        u.GetOwner(),
+			Page:         pageResponse(u.GetPage()),
+			Health:       healthResponse(u.GetHealth()),
+
+			PasswordProtected: u.GetPasswordProtected(),
+			MaxClicks:         int(u.GetMaxClicks()),
+			ClicksRemaining:   int(u.GetClicksRemaining()),
+		}
+		if link.Tags == nil {
+			link.Tags = []string{}
+		}
+		if u.GetExpiresAt() != nil {
+			expiresAt := u.GetExpiresAt().AsTime()
+			link.ExpiresAt = &expiresAt
+		}
+		list.Links = append(list.Links, link)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "zipit/gen/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestListLinks(t *testing.T) {
+	createdAt := time.Date(2026, 4, 1, 9, 30, 0, 0, time.UTC)
+	owner := "globex"
+	tests := []struct {
+		name           string
+		query          string
+		mockResp       *pb.URLList
+		mockErr        error
+		wantReq        *pb.ListURLsRequest
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "By Tag",
+			query: "?tag=q3-campaign&limit=20",
+			mockResp: &pb.URLList{Urls: []*pb.URLInfo{
+				{Alias: "abcde", Url: "https://example.com/", Title: "Launch", Tags: []string{"q3-campaign"}, CreatedAt: timestamppb.New(createdAt), RedirectType: 302},
+			}},
+			wantReq:        &pb.ListURLsRequest{Tag: "q3-campaign", Limit: 20},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"links":[{"short_code":"abcde","long_url":"https://example.com/","title":"Launch","tags":["q3-campaign"],"created_at":"2026-04-01T09:30:00Z","disabled":false,"interstitial":false,"redirect_type":302}]}`,
+		},
+		{
+			name: "Without Tags",
+			mockResp: &pb.URLList{Urls: []*pb.URLInfo{
+				{Alias: "abcde", Url: "https://example.com/", CreatedAt: timestamppb.New(createdAt), Disabled: true, RedirectType: 301},
+			}},
+			wantReq:        &pb.ListURLsRequest{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"links":[{"short_code":"abcde","long_url":"https://example.com/","tags":[],"created_at":"2026-04-01T09:30:00Z","disabled":true,"interstitial":false,"redirect_type":301}]}`,
+		},
+		{
+			name:  "Filters And Cursor",
+			query: "?domain=example.com&q=promo&created_after=2026-04-01T09:30:00Z&cursor=42",
+			mockResp: &pb.URLList{Urls: []*pb.URLInfo{
+				{Alias: "abcde", Url: "https://example.com/promo", CreatedAt: timestamppb.New(createdAt), RedirectType: 302},
+			}, NextCursor: "17"},
+			wantReq:        &pb.ListURLsRequest{Domain: "example.com", Search: "promo", CreatedAfter: timestamppb.New(createdAt), Cursor: "42"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"links":[{"short_code":"abcde","long_url":"https://example.com/promo","tags":[],"created_at":"2026-04-01T09:30:00Z","disabled":false,"interstitial":false,"redirect_type":302}],"next_cursor":"17"}`,
+		},
+		{
+			name: "With Page And Health",
+			mockResp: &pb.URLList{Urls: []*pb.URLInfo{
+				{Alias: "abcde", Url: "https://example.com/", CreatedAt: timestamppb.New(createdAt), RedirectType: 302,
+					Page:   &pb.PageInfo{Title: "Example", StatusCode: 404, FetchedAt: timestamppb.New(createdAt)},
+					Health: &pb.LinkHealth{LastCheckedAt: timestamppb.New(createdAt), LastStatus: 404, ConsecutiveFailures: 1}},
+			}},
+			wantReq:        &pb.ListURLsRequest{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"links":[{"short_code":"abcde","long_url":"https://example.com/","tags":[],"created_at":"2026-04-01T09:30:00Z","disabled":false,"interstitial":false,"redirect_type":302,"page":{"title":"Example","status_code":404,"fetched_at":"2026-04-01T09:30:00Z"},"health":{"last_checked_at":"2026-04-01T09:30:00Z","last_status":404,"consecutive_failures":1}}]}`,
+		},
+		{
+			name:  "Other Owner",
+			query: "?owner=globex",
+			mockResp: &pb.URLList{Urls: []*pb.URLInfo{
+				{Alias: "abcde", Url: "https://example.com/", CreatedAt: timestamppb.New(createdAt), RedirectType: 302, Owner: "globex"},
+			}},
+			wantReq:        &pb.ListURLsRequest{Owner: &owner},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"links":[{"short_code":"abcde","long_url":"https://example.com/","tags":[],"created_at":"2026-04-01T09:30:00Z","disabled":false,"interstitial":false,"redirect_type":302,"owner":"globex"}]}`,
+		},
+		{
+			name:           "All Owners",
+			query:          "?all_owners=1",
+			mockResp:       &pb.URLList{},
+			wantReq:        &pb.ListURLsRequest{AllOwners: true},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"links":[]}`,
+		},
+		{
+			name:           "No Links",
+			mockResp:       &pb.URLList{},
+			wantReq:        &pb.ListURLsRequest{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"links":[]}`,
+		},
+		{
+			name:           "Invalid Limit",
+			query:          "?limit=many",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"limit must be between 1 and 100"}`,
+		},
+		{
+			name:           "Limit Too Large",
+			query:          "?limit=101",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"limit must be between 1 and 100"}`,
+		},
+		{
+			name:           "Invalid Timestamp",
+			query:          "?created_before=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"created_before must be an RFC 3339 timestamp"}`,
+		},
+		{
+			name:           "Invalid Filter",
+			query:          "?cursor=abc",
+			mockErr:        status.Error(codes.InvalidArgument, "invalid cursor"),
+			wantReq:        &pb.ListURLsRequest{Cursor: "abc"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid cursor"}`,
+		},
+		{
+			name:           "Not Admin",
+			query:          "?all_owners=true",
+			mockErr:        status.Error(codes.PermissionDenied, "only admins may list the links of other owners"),
+			wantReq:        &pb.ListURLsRequest{AllOwners: true},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"only admins may list the links of other owners"}`,
+		},
+		{
+			name:           "Internal gRPC Error",
+			mockErr:        errors.New("some grpc error"),
+			wantReq:        &pb.ListURLsRequest{},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to list links"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *pb.ListURLsRequest
+			mockSvc := &mockURLServiceClient{
+				listURLsFunc: func(ctx context.Context, in *pb.ListURLsRequest, opts ...grpc.CallOption) (*pb.URLList, error) {
+					got = in
+					return tt.mockResp, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/links"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			h.ListLinks(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if tt.wantReq != nil && !proto.Equal(got, tt.wantReq) {
+				t.Errorf("expected request %v, got %v", tt.wantReq, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pb "zipit/gen/url"
+	"zipit/pkg/redirect"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeleteURL handles DELETE /api/{code}
+func (h *GatewayHandler) DeleteURL(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	if _, err := h.urlSvc.DeleteURL(r.Context(), &pb.ShortURL{Alias: code}); err != nil {
+		writeManageError(w, err, "failed to delete url")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateURL handles PATCH /api/{code}. The destination and settings are
+// changed first, and the link disabled or re-enabled after, with separate
+// calls to the url service. The update is not atomic: if a later step fails,
+// the client gets the error but the earlier ones stay applied, so it should
+// send the whole update again.
+func (h *GatewayHandler) UpdateURL(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB limit
+
+	var req UpdateURLRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if req.LongURL == "" && req.Disabled == nil && req.Interstitial == nil && req.RedirectType == nil {
+		writeJSONError(w, http.StatusBadRequest, "nothing to update")
+		return
+	}
+	if req.RedirectType != nil && !redirect.Valid(*req.RedirectType) {
+		writeJSONError(w, http.StatusBadRequest, invalidRedirectType)
+		return
+	}
+
+	if req.LongURL != "" || req.Interstitial != nil || req.RedirectType != nil {
+		update := &pb.UpdateURLRequest{Alias: code, Url: req.LongURL, Interstitial: req.Interstitial}
+		if req.RedirectType != nil {
+			redirectType := int32(*req.RedirectType)
+			update.RedirectType = &redirectType
+		}
+		if _, err := h.urlSvc.UpdateURL(r.Context(), update); err != nil {
+			writeManageError(w, err, "failed to update url")
+			return
+		}
+	}
+	if req.Disabled != nil {
+		if _, err := h.urlSvc.DisableURL(r.Context(), &pb.DisableURLRequest{Alias: code, Disabled: *req.Disabled}); err != nil {
+			writeManageError(w, err, "failed to update url")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetURLHistory handles GET /api/{code}/history
+func (h *GatewayHandler) GetURLHistory(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	resp, err := h.urlSvc.GetURLHistory(r.Context(), &pb.ShortURL{Alias: code})
+	if err != nil {
+		writeManageError(w, err, "failed to fetch url history")
+		return
+	}
+
+	history := URLHistoryResponse{ShortCode: code, History: make([]URLChangeResponse, 0, len(resp.GetChanges()))}
+	for _, c := range resp.GetChanges() {
+		history.History = append(history.History, URLChangeResponse{
+			OldLongURL: c.GetOldUrl(),
+			NewLongURL: c.GetNewUrl(),
+			ChangedAt:  c.GetChangedAt().AsTime(),
+		})
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// GetRules handles GET /api/{code}/rules
+func (h *GatewayHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	resp, err := h.urlSvc.GetRules(r.Context(), &pb.ShortURL{Alias: code})
+	if err != nil {
+		writeManageError(w, err, "failed to fetch url rules")
+		return
+	}
+
+	rules := RulesResponse{ShortCode: code, Rules: make([]RoutingRule, 0, len(resp.GetRules()))}
+	for _, rule := range resp.GetRules() {
+		rules.Rules = append(rules.Rules, RoutingRule{
+			Platforms: rule.GetPlatforms(),
+			Languages: rule.GetLanguages(),
+			Countries: rule.GetCountries(),
+			LongURL:   rule.GetUrl(),
+		})
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// SetRules handles PUT /api/{code}/rules. The rules replace every rule the
+// link had.
+func (h *GatewayHandler) SetRules(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB limit
+
+	var req SetRulesRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	update := &pb.SetRulesRequest{Alias: code, Rules: make([]*pb.RoutingRule, 0, len(req.Rules))}
+	for _, rule := range req.Rules {
+		update.Rules = append(update.Rules, &pb.RoutingRule{
+			Platforms: rule.Platforms,
+			Languages: rule.Languages,
+			Countries: rule.Countries,
+			Url:       rule.LongURL,
+		})
+	}
+	if _, err := h.urlSvc.SetRules(r.Context(), update); err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			// The message says which rule is invalid.
+			writeJSONError(w, http.StatusBadRequest, status.Convert(err).Message())
+			return
+		}
+		writeManageError(w, err, "failed to update url rules")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetVariants handles GET /api/{code}/variants
+func (h *GatewayHandler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	resp, err := h.urlSvc.GetVariants(r.Context(), &pb.ShortURL{Alias: code})
+	if err != nil {
+		writeManageError(w, err, "failed to fetch url variants")
+		return
+	}
+
+	variants := VariantsResponse{ShortCode: code, Variants: make([]Variant, 0, len(resp.GetVariants()))}
+	for _, v := range resp.GetVariants() {
+		variants.Variants = append(variants.Variants, Variant{Name: v.GetName(), LongURL: v.GetUrl(), Weight: v.GetWeight()})
+	}
+	writeJSON(w, http.StatusOK, variants)
+}
+
+// SetVariants handles PUT /api/{code}/variants. The variants replace every
+// variant the link had.
+func (h *GatewayHandler) SetVariants(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB limit
+
+	var req SetVariantsRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	update := &pb.SetVariantsRequest{Alias: code, Variants: make([]*pb.Variant, 0, len(req.Variants))}
+	for _, v := range req.Variants {
+		update.Variants = append(update.Variants, &pb.Variant{Name: v.Name, Url: v.LongURL, Weight: v.Weight})
+	}
+	if _, err := h.urlSvc.SetVariants(r.Context(), update); err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			// The message says which variant is invalid.
+			writeJSONError(w, http.StatusBadRequest, status.Convert(err).Message())
+			return
+		}
+		writeManageError(w, err, "failed to update url variants")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeManageError maps a gRPC error from a change to an existing link.
+func writeManageError(w http.ResponseWriter, err error, fallback string) {
+	switch status.Code(err) {
+	case codes.NotFound:
+		writeJSONError(w, http.StatusNotFound, "short url not found")
+		return
+	case codes.InvalidArgument:
+		writeJSONError(w, http.StatusBadRequest, "invalid url")
+		return
+	case codes.PermissionDenied:
+		writeURLNotAllowed(w, err)
+		return
+	}
+	writeJSONError(w, http.StatusInternalServerError, fallback)
+}
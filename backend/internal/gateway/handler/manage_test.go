@@ -0,0 +1,646 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "zipit/gen/url"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// withCode attaches the {code} route parameter to req.
+func withCode(req *http.Request, code string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("code", code)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestDeleteURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           string
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Success",
+			code:           "abcde",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "Not Found",
+			code:           "miss",
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+		},
+		{
+			name:           "Internal gRPC Error",
+			code:           "err",
+			mockErr:        errors.New("some grpc error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to delete url"}`,
+		},
+		{
+			name:           "Invalid Code Format",
+			code:           "../../etc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid short code format"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted string
+			mockSvc := &mockURLServiceClient{
+				deleteURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					deleted = in.Alias
+					return &emptypb.Empty{}, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/%s", tt.code), nil), tt.code)
+			rr := httptest.NewRecorder()
+
+			h.DeleteURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusNoContent && deleted != tt.code {
+				t.Errorf("expected %s to be deleted, got %q", tt.code, deleted)
+			}
+		})
+	}
+}
+
+func TestUpdateURL_Disable(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           string
+		payload        string
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+		wantDisabled   bool
+	}{
+		{
+			name:           "Disable",
+			code:           "abcde",
+			payload:        `{"disabled": true}`,
+			expectedStatus: http.StatusNoContent,
+			wantDisabled:   true,
+		},
+		{
+			name:           "Enable",
+			code:           "abcde",
+			payload:        `{"disabled": false}`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "Not Found",
+			code:           "miss",
+			payload:        `{"disabled": true}`,
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+		},
+		{
+			name:           "Internal gRPC Error",
+			code:           "err",
+			payload:        `{"disabled": true}`,
+			mockErr:        errors.New("some grpc error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to update url"}`,
+		},
+		{
+			name:           "Empty Payload",
+			code:           "abcde",
+			payload:        `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"nothing to update"}`,
+		},
+		{
+			name:           "Unknown Fields",
+			code:           "abcde",
+			payload:        `{"enabled": true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid JSON payload"}`,
+		},
+		{
+			name:           "Invalid Code Format",
+			code:           "../../etc",
+			payload:        `{"disabled": true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid short code format"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *pb.DisableURLRequest
+			mockSvc := &mockURLServiceClient{
+				disableURLFunc: func(ctx context.Context, in *pb.DisableURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					got = in
+					return &emptypb.Empty{}, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/%s", tt.code), strings.NewReader(tt.payload)), tt.code)
+			rr := httptest.NewRecorder()
+
+			h.UpdateURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusNoContent && (got == nil || got.Alias != tt.code || got.Disabled != tt.wantDisabled) {
+				t.Errorf("expected DisableURL(%s, %v), got %v", tt.code, tt.wantDisabled, got)
+			}
+		})
+	}
+}
+
+func TestUpdateURL_Destination(t *testing.T) {
+	tests := []struct {
+		name           string
+		payload        string
+		updateErr      error
+		expectedStatus int
+		expectedBody   string
+		wantUpdate     bool
+		wantDisable    bool
+	}{
+		{
+			name:           "Success",
+			payload:        `{"long_url": "https://example.com/q2"}`,
+			expectedStatus: http.StatusNoContent,
+			wantUpdate:     true,
+		},
+		{
+			name:           "With Disabled",
+			payload:        `{"long_url": "https://example.com/q2", "disabled": true}`,
+			expectedStatus: http.StatusNoContent,
+			wantUpdate:     true,
+			wantDisable:    true,
+		},
+		{
+			name:           "Interstitial Only",
+			payload:        `{"interstitial": true}`,
+			expectedStatus: http.StatusNoContent,
+			wantUpdate:     true,
+		},
+		{
+			name:           "Redirect Type Only",
+			payload:        `{"redirect_type": 301}`,
+			expectedStatus: http.StatusNoContent,
+			wantUpdate:     true,
+		},
+		{
+			name:           "Invalid Redirect Type",
+			payload:        `{"redirect_type": 300, "disabled": true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"redirect_type must be 301, 302, 307 or 308"}`,
+		},
+		{
+			name:           "Invalid URL",
+			payload:        `{"long_url": "not a url", "disabled": true}`,
+			updateErr:      status.Error(codes.InvalidArgument, "invalid URL"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid url"}`,
+			wantUpdate:     true,
+		},
+		{
+			name:           "URL Not Allowed",
+			payload:        `{"long_url": "http://localhost/"}`,
+			updateErr:      status.Error(codes.PermissionDenied, "destination is localhost"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":"url not allowed","reason":"destination is localhost"}`,
+			wantUpdate:     true,
+		},
+		{
+			name:           "Not Found",
+			payload:        `{"long_url": "https://example.com/q2"}`,
+			updateErr:      status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+			wantUpdate:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, disabled := false, false
+			mockSvc := &mockURLServiceClient{
+				updateURLFunc: func(ctx context.Context, in *pb.UpdateURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					updated = true
+					if in.Alias != "abcde" || (in.Url == "" && in.Interstitial == nil && in.RedirectType == nil) {
+						t.Errorf("unexpected update request %v", in)
+					}
+					return &emptypb.Empty{}, tt.updateErr
+				},
+				disableURLFunc: func(ctx context.Context, in *pb.DisableURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					disabled = true
+					return &emptypb.Empty{}, nil
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodPatch, "/api/abcde", strings.NewReader(tt.payload)), "abcde")
+			rr := httptest.NewRecorder()
+
+			h.UpdateURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if updated != tt.wantUpdate || disabled != tt.wantDisable {
+				t.Errorf("expected update=%v disable=%v, got update=%v disable=%v", tt.wantUpdate, tt.wantDisable, updated, disabled)
+			}
+		})
+	}
+}
+
+func TestGetURLHistory(t *testing.T) {
+	changedAt := time.Date(2026, 4, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		code           string
+		mockResp       *pb.URLHistory
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			code: "abcde",
+			mockResp: &pb.URLHistory{Changes: []*pb.URLChange{
+				{OldUrl: "https://example.com/q1", NewUrl: "https://example.com/q2", ChangedAt: timestamppb.New(changedAt)},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_code":"abcde","history":[{"old_long_url":"https://example.com/q1","new_long_url":"https://example.com/q2","changed_at":"2026-04-01T09:30:00Z"}]}`,
+		},
+		{
+			name:           "Empty History",
+			code:           "abcde",
+			mockResp:       &pb.URLHistory{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_code":"abcde","history":[]}`,
+		},
+		{
+			name:           "Not Found",
+			code:           "miss",
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+		},
+		{
+			name:           "Internal gRPC Error",
+			code:           "err",
+			mockErr:        errors.New("some grpc error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to fetch url history"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				historyFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLHistory, error) {
+					return tt.mockResp, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/%s/history", tt.code), nil), tt.code)
+			rr := httptest.NewRecorder()
+
+			h.GetURLHistory(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           string
+		mockResp       *pb.RoutingRules
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			code: "abcde",
+			mockResp: &pb.RoutingRules{Rules: []*pb.RoutingRule{
+				{Platforms: []string{"ios"}, Url: "https://apps.apple.com/app/id1"},
+				{Languages: []string{"de"}, Countries: []string{"AT"}, Url: "https://example.com/at"},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_code":"abcde","rules":[{"platforms":["ios"],"long_url":"https://apps.apple.com/app/id1"},{"languages":["de"],"countries":["AT"],"long_url":"https://example.com/at"}]}`,
+		},
+		{
+			name:           "No Rules",
+			code:           "abcde",
+			mockResp:       &pb.RoutingRules{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_code":"abcde","rules":[]}`,
+		},
+		{
+			name:           "Not Found",
+			code:           "miss",
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+		},
+		{
+			name:           "Internal gRPC Error",
+			code:           "err",
+			mockErr:        errors.New("some grpc error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to fetch url rules"}`,
+		},
+		{
+			name:           "Invalid Code Format",
+			code:           "../../etc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid short code format"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				getRulesFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.RoutingRules, error) {
+					return tt.mockResp, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/%s/rules", tt.code), nil), tt.code)
+			rr := httptest.NewRecorder()
+
+			h.GetRules(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		payload        string
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+		wantCall       bool
+	}{
+		{
+			name:           "Success",
+			payload:        `{"rules":[{"platforms":["ios"],"long_url":"https://apps.apple.com/app/id1"},{"platforms":["android"],"long_url":"https://play.google.com/store/apps/details?id=app"}]}`,
+			expectedStatus: http.StatusNoContent,
+			wantCall:       true,
+		},
+		{
+			name:           "Clear Rules",
+			payload:        `{"rules":[]}`,
+			expectedStatus: http.StatusNoContent,
+			wantCall:       true,
+		},
+		{
+			name:           "Unknown Fields",
+			payload:        `{"rules":[{"os":["ios"],"long_url":"https://example.com"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid JSON payload"}`,
+		},
+		{
+			name:           "Invalid Rule",
+			payload:        `{"rules":[{"platforms":["symbian"],"long_url":"https://example.com"}]}`,
+			mockErr:        status.Error(codes.InvalidArgument, `invalid routing rules: rule 1: unknown platform "symbian"`),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid routing rules: rule 1: unknown platform \"symbian\""}`,
+			wantCall:       true,
+		},
+		{
+			name:           "Not Found",
+			payload:        `{"rules":[]}`,
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+			wantCall:       true,
+		},
+		{
+			name:           "Internal gRPC Error",
+			payload:        `{"rules":[]}`,
+			mockErr:        errors.New("some grpc error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to update url rules"}`,
+			wantCall:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *pb.SetRulesRequest
+			mockSvc := &mockURLServiceClient{
+				setRulesFunc: func(ctx context.Context, in *pb.SetRulesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					got = in
+					return &emptypb.Empty{}, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodPut, "/api/abcde/rules", strings.NewReader(tt.payload)), "abcde")
+			rr := httptest.NewRecorder()
+
+			h.SetRules(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if (got != nil) != tt.wantCall {
+				t.Fatalf("expected SetRules called=%v", tt.wantCall)
+			}
+			if tt.name == "Success" {
+				if got.GetAlias() != "abcde" || len(got.GetRules()) != 2 || got.GetRules()[1].GetPlatforms()[0] != "android" ||
+					got.GetRules()[0].GetUrl() != "https://apps.apple.com/app/id1" {
+					t.Errorf("unexpected request %v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetVariants(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockResp       *pb.Variants
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			mockResp: &pb.Variants{Variants: []*pb.Variant{
+				{Name: "A", Url: "https://example.com/a", Weight: 70},
+				{Name: "B", Url: "https://example.com/b", Weight: 30},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_code":"abcde","variants":[{"name":"A","long_url":"https://example.com/a","weight":70},{"name":"B","long_url":"https://example.com/b","weight":30}]}`,
+		},
+		{
+			name:           "No Variants",
+			mockResp:       &pb.Variants{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_code":"abcde","variants":[]}`,
+		},
+		{
+			name:           "Not Found",
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+		},
+		{
+			name:           "Internal gRPC Error",
+			mockErr:        errors.New("some grpc error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"failed to fetch url variants"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				getVariantsFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.Variants, error) {
+					return tt.mockResp, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, "/api/abcde/variants", nil), "abcde")
+			rr := httptest.NewRecorder()
+
+			h.GetVariants(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetVariants(t *testing.T) {
+	tests := []struct {
+		name           string
+		payload        string
+		mockErr        error
+		expectedStatus int
+		expectedBody   string
+		wantCall       bool
+	}{
+		{
+			name:           "Success",
+			payload:        `{"variants":[{"long_url":"https://example.com/a","weight":70},{"name":"control","long_url":"https://example.com/b","weight":30}]}`,
+			expectedStatus: http.StatusNoContent,
+			wantCall:       true,
+		},
+		{
+			name:           "Weight Out Of Range",
+			payload:        `{"variants":[{"long_url":"https://example.com/a","weight":4294967296}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid JSON payload"}`,
+		},
+		{
+			name:           "Invalid Variant",
+			payload:        `{"variants":[{"long_url":"https://example.com/a","weight":1},{"long_url":"https://example.com/b","weight":0}]}`,
+			mockErr:        status.Error(codes.InvalidArgument, "invalid link variants: variant 2: weight must be between 1 and 1000"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid link variants: variant 2: weight must be between 1 and 1000"}`,
+			wantCall:       true,
+		},
+		{
+			name:           "Not Found",
+			payload:        `{"variants":[]}`,
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"short url not found"}`,
+			wantCall:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *pb.SetVariantsRequest
+			mockSvc := &mockURLServiceClient{
+				setVariantsFunc: func(ctx context.Context, in *pb.SetVariantsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					got = in
+					return &emptypb.Empty{}, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodPut, "/api/abcde/variants", strings.NewReader(tt.payload)), "abcde")
+			rr := httptest.NewRecorder()
+
+			h.SetVariants(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if (got != nil) != tt.wantCall {
+				t.Fatalf("expected SetVariants called=%v", tt.wantCall)
+			}
+			if tt.name == "Success" {
+				if v := got.GetVariants(); got.GetAlias() != "abcde" || len(v) != 2 || v[0].GetWeight() != 70 || v[1].GetName() != "control" || v[1].GetUrl() != "https://example.com/b" {
+					t.Errorf("unexpected request %v", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	pb "zipit/gen/url"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// linkPage is the HTML shown instead of a redirect: the preview of a link, or
+// the warning before following a flagged one.
+var linkPage = template.Must(template.New("link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Warning}}Warning: {{end}}{{.ShortCode}}</title>
+</head>
+<body>
+{{if .Warning}}<h1>This link may not be safe</h1>
+<p>{{.Warning}}.</p>
+{{else}}<h1>Link preview</h1>
+{{end}}{{if .LongURL}}<p>This short link leads to:</p>
+<p><code>{{.LongURL}}</code></p>
+{{end}}<dl>
+{{if .Title}}<dt>Title</dt><dd>{{.Title}}</dd>
+{{end}}{{if .Description}}<dt>Description</dt><dd>{{.Description}}</dd>
+{{end}}{{if not .CreatedAt.IsZero}}<dt>Created</dt><dd>{{.CreatedAt.Format "2 January 2006"}}</dd>
+{{end}}{{if .ExpiresAt}}<dt>Expires</dt><dd>{{.ExpiresAt.Format "2 January 2006 15:04 MST"}}</dd>
+{{end}}{{if .Safety}}<dt>Safety</dt><dd>{{.Safety}}</dd>
+{{end}}</dl>
+<p><a href="{{.ContinueURL}}" rel="noreferrer">Continue to the destination</a></p>
+</body>
+</html>
+`))
+
+// linkPageData fills linkPage.
+type linkPageData struct {
+	ShortCode   string
+	LongURL     string
+	Title       string
+	Description string
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time
+	Safety      string
+	Warning     string
+	ContinueURL string
+}
+
+// PreviewURL handles GET /{code}+ and GET /{code}?preview=1. It describes the
+// link without following it, as JSON or as an HTML page for browsers, but
+// not the destination of click-limited links. Password-protected links need
+// their password in the X-Link-Password header.
+func (h *GatewayHandler) PreviewURL(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+
+	resp, err := h.urlSvc.PreviewURL(r.Context(), visit(r, code, r.Header.Get(passwordHeader), storedVisitorID(r)))
+	if err != nil {
+		writeResolveError(w, err)
+		return
+	}
+
+	preview := PreviewResponse{
+		ShortCode:    code,
+		LongURL:      resp.GetUrl(),
+		CreatedAt:    resp.GetCreatedAt().AsTime(),
+		Safety:       resp.GetSafety(),
+		SafetyReason: resp.GetSafetyReason(),
+		Title:        resp.GetTitle(),
+		Description:  resp.GetDescription(),
+		Tags:         resp.GetTags(),
+		Page:         pageResponse(resp.GetPage()),
+	}
+	if resp.GetExpiresAt() != nil {
+		expiresAt := resp.GetExpiresAt().AsTime()
+		preview.ExpiresAt = &expiresAt
+	}
+	if !prefersHTML(r) {
+		writeJSON(w, http.StatusOK, preview)
+		return
+	}
+
+	data := linkPageData{
+		ShortCode:   code,
+		LongURL:     preview.LongURL,
+		Title:       preview.Title,
+		Description: preview.Description,
+		CreatedAt:   preview.CreatedAt,
+		ExpiresAt:   preview.ExpiresAt,
+		Safety:      preview.Safety,
+		ContinueURL: confirmURL(code),
+	}
+	if preview.SafetyReason != "" {
+		data.Safety += " (" + preview.SafetyReason + ")"
+	}
+	// Without a title from the owner, show the one of the destination page.
+	if data.Title == "" && preview.Page != nil {
+		data.Title = preview.Page.OGTitle
+		if data.Title == "" {
+			data.Title = preview.Page.Title
+		}
+	}
+	writeLinkPage(w, data)
+}
+
+// pageResponse converts the fetched destination page of a link, or returns
+// nil if it has not been fetched.
+func pageResponse(page *pb.PageInfo) *PageResponse {
+	if page == nil {
+		return nil
+	}
+	return &PageResponse{
+		Title:      page.GetTitle(),
+		OGTitle:    page.GetOgTitle(),
+		OGImage:    page.GetOgImage(),
+		StatusCode: int(page.GetStatusCode()),
+		FetchedAt:  page.GetFetchedAt().AsTime(),
+	}
+}
+
+// writeInterstitial warns the client before it follows the link for code to
+// longURL, which its owner or the URL policy flagged, through continueURL.
+func writeInterstitial(w http.ResponseWriter, code, longURL, warning, continueURL string) {
+	if warning == "" {
+		warning = "The destination of this link has been flagged"
+	}
+	// Upper-case the reason, which the url service words as a clause.
+	warning = strings.ToUpper(warning[:1]) + warning[1:]
+	writeLinkPage(w, linkPageData{ShortCode: code, LongURL: longURL, Warning: warning, ContinueURL: continueURL})
+}
+
+// confirmURL is the link that follows code past its interstitial. It is
+// relative, so it works behind any path prefix the gateway is mounted under.
+func confirmURL(code string) string {
+	return code + "?confirm=1"
+}
+
+// writeLinkPage renders linkPage. The page describes the link as it is now,
+// so it is never cached.
+func writeLinkPage(w http.ResponseWriter, data linkPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := linkPage.Execute(w, data); err != nil {
+		slog.Warn("failed to render link page", "alias", data.ShortCode, "error", err)
+	}
+}
+
+// isSet reports whether a boolean query parameter such as ?preview=1 is on.
+func isSet(r *http.Request, param string) bool {
+	switch strings.ToLower(r.URL.Query().Get(param)) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
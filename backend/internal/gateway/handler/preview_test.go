@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	analyticspb "zipit/gen/analytics"
+	pb "zipit/gen/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPreviewURL(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		code           string
+		accept         string
+		mockResp       *pb.URLPreview
+		mockErr        error
+		expectedStatus int
+		expectedType   string
+		expectedBody   []string
+	}{
+		{
+			name:           "JSON",
+			code:           "abcde",
+			mockResp:       &pb.URLPreview{Url: "https://example.com/", CreatedAt: timestamppb.New(createdAt), Safety: "ok"},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/json",
+			expectedBody:   []string{`{"short_code":"abcde","long_url":"https://example.com/","created_at":"2026-03-01T12:00:00Z","safety":"ok"}`},
+		},
+		{
+			name:   "JSON With Expiry And Reason",
+			code:   "abcde",
+			accept: "application/json, text/html",
+			mockResp: &pb.URLPreview{
+				Url: "https://evil.com/", CreatedAt: timestamppb.New(createdAt), ExpiresAt: timestamppb.New(expiresAt),
+				Safety: "blocked", SafetyReason: "destination domain is blocked",
+			},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/json",
+			expectedBody:   []string{`{"short_code":"abcde","long_url":"https://evil.com/","created_at":"2026-03-01T12:00:00Z","expires_at":"2026-04-01T12:00:00Z","safety":"blocked","safety_reason":"destination domain is blocked"}`},
+		},
+		{
+			name: "JSON With Page",
+			code: "abcde",
+			mockResp: &pb.URLPreview{Url: "https://example.com/", CreatedAt: timestamppb.New(createdAt), Safety: "ok", Page: &pb.PageInfo{
+				Title: "Example", OgTitle: "Example Domain", OgImage: "https://example.com/og.png", StatusCode: 200, FetchedAt: timestamppb.New(createdAt),
+			}},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/json",
+			expectedBody:   []string{`"page":{"title":"Example","og_title":"Example Domain","og_image":"https://example.com/og.png","status_code":200,"fetched_at":"2026-03-01T12:00:00Z"}`},
+		},
+		{
+			name:   "HTML With Page Title",
+			code:   "abcde",
+			accept: "text/html",
+			mockResp: &pb.URLPreview{Url: "https://example.com/", CreatedAt: timestamppb.New(createdAt), Safety: "ok", Page: &pb.PageInfo{
+				Title: "Example", OgTitle: "Example <Domain>", StatusCode: 200, FetchedAt: timestamppb.New(createdAt),
+			}},
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/html; charset=utf-8",
+			expectedBody:   []string{"Example &lt;Domain&gt;"},
+		},
+		{
+			name:           "HTML",
+			code:           "abcde",
+			accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			mockResp:       &pb.URLPreview{Url: "https://example.com/?a=1&b=<2>", CreatedAt: timestamppb.New(createdAt), Safety: "flagged", SafetyReason: "flagged by the link owner"},
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/html; charset=utf-8",
+			expectedBody: []string{
+				"<h1>Link preview</h1>",
+				"https://example.com/?a=1&amp;b=&lt;2&gt;",
+				"1 March 2026",
+				"flagged (flagged by the link owner)",
+				`href="abcde?confirm=1"`,
+			},
+		},
+		{
+			name:           "Not Found",
+			code:           "miss",
+			mockErr:        status.Error(codes.NotFound, "url not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedType:   "application/json",
+			expectedBody:   []string{`{"error":"short url not found"}`},
+		},
+		{
+			name:           "Expired",
+			code:           "old",
+			mockErr:        status.Error(codes.FailedPrecondition, "url has expired"),
+			expectedStatus: http.StatusGone,
+			expectedType:   "application/json",
+			expectedBody:   []string{`{"error":"short url has expired"}`},
+		},
+		{
+			name:           "Internal gRPC Error",
+			code:           "err",
+			mockErr:        errors.New("some grpc error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "application/json",
+			expectedBody:   []string{`{"error":"failed to resolve url"}`},
+		},
+		{
+			name:           "Invalid Code Format",
+			code:           "../../etc",
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "application/json",
+			expectedBody:   []string{`{"error":"invalid short code format"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				previewFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLPreview, error) {
+					return tt.mockResp, tt.mockErr
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, "/"+tt.code+"+", nil), tt.code)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			h.PreviewURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != tt.expectedType {
+				t.Errorf("expected Content-Type %s, got %s", tt.expectedType, ct)
+			}
+			for _, want := range tt.expectedBody {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("expected body to contain %s, got %s", want, rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestResolveURL_Preview(t *testing.T) {
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			t.Error("a preview should not resolve the link")
+			return nil, errors.New("unexpected call")
+		},
+		previewFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLPreview, error) {
+			return &pb.URLPreview{Url: "https://example.com/", CreatedAt: timestamppb.Now(), Safety: "ok"}, nil
+		},
+	}
+	mockAnalytics := &mockAnalyticsServiceClient{
+		trackClickFunc: func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+			t.Error("a preview should not be tracked as a click")
+			return &emptypb.Empty{}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, mockAnalytics)
+
+	req := withCode(httptest.NewRequest(http.MethodGet, "/abcde?preview=1", nil), "abcde")
+	rr := httptest.NewRecorder()
+
+	h.ResolveURL(rr, req)
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"long_url":"https://example.com/"`) {
+		t.Errorf("expected a preview, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestResolveURL_Interstitial(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedBody   []string
+		wantClick      bool
+	}{
+		{
+			name:           "Warning Page",
+			target:         "/abcde",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"This link may not be safe", "Destination domain is blocked.", "https://evil.com/", `href="abcde?confirm=1"`},
+		},
+		{
+			name:           "Confirmed",
+			target:         "/abcde?confirm=1",
+			expectedStatus: http.StatusFound,
+			wantClick:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+					return &pb.LongURL{Url: "https://evil.com/", Interstitial: true, Warning: "destination domain is blocked"}, nil
+				},
+			}
+			clicks := make(chan *analyticspb.ClickData, 1)
+			mockAnalytics := &mockAnalyticsServiceClient{
+				trackClickFunc: func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					clicks <- in
+					return &emptypb.Empty{}, nil
+				},
+			}
+			h := NewGatewayHandler(mockSvc, mockAnalytics)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, tt.target, nil), "abcde")
+			rr := httptest.NewRecorder()
+
+			h.ResolveURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			for _, want := range tt.expectedBody {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("expected body to contain %s, got %s", want, rr.Body.String())
+				}
+			}
+			if tt.expectedStatus == http.StatusFound && rr.Header().Get("Location") != "https://evil.com/" {
+				t.Errorf("expected a redirect to https://evil.com/, got %s", rr.Header().Get("Location"))
+			}
+
+			select {
+			case <-clicks:
+				if !tt.wantClick {
+					t.Error("the warning page should not be tracked as a click")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantClick {
+					t.Error("expected the confirmed redirect to be tracked")
+				}
+			}
+		})
+	}
+}
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/json", want: false},
+		{accept: "text/html", want: true},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: true},
+		{accept: "application/json, text/html", want: false},
+		{accept: "text/plain, TEXT/HTML;q=0.5", want: true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/abcde+", nil)
+		req.Header.Set("Accept", tt.accept)
+		if got := prefersHTML(req); got != tt.want {
+			t.Errorf("prefersHTML(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
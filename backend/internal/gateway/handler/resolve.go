@@ -1,19 +1,33 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"regexp"
+	"time"
 
 	pb "zipit/gen/url"
+	"zipit/pkg/redirect"
 
 	"github.com/go-chi/chi/v5"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var validShortCode = regexp.MustCompile(`^[0-9a-zA-Z]{1,12}$`)
 
-// ResolveURL handles GET /api/{code}
+// ResolveURL handles GET /api/{code}. Links flagged by their owner or the URL
+// policy get a warning page instead of a redirect, whose link comes back with
+// ?confirm=1 to go through; ?preview=1 shows the link without following it.
+// Clients that ask for JSON get the destination as a ResolveResponse instead
+// of a redirect, which is not counted as a click. Click-limited links only
+// reveal their destination by redirecting, which uses up a click, so it is
+// left out of JSON and the warning page. Password-protected links
+// take their password from the X-Link-Password header; browsers without one
+// get a form that posts it to UnlockURL. Links with routing rules send the
+// visitor where the first rule it matches says; links split into variants
+// send it to the variant picked for its visitor cookie, set on first use.
 func (h *GatewayHandler) ResolveURL(w http.ResponseWriter, r *http.Request) {
 	code := chi.URLParam(r, "code")
 	if code == "" {
@@ -25,16 +39,155 @@ func (h *GatewayHandler) ResolveURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.urlSvc.GetLongURL(r.Context(), &pb.ShortURL{Alias: code})
+	if isSet(r, "preview") {
+		h.PreviewURL(w, r)
+		return
+	}
+
+	password := r.Header.Get(passwordHeader)
+	visitor, fresh := visitorID(r)
+	req := visit(r, code, password, visitor)
+	req.LookupOnly, req.Confirmed = prefersJSON(r), isSet(r, "confirm")
+	resp, err := h.urlSvc.GetLongURL(r.Context(), req)
 	if err != nil {
-		grpcStatus, ok := status.FromError(err)
-		if ok && grpcStatus.Code() == codes.NotFound {
-			writeJSONError(w, http.StatusNotFound, "short url not found")
+		if prefersHTML(r) && writeUnlockError(w, code, password, err) {
 			return
 		}
-		writeJSONError(w, http.StatusInternalServerError, "failed to resolve url")
+		writeResolveError(w, err)
+		return
+	}
+	if resp.GetVariant() != "" && fresh {
+		setVisitorCookie(w, r, visitor)
+	}
+	// The response depends on the Accept header, so caches must key on it.
+	w.Header().Set("Vary", "Accept")
+	if prefersJSON(r) {
+		writeResolveJSON(w, resp)
 		return
 	}
+	if resp.GetInterstitial() && !isSet(r, "confirm") {
+		writeInterstitial(w, code, resp.GetUrl(), resp.GetWarning(), confirmURL(code))
+		return
+	}
+
+	redirectType := int(resp.GetRedirectType())
+	if !redirect.Valid(redirectType) {
+		redirectType = redirect.Default
+	}
+	switch {
+	case password != "":
+		// Nobody else may follow a redirect that took a password.
+		w.Header().Set("Cache-Control", "no-store")
+	case resp.GetRouted():
+		// Other visitors may be sent somewhere else.
+		w.Header().Set("Cache-Control", "private, no-cache")
+	default:
+		w.Header().Set("Cache-Control", redirectCacheControl(redirectType, resp.GetExpiresAt()))
+	}
+
+	h.trackClick(r, code, resp.GetVariant())
+	http.Redirect(w, r, resp.GetUrl(), redirectType)
+}
+
+// visit describes a request to follow the link code to the url service: the
+// password it carries, what the link's routing rules match on and the ID
+// its variant is picked by.
+func visit(r *http.Request, code, password, visitorID string) *pb.ShortURL {
+	return &pb.ShortURL{
+		Alias:          code,
+		Password:       password,
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		IpAddress:      clientIP(r),
+		VisitorId:      visitorID,
+	}
+}
+
+// permanentRedirectMaxAge is how long clients may cache a permanent redirect.
+// Browsers follow a cached redirect without asking again, so clicks go
+// uncounted and a new destination unnoticed until it runs out.
+const permanentRedirectMaxAge = 24 * time.Hour
+
+// redirectCacheControl returns the Cache-Control header for a redirect with
+// status redirectType. Permanent redirects may be cached by anyone, but not
+// past the link's expiry; temporary ones are checked with us every time.
+func redirectCacheControl(redirectType int, expiresAt *timestamppb.Timestamp) string {
+	if redirectType != http.StatusMovedPermanently && redirectType != http.StatusPermanentRedirect {
+		return "private, no-cache"
+	}
+	maxAge := permanentRedirectMaxAge
+	if expiresAt != nil {
+		maxAge = min(maxAge, time.Until(expiresAt.AsTime()))
+	}
+	if maxAge <= 0 {
+		return "private, no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+// invalidRedirectType is the error message for a redirect type links cannot use.
+const invalidRedirectType = "redirect_type must be " + redirect.Allowed
 
-	http.Redirect(w, r, resp.GetUrl(), http.StatusFound)
+// writeResolveJSON describes the link resp instead of redirecting to it.
+func writeResolveJSON(w http.ResponseWriter, resp *pb.LongURL) {
+	body := ResolveResponse{
+		LongURL:     resp.GetUrl(),
+		Title:       resp.GetTitle(),
+		Description: resp.GetDescription(),
+		Tags:        resp.GetTags(),
+		Health:      healthResponse(resp.GetHealth()),
+	}
+	if resp.GetCreatedAt() != nil {
+		createdAt := resp.GetCreatedAt().AsTime()
+		body.CreatedAt = &createdAt
+	}
+	if resp.GetExpiresAt() != nil {
+		expiresAt := resp.GetExpiresAt().AsTime()
+		body.ExpiresAt = &expiresAt
+	}
+	if resp.GetInterstitial() {
+		body.Warning = resp.GetWarning()
+		if body.Warning == "" {
+			body.Warning = "destination flagged"
+		}
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	writeJSON(w, http.StatusOK, body)
+}
+
+// healthResponse converts the health status of a link, or returns nil if its
+// destination has not been checked.
+func healthResponse(health *pb.LinkHealth) *HealthResponse {
+	if health == nil {
+		return nil
+	}
+	return &HealthResponse{
+		LastCheckedAt:       health.GetLastCheckedAt().AsTime(),
+		LastStatus:          int(health.GetLastStatus()),
+		ConsecutiveFailures: int(health.GetConsecutiveFailures()),
+	}
+}
+
+// writeResolveError maps a gRPC error from looking up a link to follow.
+func writeResolveError(w http.ResponseWriter, err error) {
+	grpcStatus, ok := status.FromError(err)
+	if ok && grpcStatus.Code() == codes.NotFound {
+		writeJSONError(w, http.StatusNotFound, "short url not found")
+		return
+	}
+	if ok && grpcStatus.Code() == codes.Unauthenticated {
+		// The password is missing or wrong; the message says which.
+		writeJSONError(w, http.StatusUnauthorized, grpcStatus.Message())
+		return
+	}
+	if ok && grpcStatus.Code() == codes.ResourceExhausted {
+		writeJSONError(w, http.StatusTooManyRequests, grpcStatus.Message())
+		return
+	}
+	if ok && grpcStatus.Code() == codes.FailedPrecondition {
+		// Expired or disabled; the message says which.
+		writeJSONError(w, http.StatusGone, "short "+grpcStatus.Message())
+		return
+	}
+	writeJSONError(w, http.StatusInternalServerError, "failed to resolve url")
 }
@@ -8,15 +8,28 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	analyticspb "zipit/gen/analytics"
 	pb "zipit/gen/url"
 
 	"github.com/go-chi/chi/v5"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+type mockAnalyticsServiceClient struct {
+	analyticspb.AnalyticsServiceClient
+	trackClickFunc func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+func (m *mockAnalyticsServiceClient) TrackClick(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.trackClickFunc(ctx, in, opts...)
+}
+
 func TestResolveURL(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -40,6 +53,27 @@ func TestResolveURL(t *testing.T) {
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   `{"error":"short url not found"}`,
 		},
+		{
+			name:           "Expired",
+			code:           "old",
+			mockErr:        status.Error(codes.FailedPrecondition, "url has expired"),
+			expectedStatus: http.StatusGone,
+			expectedBody:   `{"error":"short url has expired"}`,
+		},
+		{
+			name:           "Disabled",
+			code:           "off",
+			mockErr:        status.Error(codes.FailedPrecondition, "url has been disabled"),
+			expectedStatus: http.StatusGone,
+			expectedBody:   `{"error":"short url has been disabled"}`,
+		},
+		{
+			name:           "Exhausted",
+			code:           "once",
+			mockErr:        status.Error(codes.FailedPrecondition, "url has reached its click limit"),
+			expectedStatus: http.StatusGone,
+			expectedBody:   `{"error":"short url has reached its click limit"}`,
+		},
 		{
 			name:           "Internal gRPC Error",
 			code:           "err",
@@ -74,9 +108,10 @@ func TestResolveURL(t *testing.T) {
 					return tt.mockResp, tt.mockErr
 				},
 			}
-			h := NewGatewayHandler(mockSvc)
+			h := NewGatewayHandler(mockSvc, nil)
 
 			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/%s", tt.code), nil)
+			req.Header.Set("Accept", "application/json")
 
 			// Chi context logic to simulate {code} parameter
 			rctx := chi.NewRouteContext()
@@ -97,3 +132,431 @@ func TestResolveURL(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveURL_TracksClick(t *testing.T) {
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			return &pb.LongURL{Url: "https://example.com"}, nil
+		},
+	}
+	clicks := make(chan *analyticspb.ClickData, 1)
+	mockAnalytics := &mockAnalyticsServiceClient{
+		trackClickFunc: func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+			clicks <- in
+			return &emptypb.Empty{}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, mockAnalytics)
+
+	req := httptest.NewRequest(http.MethodGet, "/abcde", nil)
+	req.RemoteAddr = "203.0.113.7:54321"
+	req.Header.Set("User-Agent", "test-agent")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("code", "abcde")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	h.ResolveURL(rr, req)
+
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected status %d, got %d", http.StatusFound, rr.Code)
+	}
+
+	select {
+	case click := <-clicks:
+		if click.Alias != "abcde" {
+			t.Errorf("expected alias abcde, got %s", click.Alias)
+		}
+		if click.IpAddress != "203.0.113.7" {
+			t.Errorf("expected ip 203.0.113.7, got %s", click.IpAddress)
+		}
+		if click.UserAgent != "test-agent" {
+			t.Errorf("expected user agent test-agent, got %s", click.UserAgent)
+		}
+		if click.Timestamp == nil {
+			t.Error("expected timestamp to be set")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected click to be tracked")
+	}
+}
+
+func TestResolveURL_ForwardsVisitor(t *testing.T) {
+	var got *pb.ShortURL
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			got = in
+			return &pb.LongURL{Url: "https://apps.apple.com/app/id1", Routed: true}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, nil)
+
+	req := withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde")
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X)")
+	req.Header.Set("Accept-Language", "de-DE,en;q=0.5")
+	rr := httptest.NewRecorder()
+
+	h.ResolveURL(rr, req)
+
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected status %d, got %d", http.StatusFound, rr.Code)
+	}
+	if got.GetAlias() != "abcde" || got.GetUserAgent() != "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X)" ||
+		got.GetAcceptLanguage() != "de-DE,en;q=0.5" || got.GetIpAddress() != "203.0.113.7" {
+		t.Errorf("unexpected request %v", got)
+	}
+}
+
+func TestResolveURL_Variant(t *testing.T) {
+	var visitorIDs []string
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			visitorIDs = append(visitorIDs, in.GetVisitorId())
+			return &pb.LongURL{Url: "https://example.com/b", Routed: true, Variant: "B"}, nil
+		},
+	}
+	clicks := make(chan *analyticspb.ClickData, 1)
+	mockAnalytics := &mockAnalyticsServiceClient{
+		trackClickFunc: func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+			clicks <- in
+			return &emptypb.Empty{}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, mockAnalytics)
+
+	// 1. New visitors get an ID, kept in a cookie
+	rr := httptest.NewRecorder()
+	h.ResolveURL(rr, withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde"))
+
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "https://example.com/b" {
+		t.Fatalf("expected a redirect to the variant, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	if cc := rr.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("expected Cache-Control private, no-cache, got %q", cc)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != visitorCookie || cookies[0].Value != visitorIDs[0] || !cookies[0].HttpOnly || cookies[0].MaxAge <= 0 {
+		t.Fatalf("expected a visitor cookie for ID %q, got %v", visitorIDs[0], cookies)
+	}
+	select {
+	case click := <-clicks:
+		if click.Variant != "B" {
+			t.Errorf("expected the click to name variant B, got %q", click.Variant)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected click to be tracked")
+	}
+
+	// 2. Returning visitors keep their ID
+	req := withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde")
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	h.ResolveURL(rr, req)
+	<-clicks
+
+	if visitorIDs[1] != visitorIDs[0] {
+		t.Errorf("expected visitor ID %q to be forwarded, got %q", visitorIDs[0], visitorIDs[1])
+	}
+	if len(rr.Result().Cookies()) != 0 {
+		t.Errorf("expected the cookie not to be set again, got %v", rr.Result().Cookies())
+	}
+
+	// 3. Links without variants set no cookie
+	mockSvc.getLongURLFunc = func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+		return &pb.LongURL{Url: "https://example.com"}, nil
+	}
+	rr = httptest.NewRecorder()
+	h.ResolveURL(rr, withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde"))
+	<-clicks
+
+	if len(rr.Result().Cookies()) != 0 {
+		t.Errorf("expected no cookie for a link without variants, got %v", rr.Result().Cookies())
+	}
+}
+
+// TestResolveURL_MaxClicks follows a flagged link limited to one click and
+// checks that only the redirect past its warning page uses up the click, and
+// reveals the destination.
+func TestResolveURL_MaxClicks(t *testing.T) {
+	remaining := 1
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			if remaining <= 0 {
+				return nil, status.Error(codes.FailedPrecondition, "url has reached its click limit")
+			}
+			// Like the url service, for a link with an interstitial.
+			resp := &pb.LongURL{Interstitial: true, Warning: "flagged by the link owner"}
+			if !in.GetLookupOnly() && in.GetConfirmed() {
+				remaining--
+				resp.Url = "https://example.com/invite"
+			}
+			return resp, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, nil)
+
+	// 1. JSON lookups do not use up the click
+	req := withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde")
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	h.ResolveURL(rr, req)
+
+	if rr.Code != http.StatusOK || remaining != 1 || strings.Contains(rr.Body.String(), "long_url") {
+		t.Fatalf("expected the link described without its destination and with its click left, got %d, %s and %d clicks left", rr.Code, rr.Body.String(), remaining)
+	}
+
+	// 2. Neither does the warning page
+	rr = httptest.NewRecorder()
+	h.ResolveURL(rr, withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde"))
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "abcde?confirm=1") || strings.Contains(rr.Body.String(), "leads to") || remaining != 1 {
+		t.Fatalf("expected the warning page without the destination and with the click left, got %d and %d clicks left", rr.Code, remaining)
+	}
+
+	// 3. Going past it does
+	rr = httptest.NewRecorder()
+	h.ResolveURL(rr, withCode(httptest.NewRequest(http.MethodGet, "/abcde?confirm=1", nil), "abcde"))
+
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "https://example.com/invite" || remaining != 0 {
+		t.Fatalf("expected a redirect using up the click, got %d and %d clicks left", rr.Code, remaining)
+	}
+
+	// 4. After which the link is used up, also for JSON lookups
+	rr = httptest.NewRecorder()
+	h.ResolveURL(rr, withCode(httptest.NewRequest(http.MethodGet, "/abcde?confirm=1", nil), "abcde"))
+
+	if rr.Code != http.StatusGone {
+		t.Errorf("expected status %d, got %d", http.StatusGone, rr.Code)
+	}
+	req = withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde")
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	h.ResolveURL(rr, req)
+
+	if rr.Code != http.StatusGone || strings.Contains(rr.Body.String(), "example.com") {
+		t.Errorf("expected status %d without the destination, got %d: %s", http.StatusGone, rr.Code, rr.Body.String())
+	}
+}
+
+func TestResolveURL_DoesNotTrackFailedResolve(t *testing.T) {
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		},
+	}
+	mockAnalytics := &mockAnalyticsServiceClient{
+		trackClickFunc: func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+			t.Error("click should not be tracked for unresolved codes")
+			return &emptypb.Empty{}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, mockAnalytics)
+
+	req := httptest.NewRequest(http.MethodGet, "/miss", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("code", "miss")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	h.ResolveURL(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestResolveURL_RedirectType(t *testing.T) {
+	tests := []struct {
+		name           string
+		resp           *pb.LongURL
+		expectedStatus int
+		expectedCache  string
+	}{
+		{
+			name:           "Unset",
+			resp:           &pb.LongURL{Url: "https://example.com"},
+			expectedStatus: http.StatusFound,
+			expectedCache:  "private, no-cache",
+		},
+		{
+			name:           "Found",
+			resp:           &pb.LongURL{Url: "https://example.com", RedirectType: 302},
+			expectedStatus: http.StatusFound,
+			expectedCache:  "private, no-cache",
+		},
+		{
+			name:           "Temporary Redirect",
+			resp:           &pb.LongURL{Url: "https://example.com", RedirectType: 307},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedCache:  "private, no-cache",
+		},
+		{
+			name:           "Moved Permanently",
+			resp:           &pb.LongURL{Url: "https://example.com", RedirectType: 301},
+			expectedStatus: http.StatusMovedPermanently,
+			expectedCache:  "public, max-age=86400",
+		},
+		{
+			name:           "Permanent Redirect Expiring Soon",
+			resp:           &pb.LongURL{Url: "https://example.com", RedirectType: 308, ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))},
+			expectedStatus: http.StatusPermanentRedirect,
+			expectedCache:  "public, max-age=359", // just under an hour
+		},
+		{
+			name:           "Unknown",
+			resp:           &pb.LongURL{Url: "https://example.com", RedirectType: 200},
+			expectedStatus: http.StatusFound,
+			expectedCache:  "private, no-cache",
+		},
+		{
+			name:           "Permanent Redirect Routed By Rules",
+			resp:           &pb.LongURL{Url: "https://example.com", RedirectType: 301, Routed: true},
+			expectedStatus: http.StatusMovedPermanently,
+			expectedCache:  "private, no-cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+					return tt.resp, nil
+				},
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde")
+			rr := httptest.NewRecorder()
+
+			h.ResolveURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if loc := rr.Header().Get("Location"); loc != "https://example.com" {
+				t.Errorf("expected Location https://example.com, got %s", loc)
+			}
+			if cc := rr.Header().Get("Cache-Control"); !strings.HasPrefix(cc, tt.expectedCache) || len(cc) > len(tt.expectedCache)+1 {
+				t.Errorf("expected Cache-Control %q, got %q", tt.expectedCache, cc)
+			}
+		})
+	}
+}
+
+func TestResolveURL_JSON(t *testing.T) {
+	createdAt := time.Date(2026, 4, 1, 9, 30, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(24 * time.Hour)
+	tests := []struct {
+		name           string
+		accept         string
+		mockResp       *pb.LongURL
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Metadata",
+			accept:         "application/json",
+			mockResp:       &pb.LongURL{Url: "https://example.com", CreatedAt: timestamppb.New(createdAt), ExpiresAt: timestamppb.New(expiresAt), Title: "Launch", Tags: []string{"q3-campaign"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"long_url":"https://example.com","created_at":"2026-04-01T09:30:00Z","expires_at":"2026-04-02T09:30:00Z","title":"Launch","tags":["q3-campaign"]}`,
+		},
+		{
+			name:   "Health",
+			accept: "application/json",
+			mockResp: &pb.LongURL{Url: "https://example.com", Health: &pb.LinkHealth{
+				LastCheckedAt: timestamppb.New(createdAt), LastStatus: 404, ConsecutiveFailures: 3,
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"long_url":"https://example.com","health":{"last_checked_at":"2026-04-01T09:30:00Z","last_status":404,"consecutive_failures":3}}`,
+		},
+		{
+			name:           "Flagged",
+			accept:         "application/json",
+			mockResp:       &pb.LongURL{Url: "https://example.com", Interstitial: true, Warning: "flagged by the link owner"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"long_url":"https://example.com","warning":"flagged by the link owner"}`,
+		},
+		{
+			name:           "Browser",
+			accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			mockResp:       &pb.LongURL{Url: "https://example.com", CreatedAt: timestamppb.New(createdAt)},
+			expectedStatus: http.StatusFound,
+		},
+		{
+			name:           "No Accept Header",
+			mockResp:       &pb.LongURL{Url: "https://example.com"},
+			expectedStatus: http.StatusFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+					return tt.mockResp, nil
+				},
+			}
+			tracked := make(chan struct{}, 1)
+			mockAnalytics := &mockAnalyticsServiceClient{
+				trackClickFunc: func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					tracked <- struct{}{}
+					return &emptypb.Empty{}, nil
+				},
+			}
+			h := NewGatewayHandler(mockSvc, mockAnalytics)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ResolveURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("expected Vary: Accept, got %q", got)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if got := strings.TrimSpace(rr.Body.String()); got != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, got)
+			}
+			if got := rr.Header().Get("Cache-Control"); got != "private, no-cache" {
+				t.Errorf("expected Cache-Control private, no-cache, got %q", got)
+			}
+			select {
+			case <-tracked:
+				t.Error("JSON lookups should not be tracked as clicks")
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestPrefersJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/json", want: true},
+		{accept: "Application/JSON; charset=utf-8", want: true},
+		{accept: "application/json, text/html", want: true},
+		{accept: "text/html, application/json", want: false},
+		{accept: "application/xhtml+xml, application/json", want: false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/abcde", nil)
+		req.Header.Set("Accept", tt.accept)
+		if got := prefersJSON(req); got != tt.want {
+			t.Errorf("prefersJSON(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
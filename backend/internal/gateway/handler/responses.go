@@ -3,6 +3,9 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/status"
 )
 
 func writeJSON(w http.ResponseWriter, statusCode int, payload any) {
@@ -14,3 +17,43 @@ func writeJSON(w http.ResponseWriter, statusCode int, payload any) {
 func writeJSONError(w http.ResponseWriter, statusCode int, message string) {
 	writeJSON(w, statusCode, map[string]string{"error": message})
 }
+
+// writeURLNotAllowed reports a destination rejected by the url service's URL
+// policy, whose status message says why.
+func writeURLNotAllowed(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusUnprocessableEntity, URLNotAllowedResponse{
+		Error:  "url not allowed",
+		Reason: status.Convert(err).Message(),
+	})
+}
+
+// acceptsFirst returns whichever of offers comes first in the request's Accept
+// header, or "" if it names none of them. Quality values are ignored: clients
+// list the types they prefer first.
+func acceptsFirst(r *http.Request, offers ...string) string {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		for _, offer := range offers {
+			if mediaType == offer {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// prefersHTML reports whether the client asked for HTML rather than JSON.
+// Browsers list text/html first; API clients rarely mention it.
+func prefersHTML(r *http.Request) bool {
+	switch acceptsFirst(r, "text/html", "application/xhtml+xml", "application/json") {
+	case "text/html", "application/xhtml+xml":
+		return true
+	}
+	return false
+}
+
+// prefersJSON reports whether the client asked for JSON rather than HTML.
+func prefersJSON(r *http.Request) bool {
+	return acceptsFirst(r, "text/html", "application/xhtml+xml", "application/json") == "application/json"
+}
@@ -2,14 +2,35 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	pb "zipit/gen/url"
+	"zipit/pkg/alias"
+	"zipit/pkg/redirect"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Limits on links and their metadata, mirroring the url service's.
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 1000
+	maxTags              = 10
+	maxPasswordLength    = 72                      // bytes
+	maxTTLSeconds        = 10 * 365 * 24 * 60 * 60 // about ten years
+)
+
+// validTag matches the tags the url service accepts, once lower-cased.
+var validTag = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}][\p{Ll}\p{Lo}\p{N}_.:-]{0,49}$`)
+
 // ShortenURL handles POST /api/shorten
 func (h *GatewayHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB limit
@@ -21,16 +42,26 @@ func (h *GatewayHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusBadRequest, "invalid JSON payload")
 		return
 	}
-	if req.LongURL == "" {
-		writeJSONError(w, http.StatusBadRequest, "long_url is required")
+	postReq, message := req.toProto()
+	if message != "" {
+		writeJSONError(w, http.StatusBadRequest, message)
 		return
 	}
 
-	resp, err := h.urlSvc.PostURL(r.Context(), &pb.LongURL{Url: req.LongURL})
+	resp, err := h.urlSvc.PostURL(r.Context(), postReq)
 	if err != nil {
 		grpcStatus, ok := status.FromError(err)
 		if ok && grpcStatus.Code() == codes.InvalidArgument {
-			writeJSONError(w, http.StatusBadRequest, "invalid url")
+			// The message says which field is invalid.
+			writeJSONError(w, http.StatusBadRequest, strings.ToLower(grpcStatus.Message()))
+			return
+		}
+		if ok && grpcStatus.Code() == codes.AlreadyExists {
+			writeJSONError(w, http.StatusConflict, "custom alias already in use")
+			return
+		}
+		if ok && grpcStatus.Code() == codes.PermissionDenied {
+			writeURLNotAllowed(w, err)
 			return
 		}
 		writeJSONError(w, http.StatusInternalServerError, "failed to shorten url")
@@ -39,3 +70,80 @@ func (h *GatewayHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusCreated, ShortenResponse{ShortCode: resp.Alias})
 }
+
+// toProto validates req and converts it into a PostURL request. If req is
+// invalid it returns a message explaining why instead.
+func (req PostURLRequest) toProto() (*pb.LongURL, string) {
+	if req.LongURL == "" {
+		return nil, "long_url is required"
+	}
+	if req.CustomAlias != "" && (!validShortCode.MatchString(req.CustomAlias) || alias.Reserved(req.CustomAlias)) {
+		return nil, "invalid custom alias"
+	}
+
+	if req.TTLSeconds < 0 {
+		return nil, "ttl_seconds must be positive"
+	}
+	if req.TTLSeconds > maxTTLSeconds {
+		return nil, fmt.Sprintf("ttl_seconds must be at most %d", maxTTLSeconds)
+	}
+	if req.TTLSeconds > 0 && req.ExpiresAt != nil {
+		return nil, "only one of ttl_seconds and expires_at may be set"
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "expires_at must be in the future"
+	}
+	if req.RedirectType != 0 && !redirect.Valid(req.RedirectType) {
+		return nil, invalidRedirectType
+	}
+	if message := validateMetadata(req.Title, req.Description, req.Tags); message != "" {
+		return nil, message
+	}
+	if len(req.Password) > maxPasswordLength {
+		return nil, fmt.Sprintf("password must be at most %d bytes", maxPasswordLength)
+	}
+	if req.MaxClicks < 0 {
+		return nil, "max_clicks must not be negative"
+	}
+	if req.MaxClicks > math.MaxInt32 {
+		return nil, fmt.Sprintf("max_clicks must be at most %d", math.MaxInt32)
+	}
+
+	postReq := &pb.LongURL{
+		Url:          req.LongURL,
+		CustomAlias:  req.CustomAlias,
+		TtlSeconds:   req.TTLSeconds,
+		Interstitial: req.Interstitial,
+		RedirectType: int32(req.RedirectType),
+		Title:        req.Title,
+		Description:  req.Description,
+		Tags:         req.Tags,
+		Password:     req.Password,
+		MaxClicks:    int32(req.MaxClicks),
+	}
+	if req.ExpiresAt != nil {
+		postReq.ExpiresAt = timestamppb.New(*req.ExpiresAt)
+	}
+	return postReq, ""
+}
+
+// validateMetadata checks the title, description and tags of a link against
+// the url service's limits, and returns a message explaining the first
+// violation, or "" if there is none.
+func validateMetadata(title, description string, tags []string) string {
+	if utf8.RuneCountInString(strings.TrimSpace(title)) > maxTitleLength {
+		return fmt.Sprintf("title must be at most %d characters", maxTitleLength)
+	}
+	if utf8.RuneCountInString(strings.TrimSpace(description)) > maxDescriptionLength {
+		return fmt.Sprintf("description must be at most %d characters", maxDescriptionLength)
+	}
+	if len(tags) > maxTags {
+		return fmt.Sprintf("at most %d tags are allowed", maxTags)
+	}
+	for _, tag := range tags {
+		if !validTag.MatchString(strings.ToLower(strings.TrimSpace(tag))) {
+			return fmt.Sprintf("invalid tag %q", tag)
+		}
+	}
+	return ""
+}
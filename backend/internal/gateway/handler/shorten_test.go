@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,22 +14,78 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 type mockURLServiceClient struct {
 	pb.URLServiceClient
-	postURLFunc    func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error)
-	getLongURLFunc func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error)
+	postURLFunc     func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error)
+	batchPostFunc   func(ctx context.Context, in *pb.BatchLongURL, opts ...grpc.CallOption) (*pb.BatchShortURL, error)
+	getLongURLFunc  func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error)
+	previewFunc     func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLPreview, error)
+	deleteURLFunc   func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	disableURLFunc  func(ctx context.Context, in *pb.DisableURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	updateURLFunc   func(ctx context.Context, in *pb.UpdateURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	historyFunc     func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLHistory, error)
+	listURLsFunc    func(ctx context.Context, in *pb.ListURLsRequest, opts ...grpc.CallOption) (*pb.URLList, error)
+	getRulesFunc    func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.RoutingRules, error)
+	setRulesFunc    func(ctx context.Context, in *pb.SetRulesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	getVariantsFunc func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.Variants, error)
+	setVariantsFunc func(ctx context.Context, in *pb.SetVariantsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 func (m *mockURLServiceClient) PostURL(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error) {
 	return m.postURLFunc(ctx, in, opts...)
 }
 
+func (m *mockURLServiceClient) BatchPostURL(ctx context.Context, in *pb.BatchLongURL, opts ...grpc.CallOption) (*pb.BatchShortURL, error) {
+	return m.batchPostFunc(ctx, in, opts...)
+}
+
 func (m *mockURLServiceClient) GetLongURL(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
 	return m.getLongURLFunc(ctx, in, opts...)
 }
 
+func (m *mockURLServiceClient) PreviewURL(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLPreview, error) {
+	return m.previewFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) DeleteURL(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.deleteURLFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) DisableURL(ctx context.Context, in *pb.DisableURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.disableURLFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) UpdateURL(ctx context.Context, in *pb.UpdateURLRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.updateURLFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) GetURLHistory(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLHistory, error) {
+	return m.historyFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) ListURLs(ctx context.Context, in *pb.ListURLsRequest, opts ...grpc.CallOption) (*pb.URLList, error) {
+	return m.listURLsFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) GetRules(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.RoutingRules, error) {
+	return m.getRulesFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) SetRules(ctx context.Context, in *pb.SetRulesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.setRulesFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) GetVariants(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.Variants, error) {
+	return m.getVariantsFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) SetVariants(ctx context.Context, in *pb.SetVariantsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.setVariantsFunc(ctx, in, opts...)
+}
+
 func TestShortenURL(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -70,6 +127,153 @@ func TestShortenURL(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   `{"error":"invalid url"}`,
 		},
+		{
+			name:           "Custom Alias",
+			payload:        `{"long_url": "https://example.com", "custom_alias": "launch2026"}`,
+			mockResp:       &pb.ShortURL{Alias: "launch2026"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"short_code":"launch2026"}`,
+		},
+		{
+			name:           "Redirect Type",
+			payload:        `{"long_url": "https://example.com", "redirect_type": 308}`,
+			mockResp:       &pb.ShortURL{Alias: "abcde"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"short_code":"abcde"}`,
+		},
+		{
+			name:           "Invalid Redirect Type",
+			payload:        `{"long_url": "https://example.com", "redirect_type": 303}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"redirect_type must be 301, 302, 307 or 308"}`,
+		},
+		{
+			name:           "Metadata",
+			payload:        `{"long_url": "https://example.com", "title": "Launch", "description": "Launch post", "tags": ["q3-campaign", "Blog"]}`,
+			mockResp:       &pb.ShortURL{Alias: "abcde"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"short_code":"abcde"}`,
+		},
+		{
+			name:           "Invalid Tag",
+			payload:        `{"long_url": "https://example.com", "tags": ["q3 campaign"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid tag \"q3 campaign\""}`,
+		},
+		{
+			name:           "Too Many Tags",
+			payload:        `{"long_url": "https://example.com", "tags": ["a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"at most 10 tags are allowed"}`,
+		},
+		{
+			name:           "Title Too Long",
+			payload:        `{"long_url": "https://example.com", "title": "` + strings.Repeat("t", 201) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"title must be at most 200 characters"}`,
+		},
+		{
+			name:           "Invalid Custom Alias",
+			payload:        `{"long_url": "https://example.com", "custom_alias": "launch-2026!"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid custom alias"}`,
+		},
+		{
+			name:           "Custom Alias Too Long",
+			payload:        `{"long_url": "https://example.com", "custom_alias": "abcdefghijklm"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid custom alias"}`,
+		},
+		{
+			name:           "Reserved Custom Alias",
+			payload:        `{"long_url": "https://example.com", "custom_alias": "shorten"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid custom alias"}`,
+		},
+		{
+			name:           "Custom Alias Taken",
+			payload:        `{"long_url": "https://example.com", "custom_alias": "taken"}`,
+			mockErr:        status.Error(codes.AlreadyExists, "custom alias already in use"),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"custom alias already in use"}`,
+		},
+		{
+			name:           "URL Not Allowed",
+			payload:        `{"long_url": "http://169.254.169.254/latest/meta-data/"}`,
+			mockErr:        status.Error(codes.PermissionDenied, "destination is a private network address"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":"url not allowed","reason":"destination is a private network address"}`,
+		},
+		{
+			name:           "TTL Seconds",
+			payload:        `{"long_url": "https://example.com", "ttl_seconds": 3600}`,
+			mockResp:       &pb.ShortURL{Alias: "abcde"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"short_code":"abcde"}`,
+		},
+		{
+			name:           "Absolute Expiry",
+			payload:        `{"long_url": "https://example.com", "expires_at": "2999-01-01T00:00:00Z"}`,
+			mockResp:       &pb.ShortURL{Alias: "abcde"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"short_code":"abcde"}`,
+		},
+		{
+			name:           "Negative TTL",
+			payload:        `{"long_url": "https://example.com", "ttl_seconds": -5}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"ttl_seconds must be positive"}`,
+		},
+		{
+			name:           "TTL Too Long",
+			payload:        `{"long_url": "https://example.com", "ttl_seconds": 10000000000}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"ttl_seconds must be at most 315360000"}`,
+		},
+		{
+			name:           "Invalid Expiry (gRPC error)",
+			payload:        `{"long_url": "https://example.com", "expires_at": "2999-01-01T00:00:00Z"}`,
+			mockErr:        status.Error(codes.InvalidArgument, "expiry must be in the future"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"expiry must be in the future"}`,
+		},
+		{
+			name:           "Max Clicks",
+			payload:        `{"long_url": "https://example.com", "max_clicks": 1}`,
+			mockResp:       &pb.ShortURL{Alias: "abcde"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"short_code":"abcde"}`,
+		},
+		{
+			name:           "Negative Max Clicks",
+			payload:        `{"long_url": "https://example.com", "max_clicks": -1}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"max_clicks must not be negative"}`,
+		},
+		{
+			name:           "Max Clicks Too Large",
+			payload:        `{"long_url": "https://example.com", "max_clicks": 2147483648}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"max_clicks must be at most 2147483647"}`,
+		},
+		{
+			name:           "Max Clicks Wrapping To One",
+			payload:        `{"long_url": "https://example.com", "max_clicks": 4294967297}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"max_clicks must be at most 2147483647"}`,
+		},
+		{
+			name:           "TTL And Expiry Both Set",
+			payload:        `{"long_url": "https://example.com", "ttl_seconds": 60, "expires_at": "2999-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"only one of ttl_seconds and expires_at may be set"}`,
+		},
+		{
+			name:           "Expiry In The Past",
+			payload:        `{"long_url": "https://example.com", "expires_at": "2001-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"expires_at must be in the future"}`,
+		},
 		{
 			name:           "Internal gRPC Error",
 			payload:        `{"long_url": "https://example.com"}`,
@@ -86,7 +290,7 @@ func TestShortenURL(t *testing.T) {
 					return tt.mockResp, tt.mockErr
 				},
 			}
-			h := NewGatewayHandler(mockSvc)
+			h := NewGatewayHandler(mockSvc, nil)
 
 			req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.payload))
 			rr := httptest.NewRecorder()
@@ -103,3 +307,83 @@ func TestShortenURL(t *testing.T) {
 		})
 	}
 }
+
+func TestShortenURL_Interstitial(t *testing.T) {
+	var got *pb.LongURL
+	mockSvc := &mockURLServiceClient{
+		postURLFunc: func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error) {
+			got = in
+			return &pb.ShortURL{Alias: "abcde"}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"long_url": "https://example.com", "interstitial": true}`))
+	rr := httptest.NewRecorder()
+
+	h.ShortenURL(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+	}
+	if got == nil || !got.Interstitial {
+		t.Errorf("expected the interstitial option to be passed on, got %v", got)
+	}
+}
+
+func TestShortenURL_Metadata(t *testing.T) {
+	var got *pb.LongURL
+	mockSvc := &mockURLServiceClient{
+		postURLFunc: func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error) {
+			got = in
+			return &pb.ShortURL{Alias: "abcde"}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"long_url": "https://example.com", "title": "Launch", "description": "Launch post", "tags": ["q3-campaign"]}`))
+	rr := httptest.NewRecorder()
+
+	h.ShortenURL(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+	}
+	if got == nil || got.Title != "Launch" || got.Description != "Launch post" || len(got.Tags) != 1 || got.Tags[0] != "q3-campaign" {
+		t.Errorf("expected the metadata to be passed on, got %v", got)
+	}
+}
+
+func TestShortenURL_Password(t *testing.T) {
+	var got *pb.LongURL
+	mockSvc := &mockURLServiceClient{
+		postURLFunc: func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error) {
+			got = in
+			return &pb.ShortURL{Alias: "abcde"}, nil
+		},
+	}
+	h := NewGatewayHandler(mockSvc, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"long_url": "https://example.com", "password": "s3cret"}`))
+	rr := httptest.NewRecorder()
+	h.ShortenURL(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+	}
+	if got == nil || got.Password != "s3cret" {
+		t.Errorf("expected the password to be passed on, got %v", got)
+	}
+
+	got = nil
+	body := fmt.Sprintf(`{"long_url": "https://example.com", "password": %q}`, strings.Repeat("x", 73))
+	rr = httptest.NewRecorder()
+	h.ShortenURL(rr, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body)))
+
+	if rr.Code != http.StatusBadRequest || strings.TrimSpace(rr.Body.String()) != `{"error":"password must be at most 72 bytes"}` {
+		t.Errorf("expected a too long password to be rejected, got %d %s", rr.Code, rr.Body.String())
+	}
+	if got != nil {
+		t.Error("expected the url service not to be called")
+	}
+}
@@ -1,13 +1,170 @@
 package handler
 
+import "time"
+
 type PostURLRequest struct {
-	LongURL string `json:"long_url"`
+	LongURL     string     `json:"long_url"`
+	CustomAlias string     `json:"custom_alias,omitempty"`
+	TTLSeconds  int64      `json:"ttl_seconds,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// Interstitial shows visitors a warning page before redirecting them.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// RedirectType is the status visitors are redirected with: 301, 302
+	// (the default), 307 or 308.
+	RedirectType int `json:"redirect_type,omitempty"`
+	// Title, Description and Tags help the owner find the link again.
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Password, if set, must be supplied by visitors to follow the link.
+	Password string `json:"password,omitempty"`
+	// MaxClicks, if set, is how many times the link can be followed
+	// before it stops resolving.
+	MaxClicks int `json:"max_clicks,omitempty"`
 }
 
 type ShortenResponse struct {
 	ShortCode string `json:"short_code"`
 }
 
-type ResolveResponse struct {
+type URLNotAllowedResponse struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+type UpdateURLRequest struct {
+	LongURL      string `json:"long_url,omitempty"`
+	Disabled     *bool  `json:"disabled,omitempty"`
+	Interstitial *bool  `json:"interstitial,omitempty"`
+	RedirectType *int   `json:"redirect_type,omitempty"`
+}
+
+type URLChangeResponse struct {
+	OldLongURL string    `json:"old_long_url"`
+	NewLongURL string    `json:"new_long_url"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+type URLHistoryResponse struct {
+	ShortCode string              `json:"short_code"`
+	History   []URLChangeResponse `json:"history"`
+}
+
+// RoutingRule sends visitors matching every condition it sets to LongURL
+// instead of the link's destination.
+type RoutingRule struct {
+	// Platforms are "ios", "android", "windows", "macos" or "linux".
+	Platforms []string `json:"platforms,omitempty"`
+	// Languages match the visitor's preferred language; "en" matches "en-GB" too.
+	Languages []string `json:"languages,omitempty"`
+	// Countries are ISO 3166-1 alpha-2 codes.
+	Countries []string `json:"countries,omitempty"`
+	LongURL   string   `json:"long_url"`
+}
+
+type SetRulesRequest struct {
+	Rules []RoutingRule `json:"rules"` // tried in order; an empty list removes them
+}
+
+type RulesResponse struct {
+	ShortCode string        `json:"short_code"`
+	Rules     []RoutingRule `json:"rules"`
+}
+
+// Variant is one destination of an A/B split, which gets a share of visitors
+// in proportion to its Weight.
+type Variant struct {
+	Name    string `json:"name,omitempty"` // defaults to "A", "B", ... by position
 	LongURL string `json:"long_url"`
+	Weight  int32  `json:"weight"`
+}
+
+type SetVariantsRequest struct {
+	Variants []Variant `json:"variants"` // 2 to 10; an empty list removes the split
+}
+
+type VariantsResponse struct {
+	ShortCode string    `json:"short_code"`
+	Variants  []Variant `json:"variants"`
+}
+
+type ResolveResponse struct {
+	LongURL     string          `json:"long_url,omitempty"` // unset for links with a click limit, which only reveal it by redirecting
+	CreatedAt   *time.Time      `json:"created_at,omitempty"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+	Warning     string          `json:"warning,omitempty"` // set if browsers are shown a warning page first
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Health      *HealthResponse `json:"health,omitempty"` // unset until the destination has been checked
+}
+
+// HealthResponse is the outcome of the url service's periodic checks of a
+// link's destination.
+type HealthResponse struct {
+	LastCheckedAt       time.Time `json:"last_checked_at"`
+	LastStatus          int       `json:"last_status"` // 0 if the destination did not answer
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+type PreviewResponse struct {
+	ShortCode    string        `json:"short_code"`
+	LongURL      string        `json:"long_url,omitempty"` // unset for links with a click limit
+	CreatedAt    time.Time     `json:"created_at"`
+	ExpiresAt    *time.Time    `json:"expires_at,omitempty"`
+	Safety       string        `json:"safety"`                  // "ok", "flagged" or "blocked"
+	SafetyReason string        `json:"safety_reason,omitempty"` // why the destination is flagged or blocked
+	Title        string        `json:"title,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Tags         []string      `json:"tags,omitempty"`
+	Page         *PageResponse `json:"page,omitempty"` // unset until the destination page has been fetched
+}
+
+// PageResponse describes the destination page of a link, as fetched by the
+// url service in the background.
+type PageResponse struct {
+	Title      string    `json:"title,omitempty"`
+	OGTitle    string    `json:"og_title,omitempty"`
+	OGImage    string    `json:"og_image,omitempty"`
+	StatusCode int       `json:"status_code"`
+	FetchedAt  time.Time `json:"fetched_at"`
+}
+
+type LinkResponse struct {
+	ShortCode    string          `json:"short_code"`
+	LongURL      string          `json:"long_url"`
+	Title        string          `json:"title,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	Tags         []string        `json:"tags"`
+	CreatedAt    time.Time       `json:"created_at"`
+	ExpiresAt    *time.Time      `json:"expires_at,omitempty"`
+	Disabled     bool            `json:"disabled"`
+	Interstitial bool            `json:"interstitial"`
+	RedirectType int             `json:"redirect_type"`
+	Owner        string          `json:"owner,omitempty"`
+	Page         *PageResponse   `json:"page,omitempty"`
+	Health       *HealthResponse `json:"health,omitempty"`
+
+	PasswordProtected bool `json:"password_protected,omitempty"`
+	MaxClicks         int  `json:"max_clicks,omitempty"`
+	ClicksRemaining   int  `json:"clicks_remaining,omitempty"`
+}
+
+type LinkListResponse struct {
+	Links      []LinkResponse `json:"links"`
+	NextCursor string         `json:"next_cursor,omitempty"` // pass as ?cursor= for the next page
+}
+
+type BatchItemResponse struct {
+	LongURL   string `json:"long_url"`
+	ShortCode string `json:"short_code,omitempty"`
+	Status    int    `json:"status"` // the status POST /shorten would have returned
+	Error     string `json:"error,omitempty"`
+	Reason    string `json:"reason,omitempty"` // why the url is not allowed, with status 422
+}
+
+type BatchShortenResponse struct {
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Results []BatchItemResponse `json:"results"`
 }
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// passwordHeader carries the password of a protected link on API requests.
+const passwordHeader = "X-Link-Password"
+
+// maxUnlockBody bounds the size of an unlock form submission.
+const maxUnlockBody = 4 << 10
+
+// unlockPage asks for the password of a protected link. The form posts back
+// to the short link itself, relative like confirmURL.
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Protected link: {{.ShortCode}}</title>
+</head>
+<body>
+<h1>This link is password protected</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>
+{{end}}<form method="post" action="{{.ShortCode}}">
+<label for="password">Password</label>
+<input type="password" id="password" name="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// unlockPageData fills unlockPage.
+type unlockPageData struct {
+	ShortCode string
+	Error     string
+}
+
+// UnlockURL handles POST /{code}, the unlock form of a password-protected
+// link. The right password redirects to the destination with 303 See Other,
+// so the browser follows it with a GET; a wrong one shows the form again.
+// Links flagged by their owner or the URL policy show their warning page,
+// which leads straight to the destination since the password was given.
+func (h *GatewayHandler) UnlockURL(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if !validShortCode.MatchString(code) {
+		writeJSONError(w, http.StatusBadRequest, "invalid short code format")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUnlockBody)
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid form")
+		return
+	}
+	password := r.PostForm.Get("password")
+	if password == "" {
+		writeUnlockPage(w, http.StatusUnauthorized, unlockPageData{ShortCode: code, Error: "Enter the password of this link."})
+		return
+	}
+
+	visitor, fresh := visitorID(r)
+	req := visit(r, code, password, visitor)
+	// The warning page links straight to the destination, since following
+	// the link again would take the password again.
+	req.Confirmed = true
+	resp, err := h.urlSvc.GetLongURL(r.Context(), req)
+	if err != nil {
+		if !writeUnlockError(w, code, password, err) {
+			writeResolveError(w, err)
+		}
+		return
+	}
+	if resp.GetVariant() != "" && fresh {
+		setVisitorCookie(w, r, visitor)
+	}
+	if resp.GetInterstitial() {
+		writeInterstitial(w, code, resp.GetUrl(), resp.GetWarning(), resp.GetUrl())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.trackClick(r, code, resp.GetVariant())
+	http.Redirect(w, r, resp.GetUrl(), http.StatusSeeOther)
+}
+
+// writeUnlockError shows the unlock form for code if err says the link's
+// password is missing, wrong or locked, and reports whether it did. Wrong
+// passwords are told apart from missing ones by whether one was sent.
+func writeUnlockError(w http.ResponseWriter, code, password string, err error) bool {
+	data := unlockPageData{ShortCode: code}
+	statusCode := http.StatusUnauthorized
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		if password != "" {
+			data.Error = "Incorrect password."
+		}
+	case codes.ResourceExhausted:
+		statusCode, data.Error = http.StatusTooManyRequests, "Too many incorrect passwords. Try again later."
+	default:
+		return false
+	}
+	writeUnlockPage(w, statusCode, data)
+	return true
+}
+
+// writeUnlockPage renders unlockPage with statusCode.
+func writeUnlockPage(w http.ResponseWriter, statusCode int, data unlockPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := unlockPage.Execute(w, data); err != nil {
+		slog.Warn("failed to render unlock page", "alias", data.ShortCode, "error", err)
+	}
+}
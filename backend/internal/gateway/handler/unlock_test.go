@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	analyticspb "zipit/gen/analytics"
+	pb "zipit/gen/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// protectedLink mocks GetLongURL for a link with the password "s3cret",
+// which is locked against every password if locked is set.
+func protectedLink(resp *pb.LongURL, locked bool) func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+	return func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+		switch {
+		case in.Password == "":
+			return nil, status.Error(codes.Unauthenticated, "password required")
+		case locked:
+			return nil, status.Error(codes.ResourceExhausted, "too many incorrect passwords, try again later")
+		case in.Password != "s3cret":
+			return nil, status.Error(codes.Unauthenticated, "incorrect password")
+		}
+		return resp, nil
+	}
+}
+
+func TestResolveURL_Password(t *testing.T) {
+	tests := []struct {
+		name           string
+		accept         string
+		password       string
+		locked         bool
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "Header Unlocks",
+			password:       "s3cret",
+			expectedStatus: http.StatusMovedPermanently,
+		},
+		{
+			name:           "Missing Password",
+			accept:         "application/json",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   []string{`{"error":"password required"}`},
+		},
+		{
+			name:           "Wrong Password",
+			accept:         "application/json",
+			password:       "guess",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   []string{`{"error":"incorrect password"}`},
+		},
+		{
+			name:           "Locked",
+			accept:         "application/json",
+			password:       "s3cret",
+			locked:         true,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   []string{`{"error":"too many incorrect passwords, try again later"}`},
+		},
+		{
+			name:           "Browser Gets Form",
+			accept:         "text/html",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   []string{"This link is password protected", `action="abcde"`, `name="password"`},
+		},
+		{
+			name:           "Browser With Wrong Password",
+			accept:         "text/html",
+			password:       "guess",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   []string{"Incorrect password."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{
+				getLongURLFunc: protectedLink(&pb.LongURL{Url: "https://example.com/doc", RedirectType: 301}, tt.locked),
+			}
+			h := NewGatewayHandler(mockSvc, nil)
+
+			req := withCode(httptest.NewRequest(http.MethodGet, "/abcde", nil), "abcde")
+			req.Header.Set("Accept", tt.accept)
+			if tt.password != "" {
+				req.Header.Set("X-Link-Password", tt.password)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ResolveURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			for _, want := range tt.expectedBody {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("expected body to contain %s, got %s", want, rr.Body.String())
+				}
+			}
+			if tt.expectedStatus == http.StatusMovedPermanently {
+				if rr.Header().Get("Location") != "https://example.com/doc" {
+					t.Errorf("expected a redirect to the destination, got %s", rr.Header().Get("Location"))
+				}
+				// Even permanent redirects must not be cached once unlocked.
+				if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+					t.Errorf("expected Cache-Control no-store, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestUnlockURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		resp           *pb.LongURL
+		locked         bool
+		expectedStatus int
+		expectedBody   []string
+		wantLocation   string
+		wantClick      bool
+	}{
+		{
+			name:           "Correct Password",
+			form:           url.Values{"password": {"s3cret"}},
+			resp:           &pb.LongURL{Url: "https://example.com/doc"},
+			expectedStatus: http.StatusSeeOther,
+			wantLocation:   "https://example.com/doc",
+			wantClick:      true,
+		},
+		{
+			name:           "Wrong Password",
+			form:           url.Values{"password": {"guess"}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   []string{"Incorrect password.", `action="abcde"`},
+		},
+		{
+			name:           "Empty Password",
+			form:           url.Values{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   []string{"Enter the password of this link."},
+		},
+		{
+			name:           "Locked",
+			form:           url.Values{"password": {"s3cret"}},
+			locked:         true,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   []string{"Too many incorrect passwords. Try again later."},
+		},
+		{
+			name:           "Flagged Destination",
+			form:           url.Values{"password": {"s3cret"}},
+			resp:           &pb.LongURL{Url: "https://evil.com/", Interstitial: true, Warning: "destination domain is blocked"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"This link may not be safe", `href="https://evil.com/"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockURLServiceClient{getLongURLFunc: protectedLink(tt.resp, tt.locked)}
+			clicks := make(chan *analyticspb.ClickData, 1)
+			mockAnalytics := &mockAnalyticsServiceClient{
+				trackClickFunc: func(ctx context.Context, in *analyticspb.ClickData, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					clicks <- in
+					return &emptypb.Empty{}, nil
+				},
+			}
+			h := NewGatewayHandler(mockSvc, mockAnalytics)
+
+			req := withCode(httptest.NewRequest(http.MethodPost, "/abcde", strings.NewReader(tt.form.Encode())), "abcde")
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+
+			h.UnlockURL(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			for _, want := range tt.expectedBody {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("expected body to contain %s, got %s", want, rr.Body.String())
+				}
+			}
+			if got := rr.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected Location %q, got %q", tt.wantLocation, got)
+			}
+
+			select {
+			case <-clicks:
+				if !tt.wantClick {
+					t.Error("expected no click to be tracked")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantClick {
+					t.Error("expected the unlocked redirect to be tracked")
+				}
+			}
+		})
+	}
+}
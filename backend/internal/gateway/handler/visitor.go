@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"crypto/rand"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// visitorCookie keeps a visitor's ID across visits, so that links split into
+// A/B variants keep sending the visitor to the same one.
+const visitorCookie = "zipit_visitor"
+
+// visitorCookieMaxAge is how long a visitor keeps its ID, and so its variants.
+const visitorCookieMaxAge = 365 * 24 * time.Hour
+
+var validVisitorID = regexp.MustCompile(`^[0-9A-Za-z]{16,64}$`)
+
+// storedVisitorID returns the visitor ID the request's cookie carries, or ""
+// if it has none.
+func storedVisitorID(r *http.Request) string {
+	cookie, err := r.Cookie(visitorCookie)
+	if err != nil || !validVisitorID.MatchString(cookie.Value) {
+		return ""
+	}
+	return cookie.Value
+}
+
+// visitorID returns the visitor ID of the request, or a new one if it has
+// none; fresh reports whether the ID is new and so still needs its cookie.
+func visitorID(r *http.Request) (id string, fresh bool) {
+	if id := storedVisitorID(r); id != "" {
+		return id, false
+	}
+	return rand.Text(), true
+}
+
+// setVisitorCookie hands the visitor its new ID. The cookie is only set once a
+// link with variants is followed, so other visitors are never given one.
+func setVisitorCookie(w http.ResponseWriter, r *http.Request, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(visitorCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,74 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	authpb "zipit/gen/auth"
+	"zipit/pkg/identity"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// apiKey returns the API key sent as "Authorization: Bearer <key>" or "X-API-Key: <key>".
+func apiKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(key)
+	}
+	return ""
+}
+
+// Authenticate validates the API key sent with a request, if any, and attaches
+// its owner to the request context with identity.WithOwner, so that calls to
+// the backend services made with that context carry it. Requests with an
+// unknown or revoked key are rejected; requests without one stay anonymous.
+func Authenticate(auth authpb.AuthServiceClient) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKey(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			resp, err := auth.Authenticate(r.Context(), &authpb.APIKey{Key: key})
+			if err != nil {
+				if status.Code(err) == codes.Unauthenticated {
+					writeUnauthorized(w, "invalid api key")
+					return
+				}
+				writeJSONError(w, http.StatusInternalServerError, "failed to authenticate")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(identity.WithOwner(r.Context(), resp.GetOwner())))
+		})
+	}
+}
+
+// RequireOwner rejects requests that were not authenticated with an API key.
+func RequireOwner(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity.Owner(r.Context()) == "" {
+			writeUnauthorized(w, "api key is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeJSONError(w, http.StatusUnauthorized, message)
+}
+
+func writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
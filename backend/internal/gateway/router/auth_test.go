@@ -0,0 +1,148 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authpb "zipit/gen/auth"
+	pb "zipit/gen/url"
+	"zipit/internal/gateway/handler"
+	"zipit/pkg/identity"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type mockAuthServiceClient struct {
+	authpb.AuthServiceClient
+	owners map[string]string
+	err    error
+}
+
+func (m *mockAuthServiceClient) Authenticate(ctx context.Context, in *authpb.APIKey, opts ...grpc.CallOption) (*authpb.Identity, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if owner, ok := m.owners[in.Key]; ok {
+		return &authpb.Identity{Owner: owner}, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid api key")
+}
+
+func TestAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{name: "None", headers: map[string]string{}, want: ""},
+		{name: "Bearer", headers: map[string]string{"Authorization": "Bearer zk_abc"}, want: "zk_abc"},
+		{name: "Lowercase scheme", headers: map[string]string{"Authorization": "bearer zk_abc"}, want: "zk_abc"},
+		{name: "Basic scheme", headers: map[string]string{"Authorization": "Basic dXNlcg=="}, want: ""},
+		{name: "X-API-Key", headers: map[string]string{"X-API-Key": "zk_abc"}, want: "zk_abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := apiKey(req); got != tt.want {
+				t.Errorf("expected key %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthRoutes(t *testing.T) {
+	auth := &mockAuthServiceClient{owners: map[string]string{"zk_acme": "acme"}}
+
+	tests := []struct {
+		name           string
+		cfg            Config
+		method         string
+		path           string
+		body           string
+		key            string
+		expectedStatus int
+		expectedOwner  string
+	}{
+		{name: "Shorten with key", cfg: Config{Auth: auth}, method: http.MethodPost, path: "/shorten", body: `{"long_url":"https://example.com"}`, key: "zk_acme", expectedStatus: http.StatusCreated, expectedOwner: "acme"},
+		{name: "Shorten without key", cfg: Config{Auth: auth}, method: http.MethodPost, path: "/shorten", body: `{"long_url":"https://example.com"}`, expectedStatus: http.StatusUnauthorized},
+		{name: "Shorten with invalid key", cfg: Config{Auth: auth, AllowAnonymousShorten: true}, method: http.MethodPost, path: "/shorten", body: `{"long_url":"https://example.com"}`, key: "zk_bogus", expectedStatus: http.StatusUnauthorized},
+		{name: "Anonymous shorten allowed", cfg: Config{Auth: auth, AllowAnonymousShorten: true}, method: http.MethodPost, path: "/shorten", body: `{"long_url":"https://example.com"}`, expectedStatus: http.StatusCreated},
+		{name: "Batch without key", cfg: Config{Auth: auth, AllowAnonymousShorten: true}, method: http.MethodPost, path: "/shorten/batch", body: `[{"long_url":"https://example.com"}]`, expectedStatus: http.StatusUnauthorized},
+		{name: "Delete without key", cfg: Config{Auth: auth, AllowAnonymousShorten: true}, method: http.MethodDelete, path: "/abc", expectedStatus: http.StatusUnauthorized},
+		{name: "Delete with key", cfg: Config{Auth: auth}, method: http.MethodDelete, path: "/abc", key: "zk_acme", expectedStatus: http.StatusNoContent, expectedOwner: "acme"},
+		{name: "Resolve without key", cfg: Config{Auth: auth}, method: http.MethodGet, path: "/abc", expectedStatus: http.StatusFound},
+		{name: "Auth disabled", cfg: Config{}, method: http.MethodPost, path: "/shorten", body: `{"long_url":"https://example.com"}`, expectedStatus: http.StatusCreated},
+		{name: "Delete with auth disabled", cfg: Config{}, method: http.MethodDelete, path: "/abc", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOwner string
+			owner := func(ctx context.Context) {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				if values := md.Get(identity.MetadataKey); len(values) > 0 {
+					gotOwner = values[0]
+				}
+			}
+			mockSvc := &mockURLServiceClient{
+				postURLFunc: func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error) {
+					owner(ctx)
+					return &pb.ShortURL{Alias: "abc"}, nil
+				},
+				getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+					return &pb.LongURL{Url: "https://example.com"}, nil
+				},
+				deleteURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+					owner(ctx)
+					return &emptypb.Empty{}, nil
+				},
+			}
+			r := New(handler.NewGatewayHandler(mockSvc, nil), tt.cfg)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("expected WWW-Authenticate: Bearer on 401")
+			}
+			if gotOwner != tt.expectedOwner {
+				t.Errorf("expected owner %q in gRPC metadata, got %q", tt.expectedOwner, gotOwner)
+			}
+		})
+	}
+}
+
+func TestAuthServiceDown(t *testing.T) {
+	auth := &mockAuthServiceClient{err: errors.New("connection refused")}
+	r := New(handler.NewGatewayHandler(&mockURLServiceClient{}, nil), Config{Auth: auth})
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"long_url":"https://example.com"}`))
+	req.Header.Set("X-API-Key", "zk_acme")
+	rr := httptest.NewRecorder()
+
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zipit/pkg/identity"
+	"zipit/pkg/ratelimit"
+)
+
+// clientKey identifies the caller a request is billed to: its API key owner
+// when authenticated, otherwise its IP address as set by RealIP.
+func clientKey(r *http.Request) string {
+	if owner := identity.Owner(r.Context()); owner != "" {
+		return "key:" + owner
+	}
+	return ipKey(r)
+}
+
+// ipKey identifies the caller by its IP address as set by RealIP.
+func ipKey(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + ip
+}
+
+// RateLimit takes a token from the caller's bucket in limiter for every request
+// and rejects the request with 429 once the bucket is empty. It reports the
+// caller's budget in X-RateLimit-* headers. It must run after Authenticate so
+// that authenticated callers get a budget per API key rather than per IP. If
+// the limiter fails, requests are let through rather than taking the gateway
+// down with it.
+func RateLimit(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return limitBy(limiter, clientKey)
+}
+
+// LimitKeyChecks is RateLimit per IP for requests with an API key, and must
+// run before Authenticate, so that each key it checks is paid for up front.
+// Requests without a key pass through.
+func LimitKeyChecks(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return limitBy(limiter, func(r *http.Request) string {
+		if apiKey(r) == "" {
+			return ""
+		}
+		return ipKey(r)
+	})
+}
+
+// limitBy rate limits requests by the caller key returns, skipping those it
+// returns "" for.
+func limitBy(limiter ratelimit.Limiter, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller := key(r)
+			if caller == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := limiter.Allow(r.Context(), caller)
+			if err != nil {
+				slog.Error("failed to apply rate limit", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("X-RateLimit-Reset", seconds(res.Reset))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", seconds(res.RetryAfter))
+				writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds formats d as a whole number of seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
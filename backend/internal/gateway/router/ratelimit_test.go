@@ -0,0 +1,186 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authpb "zipit/gen/auth"
+	pb "zipit/gen/url"
+	"zipit/internal/gateway/handler"
+	"zipit/pkg/ratelimit"
+
+	"google.golang.org/grpc"
+)
+
+type errLimiter struct{}
+
+func (errLimiter) Allow(ctx context.Context, key string) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func newRateLimitedRouter(cfg Config) http.Handler {
+	mockSvc := &mockURLServiceClient{
+		postURLFunc: func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error) {
+			return &pb.ShortURL{Alias: "abc"}, nil
+		},
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			return &pb.LongURL{Url: "https://example.com"}, nil
+		},
+	}
+	return New(handler.NewGatewayHandler(mockSvc, nil), cfg)
+}
+
+func shorten(r http.Handler, remoteAddr, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"long_url":"https://example.com"}`))
+	req.RemoteAddr = remoteAddr
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimit_Shorten(t *testing.T) {
+	r := newRateLimitedRouter(Config{
+		ShortenLimiter: ratelimit.NewMemoryLimiter(ratelimit.Limit{Rate: 1.0 / 60, Burst: 2}),
+	})
+
+	for i := 0; i < 2; i++ {
+		rr := shorten(r, "1.2.3.4:1000", "")
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("request %d: expected status 201, got %d", i, rr.Code)
+		}
+		if rr.Header().Get("X-RateLimit-Limit") != "2" || rr.Header().Get("X-RateLimit-Remaining") != []string{"1", "0"}[i] {
+			t.Errorf("request %d: unexpected rate limit headers %v", i, rr.Header())
+		}
+	}
+
+	rr := shorten(r, "1.2.3.4:2000", "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+	if got := rr.Header().Get("X-RateLimit-Reset"); got != "120" {
+		t.Errorf("expected X-RateLimit-Reset 120, got %q", got)
+	}
+	if !strings.Contains(rr.Body.String(), "rate limit exceeded") {
+		t.Errorf("unexpected body %q", rr.Body.String())
+	}
+
+	// Other clients have their own budget
+	if rr := shorten(r, "5.6.7.8:1000", ""); rr.Code != http.StatusCreated {
+		t.Errorf("expected another IP to be allowed, got %d", rr.Code)
+	}
+}
+
+func TestRateLimit_PerAPIKey(t *testing.T) {
+	r := newRateLimitedRouter(Config{
+		Auth:           &mockAuthServiceClient{owners: map[string]string{"zk_acme": "acme", "zk_other": "other"}},
+		ShortenLimiter: ratelimit.NewMemoryLimiter(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1}),
+	})
+
+	if rr := shorten(r, "1.2.3.4:1000", "zk_acme"); rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rr.Code)
+	}
+	// The same key is throttled from any address...
+	if rr := shorten(r, "5.6.7.8:1000", "zk_acme"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 for the same key, got %d", rr.Code)
+	}
+	// ...while another key behind the same address is not
+	if rr := shorten(r, "1.2.3.4:1000", "zk_other"); rr.Code != http.StatusCreated {
+		t.Errorf("expected status 201 for another key, got %d", rr.Code)
+	}
+}
+
+// countingAuthServiceClient counts the API keys checked with the auth service.
+type countingAuthServiceClient struct {
+	mockAuthServiceClient
+	calls int
+}
+
+func (m *countingAuthServiceClient) Authenticate(ctx context.Context, in *authpb.APIKey, opts ...grpc.CallOption) (*authpb.Identity, error) {
+	m.calls++
+	return m.mockAuthServiceClient.Authenticate(ctx, in, opts...)
+}
+
+func TestRateLimit_InvalidAPIKeys(t *testing.T) {
+	auth := &countingAuthServiceClient{mockAuthServiceClient: mockAuthServiceClient{owners: map[string]string{"zk_acme": "acme"}}}
+	r := newRateLimitedRouter(Config{
+		Auth:        auth,
+		AuthLimiter: ratelimit.NewMemoryLimiter(ratelimit.Limit{Rate: 1.0 / 60, Burst: 3}),
+	})
+
+	for i := 0; i < 3; i++ {
+		if rr := shorten(r, "1.2.3.4:1000", fmt.Sprintf("zk_guess%d", i)); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("request %d: expected status 401, got %d", i, rr.Code)
+		}
+	}
+	// Made-up keys are no longer checked once the address is out of budget
+	if rr := shorten(r, "1.2.3.4:1000", "zk_guess3"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rr.Code)
+	}
+	if auth.calls != 3 {
+		t.Errorf("expected 3 keys checked, got %d", auth.calls)
+	}
+
+	// Other addresses, and requests without a key, have their own budget
+	if rr := shorten(r, "5.6.7.8:1000", "zk_acme"); rr.Code != http.StatusCreated {
+		t.Errorf("expected status 201 from another address, got %d", rr.Code)
+	}
+	if rr := shorten(r, "1.2.3.4:1000", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without a key, got %d", rr.Code)
+	}
+}
+
+func TestRateLimit_SeparateResolveBudget(t *testing.T) {
+	r := newRateLimitedRouter(Config{
+		ShortenLimiter: ratelimit.NewMemoryLimiter(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1}),
+		ResolveLimiter: ratelimit.NewMemoryLimiter(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1}),
+	})
+
+	_ = shorten(r, "1.2.3.4:1000", "")
+	if rr := shorten(r, "1.2.3.4:1000", ""); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the shorten budget to be spent, got %d", rr.Code)
+	}
+
+	resolve := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.RemoteAddr = "1.2.3.4:1000"
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := resolve(); code != http.StatusFound {
+		t.Errorf("expected resolving to have its own budget, got %d", code)
+	}
+	if code := resolve(); code != http.StatusTooManyRequests {
+		t.Errorf("expected the resolve budget to be spent, got %d", code)
+	}
+
+	// The health check is never throttled
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.RemoteAddr = "1.2.3.4:1000"
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected /health to return 200, got %d", rr.Code)
+		}
+	}
+}
+
+func TestRateLimit_LimiterDown(t *testing.T) {
+	r := newRateLimitedRouter(Config{ShortenLimiter: errLimiter{}})
+
+	if rr := shorten(r, "1.2.3.4:1000", ""); rr.Code != http.StatusCreated {
+		t.Errorf("expected requests to be let through, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP sets the remote address of requests from one of the trusted proxies
+// to the client address they forwarded in X-Forwarded-For or X-Real-IP.
+// Requests from anyone else keep the address they connected from: the headers
+// are whatever the sender put in them, and believing them would let callers
+// pick a new address per request to get around per-IP rate limits.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwardedIP(r, trusted); ok {
+				r.RemoteAddr = ip.String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedIP returns the client address a trusted proxy forwarded r for.
+// Every proxy appends the address it got the request from to
+// X-Forwarded-For, so the list is read from the right, past the trusted
+// proxies: the entries left of the first untrusted one may be made up.
+func forwardedIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil || !isTrusted(peer.Addr(), trusted) {
+		return netip.Addr{}, false
+	}
+
+	if hops := r.Header.Values("X-Forwarded-For"); len(hops) > 0 {
+		var client netip.Addr
+		entries := strings.Split(strings.Join(hops, ","), ",")
+		for i := len(entries) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(entries[i]))
+			if err != nil {
+				break
+			}
+			client = addr.Unmap()
+			if !isTrusted(client, trusted) {
+				break
+			}
+		}
+		return client, client.IsValid()
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// isTrusted reports whether addr belongs to one of the trusted proxies.
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"zipit/pkg/ratelimit"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.7/32")}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{name: "Direct", remoteAddr: "203.0.113.7:1000", want: "203.0.113.7:1000"},
+		{name: "Spoofed X-Forwarded-For", remoteAddr: "203.0.113.7:1000", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7:1000"},
+		{name: "Spoofed X-Real-IP", remoteAddr: "203.0.113.7:1000", realIP: "198.51.100.1", want: "203.0.113.7:1000"},
+		{name: "Trusted proxy", remoteAddr: "10.1.2.3:1000", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "Trusted proxy X-Real-IP", remoteAddr: "192.0.2.7:1000", realIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "Chain of trusted proxies", remoteAddr: "10.1.2.3:1000", forwarded: []string{"203.0.113.7, 192.0.2.7", "10.4.5.6"}, want: "203.0.113.7"},
+		{name: "Made up entries", remoteAddr: "10.1.2.3:1000", forwarded: []string{"198.51.100.1, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "Only trusted proxies", remoteAddr: "10.1.2.3:1000", forwarded: []string{"10.4.5.6"}, want: "10.4.5.6"},
+		{name: "Invalid entry", remoteAddr: "10.1.2.3:1000", forwarded: []string{"garbage"}, want: "10.1.2.3:1000"},
+		{name: "IPv4-mapped proxy", remoteAddr: "[::ffff:10.1.2.3]:1000", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, forwarded := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("expected remote address %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimit_IgnoresSpoofedHeaders(t *testing.T) {
+	r := newRateLimitedRouter(Config{
+		ShortenLimiter: ratelimit.NewMemoryLimiter(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1}),
+	})
+
+	for i, forwarded := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"long_url":"https://example.com"}`))
+		req.RemoteAddr = "1.2.3.4:1000"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.Header.Set("X-Real-IP", forwarded)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if want := []int{http.StatusCreated, http.StatusTooManyRequests}[i]; rr.Code != want {
+			t.Errorf("request %d: expected status %d, got %d", i, want, rr.Code)
+		}
+	}
+}
@@ -2,23 +2,64 @@ package router
 
 import (
 	"net/http"
+	"net/netip"
 
+	authpb "zipit/gen/auth"
 	"zipit/internal/gateway/handler"
+	"zipit/pkg/metrics"
+	"zipit/pkg/ratelimit"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-func New(h *handler.GatewayHandler) http.Handler {
+// Config holds the gateway's access settings.
+type Config struct {
+	// Auth validates API keys. If nil, authentication is disabled: every
+	// request is anonymous, and the endpoints managing links are not served,
+	// since anyone could manage anyone's links.
+	Auth authpb.AuthServiceClient
+	// AllowAnonymousShorten lets requests without an API key create links.
+	AllowAnonymousShorten bool
+	// AllowedOrigins lists the origins allowed by CORS; empty allows any origin.
+	AllowedOrigins []string
+	// ShortenLimiter throttles creating and managing links, and ResolveLimiter
+	// throttles resolving them. AuthLimiter throttles requests with an API
+	// key per IP before the key is checked. A nil limiter disables rate
+	// limiting.
+	ShortenLimiter ratelimit.Limiter
+	ResolveLimiter ratelimit.Limiter
+	AuthLimiter    ratelimit.Limiter
+	// Metrics, if set, collects request metrics. They are not served here,
+	// but on an admin port the caller keeps private.
+	Metrics *prometheus.Registry
+	// TrustedProxies are the reverse proxies in front of the gateway, whose
+	// X-Forwarded-For and X-Real-IP headers give the client's address. The
+	// headers of everyone else are ignored.
+	TrustedProxies []netip.Prefix
+}
+
+func New(h *handler.GatewayHandler, cfg Config) http.Handler {
 	r := chi.NewRouter()
 
+	allowedOrigins := cfg.AllowedOrigins
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"*"}
+	}
+
+	r.Use(RealIP(cfg.TrustedProxies))
 	r.Use(middleware.Logger)
+	if cfg.Metrics != nil {
+		// Outside Recoverer, so that panics are counted as the 500 they become
+		r.Use(metrics.NewHTTPMetrics(cfg.Metrics).Middleware)
+	}
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type"},
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Link-Password"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -30,8 +71,60 @@ func New(h *handler.GatewayHandler) http.Handler {
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
 
-	r.Post("/shorten", h.ShortenURL)
-	r.Get("/{code}", h.ResolveURL)
+	// Managing a link always needs an API key, since links are scoped to
+	// their owner, and so does bulk shortening. Shortening a single link may
+	// be left open to anonymous callers. Without authentication, only
+	// shortening and following links are served.
+	var shortenAuth, manageAuth []func(http.Handler) http.Handler
+	if cfg.Auth != nil {
+		manageAuth = []func(http.Handler) http.Handler{Authenticate(cfg.Auth), RequireOwner}
+		shortenAuth = manageAuth
+		if cfg.AllowAnonymousShorten {
+			shortenAuth = manageAuth[:1]
+		}
+	}
+
+	// Limits apply after authentication, so callers with an API key are
+	// throttled per key and anonymous callers per IP.
+	var resolveLimit []func(http.Handler) http.Handler
+	if cfg.ShortenLimiter != nil {
+		shortenAuth = withLimit(shortenAuth, cfg.ShortenLimiter)
+		manageAuth = withLimit(manageAuth, cfg.ShortenLimiter)
+	}
+	if cfg.ResolveLimiter != nil {
+		resolveLimit = []func(http.Handler) http.Handler{RateLimit(cfg.ResolveLimiter)}
+	}
+	// Checking an API key costs a call to the auth service, so callers are
+	// also throttled per IP before, or made-up keys could be tried freely.
+	if cfg.Auth != nil && cfg.AuthLimiter != nil {
+		shortenAuth = append([]func(http.Handler) http.Handler{LimitKeyChecks(cfg.AuthLimiter)}, shortenAuth...)
+		manageAuth = append([]func(http.Handler) http.Handler{LimitKeyChecks(cfg.AuthLimiter)}, manageAuth...)
+	}
+
+	r.With(shortenAuth...).Post("/shorten", h.ShortenURL)
+	r.With(resolveLimit...).Get("/{code}", h.ResolveURL)
+	r.With(resolveLimit...).Get("/{code}+", h.PreviewURL)
+	r.With(resolveLimit...).Post("/{code}", h.UnlockURL)
+	if cfg.Auth != nil {
+		r.With(manageAuth...).Post("/shorten/batch", h.ShortenBatch)
+		r.With(manageAuth...).Get("/links", h.ListLinks)
+		r.With(manageAuth...).Patch("/{code}", h.UpdateURL)
+		r.With(manageAuth...).Delete("/{code}", h.DeleteURL)
+		r.With(manageAuth...).Get("/{code}/history", h.GetURLHistory)
+		r.With(manageAuth...).Get("/{code}/rules", h.GetRules)
+		r.With(manageAuth...).Put("/{code}/rules", h.SetRules)
+		r.With(manageAuth...).Get("/{code}/variants", h.GetVariants)
+		r.With(manageAuth...).Put("/{code}/variants", h.SetVariants)
+	}
 
 	return r
 }
+
+// withLimit returns a copy of middlewares with a RateLimit middleware inserted
+// right after Authenticate, or first if there is no authentication.
+func withLimit(middlewares []func(http.Handler) http.Handler, limiter ratelimit.Limiter) []func(http.Handler) http.Handler {
+	at := min(1, len(middlewares))
+	out := append([]func(http.Handler) http.Handler{}, middlewares[:at]...)
+	out = append(out, RateLimit(limiter))
+	return append(out, middlewares[at:]...)
+}
@@ -9,14 +9,23 @@ import (
 
 	pb "zipit/gen/url"
 	"zipit/internal/gateway/handler"
+	"zipit/pkg/alias"
+	"zipit/pkg/metrics"
 
+	"github.com/go-chi/chi/v5"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 type mockURLServiceClient struct {
 	pb.URLServiceClient
 	postURLFunc    func(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error)
 	getLongURLFunc func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error)
+	previewFunc    func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLPreview, error)
+	deleteURLFunc  func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	listURLsFunc   func(ctx context.Context, in *pb.ListURLsRequest, opts ...grpc.CallOption) (*pb.URLList, error)
 }
 
 func (m *mockURLServiceClient) PostURL(ctx context.Context, in *pb.LongURL, opts ...grpc.CallOption) (*pb.ShortURL, error) {
@@ -27,10 +36,22 @@ func (m *mockURLServiceClient) GetLongURL(ctx context.Context, in *pb.ShortURL,
 	return m.getLongURLFunc(ctx, in, opts...)
 }
 
+func (m *mockURLServiceClient) PreviewURL(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLPreview, error) {
+	return m.previewFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) DeleteURL(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.deleteURLFunc(ctx, in, opts...)
+}
+
+func (m *mockURLServiceClient) ListURLs(ctx context.Context, in *pb.ListURLsRequest, opts ...grpc.CallOption) (*pb.URLList, error) {
+	return m.listURLsFunc(ctx, in, opts...)
+}
+
 func TestHealthEndpoint(t *testing.T) {
 	mockSvc := &mockURLServiceClient{}
-	h := handler.NewGatewayHandler(mockSvc)
-	r := New(h)
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{})
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rr := httptest.NewRecorder()
@@ -51,10 +72,38 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestMetrics(t *testing.T) {
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		},
+	}
+	registry := metrics.NewRegistry()
+	r := New(handler.NewGatewayHandler(mockSvc, nil), Config{Metrics: registry})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	// The metrics are kept off the public router
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	metrics.Handler(registry).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	want := `zipit_http_requests_total{code="200",method="GET",route="/health"} 1`
+	if !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("expected %s in the metrics, got:\n%s", want, rr.Body.String())
+	}
+}
+
 func TestRouteNotFound(t *testing.T) {
 	mockSvc := &mockURLServiceClient{}
-	h := handler.NewGatewayHandler(mockSvc)
-	r := New(h)
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{})
 
 	req := httptest.NewRequest(http.MethodGet, "/nonexistent", nil)
 	rr := httptest.NewRecorder()
@@ -68,8 +117,8 @@ func TestRouteNotFound(t *testing.T) {
 
 func TestCORSPreflight(t *testing.T) {
 	mockSvc := &mockURLServiceClient{}
-	h := handler.NewGatewayHandler(mockSvc)
-	r := New(h)
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{})
 
 	req := httptest.NewRequest(http.MethodOptions, "/api/shorten", nil)
 	req.Header.Set("Origin", "https://example.com")
@@ -89,8 +138,8 @@ func TestCORSPreflight(t *testing.T) {
 
 func TestMethodNotAllowed(t *testing.T) {
 	mockSvc := &mockURLServiceClient{}
-	h := handler.NewGatewayHandler(mockSvc)
-	r := New(h)
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{})
 
 	// DELETE is not allowed on /api/shorten
 	req := httptest.NewRequest(http.MethodDelete, "/api/shorten", nil)
@@ -109,8 +158,8 @@ func TestShortenRouteExists(t *testing.T) {
 			return &pb.ShortURL{Alias: "abc"}, nil
 		},
 	}
-	h := handler.NewGatewayHandler(mockSvc)
-	r := New(h)
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{})
 
 	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"long_url":"https://example.com"}`))
 	req.Header.Set("Content-Type", "application/json")
@@ -129,8 +178,8 @@ func TestResolveRouteExists(t *testing.T) {
 			return &pb.LongURL{Url: "https://example.com"}, nil
 		},
 	}
-	h := handler.NewGatewayHandler(mockSvc)
-	r := New(h)
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/abc", nil)
 	rr := httptest.NewRecorder()
@@ -141,3 +190,116 @@ func TestResolveRouteExists(t *testing.T) {
 		t.Errorf("expected status 200, got %d", rr.Code)
 	}
 }
+
+func TestPreviewRoute(t *testing.T) {
+	var previewed string
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			t.Errorf("expected a preview, got a resolve of %s", in.Alias)
+			return &pb.LongURL{Url: "https://example.com"}, nil
+		},
+		previewFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.URLPreview, error) {
+			previewed = in.Alias
+			return &pb.URLPreview{Url: "https://example.com", Safety: "ok"}, nil
+		},
+	}
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc+", nil)
+	rr := httptest.NewRecorder()
+
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+	if previewed != "abc" {
+		t.Errorf("expected abc to be previewed, got %q", previewed)
+	}
+}
+
+func TestListLinksRoute(t *testing.T) {
+	listed := false
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			t.Errorf("expected a list, got a resolve of %s", in.Alias)
+			return &pb.LongURL{Url: "https://example.com"}, nil
+		},
+		listURLsFunc: func(ctx context.Context, in *pb.ListURLsRequest, opts ...grpc.CallOption) (*pb.URLList, error) {
+			listed = true
+			return &pb.URLList{}, nil
+		},
+	}
+	h := handler.NewGatewayHandler(mockSvc, nil)
+	r := New(h, Config{Auth: &mockAuthServiceClient{owners: map[string]string{"zk_acme": "acme"}}})
+
+	req := httptest.NewRequest(http.MethodGet, "/links?tag=q3-campaign", nil)
+	req.Header.Set("X-API-Key", "zk_acme")
+	rr := httptest.NewRecorder()
+
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || !listed {
+		t.Errorf("expected the links to be listed, got status %d", rr.Code)
+	}
+}
+
+// TestAuthDisabled_NoManagementRoutes checks that links cannot be managed
+// without authentication, since every caller would be the same anonymous
+// owner of every link.
+func TestAuthDisabled_NoManagementRoutes(t *testing.T) {
+	// Any other call of the url service panics.
+	mockSvc := &mockURLServiceClient{
+		getLongURLFunc: func(ctx context.Context, in *pb.ShortURL, opts ...grpc.CallOption) (*pb.LongURL, error) {
+			return nil, status.Error(codes.NotFound, "url not found")
+		},
+	}
+	r := New(handler.NewGatewayHandler(mockSvc, nil), Config{})
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/shorten/batch", `[{"long_url":"https://example.com"}]`},
+		{http.MethodGet, "/links", ""},
+		{http.MethodPatch, "/abc", `{"long_url":"https://evil.com"}`},
+		{http.MethodDelete, "/abc", ""},
+		{http.MethodGet, "/abc/history", ""},
+		{http.MethodGet, "/abc/rules", ""},
+		{http.MethodPut, "/abc/rules", `{"rules":[]}`},
+		{http.MethodGet, "/abc/variants", ""},
+		{http.MethodPut, "/abc/variants", `{"variants":[]}`},
+	}
+	for _, route := range routes {
+		req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+		rr := httptest.NewRecorder()
+
+		r.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotFound && rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected status 404 or 405, got %d", route.method, route.path, rr.Code)
+		}
+	}
+}
+
+// TestRoutesReserved checks that every path the gateway serves itself is a
+// reserved alias, since a link created under it could never be followed.
+func TestRoutesReserved(t *testing.T) {
+	r := New(handler.NewGatewayHandler(&mockURLServiceClient{}, nil), Config{
+		Auth:    &mockAuthServiceClient{},
+		Metrics: metrics.NewRegistry(),
+	})
+
+	err := chi.Walk(r.(chi.Routes), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if !strings.Contains(segment, "{") && !alias.Reserved(segment) {
+			t.Errorf("%s %s: expected %q to be a reserved alias", method, route, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk the routes: %v", err)
+	}
+}
@@ -3,48 +3,672 @@ package grpc
 import (
 	"context"
 	"errors"
+	"io"
+	"strconv"
+	"time"
 	pb "zipit/gen/url"
+	"zipit/internal/url/policy"
+	"zipit/internal/url/repository"
+	"zipit/internal/url/rules"
 	"zipit/internal/url/service"
+	"zipit/pkg/identity"
 
+	"google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type URLHandler struct {
 	pb.UnimplementedURLServiceServer
-	svc service.URLService
+	svc    service.URLService
+	admins map[string]bool
 }
 
-func NewURLHandler(svc service.URLService) *URLHandler {
-	return &URLHandler{svc: svc}
+// NewURLHandler serves svc over gRPC. The owners in admins may list the
+// links of every owner.
+func NewURLHandler(svc service.URLService, admins []string) *URLHandler {
+	h := &URLHandler{svc: svc, admins: map[string]bool{}}
+	for _, owner := range admins {
+		h.admins[owner] = true
+	}
+	return h
 }
 
 func (h *URLHandler) PostURL(ctx context.Context, req *pb.LongURL) (*pb.ShortURL, error) {
 	if req == nil || req.Url == "" {
 		return nil, status.Error(codes.InvalidArgument, "url is required")
 	}
+	opts, err := shortenOptions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
-	shortCode, err := h.svc.ShortenURL(ctx, req.Url)
+	shortCode, err := h.svc.ShortenURL(ctx, req.Url, opts)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidURL) {
-			return nil, status.Error(codes.InvalidArgument, "invalid URL")
-		}
-		return nil, status.Error(codes.Internal, "failed to shorten URL")
+		return nil, shortenStatus(err)
 	}
 	return &pb.ShortURL{Alias: shortCode}, nil
 }
 
+// BatchPostURL shortens up to service.MaxBatchSize urls at once, with a result
+// per url so that one invalid url does not fail the others.
+func (h *URLHandler) BatchPostURL(ctx context.Context, req *pb.BatchLongURL) (*pb.BatchShortURL, error) {
+	if req == nil || len(req.Urls) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "urls are required")
+	}
+	if len(req.Urls) > service.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d urls are allowed per batch", service.MaxBatchSize)
+	}
+
+	results, err := h.shortenBatch(ctx, req.Urls)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.BatchShortURL{Results: results}, nil
+}
+
+// maxStreamURLs bounds how many urls one BatchPostURLStream call may send,
+// since all results are held until the stream ends.
+const maxStreamURLs = 100 * service.MaxBatchSize
+
+// BatchPostURLStream shortens the urls sent on the stream, storing them in
+// batches of service.MaxBatchSize as they arrive, and returns a result per url
+// once the client closes the stream. Stored batches stay stored if the stream
+// fails later on, so their results are still returned: the urls received but
+// not stored fail with the error, and those after them get no result, which
+// tells the client what to send again.
+func (h *URLHandler) BatchPostURLStream(stream grpc.ClientStreamingServer[pb.LongURL, pb.BatchShortURL]) error {
+	ctx := stream.Context()
+	var results []*pb.BatchResult
+	batch := make([]*pb.LongURL, 0, service.MaxBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		batchResults, err := h.shortenBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		results = append(results, batchResults...)
+		batch = batch[:0]
+		return nil
+	}
+	// fail ends the stream with err, or with the results so far if any urls
+	// have been stored.
+	fail := func(err error) error {
+		if len(results) == 0 {
+			return err
+		}
+		failed := batchError(err)
+		for range batch {
+			results = append(results, failed)
+		}
+		return stream.SendAndClose(&pb.BatchShortURL{Results: results})
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if len(results)+len(batch) == maxStreamURLs {
+			return fail(status.Errorf(codes.InvalidArgument, "at most %d urls are allowed per stream", maxStreamURLs))
+		}
+		batch = append(batch, req)
+		if len(batch) == service.MaxBatchSize {
+			if err := flush(); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return fail(err)
+	}
+	if len(results) == 0 {
+		return status.Error(codes.InvalidArgument, "urls are required")
+	}
+	return stream.SendAndClose(&pb.BatchShortURL{Results: results})
+}
+
+// shortenBatch shortens reqs with one service call and returns their results
+// in order. Requests that are invalid on their own fail without reaching the
+// service.
+func (h *URLHandler) shortenBatch(ctx context.Context, reqs []*pb.LongURL) ([]*pb.BatchResult, error) {
+	results := make([]*pb.BatchResult, len(reqs))
+	items := make([]service.BatchItem, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		if req.Url == "" {
+			results[i] = batchError(status.Error(codes.InvalidArgument, "url is required"))
+			continue
+		}
+		opts, err := shortenOptions(ctx, req)
+		if err != nil {
+			results[i] = batchError(err)
+			continue
+		}
+		items = append(items, service.BatchItem{LongURL: req.Url, Opts: opts})
+		indexes = append(indexes, i)
+	}
+	if len(items) == 0 {
+		return results, nil
+	}
+
+	created, err := h.svc.ShortenURLs(ctx, items)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to shorten urls")
+	}
+	for n, res := range created {
+		if res.Err != nil {
+			results[indexes[n]] = batchError(shortenStatus(res.Err))
+		} else {
+			results[indexes[n]] = &pb.BatchResult{Alias: res.ShortCode}
+		}
+	}
+	return results, nil
+}
+
+// batchError converts a status error into a failed batch result.
+func batchError(err error) *pb.BatchResult {
+	st := status.Convert(err)
+	return &pb.BatchResult{Code: int32(st.Code()), Error: st.Message()}
+}
+
+// maxTTLSeconds bounds ttl_seconds to about ten years, far below where the
+// lifetime would overflow a time.Duration.
+const maxTTLSeconds = 10 * 365 * 24 * 60 * 60
+
+// shortenOptions reads the options of a shorten request. The owner comes from
+// the request metadata.
+func shortenOptions(ctx context.Context, req *pb.LongURL) (service.ShortenOptions, error) {
+	opts := service.ShortenOptions{
+		CustomAlias:  req.CustomAlias,
+		Owner:        identity.FromIncomingContext(ctx),
+		Interstitial: req.Interstitial,
+		RedirectType: int(req.RedirectType),
+		Title:        req.Title,
+		Description:  req.Description,
+		Tags:         req.Tags,
+		Password:     req.Password,
+		MaxClicks:    int(req.MaxClicks),
+	}
+	switch {
+	case req.TtlSeconds < 0:
+		return opts, status.Error(codes.InvalidArgument, "ttl_seconds must be positive")
+	case req.TtlSeconds > maxTTLSeconds:
+		return opts, status.Errorf(codes.InvalidArgument, "ttl_seconds must be at most %d", maxTTLSeconds)
+	case req.TtlSeconds > 0 && req.ExpiresAt != nil:
+		return opts, status.Error(codes.InvalidArgument, "only one of ttl_seconds and expires_at may be set")
+	case req.TtlSeconds > 0:
+		expiresAt := time.Now().Add(time.Duration(req.TtlSeconds) * time.Second)
+		opts.ExpiresAt = &expiresAt
+	case req.ExpiresAt != nil:
+		expiresAt := req.ExpiresAt.AsTime()
+		opts.ExpiresAt = &expiresAt
+	}
+	return opts, nil
+}
+
+// shortenStatus maps an error of ShortenURL to a gRPC status.
+func shortenStatus(err error) error {
+	if errors.Is(err, service.ErrInvalidURL) {
+		return status.Error(codes.InvalidArgument, "invalid URL")
+	}
+	if errors.Is(err, service.ErrInvalidAlias) {
+		return status.Error(codes.InvalidArgument, "invalid custom alias")
+	}
+	if errors.Is(err, service.ErrInvalidExpiry) {
+		return status.Error(codes.InvalidArgument, "expiry must be in the future")
+	}
+	if errors.Is(err, service.ErrInvalidRedirectType) {
+		return status.Error(codes.InvalidArgument, "invalid redirect type")
+	}
+	if errors.Is(err, service.ErrInvalidMetadata) {
+		// The message says which limit was exceeded.
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, service.ErrInvalidPassword) {
+		return status.Errorf(codes.InvalidArgument, "password must be at most %d bytes", service.MaxPasswordLength)
+	}
+	if errors.Is(err, service.ErrInvalidMaxClicks) {
+		return status.Error(codes.InvalidArgument, "max_clicks must not be negative")
+	}
+	if errors.Is(err, service.ErrAliasTaken) {
+		return status.Error(codes.AlreadyExists, "custom alias already in use")
+	}
+	if errors.Is(err, service.ErrBlockedURL) {
+		return policyStatus(err)
+	}
+	return status.Error(codes.Internal, "failed to shorten URL")
+}
+
+// policyStatus maps a URL policy violation to PermissionDenied. The gateway
+// shows the message to the client as the reason the url was rejected.
+func policyStatus(err error) error {
+	var violation *policy.Violation
+	if errors.As(err, &violation) {
+		return status.Error(codes.PermissionDenied, violation.Reason)
+	}
+	return status.Error(codes.PermissionDenied, "url not allowed")
+}
+
 func (h *URLHandler) GetLongURL(ctx context.Context, req *pb.ShortURL) (*pb.LongURL, error) {
 	if req == nil || req.Alias == "" {
 		return nil, status.Error(codes.InvalidArgument, "alias is required")
 	}
-	longURL, err := h.svc.GetLongURL(ctx, req.Alias)
+	link, err := h.svc.GetLongURL(ctx, req.Alias, resolveOptions(req))
+	if err != nil {
+		return nil, linkStatus(err)
+	}
+	resp := &pb.LongURL{
+		Url:          link.LongURL,
+		Interstitial: link.Interstitial(),
+		RedirectType: int32(link.RedirectType),
+		CreatedAt:    timestamppb.New(link.CreatedAt),
+		Title:        link.Title,
+		Description:  link.Description,
+		Tags:         link.Tags,
+		Health:       linkHealth(link.Health),
+		Routed:       link.Routed,
+		Variant:      link.Variant,
+	}
+	if resp.Interstitial {
+		resp.Warning = link.SafetyReason
+	}
+	if link.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(*link.ExpiresAt)
+	}
+	return resp, nil
+}
+
+// PreviewURL describes the link for a short code without following it.
+func (h *URLHandler) PreviewURL(ctx context.Context, req *pb.ShortURL) (*pb.URLPreview, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	link, err := h.svc.PreviewURL(ctx, req.Alias, resolveOptions(req))
+	if err != nil {
+		return nil, linkStatus(err)
+	}
+	resp := &pb.URLPreview{
+		Url:          link.LongURL,
+		CreatedAt:    timestamppb.New(link.CreatedAt),
+		Safety:       link.Safety,
+		SafetyReason: link.SafetyReason,
+		Title:        link.Title,
+		Description:  link.Description,
+		Tags:         link.Tags,
+		Page:         pageInfo(link.Page),
+	}
+	if link.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(*link.ExpiresAt)
+	}
+	return resp, nil
+}
+
+// resolveOptions reads what the visitor supplied with a request to follow a
+// link.
+func resolveOptions(req *pb.ShortURL) service.ResolveOptions {
+	return service.ResolveOptions{
+		Password: req.Password,
+		Visitor: rules.Visitor{
+			UserAgent:      req.UserAgent,
+			AcceptLanguage: req.AcceptLanguage,
+			IP:             req.IpAddress,
+		},
+		VisitorID:  req.VisitorId,
+		LookupOnly: req.LookupOnly,
+		Confirmed:  req.Confirmed,
+	}
+}
+
+// pageInfo converts the fetched destination page of a link, or returns nil
+// if it has not been fetched.
+func pageInfo(page repository.PageInfo) *pb.PageInfo {
+	if page.FetchedAt == nil {
+		return nil
+	}
+	return &pb.PageInfo{
+		Title:      page.Title,
+		OgTitle:    page.OGTitle,
+		OgImage:    page.OGImage,
+		StatusCode: int32(page.StatusCode),
+		FetchedAt:  timestamppb.New(*page.FetchedAt),
+	}
+}
+
+// linkHealth converts the health status of a link, or returns nil if its
+// destination has not been checked.
+func linkHealth(health repository.HealthStatus) *pb.LinkHealth {
+	if health.LastCheckedAt == nil {
+		return nil
+	}
+	return &pb.LinkHealth{
+		LastCheckedAt:       timestamppb.New(*health.LastCheckedAt),
+		LastStatus:          int32(health.LastStatus),
+		ConsecutiveFailures: int32(health.ConsecutiveFailures),
+	}
+}
+
+// linkStatus maps an error looking up a link to follow to a gRPC status.
+func linkStatus(err error) error {
+	if errors.Is(err, service.ErrNotFound) {
+		return status.Error(codes.NotFound, "url not found")
+	}
+	// The gateway shows these messages to the client, prefixed with "short ".
+	if errors.Is(err, service.ErrExpired) {
+		return status.Error(codes.FailedPrecondition, "url has expired")
+	}
+	if errors.Is(err, service.ErrDisabled) {
+		return status.Error(codes.FailedPrecondition, "url has been disabled")
+	}
+	if errors.Is(err, service.ErrExhausted) {
+		return status.Error(codes.FailedPrecondition, "url has reached its click limit")
+	}
+	if errors.Is(err, service.ErrPasswordRequired) {
+		return status.Error(codes.Unauthenticated, "password required")
+	}
+	if errors.Is(err, service.ErrWrongPassword) {
+		return status.Error(codes.Unauthenticated, "incorrect password")
+	}
+	if errors.Is(err, service.ErrPasswordLocked) {
+		return status.Error(codes.ResourceExhausted, "too many incorrect passwords, try again later")
+	}
+	return status.Error(codes.Internal, "failed to fetch url")
+}
+
+func (h *URLHandler) DeleteURL(ctx context.Context, req *pb.ShortURL) (*emptypb.Empty, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	if err := h.svc.DeleteURL(ctx, identity.FromIncomingContext(ctx), req.Alias); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "url not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to delete url")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (h *URLHandler) DisableURL(ctx context.Context, req *pb.DisableURLRequest) (*emptypb.Empty, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	if err := h.svc.DisableURL(ctx, identity.FromIncomingContext(ctx), req.Alias, req.Disabled); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "url not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to update url")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// UpdateURL changes the destination of a link, its settings, or both. The
+// destination is changed first, so a rejected destination changes nothing,
+// but a failure to change the settings leaves the new destination in place.
+func (h *URLHandler) UpdateURL(ctx context.Context, req *pb.UpdateURLRequest) (*emptypb.Empty, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	settings := repository.LinkSettings{Interstitial: req.Interstitial}
+	if req.RedirectType != nil {
+		redirectType := int(*req.RedirectType)
+		settings.RedirectType = &redirectType
+	}
+	if req.Url == "" && settings == (repository.LinkSettings{}) {
+		return nil, status.Error(codes.InvalidArgument, "url or a setting is required")
+	}
+
+	owner := identity.FromIncomingContext(ctx)
+	if req.Url != "" {
+		if err := h.svc.UpdateURL(ctx, owner, req.Alias, req.Url); err != nil {
+			if errors.Is(err, service.ErrInvalidURL) {
+				return nil, status.Error(codes.InvalidArgument, "invalid URL")
+			}
+			if errors.Is(err, service.ErrBlockedURL) {
+				return nil, policyStatus(err)
+			}
+			if errors.Is(err, service.ErrNotFound) {
+				return nil, status.Error(codes.NotFound, "url not found")
+			}
+			return nil, status.Error(codes.Internal, "failed to update url")
+		}
+	}
+	if settings != (repository.LinkSettings{}) {
+		if err := h.svc.UpdateSettings(ctx, owner, req.Alias, settings); err != nil {
+			if errors.Is(err, service.ErrInvalidRedirectType) {
+				return nil, status.Error(codes.InvalidArgument, "invalid redirect type")
+			}
+			if errors.Is(err, service.ErrNotFound) {
+				return nil, status.Error(codes.NotFound, "url not found")
+			}
+			return nil, status.Error(codes.Internal, "failed to update url")
+		}
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (h *URLHandler) GetURLHistory(ctx context.Context, req *pb.ShortURL) (*pb.URLHistory, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	changes, err := h.svc.GetURLHistory(ctx, identity.FromIncomingContext(ctx), req.Alias)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "url not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to fetch url history")
+	}
+
+	resp := &pb.URLHistory{Changes: make([]*pb.URLChange, 0, len(changes))}
+	for _, c := range changes {
+		resp.Changes = append(resp.Changes, &pb.URLChange{
+			OldUrl:    c.OldLongURL,
+			NewUrl:    c.NewLongURL,
+			ChangedAt: timestamppb.New(c.ChangedAt),
+		})
+	}
+	return resp, nil
+}
+
+func (h *URLHandler) GetRules(ctx context.Context, req *pb.ShortURL) (*pb.RoutingRules, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	linkRules, err := h.svc.GetRules(ctx, identity.FromIncomingContext(ctx), req.Alias)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "url not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to fetch url rules")
+	}
+
+	resp := &pb.RoutingRules{Rules: make([]*pb.RoutingRule, 0, len(linkRules))}
+	for _, r := range linkRules {
+		resp.Rules = append(resp.Rules, &pb.RoutingRule{
+			Platforms: r.Platforms,
+			Languages: r.Languages,
+			Countries: r.Countries,
+			Url:       r.LongURL,
+		})
+	}
+	return resp, nil
+}
+
+func (h *URLHandler) SetRules(ctx context.Context, req *pb.SetRulesRequest) (*emptypb.Empty, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	linkRules := make([]repository.Rule, 0, len(req.Rules))
+	for _, r := range req.Rules {
+		linkRules = append(linkRules, repository.Rule{
+			Platforms: r.GetPlatforms(),
+			Languages: r.GetLanguages(),
+			Countries: r.GetCountries(),
+			LongURL:   r.GetUrl(),
+		})
+	}
+
+	if err := h.svc.SetRules(ctx, identity.FromIncomingContext(ctx), req.Alias, linkRules); err != nil {
+		if errors.Is(err, service.ErrInvalidRules) {
+			// The message says which rule is invalid.
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, service.ErrBlockedURL) {
+			return nil, policyStatus(err)
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "url not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to update url rules")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (h *URLHandler) GetVariants(ctx context.Context, req *pb.ShortURL) (*pb.Variants, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	variants, err := h.svc.GetVariants(ctx, identity.FromIncomingContext(ctx), req.Alias)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
 			return nil, status.Error(codes.NotFound, "url not found")
 		}
-		return nil, status.Error(codes.Internal, "failed to fetch url")
+		return nil, status.Error(codes.Internal, "failed to fetch url variants")
+	}
+
+	resp := &pb.Variants{Variants: make([]*pb.Variant, 0, len(variants))}
+	for _, v := range variants {
+		resp.Variants = append(resp.Variants, &pb.Variant{Name: v.Name, Url: v.LongURL, Weight: int32(v.Weight)})
+	}
+	return resp, nil
+}
+
+func (h *URLHandler) SetVariants(ctx context.Context, req *pb.SetVariantsRequest) (*emptypb.Empty, error) {
+	if req == nil || req.Alias == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+	variants := make([]repository.Variant, 0, len(req.Variants))
+	for _, v := range req.Variants {
+		variants = append(variants, repository.Variant{Name: v.GetName(), LongURL: v.GetUrl(), Weight: int(v.GetWeight())})
+	}
+
+	if err := h.svc.SetVariants(ctx, identity.FromIncomingContext(ctx), req.Alias, variants); err != nil {
+		if errors.Is(err, service.ErrInvalidVariants) {
+			// The message says which variant is invalid.
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, service.ErrBlockedURL) {
+			return nil, policyStatus(err)
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "url not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to update url variants")
 	}
-	return &pb.LongURL{Url: longURL}, nil
+	return &emptypb.Empty{}, nil
+}
+
+// maxSearchLength bounds the destination substring ListURLs searches for.
+const maxSearchLength = 200
+
+// ListURLs lists a page of links. Callers see their own links unless they are
+// admins, who may list another owner's links or those of every owner.
+func (h *URLHandler) ListURLs(ctx context.Context, req *pb.ListURLsRequest) (*pb.URLList, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	filter, err := h.listFilter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := h.svc.ListURLs(ctx, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFilter) {
+			return nil, status.Error(codes.InvalidArgument, "created_after must be before created_before")
+		}
+		return nil, status.Error(codes.Internal, "failed to list urls")
+	}
+
+	resp := &pb.URLList{Urls: make([]*pb.URLInfo, 0, len(page.URLs))}
+	for _, u := range page.URLs {
+		info := &pb.URLInfo{
+			Alias:        u.ShortCode,
+			Url:          u.LongURL,
+			Title:        u.Title,
+			Description:  u.Description,
+			Tags:         u.Tags,
+			CreatedAt:    timestamppb.New(u.CreatedAt),
+			Disabled:     u.DisabledAt != nil,
+			Interstitial: u.Interstitial,
+			RedirectType: int32(u.RedirectType),
+			Owner:        u.Owner,
+			Page:         pageInfo(u.Page),
+			Health:       linkHealth(u.Health),
+
+			PasswordProtected: u.PasswordHash != "",
+		}
+		if u.ExpiresAt != nil {
+			info.ExpiresAt = timestamppb.New(*u.ExpiresAt)
+		}
+		if u.MaxClicks > 0 {
+			info.MaxClicks, info.ClicksRemaining = int32(u.MaxClicks), int32(u.ClicksRemaining)
+		}
+		resp.Urls = append(resp.Urls, info)
+	}
+	if page.NextCursor > 0 {
+		resp.NextCursor = strconv.FormatInt(page.NextCursor, 10)
+	}
+	return resp, nil
+}
 
+// listFilter validates a ListURLs request and converts it into a filter,
+// checking that the caller may list the owners it selects.
+func (h *URLHandler) listFilter(ctx context.Context, req *pb.ListURLsRequest) (repository.ListFilter, error) {
+	caller := identity.FromIncomingContext(ctx)
+	filter := repository.ListFilter{
+		Owner:     caller,
+		AllOwners: req.AllOwners,
+		Tag:       req.Tag,
+		Domain:    req.Domain,
+		Search:    req.Search,
+		Limit:     int(req.Limit),
+	}
+	if req.Owner != nil {
+		filter.Owner = *req.Owner
+	}
+
+	switch {
+	case req.Limit < 0 || req.Limit > service.MaxListLimit:
+		return filter, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", service.MaxListLimit)
+	case req.Owner != nil && req.AllOwners:
+		return filter, status.Error(codes.InvalidArgument, "only one of owner and all_owners may be set")
+	case len(req.Search) > maxSearchLength:
+		return filter, status.Errorf(codes.InvalidArgument, "search must be at most %d characters", maxSearchLength)
+	case (req.AllOwners || filter.Owner != caller) && !h.admins[caller]:
+		return filter, status.Error(codes.PermissionDenied, "only admins may list the links of other owners")
+	}
+	if req.Cursor != "" {
+		id, err := strconv.ParseInt(req.Cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return filter, status.Error(codes.InvalidArgument, "invalid cursor")
+		}
+		filter.BeforeID = id
+	}
+	if req.CreatedAfter != nil {
+		createdAfter := req.CreatedAfter.AsTime()
+		filter.CreatedAfter = &createdAfter
+	}
+	if req.CreatedBefore != nil {
+		createdBefore := req.CreatedBefore.AsTime()
+		filter.CreatedBefore = &createdBefore
+	}
+	return filter, nil
 }
@@ -3,23 +3,97 @@ package grpc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	pb "zipit/gen/url"
+	"zipit/internal/url/policy"
+	"zipit/internal/url/repository"
 	"zipit/internal/url/service"
+	"zipit/pkg/identity"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type mockURLService struct {
-	shortenURLFunc func(ctx context.Context, longURL string) (string, error)
-	getLongURLFunc func(ctx context.Context, shortCode string) (string, error)
+	shortenURLFunc  func(ctx context.Context, longURL string, opts service.ShortenOptions) (string, error)
+	shortenURLsFunc func(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error)
+	getLongURLFunc  func(ctx context.Context, shortCode string, opts service.ResolveOptions) (*service.Link, error)
+	previewURLFunc  func(ctx context.Context, shortCode string, opts service.ResolveOptions) (*service.Link, error)
+	deleteURLFunc   func(ctx context.Context, owner, shortCode string) error
+	disableURLFunc  func(ctx context.Context, owner, shortCode string, disabled bool) error
+	updateURLFunc   func(ctx context.Context, owner, shortCode, longURL string) error
+	settingsFunc    func(ctx context.Context, owner, shortCode string, settings repository.LinkSettings) error
+	historyFunc     func(ctx context.Context, owner, shortCode string) ([]repository.URLChange, error)
+	getRulesFunc    func(ctx context.Context, owner, shortCode string) ([]repository.Rule, error)
+	setRulesFunc    func(ctx context.Context, owner, shortCode string, linkRules []repository.Rule) error
+	getVariantsFunc func(ctx context.Context, owner, shortCode string) ([]repository.Variant, error)
+	setVariantsFunc func(ctx context.Context, owner, shortCode string, variants []repository.Variant) error
+	listURLsFunc    func(ctx context.Context, filter repository.ListFilter) (*service.ListPage, error)
+}
+
+func (m *mockURLService) ShortenURL(ctx context.Context, longURL string, opts service.ShortenOptions) (string, error) {
+	return m.shortenURLFunc(ctx, longURL, opts)
+}
+
+func (m *mockURLService) ShortenURLs(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error) {
+	return m.shortenURLsFunc(ctx, items)
+}
+
+func (m *mockURLService) GetLongURL(ctx context.Context, shortCode string, opts service.ResolveOptions) (*service.Link, error) {
+	return m.getLongURLFunc(ctx, shortCode, opts)
+}
+
+func (m *mockURLService) PreviewURL(ctx context.Context, shortCode string, opts service.ResolveOptions) (*service.Link, error) {
+	return m.previewURLFunc(ctx, shortCode, opts)
+}
+
+func (m *mockURLService) DeleteURL(ctx context.Context, owner, shortCode string) error {
+	return m.deleteURLFunc(ctx, owner, shortCode)
+}
+
+func (m *mockURLService) DisableURL(ctx context.Context, owner, shortCode string, disabled bool) error {
+	return m.disableURLFunc(ctx, owner, shortCode, disabled)
+}
+
+func (m *mockURLService) UpdateURL(ctx context.Context, owner, shortCode, longURL string) error {
+	return m.updateURLFunc(ctx, owner, shortCode, longURL)
 }
 
-func (m *mockURLService) ShortenURL(ctx context.Context, longURL string) (string, error) {
-	return m.shortenURLFunc(ctx, longURL)
+func (m *mockURLService) UpdateSettings(ctx context.Context, owner, shortCode string, settings repository.LinkSettings) error {
+	return m.settingsFunc(ctx, owner, shortCode, settings)
 }
 
-func (m *mockURLService) GetLongURL(ctx context.Context, shortCode string) (string, error) {
-	return m.getLongURLFunc(ctx, shortCode)
+func (m *mockURLService) GetURLHistory(ctx context.Context, owner, shortCode string) ([]repository.URLChange, error) {
+	return m.historyFunc(ctx, owner, shortCode)
+}
+
+func (m *mockURLService) GetRules(ctx context.Context, owner, shortCode string) ([]repository.Rule, error) {
+	return m.getRulesFunc(ctx, owner, shortCode)
+}
+
+func (m *mockURLService) SetRules(ctx context.Context, owner, shortCode string, linkRules []repository.Rule) error {
+	return m.setRulesFunc(ctx, owner, shortCode, linkRules)
+}
+
+func (m *mockURLService) GetVariants(ctx context.Context, owner, shortCode string) ([]repository.Variant, error) {
+	return m.getVariantsFunc(ctx, owner, shortCode)
+}
+
+func (m *mockURLService) SetVariants(ctx context.Context, owner, shortCode string, variants []repository.Variant) error {
+	return m.setVariantsFunc(ctx, owner, shortCode, variants)
+}
+
+func (m *mockURLService) ListURLs(ctx context.Context, filter repository.ListFilter) (*service.ListPage, error) {
+	return m.listURLsFunc(ctx, filter)
 }
 
 func TestPostURL(t *testing.T) {
@@ -59,22 +133,157 @@ func TestPostURL(t *testing.T) {
 			mockErr:     errors.New("db down"),
 			wantErrCode: "Internal",
 		},
+		{
+			name:      "Custom Alias",
+			req:       &pb.LongURL{Url: "https://example.com", CustomAlias: "launch"},
+			mockCode:  "launch",
+			wantAlias: "launch",
+		},
+		{
+			name:        "Service Returns ErrInvalidAlias",
+			req:         &pb.LongURL{Url: "https://example.com", CustomAlias: "bad-alias"},
+			mockErr:     service.ErrInvalidAlias,
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:      "TTL Seconds",
+			req:       &pb.LongURL{Url: "https://example.com", TtlSeconds: 3600},
+			mockCode:  "abcde",
+			wantAlias: "abcde",
+		},
+		{
+			name:      "Interstitial",
+			req:       &pb.LongURL{Url: "https://example.com", Interstitial: true},
+			mockCode:  "abcde",
+			wantAlias: "abcde",
+		},
+		{
+			name:      "Redirect Type",
+			req:       &pb.LongURL{Url: "https://example.com", RedirectType: 308},
+			mockCode:  "abcde",
+			wantAlias: "abcde",
+		},
+		{
+			name:        "Service Returns ErrInvalidRedirectType",
+			req:         &pb.LongURL{Url: "https://example.com", RedirectType: 200},
+			mockErr:     service.ErrInvalidRedirectType,
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:      "Metadata",
+			req:       &pb.LongURL{Url: "https://example.com", Title: "Launch", Description: "Launch post", Tags: []string{"q3-campaign"}},
+			mockCode:  "abcde",
+			wantAlias: "abcde",
+		},
+		{
+			name:        "Service Returns ErrInvalidMetadata",
+			req:         &pb.LongURL{Url: "https://example.com", Tags: []string{"two words"}},
+			mockErr:     fmt.Errorf("%w: invalid tag %q", service.ErrInvalidMetadata, "two words"),
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:      "Password",
+			req:       &pb.LongURL{Url: "https://example.com", Password: "s3cret"},
+			mockCode:  "abcde",
+			wantAlias: "abcde",
+		},
+		{
+			name:        "Service Returns ErrInvalidPassword",
+			req:         &pb.LongURL{Url: "https://example.com", Password: strings.Repeat("x", 73)},
+			mockErr:     service.ErrInvalidPassword,
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:      "Max Clicks",
+			req:       &pb.LongURL{Url: "https://example.com", MaxClicks: 1},
+			mockCode:  "abcde",
+			wantAlias: "abcde",
+		},
+		{
+			name:        "Service Returns ErrInvalidMaxClicks",
+			req:         &pb.LongURL{Url: "https://example.com", MaxClicks: -1},
+			mockErr:     service.ErrInvalidMaxClicks,
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:        "Negative TTL",
+			req:         &pb.LongURL{Url: "https://example.com", TtlSeconds: -1},
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:        "TTL Too Long",
+			req:         &pb.LongURL{Url: "https://example.com", TtlSeconds: 10_000_000_000},
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:        "TTL And Expiry Both Set",
+			req:         &pb.LongURL{Url: "https://example.com", TtlSeconds: 60, ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))},
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:        "Service Returns ErrInvalidExpiry",
+			req:         &pb.LongURL{Url: "https://example.com", ExpiresAt: timestamppb.New(time.Now().Add(-time.Hour))},
+			mockErr:     service.ErrInvalidExpiry,
+			wantErrCode: "InvalidArgument",
+		},
+		{
+			name:        "Service Returns ErrBlockedURL",
+			req:         &pb.LongURL{Url: "http://169.254.169.254/"},
+			mockErr:     fmt.Errorf("%w: %w", service.ErrBlockedURL, &policy.Violation{Reason: "destination is a private network address"}),
+			wantErrCode: "PermissionDenied",
+		},
+		{
+			name:        "Service Returns ErrPolicyCheck",
+			req:         &pb.LongURL{Url: "https://example.com"},
+			mockErr:     service.ErrPolicyCheck,
+			wantErrCode: "Internal",
+		},
+		{
+			name:        "Service Returns ErrAliasTaken",
+			req:         &pb.LongURL{Url: "https://example.com", CustomAlias: "taken"},
+			mockErr:     service.ErrAliasTaken,
+			wantErrCode: "AlreadyExists",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := &mockURLService{
-				shortenURLFunc: func(ctx context.Context, longURL string) (string, error) {
+				shortenURLFunc: func(ctx context.Context, longURL string, opts service.ShortenOptions) (string, error) {
+					if opts.CustomAlias != tt.req.CustomAlias {
+						t.Errorf("expected custom alias %q, got %q", tt.req.CustomAlias, opts.CustomAlias)
+					}
+					if opts.Interstitial != tt.req.Interstitial {
+						t.Errorf("expected interstitial=%v, got %v", tt.req.Interstitial, opts.Interstitial)
+					}
+					if opts.Title != tt.req.Title || opts.Description != tt.req.Description || fmt.Sprint(opts.Tags) != fmt.Sprint(tt.req.Tags) {
+						t.Errorf("expected metadata %q %q %v, got %+v", tt.req.Title, tt.req.Description, tt.req.Tags, opts)
+					}
+					if opts.RedirectType != int(tt.req.RedirectType) {
+						t.Errorf("expected redirect type %d, got %d", tt.req.RedirectType, opts.RedirectType)
+					}
+					if opts.Password != tt.req.Password {
+						t.Errorf("expected password %q, got %q", tt.req.Password, opts.Password)
+					}
+					if opts.MaxClicks != int(tt.req.MaxClicks) {
+						t.Errorf("expected max clicks %d, got %d", tt.req.MaxClicks, opts.MaxClicks)
+					}
+					if (tt.req.TtlSeconds > 0 || tt.req.ExpiresAt != nil) != (opts.ExpiresAt != nil) {
+						t.Errorf("expected expiry to be passed through, got %v", opts.ExpiresAt)
+					}
 					return tt.mockCode, tt.mockErr
 				},
 			}
-			h := NewURLHandler(mock)
+			h := NewURLHandler(mock, nil)
 			resp, err := h.PostURL(context.Background(), tt.req)
 
 			if tt.wantErrCode != "" {
 				if err == nil {
 					t.Fatalf("expected error with code %s, got nil", tt.wantErrCode)
 				}
+				if got := status.Code(err).String(); got != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+				}
 				return
 			}
 			if err != nil {
@@ -88,19 +297,69 @@ func TestPostURL(t *testing.T) {
 }
 
 func TestGetLongURL(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
 	tests := []struct {
-		name        string
-		req         *pb.ShortURL
-		mockURL     string
-		mockErr     error
-		wantURL     string
-		wantErrCode string
+		name             string
+		req              *pb.ShortURL
+		mockLink         *service.Link
+		mockErr          error
+		wantURL          string
+		wantRedirectType int32
+		wantInterstitial bool
+		wantWarning      string
+		wantErrCode      string
 	}{
 		{
-			name:    "Success",
-			req:     &pb.ShortURL{Alias: "abcde"},
-			mockURL: "https://example.com",
-			wantURL: "https://example.com",
+			name:             "Success",
+			req:              &pb.ShortURL{Alias: "abcde"},
+			mockLink:         &service.Link{LongURL: "https://example.com", Safety: service.SafetyOK, RedirectType: 302},
+			wantURL:          "https://example.com",
+			wantRedirectType: 302,
+		},
+		{
+			name:             "Routed",
+			req:              &pb.ShortURL{Alias: "abcde", UserAgent: "Mozilla/5.0 (iPhone)", AcceptLanguage: "de-DE", IpAddress: "203.0.113.7"},
+			mockLink:         &service.Link{LongURL: "https://apps.apple.com/app/id1", Safety: service.SafetyOK, RedirectType: 302, Routed: true},
+			wantURL:          "https://apps.apple.com/app/id1",
+			wantRedirectType: 302,
+		},
+		{
+			name:             "Variant",
+			req:              &pb.ShortURL{Alias: "abcde", VisitorId: "3f2a9c"},
+			mockLink:         &service.Link{LongURL: "https://example.com/b", Safety: service.SafetyOK, RedirectType: 302, Routed: true, Variant: "B"},
+			wantURL:          "https://example.com/b",
+			wantRedirectType: 302,
+		},
+		{
+			name:             "Confirmed Lookup",
+			req:              &pb.ShortURL{Alias: "abcde", LookupOnly: true, Confirmed: true},
+			mockLink:         &service.Link{LongURL: "https://example.com", Safety: service.SafetyOK, RedirectType: 302},
+			wantURL:          "https://example.com",
+			wantRedirectType: 302,
+		},
+		{
+			name:             "Permanent",
+			req:              &pb.ShortURL{Alias: "abcde"},
+			mockLink:         &service.Link{LongURL: "https://example.com", Safety: service.SafetyOK, RedirectType: 308, CreatedAt: time.Now(), ExpiresAt: &expiresAt},
+			wantURL:          "https://example.com",
+			wantRedirectType: 308,
+		},
+		{
+			name:             "Flagged",
+			req:              &pb.ShortURL{Alias: "abcde"},
+			mockLink:         &service.Link{LongURL: "https://example.com", Safety: service.SafetyFlagged, SafetyReason: "flagged by the link owner", RedirectType: 302},
+			wantURL:          "https://example.com",
+			wantRedirectType: 302,
+			wantInterstitial: true,
+			wantWarning:      "flagged by the link owner",
+		},
+		{
+			name: "Checked",
+			req:  &pb.ShortURL{Alias: "abcde"},
+			mockLink: &service.Link{LongURL: "https://example.com", Safety: service.SafetyOK, RedirectType: 302,
+				Health: repository.HealthStatus{LastCheckedAt: &expiresAt, LastStatus: 404, ConsecutiveFailures: 2}},
+			wantURL:          "https://example.com",
+			wantRedirectType: 302,
 		},
 		{
 			name:        "Nil Request",
@@ -118,6 +377,49 @@ func TestGetLongURL(t *testing.T) {
 			mockErr:     service.ErrNotFound,
 			wantErrCode: "NotFound",
 		},
+		{
+			name:        "Expired",
+			req:         &pb.ShortURL{Alias: "old"},
+			mockErr:     service.ErrExpired,
+			wantErrCode: "FailedPrecondition",
+		},
+		{
+			name:        "Disabled",
+			req:         &pb.ShortURL{Alias: "off"},
+			mockErr:     service.ErrDisabled,
+			wantErrCode: "FailedPrecondition",
+		},
+		{
+			name:        "Exhausted",
+			req:         &pb.ShortURL{Alias: "once"},
+			mockErr:     service.ErrExhausted,
+			wantErrCode: "FailedPrecondition",
+		},
+		{
+			name:             "Unlocked",
+			req:              &pb.ShortURL{Alias: "abcde", Password: "s3cret"},
+			mockLink:         &service.Link{LongURL: "https://example.com", Safety: service.SafetyOK, RedirectType: 302},
+			wantURL:          "https://example.com",
+			wantRedirectType: 302,
+		},
+		{
+			name:        "Password Required",
+			req:         &pb.ShortURL{Alias: "abcde"},
+			mockErr:     service.ErrPasswordRequired,
+			wantErrCode: "Unauthenticated",
+		},
+		{
+			name:        "Wrong Password",
+			req:         &pb.ShortURL{Alias: "abcde", Password: "guess"},
+			mockErr:     service.ErrWrongPassword,
+			wantErrCode: "Unauthenticated",
+		},
+		{
+			name:        "Locked",
+			req:         &pb.ShortURL{Alias: "abcde", Password: "guess"},
+			mockErr:     service.ErrPasswordLocked,
+			wantErrCode: "ResourceExhausted",
+		},
 		{
 			name:        "Internal Error",
 			req:         &pb.ShortURL{Alias: "abcde"},
@@ -129,17 +431,32 @@ func TestGetLongURL(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := &mockURLService{
-				getLongURLFunc: func(ctx context.Context, shortCode string) (string, error) {
-					return tt.mockURL, tt.mockErr
+				getLongURLFunc: func(ctx context.Context, shortCode string, opts service.ResolveOptions) (*service.Link, error) {
+					if opts.Password != tt.req.GetPassword() {
+						t.Errorf("expected password %q to be passed on, got %q", tt.req.GetPassword(), opts.Password)
+					}
+					if v := opts.Visitor; v.UserAgent != tt.req.GetUserAgent() || v.AcceptLanguage != tt.req.GetAcceptLanguage() || v.IP != tt.req.GetIpAddress() {
+						t.Errorf("expected the visitor's request to be passed on, got %+v", v)
+					}
+					if opts.VisitorID != tt.req.GetVisitorId() {
+						t.Errorf("expected visitor id %q to be passed on, got %q", tt.req.GetVisitorId(), opts.VisitorID)
+					}
+					if opts.LookupOnly != tt.req.GetLookupOnly() || opts.Confirmed != tt.req.GetConfirmed() {
+						t.Errorf("expected lookup_only and confirmed to be passed on, got %+v", opts)
+					}
+					return tt.mockLink, tt.mockErr
 				},
 			}
-			h := NewURLHandler(mock)
+			h := NewURLHandler(mock, nil)
 			resp, err := h.GetLongURL(context.Background(), tt.req)
 
 			if tt.wantErrCode != "" {
 				if err == nil {
 					t.Fatalf("expected error with code %s, got nil", tt.wantErrCode)
 				}
+				if got := status.Code(err).String(); got != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+				}
 				return
 			}
 			if err != nil {
@@ -148,6 +465,891 @@ func TestGetLongURL(t *testing.T) {
 			if resp.Url != tt.wantURL {
 				t.Errorf("expected url %s, got %s", tt.wantURL, resp.Url)
 			}
+			if resp.Interstitial != tt.wantInterstitial || resp.Warning != tt.wantWarning {
+				t.Errorf("expected interstitial=%v (%q), got %v (%q)", tt.wantInterstitial, tt.wantWarning, resp.Interstitial, resp.Warning)
+			}
+			if resp.RedirectType != tt.wantRedirectType {
+				t.Errorf("expected redirect type %d, got %d", tt.wantRedirectType, resp.RedirectType)
+			}
+			if resp.Routed != tt.mockLink.Routed || resp.Variant != tt.mockLink.Variant {
+				t.Errorf("expected routed=%v variant=%q, got %v %q", tt.mockLink.Routed, tt.mockLink.Variant, resp.Routed, resp.Variant)
+			}
+			if !resp.CreatedAt.AsTime().Equal(tt.mockLink.CreatedAt) {
+				t.Errorf("expected created_at %v, got %v", tt.mockLink.CreatedAt, resp.CreatedAt.AsTime())
+			}
+			if (resp.ExpiresAt != nil) != (tt.mockLink.ExpiresAt != nil) {
+				t.Errorf("expected expires_at %v, got %v", tt.mockLink.ExpiresAt, resp.ExpiresAt)
+			}
+			if health := tt.mockLink.Health; (resp.Health != nil) != (health.LastCheckedAt != nil) || resp.Health != nil && (resp.Health.LastStatus != int32(health.LastStatus) ||
+				resp.Health.ConsecutiveFailures != int32(health.ConsecutiveFailures) || !resp.Health.LastCheckedAt.AsTime().Equal(*health.LastCheckedAt)) {
+				t.Errorf("expected health %+v, got %v", health, resp.Health)
+			}
+		})
+	}
+}
+
+func TestPreviewURL(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(24 * time.Hour)
+	tests := []struct {
+		name        string
+		req         *pb.ShortURL
+		mockLink    *service.Link
+		mockErr     error
+		wantErrCode string
+	}{
+		{
+			name:     "Success",
+			req:      &pb.ShortURL{Alias: "abcde"},
+			mockLink: &service.Link{LongURL: "https://evil.com/", CreatedAt: createdAt, ExpiresAt: &expiresAt, Safety: service.SafetyBlocked, SafetyReason: "destination domain is blocked"},
+		},
+		{
+			name:     "Never Expires",
+			req:      &pb.ShortURL{Alias: "abcde"},
+			mockLink: &service.Link{LongURL: "https://example.com/", CreatedAt: createdAt, Safety: service.SafetyOK, Title: "Launch", Tags: []string{"q3-campaign"}},
+		},
+		{
+			name: "Page Fetched",
+			req:  &pb.ShortURL{Alias: "abcde"},
+			mockLink: &service.Link{LongURL: "https://example.com/", CreatedAt: createdAt, Safety: service.SafetyOK, Page: repository.PageInfo{
+				Title: "Example", OGTitle: "Example Domain", OGImage: "https://example.com/og.png", StatusCode: 200, FetchedAt: &createdAt,
+			}},
+		},
+		{name: "Empty Alias", req: &pb.ShortURL{}, wantErrCode: "InvalidArgument"},
+		{name: "Not Found", req: &pb.ShortURL{Alias: "miss"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Expired", req: &pb.ShortURL{Alias: "old"}, mockErr: service.ErrExpired, wantErrCode: "FailedPrecondition"},
+		{name: "Internal Error", req: &pb.ShortURL{Alias: "abcde"}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				previewURLFunc: func(ctx context.Context, shortCode string, opts service.ResolveOptions) (*service.Link, error) {
+					return tt.mockLink, tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			resp, err := h.PreviewURL(context.Background(), tt.req)
+
+			if tt.wantErrCode != "" {
+				if got := status.Code(err).String(); got != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			link := tt.mockLink
+			if resp.Url != link.LongURL || !resp.CreatedAt.AsTime().Equal(link.CreatedAt) || resp.Safety != link.Safety || resp.SafetyReason != link.SafetyReason {
+				t.Errorf("unexpected preview %v for %+v", resp, link)
+			}
+			if resp.Title != link.Title || fmt.Sprint(resp.Tags) != fmt.Sprint(link.Tags) {
+				t.Errorf("expected title %q and tags %v, got %v", link.Title, link.Tags, resp)
+			}
+			if (resp.ExpiresAt != nil) != (link.ExpiresAt != nil) || (link.ExpiresAt != nil && !resp.ExpiresAt.AsTime().Equal(*link.ExpiresAt)) {
+				t.Errorf("expected expiry %v, got %v", link.ExpiresAt, resp.ExpiresAt)
+			}
+			if page := resp.Page; (page != nil) != (link.Page.FetchedAt != nil) || page != nil && (page.Title != link.Page.Title || page.OgTitle != link.Page.OGTitle ||
+				page.OgImage != link.Page.OGImage || page.StatusCode != int32(link.Page.StatusCode) || !page.FetchedAt.AsTime().Equal(*link.Page.FetchedAt)) {
+				t.Errorf("expected page %+v, got %v", link.Page, page)
+			}
+		})
+	}
+}
+
+func TestDeleteURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *pb.ShortURL
+		mockErr     error
+		wantErrCode string
+	}{
+		{name: "Success", req: &pb.ShortURL{Alias: "abcde"}},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{name: "Empty Alias", req: &pb.ShortURL{Alias: ""}, wantErrCode: "InvalidArgument"},
+		{name: "Not Found", req: &pb.ShortURL{Alias: "miss"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Internal Error", req: &pb.ShortURL{Alias: "abcde"}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				deleteURLFunc: func(ctx context.Context, owner, shortCode string) error {
+					return tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			_, err := h.DeleteURL(context.Background(), tt.req)
+
+			if got := status.Code(err).String(); tt.wantErrCode != "" && got != tt.wantErrCode {
+				t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+			}
+			if tt.wantErrCode == "" && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDisableURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *pb.DisableURLRequest
+		mockErr     error
+		wantErrCode string
+	}{
+		{name: "Disable", req: &pb.DisableURLRequest{Alias: "abcde", Disabled: true}},
+		{name: "Enable", req: &pb.DisableURLRequest{Alias: "abcde", Disabled: false}},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{name: "Empty Alias", req: &pb.DisableURLRequest{Disabled: true}, wantErrCode: "InvalidArgument"},
+		{name: "Not Found", req: &pb.DisableURLRequest{Alias: "miss", Disabled: true}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Internal Error", req: &pb.DisableURLRequest{Alias: "abcde", Disabled: true}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotDisabled bool
+			mock := &mockURLService{
+				disableURLFunc: func(ctx context.Context, owner, shortCode string, disabled bool) error {
+					gotDisabled = disabled
+					return tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			_, err := h.DisableURL(context.Background(), tt.req)
+
+			if got := status.Code(err).String(); tt.wantErrCode != "" && got != tt.wantErrCode {
+				t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+			}
+			if tt.wantErrCode == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				if gotDisabled != tt.req.Disabled {
+					t.Errorf("expected disabled=%v to be passed on, got %v", tt.req.Disabled, gotDisabled)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateURL(t *testing.T) {
+	on := true
+	permanent := int32(301)
+	tests := []struct {
+		name         string
+		req          *pb.UpdateURLRequest
+		mockErr      error
+		settingsErr  error
+		wantErrCode  string
+		wantUpdate   bool
+		wantSettings bool
+	}{
+		{name: "Success", req: &pb.UpdateURLRequest{Alias: "abcde", Url: "https://example.com/q2"}, wantUpdate: true},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{name: "Empty Alias", req: &pb.UpdateURLRequest{Url: "https://example.com"}, wantErrCode: "InvalidArgument"},
+		{name: "Nothing To Update", req: &pb.UpdateURLRequest{Alias: "abcde"}, wantErrCode: "InvalidArgument"},
+		{name: "Invalid URL", req: &pb.UpdateURLRequest{Alias: "abcde", Url: "nope", Interstitial: &on}, mockErr: service.ErrInvalidURL, wantErrCode: "InvalidArgument", wantUpdate: true},
+		{name: "Not Found", req: &pb.UpdateURLRequest{Alias: "miss", Url: "https://example.com"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound", wantUpdate: true},
+		{name: "Blocked URL", req: &pb.UpdateURLRequest{Alias: "abcde", Url: "http://10.0.0.1"}, mockErr: service.ErrBlockedURL, wantErrCode: "PermissionDenied", wantUpdate: true},
+		{name: "Internal Error", req: &pb.UpdateURLRequest{Alias: "abcde", Url: "https://example.com"}, mockErr: errors.New("db down"), wantErrCode: "Internal", wantUpdate: true},
+		{name: "Settings Only", req: &pb.UpdateURLRequest{Alias: "abcde", Interstitial: &on}, wantSettings: true},
+		{name: "URL And Settings", req: &pb.UpdateURLRequest{Alias: "abcde", Url: "https://example.com", Interstitial: &on}, wantUpdate: true, wantSettings: true},
+		{name: "Settings Not Found", req: &pb.UpdateURLRequest{Alias: "miss", Interstitial: &on}, settingsErr: service.ErrNotFound, wantErrCode: "NotFound", wantSettings: true},
+		{name: "Redirect Type", req: &pb.UpdateURLRequest{Alias: "abcde", RedirectType: &permanent}, wantSettings: true},
+		{name: "Invalid Redirect Type", req: &pb.UpdateURLRequest{Alias: "abcde", RedirectType: &permanent}, settingsErr: service.ErrInvalidRedirectType, wantErrCode: "InvalidArgument", wantSettings: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, changed := false, false
+			mock := &mockURLService{
+				updateURLFunc: func(ctx context.Context, owner, shortCode, longURL string) error {
+					updated = true
+					return tt.mockErr
+				},
+				settingsFunc: func(ctx context.Context, owner, shortCode string, settings repository.LinkSettings) error {
+					changed = true
+					if (settings.Interstitial != nil) != (tt.req.Interstitial != nil) || (settings.RedirectType != nil) != (tt.req.RedirectType != nil) {
+						t.Errorf("unexpected settings %+v for %v", settings, tt.req)
+					}
+					if settings.RedirectType != nil && *settings.RedirectType != int(*tt.req.RedirectType) {
+						t.Errorf("expected redirect type %d, got %d", *tt.req.RedirectType, *settings.RedirectType)
+					}
+					return tt.settingsErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			_, err := h.UpdateURL(context.Background(), tt.req)
+
+			if got := status.Code(err).String(); tt.wantErrCode != "" && got != tt.wantErrCode {
+				t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+			}
+			if tt.wantErrCode == "" && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if updated != tt.wantUpdate || changed != tt.wantSettings {
+				t.Errorf("expected update=%v settings=%v, got update=%v settings=%v", tt.wantUpdate, tt.wantSettings, updated, changed)
+			}
+		})
+	}
+}
+
+func TestGetURLHistory(t *testing.T) {
+	changedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		req         *pb.ShortURL
+		mockChanges []repository.URLChange
+		mockErr     error
+		wantErrCode string
+	}{
+		{
+			name:        "Success",
+			req:         &pb.ShortURL{Alias: "abcde"},
+			mockChanges: []repository.URLChange{{OldLongURL: "https://a.com", NewLongURL: "https://b.com", ChangedAt: changedAt}},
+		},
+		{name: "Empty History", req: &pb.ShortURL{Alias: "abcde"}, mockChanges: []repository.URLChange{}},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{name: "Not Found", req: &pb.ShortURL{Alias: "miss"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Internal Error", req: &pb.ShortURL{Alias: "abcde"}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				historyFunc: func(ctx context.Context, owner, shortCode string) ([]repository.URLChange, error) {
+					return tt.mockChanges, tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			resp, err := h.GetURLHistory(context.Background(), tt.req)
+
+			if tt.wantErrCode != "" {
+				if got := status.Code(err).String(); got != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(resp.Changes) != len(tt.mockChanges) {
+				t.Fatalf("expected %d changes, got %d", len(tt.mockChanges), len(resp.Changes))
+			}
+			for i, c := range tt.mockChanges {
+				got := resp.Changes[i]
+				if got.OldUrl != c.OldLongURL || got.NewUrl != c.NewLongURL || !got.ChangedAt.AsTime().Equal(c.ChangedAt) {
+					t.Errorf("change %d: expected %+v, got %v", i, c, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *pb.ShortURL
+		mockRules   []repository.Rule
+		mockErr     error
+		wantErrCode string
+	}{
+		{
+			name: "Success",
+			req:  &pb.ShortURL{Alias: "abcde"},
+			mockRules: []repository.Rule{
+				{Platforms: []string{"ios"}, LongURL: "https://apps.apple.com/app/id1"},
+				{Languages: []string{"de"}, Countries: []string{"DE", "AT"}, LongURL: "https://example.com/de"},
+			},
+		},
+		{name: "No Rules", req: &pb.ShortURL{Alias: "abcde"}, mockRules: []repository.Rule{}},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{name: "Not Found", req: &pb.ShortURL{Alias: "miss"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Internal Error", req: &pb.ShortURL{Alias: "abcde"}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				getRulesFunc: func(ctx context.Context, owner, shortCode string) ([]repository.Rule, error) {
+					return tt.mockRules, tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			resp, err := h.GetRules(context.Background(), tt.req)
+
+			if tt.wantErrCode != "" {
+				if got := status.Code(err).String(); got != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(resp.Rules) != len(tt.mockRules) {
+				t.Fatalf("expected %d rules, got %d", len(tt.mockRules), len(resp.Rules))
+			}
+			for i, r := range tt.mockRules {
+				got := resp.Rules[i]
+				if got.Url != r.LongURL || len(got.Platforms) != len(r.Platforms) || len(got.Languages) != len(r.Languages) || len(got.Countries) != len(r.Countries) {
+					t.Errorf("rule %d: expected %+v, got %v", i, r, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *pb.SetRulesRequest
+		mockErr     error
+		wantErrCode string
+		wantMessage string
+	}{
+		{
+			name: "Success",
+			req: &pb.SetRulesRequest{Alias: "abcde", Rules: []*pb.RoutingRule{
+				{Platforms: []string{"android"}, Countries: []string{"DE"}, Url: "https://play.google.com/store/apps/details?id=app"},
+			}},
+		},
+		{name: "Remove", req: &pb.SetRulesRequest{Alias: "abcde"}},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{
+			name:        "Invalid Rule",
+			req:         &pb.SetRulesRequest{Alias: "abcde", Rules: []*pb.RoutingRule{{Platforms: []string{"symbian"}, Url: "https://example.com"}}},
+			mockErr:     fmt.Errorf("%w: rule 1: unknown platform %q", service.ErrInvalidRules, "symbian"),
+			wantErrCode: "InvalidArgument",
+			wantMessage: `invalid routing rules: rule 1: unknown platform "symbian"`,
+		},
+		{
+			name:        "Blocked Destination",
+			req:         &pb.SetRulesRequest{Alias: "abcde", Rules: []*pb.RoutingRule{{Platforms: []string{"ios"}, Url: "https://evil.com"}}},
+			mockErr:     fmt.Errorf("%w: %w", service.ErrBlockedURL, &policy.Violation{Reason: "destination domain is blocked"}),
+			wantErrCode: "PermissionDenied",
+			wantMessage: "destination domain is blocked",
+		},
+		{name: "Not Found", req: &pb.SetRulesRequest{Alias: "miss"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Internal Error", req: &pb.SetRulesRequest{Alias: "abcde"}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []repository.Rule
+			mock := &mockURLService{
+				setRulesFunc: func(ctx context.Context, owner, shortCode string, linkRules []repository.Rule) error {
+					got = linkRules
+					return tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			_, err := h.SetRules(context.Background(), tt.req)
+
+			if tt.wantErrCode != "" {
+				st := status.Convert(err)
+				if st.Code().String() != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, st.Code())
+				}
+				if tt.wantMessage != "" && st.Message() != tt.wantMessage {
+					t.Errorf("expected message %q, got %q", tt.wantMessage, st.Message())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(got) != len(tt.req.Rules) {
+				t.Fatalf("expected %d rules to be passed on, got %d", len(tt.req.Rules), len(got))
+			}
+			for i, r := range tt.req.Rules {
+				if got[i].LongURL != r.Url || len(got[i].Platforms) != len(r.Platforms) || len(got[i].Countries) != len(r.Countries) {
+					t.Errorf("rule %d: expected %v, got %+v", i, r, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListURLs(t *testing.T) {
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	disabledAt := createdAt.Add(time.Hour)
+	other := "globex"
+	tests := []struct {
+		name           string
+		caller         string
+		req            *pb.ListURLsRequest
+		mockPage       *service.ListPage
+		mockErr        error
+		wantFilter     repository.ListFilter
+		wantNextCursor string
+		wantErrCode    string
+	}{
+		{
+			name:   "Success",
+			caller: "acme",
+			req:    &pb.ListURLsRequest{Tag: "q3-campaign", Limit: 10},
+			mockPage: &service.ListPage{URLs: []*repository.URL{
+				{ShortCode: "abc", LongURL: "https://example.com/a", Title: "A", Tags: []string{"q3-campaign"}, Owner: "acme", CreatedAt: createdAt, RedirectType: 302},
+				{ShortCode: "def", LongURL: "https://example.com/b", Tags: []string{"q3-campaign"}, Owner: "acme", CreatedAt: createdAt, DisabledAt: &disabledAt, RedirectType: 301, PasswordHash: "$2a$10$hash", MaxClicks: 10, ClicksRemaining: 3},
+			}},
+			wantFilter: repository.ListFilter{Owner: "acme", Tag: "q3-campaign", Limit: 10},
+		},
+		{
+			name:           "Filters And Cursor",
+			caller:         "acme",
+			req:            &pb.ListURLsRequest{Domain: "example.com", Search: "promo", Cursor: "42", CreatedAfter: timestamppb.New(createdAt)},
+			mockPage:       &service.ListPage{URLs: []*repository.URL{}, NextCursor: 17},
+			wantFilter:     repository.ListFilter{Owner: "acme", Domain: "example.com", Search: "promo", BeforeID: 42, CreatedAfter: &createdAt},
+			wantNextCursor: "17",
+		},
+		{
+			name:       "Admin Lists Other Owner",
+			caller:     "root",
+			req:        &pb.ListURLsRequest{Owner: &other},
+			mockPage:   &service.ListPage{URLs: []*repository.URL{}},
+			wantFilter: repository.ListFilter{Owner: "globex"},
+		},
+		{
+			name:       "Admin Lists All Owners",
+			caller:     "root",
+			req:        &pb.ListURLsRequest{AllOwners: true},
+			mockPage:   &service.ListPage{URLs: []*repository.URL{}},
+			wantFilter: repository.ListFilter{Owner: "root", AllOwners: true},
+		},
+		{name: "Other Owner Not Admin", caller: "acme", req: &pb.ListURLsRequest{Owner: &other}, wantErrCode: "PermissionDenied"},
+		{name: "All Owners Not Admin", caller: "acme", req: &pb.ListURLsRequest{AllOwners: true}, wantErrCode: "PermissionDenied"},
+		{name: "Owner And All Owners", caller: "root", req: &pb.ListURLsRequest{Owner: &other, AllOwners: true}, wantErrCode: "InvalidArgument"},
+		{name: "Invalid Cursor", caller: "acme", req: &pb.ListURLsRequest{Cursor: "abc"}, wantErrCode: "InvalidArgument"},
+		{name: "Negative Cursor", caller: "acme", req: &pb.ListURLsRequest{Cursor: "-1"}, wantErrCode: "InvalidArgument"},
+		{name: "Search Too Long", caller: "acme", req: &pb.ListURLsRequest{Search: strings.Repeat("a", maxSearchLength+1)}, wantErrCode: "InvalidArgument"},
+		{name: "Nil Request", caller: "acme", req: nil, wantErrCode: "InvalidArgument"},
+		{name: "Limit Too Large", caller: "acme", req: &pb.ListURLsRequest{Limit: service.MaxListLimit + 1}, wantErrCode: "InvalidArgument"},
+		{name: "Invalid Range", caller: "acme", req: &pb.ListURLsRequest{}, mockErr: service.ErrInvalidFilter, wantErrCode: "InvalidArgument"},
+		{name: "Internal Error", caller: "acme", req: &pb.ListURLsRequest{}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				listURLsFunc: func(ctx context.Context, filter repository.ListFilter) (*service.ListPage, error) {
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					if !reflect.DeepEqual(filter, tt.wantFilter) {
+						t.Errorf("expected filter %+v, got %+v", tt.wantFilter, filter)
+					}
+					return tt.mockPage, nil
+				},
+			}
+			h := NewURLHandler(mock, []string{"root"})
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(identity.MetadataKey, tt.caller))
+			resp, err := h.ListURLs(ctx, tt.req)
+
+			if tt.wantErrCode != "" {
+				if got := status.Code(err).String(); got != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if resp.NextCursor != tt.wantNextCursor {
+				t.Errorf("expected next cursor %q, got %q", tt.wantNextCursor, resp.NextCursor)
+			}
+			if len(resp.Urls) != len(tt.mockPage.URLs) {
+				t.Fatalf("expected %d urls, got %d", len(tt.mockPage.URLs), len(resp.Urls))
+			}
+			for i, u := range tt.mockPage.URLs {
+				got := resp.Urls[i]
+				if got.Alias != u.ShortCode || got.Url != u.LongURL || got.Title != u.Title || fmt.Sprint(got.Tags) != fmt.Sprint(u.Tags) || got.Owner != u.Owner ||
+					got.Disabled != (u.DisabledAt != nil) || got.RedirectType != int32(u.RedirectType) || !got.CreatedAt.AsTime().Equal(u.CreatedAt) ||
+					got.PasswordProtected != (u.PasswordHash != "") || got.MaxClicks != int32(u.MaxClicks) || got.ClicksRemaining != int32(u.ClicksRemaining) {
+					t.Errorf("url %d: expected %+v, got %v", i, u, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOwnerFromMetadata(t *testing.T) {
+	md := metadata.Pairs(identity.MetadataKey, "acme")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	var shortenOwner, deleteOwner string
+	mock := &mockURLService{
+		shortenURLFunc: func(ctx context.Context, longURL string, opts service.ShortenOptions) (string, error) {
+			shortenOwner = opts.Owner
+			return "abc", nil
+		},
+		deleteURLFunc: func(ctx context.Context, owner, shortCode string) error {
+			deleteOwner = owner
+			return nil
+		},
+	}
+	h := NewURLHandler(mock, nil)
+
+	if _, err := h.PostURL(ctx, &pb.LongURL{Url: "https://example.com"}); err != nil {
+		t.Fatalf("PostURL failed: %v", err)
+	}
+	if _, err := h.DeleteURL(ctx, &pb.ShortURL{Alias: "abc"}); err != nil {
+		t.Fatalf("DeleteURL failed: %v", err)
+	}
+	if shortenOwner != "acme" || deleteOwner != "acme" {
+		t.Errorf("expected owner acme to be passed on, got shorten=%q delete=%q", shortenOwner, deleteOwner)
+	}
+
+	// Calls without metadata are anonymous
+	if _, err := h.PostURL(context.Background(), &pb.LongURL{Url: "https://example.com"}); err != nil {
+		t.Fatalf("PostURL failed: %v", err)
+	}
+	if shortenOwner != "" {
+		t.Errorf("expected an anonymous call, got owner %q", shortenOwner)
+	}
+}
+
+// batchService returns a mock service that shortens every item to "c<n>",
+// except for the URL "https://example.com/taken", and records the batch sizes.
+func batchService(batches *[]int) *mockURLService {
+	n := 0
+	return &mockURLService{
+		shortenURLsFunc: func(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error) {
+			*batches = append(*batches, len(items))
+			results := make([]service.BatchResult, len(items))
+			for i, item := range items {
+				if item.LongURL == "https://example.com/taken" {
+					results[i].Err = service.ErrAliasTaken
+					continue
+				}
+				n++
+				results[i].ShortCode = fmt.Sprintf("c%d", n)
+			}
+			return results, nil
+		},
+	}
+}
+
+func TestBatchPostURL(t *testing.T) {
+	var batches []int
+	h := NewURLHandler(batchService(&batches), nil)
+
+	resp, err := h.BatchPostURL(context.Background(), &pb.BatchLongURL{Urls: []*pb.LongURL{
+		{Url: "https://example.com/a"},
+		{Url: ""},
+		{Url: "https://example.com/taken"},
+		{Url: "https://example.com/b", TtlSeconds: -1},
+		{Url: "https://example.com/c"},
+	}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []struct {
+		alias string
+		code  codes.Code
+	}{
+		{alias: "c1"},
+		{code: codes.InvalidArgument},
+		{code: codes.AlreadyExists},
+		{code: codes.InvalidArgument},
+		{alias: "c2"},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(resp.Results))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.Alias != w.alias || codes.Code(got.Code) != w.code || (w.code != codes.OK) != (got.Error != "") {
+			t.Errorf("result %d: expected alias %q and code %s, got %+v", i, w.alias, w.code, got)
+		}
+	}
+	if len(batches) != 1 || batches[0] != 3 {
+		t.Errorf("expected the 3 valid urls in one service call, got %v", batches)
+	}
+}
+
+func TestBatchPostURL_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *pb.BatchLongURL
+		mockErr  error
+		wantCode codes.Code
+	}{
+		{name: "Nil Request", req: nil, wantCode: codes.InvalidArgument},
+		{name: "Empty Batch", req: &pb.BatchLongURL{}, wantCode: codes.InvalidArgument},
+		{name: "Too Large", req: &pb.BatchLongURL{Urls: make([]*pb.LongURL, service.MaxBatchSize+1)}, wantCode: codes.InvalidArgument},
+		{name: "Service Error", req: &pb.BatchLongURL{Urls: []*pb.LongURL{{Url: "https://example.com"}}}, mockErr: service.ErrDatabaseWrite, wantCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				shortenURLsFunc: func(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error) {
+					return nil, tt.mockErr
+				},
+			}
+			_, err := NewURLHandler(mock, nil).BatchPostURL(context.Background(), tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("expected code %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+// fakeBatchStream feeds reqs to BatchPostURLStream and captures its response.
+type fakeBatchStream struct {
+	grpc.ServerStream
+	reqs []*pb.LongURL
+	err  error // returned by Recv once reqs run out, instead of io.EOF
+	resp *pb.BatchShortURL
+}
+
+func (s *fakeBatchStream) Context() context.Context { return context.Background() }
+
+func (s *fakeBatchStream) Recv() (*pb.LongURL, error) {
+	if len(s.reqs) == 0 && s.err != nil {
+		return nil, s.err
+	}
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *fakeBatchStream) SendAndClose(resp *pb.BatchShortURL) error {
+	s.resp = resp
+	return nil
+}
+
+func TestBatchPostURLStream(t *testing.T) {
+	var batches []int
+	h := NewURLHandler(batchService(&batches), nil)
+
+	total := service.MaxBatchSize + 5
+	stream := &fakeBatchStream{}
+	for i := 0; i < total; i++ {
+		stream.reqs = append(stream.reqs, &pb.LongURL{Url: fmt.Sprintf("https://example.com/%d", i)})
+	}
+
+	if err := h.BatchPostURLStream(stream); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stream.resp == nil || len(stream.resp.Results) != total {
+		t.Fatalf("expected %d results, got %+v", total, stream.resp)
+	}
+	if last := stream.resp.Results[total-1]; last.Alias != fmt.Sprintf("c%d", total) {
+		t.Errorf("expected results in request order, got %+v for the last url", last)
+	}
+	if len(batches) != 2 || batches[0] != service.MaxBatchSize || batches[1] != 5 {
+		t.Errorf("expected batches of %d and 5, got %v", service.MaxBatchSize, batches)
+	}
+
+	// An empty stream is rejected
+	if err := h.BatchPostURLStream(&fakeBatchStream{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an empty stream, got %v", err)
+	}
+}
+
+// TestBatchPostURLStream_Fails checks that a stream failing after a batch was
+// stored still returns the codes of that batch.
+func TestBatchPostURLStream_Fails(t *testing.T) {
+	urls := func(n int) []*pb.LongURL {
+		reqs := make([]*pb.LongURL, n)
+		for i := range reqs {
+			reqs[i] = &pb.LongURL{Url: fmt.Sprintf("https://example.com/%d", i)}
+		}
+		return reqs
+	}
+
+	t.Run("Client Fails", func(t *testing.T) {
+		var batches []int
+		h := NewURLHandler(batchService(&batches), nil)
+		stream := &fakeBatchStream{reqs: urls(service.MaxBatchSize + 5), err: status.Error(codes.Canceled, "context canceled")}
+
+		if err := h.BatchPostURLStream(stream); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if stream.resp == nil || len(stream.resp.Results) != service.MaxBatchSize+5 {
+			t.Fatalf("expected %d results, got %+v", service.MaxBatchSize+5, stream.resp)
+		}
+		if first := stream.resp.Results[0]; first.Alias != "c1" {
+			t.Errorf("expected the stored batch to be reported, got %+v", first)
+		}
+		if last := stream.resp.Results[service.MaxBatchSize+4]; codes.Code(last.Code) != codes.Canceled {
+			t.Errorf("expected the unstored urls to fail, got %+v", last)
+		}
+		if len(batches) != 1 {
+			t.Errorf("expected only the first batch stored, got %v", batches)
+		}
+	})
+
+	t.Run("Second Batch Fails", func(t *testing.T) {
+		calls := 0
+		h := NewURLHandler(&mockURLService{
+			shortenURLsFunc: func(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error) {
+				if calls++; calls > 1 {
+					return nil, errors.New("db down")
+				}
+				return make([]service.BatchResult, len(items)), nil
+			},
+		}, nil)
+		stream := &fakeBatchStream{reqs: urls(service.MaxBatchSize + 5)}
+
+		if err := h.BatchPostURLStream(stream); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if stream.resp == nil || len(stream.resp.Results) != service.MaxBatchSize+5 {
+			t.Fatalf("expected %d results, got %+v", service.MaxBatchSize+5, stream.resp)
+		}
+		if last := stream.resp.Results[service.MaxBatchSize+4]; codes.Code(last.Code) != codes.Internal {
+			t.Errorf("expected the unstored urls to fail, got %+v", last)
+		}
+	})
+
+	t.Run("Nothing Stored", func(t *testing.T) {
+		var batches []int
+		h := NewURLHandler(batchService(&batches), nil)
+		stream := &fakeBatchStream{reqs: urls(5), err: status.Error(codes.Canceled, "context canceled")}
+
+		if err := h.BatchPostURLStream(stream); status.Code(err) != codes.Canceled || stream.resp != nil {
+			t.Errorf("expected Canceled and no results, got %v and %+v", err, stream.resp)
+		}
+	})
+}
+
+func TestPolicyStatus(t *testing.T) {
+	err := shortenStatus(fmt.Errorf("%w: %w", service.ErrBlockedURL, &policy.Violation{Reason: "destination domain is blocked"}))
+	if status.Code(err) != codes.PermissionDenied || status.Convert(err).Message() != "destination domain is blocked" {
+		t.Errorf("expected PermissionDenied with the reason, got %v", err)
+	}
+	err = shortenStatus(service.ErrBlockedURL)
+	if status.Code(err) != codes.PermissionDenied || status.Convert(err).Message() != "url not allowed" {
+		t.Errorf("expected PermissionDenied with a generic reason, got %v", err)
+	}
+}
+
+func TestGetVariants(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.ShortURL
+		mockVariants []repository.Variant
+		mockErr      error
+		wantErrCode  string
+	}{
+		{
+			name: "Success",
+			req:  &pb.ShortURL{Alias: "abcde"},
+			mockVariants: []repository.Variant{
+				{Name: "A", LongURL: "https://example.com/a", Weight: 70},
+				{Name: "B", LongURL: "https://example.com/b", Weight: 30},
+			},
+		},
+		{name: "No Variants", req: &pb.ShortURL{Alias: "abcde"}, mockVariants: []repository.Variant{}},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{name: "Not Found", req: &pb.ShortURL{Alias: "miss"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Internal Error", req: &pb.ShortURL{Alias: "abcde"}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				getVariantsFunc: func(ctx context.Context, owner, shortCode string) ([]repository.Variant, error) {
+					return tt.mockVariants, tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			resp, err := h.GetVariants(context.Background(), tt.req)
+
+			if tt.wantErrCode != "" {
+				if got := status.Code(err).String(); got != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(resp.Variants) != len(tt.mockVariants) {
+				t.Fatalf("expected %d variants, got %d", len(tt.mockVariants), len(resp.Variants))
+			}
+			for i, v := range tt.mockVariants {
+				got := resp.Variants[i]
+				if got.Name != v.Name || got.Url != v.LongURL || int(got.Weight) != v.Weight {
+					t.Errorf("variant %d: expected %+v, got %v", i, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSetVariants(t *testing.T) {
+	split := []*pb.Variant{{Name: "A", Url: "https://example.com/a", Weight: 70}, {Url: "https://example.com/b", Weight: 30}}
+	tests := []struct {
+		name        string
+		req         *pb.SetVariantsRequest
+		mockErr     error
+		wantErrCode string
+		wantMessage string
+	}{
+		{name: "Success", req: &pb.SetVariantsRequest{Alias: "abcde", Variants: split}},
+		{name: "Remove", req: &pb.SetVariantsRequest{Alias: "abcde"}},
+		{name: "Nil Request", req: nil, wantErrCode: "InvalidArgument"},
+		{
+			name:        "Invalid Variant",
+			req:         &pb.SetVariantsRequest{Alias: "abcde", Variants: split},
+			mockErr:     fmt.Errorf("%w: variant 2: weight must be between 1 and 1000", service.ErrInvalidVariants),
+			wantErrCode: "InvalidArgument",
+			wantMessage: "invalid link variants: variant 2: weight must be between 1 and 1000",
+		},
+		{
+			name:        "Blocked Destination",
+			req:         &pb.SetVariantsRequest{Alias: "abcde", Variants: split},
+			mockErr:     fmt.Errorf("%w: %w", service.ErrBlockedURL, &policy.Violation{Reason: "destination domain is blocked"}),
+			wantErrCode: "PermissionDenied",
+			wantMessage: "destination domain is blocked",
+		},
+		{name: "Not Found", req: &pb.SetVariantsRequest{Alias: "miss"}, mockErr: service.ErrNotFound, wantErrCode: "NotFound"},
+		{name: "Internal Error", req: &pb.SetVariantsRequest{Alias: "abcde"}, mockErr: errors.New("db down"), wantErrCode: "Internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []repository.Variant
+			mock := &mockURLService{
+				setVariantsFunc: func(ctx context.Context, owner, shortCode string, variants []repository.Variant) error {
+					got = variants
+					return tt.mockErr
+				},
+			}
+			h := NewURLHandler(mock, nil)
+			_, err := h.SetVariants(context.Background(), tt.req)
+
+			if tt.wantErrCode != "" {
+				st := status.Convert(err)
+				if st.Code().String() != tt.wantErrCode {
+					t.Errorf("expected error code %s, got %s", tt.wantErrCode, st.Code())
+				}
+				if tt.wantMessage != "" && st.Message() != tt.wantMessage {
+					t.Errorf("expected message %q, got %q", tt.wantMessage, st.Message())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(got) != len(tt.req.Variants) {
+				t.Fatalf("expected %d variants to be passed on, got %d", len(tt.req.Variants), len(got))
+			}
+			for i, v := range tt.req.Variants {
+				if got[i].Name != v.Name || got[i].LongURL != v.Url || got[i].Weight != int(v.Weight) {
+					t.Errorf("variant %d: expected %v, got %+v", i, v, got[i])
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,140 @@
+// Package healthcheck periodically checks that the destinations of links
+// still answer, so that dead links are noticed before visitors report them.
+package healthcheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"zipit/internal/url/repository"
+)
+
+// Store is where a Checker finds links to check and records the results.
+// [repository.URLRepository] implements it.
+type Store interface {
+	ClaimURLsForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*repository.URL, error)
+	RecordHealthCheck(ctx context.Context, shortCode, longURL string, check repository.HealthCheck) (bool, error)
+}
+
+// Prober returns the status code a destination answers with. The
+// pagefetch.Fetcher implements it.
+type Prober interface {
+	Status(ctx context.Context, rawURL string) (int, error)
+}
+
+// Options configure a Checker.
+type Options struct {
+	// Interval is how often the Checker looks for links that are due.
+	Interval time.Duration
+	// RecheckAfter is how long a destination is trusted after a check.
+	RecheckAfter time.Duration
+	// BatchSize caps how many links are checked per interval, and Workers
+	// how many of them at once.
+	BatchSize int
+	Workers   int
+	// DisableAfter disables links once this many checks in a row have
+	// failed; 0 never disables links.
+	DisableAfter int
+}
+
+// Checker checks the destinations of links that are due, one batch per
+// interval. Links are claimed in the store before they are checked, so
+// several Checkers can share a store.
+type Checker struct {
+	store  Store
+	prober Prober
+	opts   Options
+}
+
+// NewChecker creates a Checker of the links in store, probing their
+// destinations with prober.
+func NewChecker(store Store, prober Prober, opts Options) *Checker {
+	return &Checker{store: store, prober: prober, opts: opts}
+}
+
+// Run checks a batch of due links every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.CheckDue(ctx); err != nil {
+				slog.Error("failed to check link destinations", "error", err)
+			}
+		}
+	}
+}
+
+// CheckDue claims up to a batch of links that are due and checks their
+// destinations, returning how many were checked.
+func (c *Checker) CheckDue(ctx context.Context) (int, error) {
+	urls, err := c.store.ClaimURLsForCheck(ctx, time.Now().Add(-c.opts.RecheckAfter), c.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	queue := make(chan *repository.URL)
+	var wg sync.WaitGroup
+	for range min(c.opts.Workers, len(urls)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range queue {
+				c.check(ctx, u)
+			}
+		}()
+	}
+	for _, u := range urls {
+		queue <- u
+	}
+	close(queue)
+	wg.Wait()
+	return len(urls), nil
+}
+
+// check probes the destination of u and records the result. Destinations
+// that cannot be reached, including those the URL policy no longer allows,
+// count as failed checks.
+func (c *Checker) check(ctx context.Context, u *repository.URL) {
+	status, err := c.prober.Status(ctx, u.LongURL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return // shutting down: the link is checked again when due
+		}
+		slog.Debug("link destination unreachable", "short_code", u.ShortCode, "error", err)
+	}
+
+	check := repository.HealthCheck{Status: status, Healthy: err == nil && Healthy(status), DisableAfter: c.opts.DisableAfter}
+	disabled, err := c.store.RecordHealthCheck(ctx, u.ShortCode, u.LongURL, check)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.Debug("link changed while its destination was checked", "short_code", u.ShortCode)
+			return
+		}
+		slog.Error("failed to record health check", "short_code", u.ShortCode, "error", err)
+		return
+	}
+	if disabled {
+		slog.Warn("disabled link with a dead destination", "short_code", u.ShortCode, "status", status,
+			"failed_checks", u.Health.ConsecutiveFailures+1)
+	}
+}
+
+// Healthy reports whether a destination answering with status is alive.
+// Pages behind a login and rate-limited servers are alive; missing pages and
+// server errors are not.
+func Healthy(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return status > 0 && status < 400
+}
@@ -0,0 +1,156 @@
+package healthcheck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"zipit/internal/url/pagefetch"
+	"zipit/internal/url/repository"
+)
+
+// memStore is an in-memory [Store] that applies checks the way the
+// repository does.
+type memStore struct {
+	mu   sync.Mutex
+	urls []*repository.URL
+}
+
+func (s *memStore) ClaimURLsForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*repository.URL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []*repository.URL
+	for _, u := range s.urls {
+		if len(claimed) == limit {
+			break
+		}
+		if u.DisabledAt != nil || u.Health.LastCheckedAt != nil && !u.Health.LastCheckedAt.Before(checkedBefore) {
+			continue
+		}
+		now := time.Now()
+		u.Health.LastCheckedAt = &now
+		copied := *u
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (s *memStore) RecordHealthCheck(ctx context.Context, shortCode, longURL string, check repository.HealthCheck) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.urls {
+		if u.ShortCode != shortCode || u.LongURL != longURL {
+			continue
+		}
+		u.Health.LastStatus = check.Status
+		u.Health.ConsecutiveFailures++
+		if check.Healthy {
+			u.Health.ConsecutiveFailures = 0
+		}
+		if check.DisableAfter > 0 && u.Health.ConsecutiveFailures >= check.DisableAfter {
+			now := time.Now()
+			u.DisabledAt = &now
+		}
+		return u.DisabledAt != nil, nil
+	}
+	return false, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+}
+
+func (s *memStore) get(shortCode string) repository.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.urls {
+		if u.ShortCode == shortCode {
+			return *u
+		}
+	}
+	return repository.URL{}
+}
+
+func TestChecker(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	store := &memStore{urls: []*repository.URL{
+		{ShortCode: "ok", LongURL: srv.URL + "/ok"},
+		{ShortCode: "login", LongURL: srv.URL + "/login"},
+		{ShortCode: "dead", LongURL: srv.URL + "/missing"},
+		{ShortCode: "broken", LongURL: srv.URL + "/broken"},
+		{ShortCode: "offline", LongURL: "http://127.0.0.1:1/"},
+	}}
+	fetcher := pagefetch.NewFetcher(pagefetch.Options{Timeout: time.Second, MaxBytes: 1024, AllowPrivate: true})
+	checker := NewChecker(store, fetcher, Options{RecheckAfter: time.Hour, BatchSize: 3, Workers: 2, DisableAfter: 2})
+	ctx := context.Background()
+
+	// 1. Each round checks one batch of due links
+	for round, want := range []int{3, 2, 0} {
+		n, err := checker.CheckDue(ctx)
+		if err != nil || n != want {
+			t.Fatalf("round %d: expected %d links checked, got %d, err=%v", round+1, want, n, err)
+		}
+	}
+	tests := []struct {
+		code         string
+		wantStatus   int
+		wantFailures int
+	}{
+		{code: "ok", wantStatus: http.StatusOK},
+		{code: "login", wantStatus: http.StatusUnauthorized},
+		{code: "dead", wantStatus: http.StatusNotFound, wantFailures: 1},
+		{code: "broken", wantStatus: http.StatusBadGateway, wantFailures: 1},
+		{code: "offline", wantStatus: 0, wantFailures: 1},
+	}
+	for _, tt := range tests {
+		if u := store.get(tt.code); u.Health.LastStatus != tt.wantStatus || u.Health.ConsecutiveFailures != tt.wantFailures || u.DisabledAt != nil {
+			t.Errorf("%s: expected status %d with %d failures, got %+v", tt.code, tt.wantStatus, tt.wantFailures, u)
+		}
+	}
+
+	// 2. Once due again, links that keep failing are disabled
+	checker.opts.RecheckAfter, checker.opts.BatchSize = 0, 10
+	if n, err := checker.CheckDue(ctx); err != nil || n != 5 {
+		t.Fatalf("expected 5 links checked, got %d, err=%v", n, err)
+	}
+	for _, tt := range tests {
+		u := store.get(tt.code)
+		if (u.DisabledAt != nil) != (tt.wantFailures > 0) {
+			t.Errorf("%s: expected disabled=%v, got %+v", tt.code, tt.wantFailures > 0, u)
+		}
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{status: 0, want: false},
+		{status: 200, want: true},
+		{status: 204, want: true},
+		{status: 304, want: true},
+		{status: 401, want: true},
+		{status: 403, want: true},
+		{status: 429, want: true},
+		{status: 404, want: false},
+		{status: 410, want: false},
+		{status: 500, want: false},
+		{status: 503, want: false},
+	}
+	for _, tt := range tests {
+		if got := Healthy(tt.status); got != tt.want {
+			t.Errorf("Healthy(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
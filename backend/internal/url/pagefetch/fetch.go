@@ -0,0 +1,200 @@
+// Package pagefetch fetches the destination pages of links in the background
+// and stores their title, og:title, og:image and final status code. Its
+// Fetcher also checks the status of destinations for the health checker.
+package pagefetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+	"zipit/internal/url/policy"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxRedirects bounds how many redirects a fetch follows.
+const maxRedirects = 5
+
+// errInternalAddr is returned when a destination resolves to an address in a
+// private network.
+var errInternalAddr = errors.New("destination resolves to a private network address")
+
+// Page is what Fetch found at a destination. Title, OGTitle and OGImage are
+// empty if the final response was not an HTML page or did not have them.
+type Page struct {
+	Title      string
+	OGTitle    string
+	OGImage    string
+	StatusCode int
+}
+
+// Options configure a Fetcher.
+type Options struct {
+	// Timeout bounds a whole fetch, including redirects and reading the body.
+	Timeout time.Duration
+	// MaxBytes caps how much of a page is read; the head of a page is
+	// almost always within the first few kilobytes.
+	MaxBytes  int64
+	UserAgent string
+	// Policy is checked for the destination and every redirect; nil allows
+	// every http(s) URL.
+	Policy policy.Policy
+	// AllowPrivate allows connecting to private network addresses. Otherwise
+	// the addresses hostnames resolve to are checked as they are dialed, which
+	// the policy cannot do.
+	AllowPrivate bool
+}
+
+// Fetcher fetches pages. It is safe for concurrent use.
+type Fetcher struct {
+	client *http.Client
+	opts   Options
+}
+
+// NewFetcher creates a Fetcher with opts.
+func NewFetcher(opts Options) *Fetcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = checkDialAddr
+	}
+	f := &Fetcher{opts: opts}
+	f.client = &http.Client{
+		// No proxy from the environment: connections must go through the
+		// dialer for its address check to apply.
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   opts.Timeout,
+			ResponseHeaderTimeout: opts.Timeout,
+			MaxIdleConnsPerHost:   2,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return f.check(req.Context(), req.URL)
+		},
+	}
+	return f
+}
+
+// Fetch requests rawURL, following redirects, and reads the head of the
+// final response if it is an HTML page. Responses with error statuses are
+// not errors; their status code is returned.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+	resp, err := f.do(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	page := &Page{StatusCode: resp.StatusCode}
+	contentType := resp.Header.Get("Content-Type")
+	if !isHTML(contentType) {
+		return page, nil
+	}
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.opts.MaxBytes), contentType)
+	if err != nil {
+		return page, nil // unknown charset: keep the status code
+	}
+	parseHead(body, resp.Request.URL, page)
+	return page, nil
+}
+
+// Status requests rawURL with HEAD, following redirects, and returns the
+// status code of the final response. Many servers answer HEAD requests with
+// errors they do not give for GET, so error statuses are confirmed with a GET
+// request whose body is not read.
+func (f *Fetcher) Status(ctx context.Context, rawURL string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+	resp, err := f.do(ctx, http.MethodHead, rawURL)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 400 {
+		return resp.StatusCode, nil
+	}
+
+	resp, err = f.do(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// do sends a method request for rawURL, checking it and every redirect
+// against the policy.
+func (f *Fetcher) do(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if err := f.check(ctx, u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+	if f.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", f.opts.UserAgent)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	return resp, nil
+}
+
+// check reports whether u may be fetched.
+func (f *Fetcher) check(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if f.opts.Policy == nil {
+		return nil
+	}
+	return f.opts.Policy.Check(ctx, u)
+}
+
+// checkDialAddr rejects connections to private network addresses.
+func checkDialAddr(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if policy.IsInternalAddr(addr) {
+		return errInternalAddr
+	}
+	return nil
+}
+
+// isHTML reports whether contentType is an HTML media type. Pages without a
+// Content-Type are assumed to be HTML.
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
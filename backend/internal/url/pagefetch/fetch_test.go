@@ -0,0 +1,230 @@
+package pagefetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"zipit/internal/url/policy"
+)
+
+// policyFunc adapts a function to [policy.Policy].
+type policyFunc func(u *url.URL) error
+
+func (f policyFunc) Check(ctx context.Context, u *url.URL) error { return f(u) }
+
+func testFetcher(opts Options) *Fetcher {
+	if opts.Timeout == 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = 64 << 10
+	}
+	opts.AllowPrivate = true // httptest servers listen on loopback
+	return NewFetcher(opts)
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "zipit-test" {
+			t.Errorf("expected the configured User-Agent, got %q", r.Header.Get("User-Agent"))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html><html><head>
+			<meta charset="utf-8">
+			<title>
+				Launch &amp; Learn
+			</title>
+			<meta property="og:title" content="Launch on Example">
+			<meta property="og:image" content="/img/launch.png">
+			</head><body><title>Not this</title></body></html>`))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><meta name="og:image" content="cover.jpg"><title>Docs</title></head></html>`))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`<title>Page not found</title>`))
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(`<title>binary</title>`))
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte("<title>Caf\xe9</title>"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><!--" + strings.Repeat("x", 128<<10) + "--><title>Too late</title></head></html>"))
+	})
+	mux.HandleFunc("/long-title", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>" + strings.Repeat("a", 1000) + "</title>"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		path string
+		want Page
+	}{
+		{
+			name: "HTML Page",
+			path: "/page",
+			want: Page{Title: "Launch & Learn", OGTitle: "Launch on Example", OGImage: srv.URL + "/img/launch.png", StatusCode: 200},
+		},
+		{
+			name: "Redirect",
+			path: "/moved",
+			want: Page{Title: "Docs", OGImage: srv.URL + "/docs/cover.jpg", StatusCode: 200},
+		},
+		{name: "Error Status", path: "/missing", want: Page{Title: "Page not found", StatusCode: 404}},
+		{name: "Not HTML", path: "/image.png", want: Page{StatusCode: 200}},
+		{name: "Charset", path: "/latin1", want: Page{Title: "Café", StatusCode: 200}},
+		{name: "Size Limit", path: "/large", want: Page{StatusCode: 200}},
+		{name: "Long Title", path: "/long-title", want: Page{Title: strings.Repeat("a", maxTextLength), StatusCode: 200}},
+	}
+
+	f := testFetcher(Options{UserAgent: "zipit-test"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if *page != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, *page)
+			}
+		})
+	}
+}
+
+func TestFetch_Errors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/to-blocked", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/blocked", http.StatusFound)
+	})
+	mux.HandleFunc("/to-ftp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	blockPath := policyFunc(func(u *url.URL) error {
+		if u.Path == "/blocked" {
+			return &policy.Violation{Reason: "destination domain is blocked"}
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name          string
+		fetcher       *Fetcher
+		rawURL        string
+		wantViolation bool
+	}{
+		{name: "Timeout", fetcher: testFetcher(Options{Timeout: 50 * time.Millisecond}), rawURL: srv.URL + "/slow"},
+		{name: "Redirect Loop", fetcher: testFetcher(Options{}), rawURL: srv.URL + "/loop"},
+		{name: "Unsupported Scheme", fetcher: testFetcher(Options{}), rawURL: "ftp://example.com/file"},
+		{name: "Redirect To Unsupported Scheme", fetcher: testFetcher(Options{}), rawURL: srv.URL + "/to-ftp"},
+		{name: "Destination Not Allowed", fetcher: testFetcher(Options{Policy: policy.InternalHosts{}}), rawURL: srv.URL + "/page", wantViolation: true},
+		{name: "Redirect Not Allowed", fetcher: testFetcher(Options{Policy: blockPath}), rawURL: srv.URL + "/to-blocked", wantViolation: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.fetcher.Fetch(context.Background(), tt.rawURL)
+			if err == nil {
+				t.Fatalf("expected an error, got %+v", page)
+			}
+			var violation *policy.Violation
+			if errors.As(err, &violation) != tt.wantViolation {
+				t.Errorf("expected a policy violation: %v, got %v", tt.wantViolation, err)
+			}
+		})
+	}
+}
+
+func TestFetch_PrivateAddressRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the private address should not have been requested")
+	}))
+	defer srv.Close()
+
+	// "localhost" passes a policy that does not resolve names, but the
+	// address it resolves to is checked when dialing.
+	u, _ := url.Parse(srv.URL)
+	_, err := NewFetcher(Options{Timeout: time.Second, MaxBytes: 1024}).Fetch(context.Background(), "http://localhost:"+u.Port()+"/")
+	if !errors.Is(err, errInternalAddr) {
+		t.Errorf("expected errInternalAddr, got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	var gets int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected only a HEAD request, got %s", r.Method)
+		}
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		gets++
+		w.Write([]byte(strings.Repeat("x", 1<<20)))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/ok", want: http.StatusOK},
+		{path: "/no-head", want: http.StatusOK},
+		{path: "/moved", want: http.StatusGone},
+		{path: "/missing", want: http.StatusNotFound},
+	}
+	f := testFetcher(Options{})
+	for _, tt := range tests {
+		status, err := f.Status(context.Background(), srv.URL+tt.path)
+		if err != nil || status != tt.want {
+			t.Errorf("%s: expected status %d, got %d, err=%v", tt.path, tt.want, status, err)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("expected one GET request to confirm the HEAD error, got %d", gets)
+	}
+
+	if _, err := testFetcher(Options{Policy: policy.InternalHosts{}}).Status(context.Background(), srv.URL+"/ok"); err == nil {
+		t.Error("expected the URL policy to apply to status checks")
+	}
+}
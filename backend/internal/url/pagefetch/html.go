@@ -0,0 +1,113 @@
+package pagefetch
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Limits on what is stored from a page, which its author controls.
+const (
+	maxTextLength = 300
+	maxURLLength  = 2048
+)
+
+// parseHead reads the <title>, og:title and og:image of the HTML page in r
+// into page. It stops at the end of the head. Relative og:image URLs are
+// resolved against base, the URL the page was served from.
+func parseHead(r io.Reader, base *url.URL, page *Page) {
+	z := html.NewTokenizer(r)
+	inTitle := false
+	var title strings.Builder
+	defer func() {
+		if page.Title == "" {
+			page.Title = cleanText(title.String())
+		}
+	}()
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = title.Len() == 0
+			case "meta":
+				if hasAttr {
+					readMeta(z, base, page)
+				}
+			case "body":
+				return
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return
+			}
+		}
+	}
+}
+
+// readMeta reads an og:title or og:image <meta> tag into page. Open Graph
+// tags use the property attribute, but some pages use name instead.
+func readMeta(z *html.Tokenizer, base *url.URL, page *Page) {
+	var property, content string
+	for {
+		key, val, more := z.TagAttr()
+		switch string(key) {
+		case "property", "name":
+			property = strings.ToLower(string(val))
+		case "content":
+			content = string(val)
+		}
+		if !more {
+			break
+		}
+	}
+
+	switch property {
+	case "og:title":
+		if page.OGTitle == "" {
+			page.OGTitle = cleanText(content)
+		}
+	case "og:image":
+		if page.OGImage == "" {
+			page.OGImage = imageURL(base, content)
+		}
+	}
+}
+
+// cleanText collapses the whitespace in s and truncates it to maxTextLength
+// characters.
+func cleanText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= maxTextLength {
+		return s
+	}
+	return string([]rune(s)[:maxTextLength])
+}
+
+// imageURL resolves ref against base. Only http(s) URLs of reasonable length
+// are kept.
+func imageURL(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	if s := u.String(); len(s) <= maxURLLength {
+		return s
+	}
+	return ""
+}
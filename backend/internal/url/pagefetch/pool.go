@@ -0,0 +1,105 @@
+package pagefetch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+	"zipit/internal/url/repository"
+)
+
+// Store is where a Pool looks up links and stores the pages it fetched.
+// [repository.URLRepository] implements it.
+type Store interface {
+	GetURLByShortCode(ctx context.Context, shortCode string) (*repository.URL, error)
+	UpdatePageInfo(ctx context.Context, shortCode, longURL string, page repository.PageInfo) error
+}
+
+// Pool fetches the destination pages of queued links with a fixed number of
+// workers. Links are looked up when their turn comes, so the page fetched is
+// that of the current destination, and links whose page has already been
+// fetched are skipped.
+type Pool struct {
+	fetcher *Fetcher
+	store   Store
+	workers int
+	queue   chan string
+}
+
+// NewPool creates a Pool of workers fetching pages with fetcher. At most
+// queueSize links wait to be fetched; more are dropped.
+func NewPool(fetcher *Fetcher, store Store, workers, queueSize int) *Pool {
+	return &Pool{
+		fetcher: fetcher,
+		store:   store,
+		workers: workers,
+		queue:   make(chan string, queueSize),
+	}
+}
+
+// Enqueue schedules fetching the destination page of shortCode. It never
+// blocks: if the queue is full, the link is skipped and keeps no page info.
+func (p *Pool) Enqueue(shortCode string) {
+	select {
+	case p.queue <- shortCode:
+	default:
+		slog.Warn("page fetch queue is full, skipping link", "short_code", shortCode)
+	}
+}
+
+// Run fetches queued pages until ctx is done, and returns once the workers
+// have stopped. Links still queued are dropped.
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case shortCode := <-p.queue:
+					p.fetch(ctx, shortCode)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// fetch fetches and stores the destination page of shortCode. Failures are
+// logged; the link stays without page info.
+func (p *Pool) fetch(ctx context.Context, shortCode string) {
+	u, err := p.store.GetURLByShortCode(ctx, shortCode)
+	if err != nil {
+		slog.Warn("failed to look up link for page fetch", "short_code", shortCode, "error", err)
+		return
+	}
+	if u.DeletedAt != nil || u.Page.FetchedAt != nil {
+		return
+	}
+
+	page, err := p.fetcher.Fetch(ctx, u.LongURL)
+	if err != nil {
+		slog.Info("failed to fetch destination page", "short_code", shortCode, "error", err)
+		return
+	}
+	now := time.Now()
+	info := repository.PageInfo{
+		Title:      page.Title,
+		OGTitle:    page.OGTitle,
+		OGImage:    page.OGImage,
+		StatusCode: page.StatusCode,
+		FetchedAt:  &now,
+	}
+	if err := p.store.UpdatePageInfo(ctx, shortCode, u.LongURL, info); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.Debug("link changed while its page was fetched", "short_code", shortCode)
+			return
+		}
+		slog.Error("failed to store page info", "short_code", shortCode, "error", err)
+	}
+}
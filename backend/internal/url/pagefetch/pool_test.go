@@ -0,0 +1,112 @@
+package pagefetch
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"zipit/internal/url/repository"
+)
+
+// memStore is an in-memory [Store] that reports stored pages on a channel.
+type memStore struct {
+	mu     sync.Mutex
+	urls   map[string]*repository.URL
+	stored chan string
+}
+
+func (s *memStore) GetURLByShortCode(ctx context.Context, shortCode string) (*repository.URL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.urls[shortCode]
+	if !ok {
+		return nil, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (s *memStore) UpdatePageInfo(ctx context.Context, shortCode, longURL string, page repository.PageInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.urls[shortCode]
+	if !ok || u.LongURL != longURL {
+		return fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+	}
+	u.Page = page
+	s.stored <- shortCode
+	return nil
+}
+
+func TestPool(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		w.Write([]byte("<title>" + r.URL.Path + "</title>"))
+	}))
+	defer srv.Close()
+
+	fetchedAt := time.Now().Add(-time.Hour)
+	deletedAt := time.Now()
+	store := &memStore{
+		urls: map[string]*repository.URL{
+			"a":       {ShortCode: "a", LongURL: srv.URL + "/a"},
+			"b":       {ShortCode: "b", LongURL: srv.URL + "/b"},
+			"fetched": {ShortCode: "fetched", LongURL: srv.URL + "/fetched", Page: repository.PageInfo{Title: "Old", FetchedAt: &fetchedAt}},
+			"deleted": {ShortCode: "deleted", LongURL: srv.URL + "/deleted", DeletedAt: &deletedAt},
+		},
+		stored: make(chan string, 10),
+	}
+	pool := NewPool(testFetcher(Options{}), store, 2, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+	for _, code := range []string{"fetched", "deleted", "unknown", "a", "b"} {
+		pool.Enqueue(code)
+	}
+
+	stored := map[string]bool{}
+	for len(stored) < 2 {
+		select {
+		case code := <-store.stored:
+			stored[code] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for pages, got %v", stored)
+		}
+	}
+	cancel()
+	<-done
+
+	for _, code := range []string{"a", "b"} {
+		u, _ := store.GetURLByShortCode(context.Background(), code)
+		if u.Page.Title != "/"+code || u.Page.StatusCode != http.StatusOK || u.Page.FetchedAt == nil {
+			t.Errorf("expected the page of %s to be stored, got %+v", code, u.Page)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/fetched"] != 0 || requests["/deleted"] != 0 {
+		t.Errorf("expected fetched and deleted links to be skipped, got requests %v", requests)
+	}
+}
+
+func TestPool_QueueFull(t *testing.T) {
+	pool := NewPool(testFetcher(Options{}), &memStore{}, 1, 2)
+	for _, code := range []string{"a", "b", "c"} {
+		pool.Enqueue(code) // must not block without running workers
+	}
+	if len(pool.queue) != 2 {
+		t.Errorf("expected 2 queued links, got %d", len(pool.queue))
+	}
+}
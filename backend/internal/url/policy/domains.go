@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainLists checks destinations against a blocklist and an allowlist of
+// domains, each read from a file with one domain per line. Blank lines and
+// lines starting with # are ignored. A domain covers its subdomains too.
+// Blocked domains are always rejected; if the allowlist is configured, only
+// allowed domains are accepted.
+//
+// The files can be edited while the service runs: Reload rereads them, and
+// Watch does so whenever they change.
+type DomainLists struct {
+	blockPath string
+	allowPath string
+
+	mu      sync.RWMutex
+	blocked map[string]bool
+	allowed map[string]bool // nil if there is no allowlist
+	stamps  map[string]fileStamp
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewDomainLists loads the lists from blockPath and allowPath. Either path may
+// be empty to go without that list.
+func NewDomainLists(blockPath, allowPath string) (*DomainLists, error) {
+	d := &DomainLists{blockPath: blockPath, allowPath: allowPath}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Check implements [Policy].
+func (d *DomainLists) Check(ctx context.Context, u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if matchesAny(host, d.blocked) {
+		return &Violation{Reason: "destination domain is blocked"}
+	}
+	if d.allowed != nil && !matchesAny(host, d.allowed) {
+		return &Violation{Reason: "destination domain is not allowed"}
+	}
+	return nil
+}
+
+// Reload rereads both lists. If either cannot be read, the current lists are
+// kept and an error is returned.
+func (d *DomainLists) Reload() error {
+	stamps := map[string]fileStamp{}
+	blocked, err := readDomains(d.blockPath, stamps)
+	if err != nil {
+		return err
+	}
+	allowed, err := readDomains(d.allowPath, stamps)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.blocked = blocked
+	d.allowed = allowed
+	d.stamps = stamps
+	return nil
+}
+
+// Watch checks the files every interval and reloads the lists when one of
+// them changed, until ctx is done. Failed reloads are logged and the previous
+// lists stay in effect.
+func (d *DomainLists) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !d.changed() {
+				continue
+			}
+			if err := d.Reload(); err != nil {
+				slog.Error("failed to reload domain lists", "error", err)
+				continue
+			}
+			slog.Info("reloaded domain lists", "blocklist", d.blockPath, "allowlist", d.allowPath)
+		}
+	}
+}
+
+// changed reports whether a list file differs from the version last loaded.
+func (d *DomainLists) changed() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, path := range []string{d.blockPath, d.allowPath} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return true // let Reload report the error
+		}
+		if stamp, ok := d.stamps[path]; !ok || !stamp.modTime.Equal(info.ModTime()) || stamp.size != info.Size() {
+			return true
+		}
+	}
+	return false
+}
+
+// readDomains reads the domains listed in the file at path, recording its
+// version in stamps. It returns nil for an empty path.
+func readDomains(path string, stamps map[string]fileStamp) (map[string]bool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open domain list: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain list %s: %w", path, err)
+	}
+
+	domains := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(line, "*"), "."))
+		domains[strings.TrimSuffix(domain, ".")] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain list %s: %w", path, err)
+	}
+
+	stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	return domains, nil
+}
+
+// matchesAny reports whether host or one of its parent domains is in domains.
+func matchesAny(host string, domains map[string]bool) bool {
+	for {
+		if domains[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeList(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestDomainLists_Blocklist(t *testing.T) {
+	blockPath := filepath.Join(t.TempDir(), "blocked.txt")
+	writeList(t, blockPath, "# phishing\nevil.com\n\n*.Bad.Example\n")
+
+	d, err := NewDomainLists(blockPath, "")
+	if err != nil {
+		t.Fatalf("NewDomainLists failed: %v", err)
+	}
+
+	for _, rawURL := range []string{"https://evil.com/", "https://login.evil.com/", "https://bad.example/", "https://x.bad.example/"} {
+		if reason := check(t, d, rawURL); reason != "destination domain is blocked" {
+			t.Errorf("%s: expected it to be blocked, got %q", rawURL, reason)
+		}
+	}
+	for _, rawURL := range []string{"https://example.com/", "https://notevil.com/", "https://evil.com.example.com/"} {
+		if reason := check(t, d, rawURL); reason != "" {
+			t.Errorf("%s: expected it to be allowed, got %q", rawURL, reason)
+		}
+	}
+}
+
+func TestDomainLists_Allowlist(t *testing.T) {
+	dir := t.TempDir()
+	blockPath, allowPath := filepath.Join(dir, "blocked.txt"), filepath.Join(dir, "allowed.txt")
+	writeList(t, blockPath, "old.example.com\n")
+	writeList(t, allowPath, "example.com\n")
+
+	d, err := NewDomainLists(blockPath, allowPath)
+	if err != nil {
+		t.Fatalf("NewDomainLists failed: %v", err)
+	}
+
+	if reason := check(t, d, "https://www.example.com/"); reason != "" {
+		t.Errorf("expected an allowed subdomain to pass, got %q", reason)
+	}
+	if reason := check(t, d, "https://example.org/"); reason != "destination domain is not allowed" {
+		t.Errorf("expected an unlisted domain to be rejected, got %q", reason)
+	}
+	// The blocklist wins over the allowlist
+	if reason := check(t, d, "https://old.example.com/"); reason != "destination domain is blocked" {
+		t.Errorf("expected a blocked subdomain to be rejected, got %q", reason)
+	}
+}
+
+func TestDomainLists_Reload(t *testing.T) {
+	blockPath := filepath.Join(t.TempDir(), "blocked.txt")
+	writeList(t, blockPath, "evil.com\n")
+
+	d, err := NewDomainLists(blockPath, "")
+	if err != nil {
+		t.Fatalf("NewDomainLists failed: %v", err)
+	}
+
+	writeList(t, blockPath, "evil.com\nworse.com\n")
+	if err := d.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if reason := check(t, d, "https://worse.com/"); reason == "" {
+		t.Error("expected a newly listed domain to be blocked")
+	}
+
+	// A failed reload keeps the current lists
+	if err := os.Remove(blockPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Reload(); err == nil {
+		t.Error("expected an error for a missing list")
+	}
+	if reason := check(t, d, "https://evil.com/"); reason == "" {
+		t.Error("expected the previous list to stay in effect")
+	}
+}
+
+func TestDomainLists_Watch(t *testing.T) {
+	blockPath := filepath.Join(t.TempDir(), "blocked.txt")
+	writeList(t, blockPath, "evil.com\n")
+
+	d, err := NewDomainLists(blockPath, "")
+	if err != nil {
+		t.Fatalf("NewDomainLists failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Watch(ctx, 10*time.Millisecond)
+
+	writeList(t, blockPath, "evil.com\nworse.com\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for check(t, d, "https://worse.com/") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the changed list to be picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewDomainLists_MissingFile(t *testing.T) {
+	if _, err := NewDomainLists(filepath.Join(t.TempDir(), "missing.txt"), ""); err == nil {
+		t.Error("expected an error for a missing blocklist")
+	}
+}
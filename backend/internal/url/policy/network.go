@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"context"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// cgnat is the shared address space of carrier-grade NAT (RFC 6598), which
+// netip does not report as private.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// InternalHosts rejects destinations that point into a private network rather
+// than at the public internet: IP literals in private, loopback, link-local,
+// multicast or unspecified ranges (such as 10.0.0.1 or 169.254.169.254),
+// localhost, single-label hosts such as "intranet", and numeric hosts such as
+// "2130706433" or "127.1" that browsers read as IP addresses.
+//
+// Hostnames are not resolved, so a public name pointing at a private address
+// is allowed; only what the link itself says is checked.
+type InternalHosts struct{}
+
+// Check implements [Policy].
+func (InternalHosts) Check(ctx context.Context, u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if IsInternalAddr(addr) {
+			return &Violation{Reason: "destination is a private network address"}
+		}
+		return nil
+	}
+
+	if hostMatches(host, "localhost") {
+		return &Violation{Reason: "destination is localhost"}
+	}
+	if !strings.Contains(host, ".") {
+		if isNumericLabel(host) {
+			return &Violation{Reason: "destination host is an ambiguous numeric address"}
+		}
+		return &Violation{Reason: "destination host is not a public domain"}
+	}
+	if isNumericHost(host) {
+		return &Violation{Reason: "destination host is an ambiguous numeric address"}
+	}
+	return nil
+}
+
+// IsInternalAddr reports whether addr is in a private, loopback, link-local,
+// multicast or unspecified range, for callers that connect to destinations
+// and must check the addresses hostnames resolve to.
+func IsInternalAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() || cgnat.Contains(addr)
+}
+
+// isNumericHost reports whether every label of host is a number, as in the
+// shorthand IPv4 forms "127.1" or "0x7f.0.0.1".
+func isNumericHost(host string) bool {
+	for _, label := range strings.Split(host, ".") {
+		if !isNumericLabel(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumericLabel reports whether label is a decimal, octal or hex number.
+func isNumericLabel(label string) bool {
+	digits := label
+	hex := false
+	if len(label) > 2 && (label[:2] == "0x") {
+		digits, hex = label[2:], true
+	}
+	if digits == "" {
+		return false
+	}
+	for _, c := range digits {
+		if c >= '0' && c <= '9' || hex && c >= 'a' && c <= 'f' {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"context"
+	"net/url"
+)
+
+// Violation is the error returned when a destination is not allowed. Reason
+// is shown to the caller.
+type Violation struct {
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return "url not allowed: " + v.Reason
+}
+
+// Policy decides whether links may point at a destination. Check returns a
+// *Violation if u is not allowed. u is an absolute, normalized http(s) URL.
+// Implementations must be safe for concurrent use.
+type Policy interface {
+	Check(ctx context.Context, u *url.URL) error
+}
+
+// Chain applies several policies in order and returns the first violation.
+type Chain []Policy
+
+// Check implements [Policy].
+func (c Chain) Check(ctx context.Context, u *url.URL) error {
+	for _, p := range c {
+		if err := p.Check(ctx, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostMatches reports whether host is domain or one of its subdomains.
+func hostMatches(host, domain string) bool {
+	if len(host) == len(domain) {
+		return host == domain
+	}
+	return len(host) > len(domain) && host[len(host)-len(domain)-1] == '.' && host[len(host)-len(domain):] == domain
+}
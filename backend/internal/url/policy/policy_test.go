@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+// check runs p against rawURL and returns the violation reason, or "" if allowed.
+func check(t *testing.T, p Policy, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("invalid test url %q: %v", rawURL, err)
+	}
+	err = p.Check(context.Background(), u)
+	if err == nil {
+		return ""
+	}
+	var v *Violation
+	if !errors.As(err, &v) {
+		t.Fatalf("expected a *Violation, got %v", err)
+	}
+	return v.Reason
+}
+
+func TestInternalHosts(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://example.com/", allowed: true},
+		{url: "https://93.184.216.34/", allowed: true},
+		{url: "https://[2606:2800:220:1::]/", allowed: true},
+		{url: "https://1password.com/", allowed: true},
+		{url: "http://10.0.0.1/"},
+		{url: "http://172.16.5.4:8080/"},
+		{url: "http://192.168.1.1/"},
+		{url: "http://169.254.169.254/latest/meta-data/"},
+		{url: "http://127.0.0.1/"},
+		{url: "http://0.0.0.0/"},
+		{url: "http://100.64.0.1/"},
+		{url: "http://[::1]/"},
+		{url: "http://[fd00::1]/"},
+		{url: "http://[::ffff:10.0.0.1]/"},
+		{url: "http://localhost/"},
+		{url: "http://api.localhost/"},
+		{url: "http://LOCALHOST./"},
+		{url: "http://intranet/"},
+		{url: "http://2130706433/"},
+		{url: "http://127.1/"},
+		{url: "http://0x7f.0.0.1/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			reason := check(t, InternalHosts{}, tt.url)
+			if (reason == "") != tt.allowed {
+				t.Errorf("expected allowed=%v, got reason %q", tt.allowed, reason)
+			}
+		})
+	}
+}
+
+func TestSelfReference(t *testing.T) {
+	p := NewSelfReference([]string{"zip.it", " Go.Zip.It ", ""})
+
+	for _, rawURL := range []string{"https://zip.it/abc", "https://www.zip.it/abc", "https://ZIP.IT./abc", "https://go.zip.it:8443/x"} {
+		if reason := check(t, p, rawURL); reason != "destination is a short link" {
+			t.Errorf("%s: expected a self reference, got %q", rawURL, reason)
+		}
+	}
+	for _, rawURL := range []string{"https://example.com/", "https://notzip.it/", "https://zip.it.example.com/"} {
+		if reason := check(t, p, rawURL); reason != "" {
+			t.Errorf("%s: expected it to be allowed, got %q", rawURL, reason)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain{NewSelfReference([]string{"zip.it"}), InternalHosts{}}
+
+	if reason := check(t, chain, "https://example.com/"); reason != "" {
+		t.Errorf("expected example.com to be allowed, got %q", reason)
+	}
+	if reason := check(t, chain, "http://localhost/"); reason != "destination is localhost" {
+		t.Errorf("expected the second policy to reject localhost, got %q", reason)
+	}
+	if reason := check(t, Chain{}, "http://localhost/"); reason != "" {
+		t.Errorf("expected an empty chain to allow everything, got %q", reason)
+	}
+}
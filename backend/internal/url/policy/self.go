@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// SelfReference rejects links to our own short domains, which would redirect
+// to another short link or back to themselves.
+type SelfReference struct {
+	hosts []string
+}
+
+// NewSelfReference creates a policy rejecting hosts and their subdomains.
+func NewSelfReference(hosts []string) *SelfReference {
+	p := &SelfReference{}
+	for _, host := range hosts {
+		if host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), "."); host != "" {
+			p.hosts = append(p.hosts, host)
+		}
+	}
+	return p
+}
+
+// Check implements [Policy].
+func (p *SelfReference) Check(ctx context.Context, u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, self := range p.hosts {
+		if hostMatches(host, self) {
+			return &Violation{Reason: "destination is a short link"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"zipit/pkg/cache"
+)
+
+// cachedRepository is a read-through cache in front of another URLRepository.
+// Lookups of unknown codes are cached too, as empty entries, so bots probing
+// random codes do not reach the database on every request. The cache is best
+// effort: if it fails, calls fall through to the wrapped repository.
+//
+// Writes replace the cached record with a tombstone rather than dropping it,
+// so that a lookup which read the record from the database before the write
+// cannot cache it afterwards: lookups only cache what they read if the key is
+// still free. Records of password-protected links are not cached, so their
+// hashes never leave the database.
+type cachedRepository struct {
+	repo        URLRepository
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+// tombstone is cached in place of a record for tombstoneTTL after it was
+// written. Lookups read through it to the database.
+var tombstone = []byte{0}
+
+// tombstoneTTL is how long a record is not cached after a write. Lookups that
+// take longer are not cached either, since a write during the lookup could
+// have tombstoned the key and the tombstone expired since.
+const tombstoneTTL = 10 * time.Second
+
+// cacheKey returns the key under which the record for shortCode is cached.
+func cacheKey(shortCode string) string {
+	return "url:" + shortCode
+}
+
+// CreateURL implements [URLRepository]. The new code is evicted from the cache
+// in case an earlier lookup cached it as unknown.
+func (c *cachedRepository) CreateURL(ctx context.Context, u *URL, encode func(id int64) string) (string, error) {
+	shortCode, err := c.repo.CreateURL(ctx, u, encode)
+	if err != nil {
+		return "", err
+	}
+	c.invalidate(ctx, shortCode)
+	return shortCode, nil
+}
+
+// CreateURLs implements [URLRepository].
+func (c *cachedRepository) CreateURLs(ctx context.Context, urls []*URL, encode func(id int64) string) ([]CreateResult, error) {
+	results, err := c.repo.CreateURLs(ctx, urls, encode)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range results {
+		if res.Err == nil {
+			c.invalidate(ctx, res.ShortCode)
+		}
+	}
+	return results, nil
+}
+
+// GetURLByShortCode implements [URLRepository].
+func (c *cachedRepository) GetURLByShortCode(ctx context.Context, shortCode string) (*URL, error) {
+	key := cacheKey(shortCode)
+
+	data, err := c.cache.Get(ctx, key)
+	switch {
+	case err == nil && len(data) == 0:
+		return nil, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+	case err == nil && bytes.Equal(data, tombstone):
+		// Just written; read through to the database.
+	case err == nil:
+		u := &URL{}
+		if err := json.Unmarshal(data, u); err == nil {
+			return u, nil
+		}
+		slog.Warn("dropping unreadable cache entry", "key", key)
+		c.invalidate(ctx, shortCode)
+	case !errors.Is(err, cache.ErrMiss):
+		slog.Warn("failed to read from cache", "key", key, "error", err)
+	}
+
+	start := time.Now()
+	u, err := c.repo.GetURLByShortCode(ctx, shortCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) && c.negativeTTL > 0 {
+			c.add(ctx, key, []byte{}, c.negativeTTL, start)
+		}
+		return nil, err
+	}
+
+	if u.PasswordHash == "" {
+		if data, err := json.Marshal(u); err == nil {
+			c.add(ctx, key, data, c.ttl, start)
+		}
+	}
+	return u, nil
+}
+
+// DeleteURL implements [URLRepository].
+func (c *cachedRepository) DeleteURL(ctx context.Context, shortCode string) error {
+	if err := c.repo.DeleteURL(ctx, shortCode); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// DisableURL implements [URLRepository].
+func (c *cachedRepository) DisableURL(ctx context.Context, shortCode string, disabled bool) error {
+	if err := c.repo.DisableURL(ctx, shortCode, disabled); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// UpdateLongURL implements [URLRepository].
+func (c *cachedRepository) UpdateLongURL(ctx context.Context, shortCode, longURL, originalURL string) error {
+	if err := c.repo.UpdateLongURL(ctx, shortCode, longURL, originalURL); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// UpdateSettings implements [URLRepository].
+func (c *cachedRepository) UpdateSettings(ctx context.Context, shortCode string, settings LinkSettings) error {
+	if err := c.repo.UpdateSettings(ctx, shortCode, settings); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// SetRules implements [URLRepository].
+func (c *cachedRepository) SetRules(ctx context.Context, shortCode string, rules []Rule) error {
+	if err := c.repo.SetRules(ctx, shortCode, rules); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// SetVariants implements [URLRepository].
+func (c *cachedRepository) SetVariants(ctx context.Context, shortCode string, variants []Variant) error {
+	if err := c.repo.SetVariants(ctx, shortCode, variants); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// UpdatePageInfo implements [URLRepository].
+func (c *cachedRepository) UpdatePageInfo(ctx context.Context, shortCode, longURL string, page PageInfo) error {
+	if err := c.repo.UpdatePageInfo(ctx, shortCode, longURL, page); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// ClaimURLsForCheck implements [URLRepository]. Claims only change when a
+// record was last checked, so cached records are left as they are until the
+// result is recorded.
+func (c *cachedRepository) ClaimURLsForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*URL, error) {
+	return c.repo.ClaimURLsForCheck(ctx, checkedBefore, limit)
+}
+
+// RecordHealthCheck implements [URLRepository].
+func (c *cachedRepository) RecordHealthCheck(ctx context.Context, shortCode, longURL string, check HealthCheck) (bool, error) {
+	disabled, err := c.repo.RecordHealthCheck(ctx, shortCode, longURL, check)
+	if err != nil {
+		return false, err
+	}
+	c.invalidate(ctx, shortCode)
+	return disabled, nil
+}
+
+// ConsumeClick implements [URLRepository]. Clicks are always counted in the
+// wrapped repository. Cached records keep their count until the last click
+// is used up, when they are evicted so the link reads as used up right away.
+func (c *cachedRepository) ConsumeClick(ctx context.Context, shortCode string) (int, error) {
+	remaining, err := c.repo.ConsumeClick(ctx, shortCode)
+	if (err == nil && remaining == 0) || errors.Is(err, ErrNoClicksLeft) {
+		c.invalidate(ctx, shortCode)
+	}
+	return remaining, err
+}
+
+// GetPasswordLock implements [URLRepository]. Lockouts are not cached, so
+// that a lockout is seen by every instance right away.
+func (c *cachedRepository) GetPasswordLock(ctx context.Context, shortCode string) (PasswordLock, error) {
+	return c.repo.GetPasswordLock(ctx, shortCode)
+}
+
+// RecordPasswordFailure implements [URLRepository].
+func (c *cachedRepository) RecordPasswordFailure(ctx context.Context, shortCode string, maxFailures int, lockFor time.Duration) (PasswordLock, error) {
+	return c.repo.RecordPasswordFailure(ctx, shortCode, maxFailures, lockFor)
+}
+
+// ResetPasswordFailures implements [URLRepository].
+func (c *cachedRepository) ResetPasswordFailures(ctx context.Context, shortCode string) error {
+	return c.repo.ResetPasswordFailures(ctx, shortCode)
+}
+
+// GetURLHistory implements [URLRepository]. History is not cached.
+func (c *cachedRepository) GetURLHistory(ctx context.Context, shortCode string) ([]URLChange, error) {
+	return c.repo.GetURLHistory(ctx, shortCode)
+}
+
+// ListURLs implements [URLRepository]. Lists are not cached.
+func (c *cachedRepository) ListURLs(ctx context.Context, filter ListFilter) ([]*URL, error) {
+	return c.repo.ListURLs(ctx, filter)
+}
+
+// add caches value, read by a lookup that started at start, under key unless
+// the key is taken, such as by the tombstone of a write during the lookup.
+// Errors are logged rather than failing the request.
+func (c *cachedRepository) add(ctx context.Context, key string, value []byte, ttl time.Duration, start time.Time) {
+	if time.Since(start) >= tombstoneTTL {
+		return
+	}
+	if _, err := c.cache.Add(ctx, key, value, ttl); err != nil {
+		slog.Warn("failed to write to cache", "key", key, "error", err)
+	}
+}
+
+// invalidate replaces any cached record for shortCode with a tombstone. It
+// must be called after every write that changes what GetURLByShortCode
+// returns for the code.
+func (c *cachedRepository) invalidate(ctx context.Context, shortCode string) {
+	if err := c.cache.Set(ctx, cacheKey(shortCode), tombstone, tombstoneTTL); err != nil {
+		slog.Error("failed to invalidate cache entry", "short_code", shortCode, "error", err)
+	}
+}
+
+// NewCachedRepository wraps repo with a read-through cache. Records are kept
+// for ttl and unknown codes for negativeTTL; a negativeTTL of 0 disables
+// caching of unknown codes.
+func NewCachedRepository(repo URLRepository, c cache.Cache, ttl, negativeTTL time.Duration) URLRepository {
+	return &cachedRepository{
+		repo:        repo,
+		cache:       c,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
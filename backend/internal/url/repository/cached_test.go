@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"zipit/pkg/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// countingRepo returns a MockRepo serving records from urls and counting lookups.
+func countingRepo(urls map[string]*URL, lookups *int) *MockRepo {
+	return &MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *URL, encode func(id int64) string) (string, error) {
+			code := u.ShortCode
+			if code == "" {
+				code = encode(int64(len(urls) + 1))
+			}
+			stored := *u
+			stored.ShortCode = code
+			urls[code] = &stored
+			return code, nil
+		},
+		CreateURLsFunc: func(ctx context.Context, us []*URL, encode func(id int64) string) ([]CreateResult, error) {
+			results := make([]CreateResult, len(us))
+			for i, u := range us {
+				stored := *u
+				urls[u.ShortCode] = &stored
+				results[i] = CreateResult{ShortCode: u.ShortCode}
+			}
+			return results, nil
+		},
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*URL, error) {
+			*lookups++
+			if u, ok := urls[shortCode]; ok {
+				return u, nil
+			}
+			return nil, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+		},
+	}
+}
+
+func TestCachedRepo(t *testing.T) {
+	srv := miniredis.RunT(t)
+
+	backends := map[string]func() cache.Cache{
+		"LRU": func() cache.Cache { return cache.NewLRU(100) },
+		"Redis": func() cache.Cache {
+			srv.FlushAll()
+			return cache.NewRedis(redis.NewClient(&redis.Options{Addr: srv.Addr()}))
+		},
+	}
+
+	for name, newCache := range backends {
+		t.Run(name+"/Hit", func(t *testing.T) {
+			ctx := context.Background()
+			expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			lookups := 0
+			urls := map[string]*URL{"abc": {ID: 1, LongURL: "https://example.com", ShortCode: "abc", ExpiresAt: &expiresAt}}
+			repo := NewCachedRepository(countingRepo(urls, &lookups), newCache(), time.Minute, time.Minute)
+
+			for i := 0; i < 3; i++ {
+				u, err := repo.GetURLByShortCode(ctx, "abc")
+				if err != nil {
+					t.Fatalf("GetURLByShortCode failed: %v", err)
+				}
+				if u.LongURL != "https://example.com" || u.ExpiresAt == nil || !u.ExpiresAt.Equal(expiresAt) {
+					t.Errorf("unexpected record %+v", u)
+				}
+			}
+			if lookups != 1 {
+				t.Errorf("expected 1 database lookup, got %d", lookups)
+			}
+		})
+
+		t.Run(name+"/Negative", func(t *testing.T) {
+			ctx := context.Background()
+			lookups := 0
+			urls := map[string]*URL{}
+			repo := NewCachedRepository(countingRepo(urls, &lookups), newCache(), time.Minute, time.Minute)
+
+			for i := 0; i < 3; i++ {
+				if _, err := repo.GetURLByShortCode(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+					t.Fatalf("expected sql.ErrNoRows, got %v", err)
+				}
+			}
+			if lookups != 1 {
+				t.Errorf("expected 1 database lookup, got %d", lookups)
+			}
+
+			// Creating the code evicts the negative entry
+			if _, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com", ShortCode: "missing"}, nil); err != nil {
+				t.Fatalf("CreateURL failed: %v", err)
+			}
+			u, err := repo.GetURLByShortCode(ctx, "missing")
+			if err != nil || u.LongURL != "https://example.com" {
+				t.Errorf("expected the new record, got %+v, err=%v", u, err)
+			}
+
+			// So does creating it in bulk
+			_, _ = repo.GetURLByShortCode(ctx, "bulk")
+			if _, err := repo.CreateURLs(ctx, []*URL{{LongURL: "https://example.com/bulk", ShortCode: "bulk"}}, nil); err != nil {
+				t.Fatalf("CreateURLs failed: %v", err)
+			}
+			u, err = repo.GetURLByShortCode(ctx, "bulk")
+			if err != nil || u.LongURL != "https://example.com/bulk" {
+				t.Errorf("expected the new record, got %+v, err=%v", u, err)
+			}
+		})
+
+		t.Run(name+"/InvalidatedOnChange", func(t *testing.T) {
+			ctx := context.Background()
+			lookups := 0
+			urls := map[string]*URL{"abc": {ID: 1, LongURL: "https://example.com", ShortCode: "abc"}}
+			mockRepo := countingRepo(urls, &lookups)
+			mockRepo.DisableURLFunc = func(ctx context.Context, shortCode string, disabled bool) error {
+				now := time.Now()
+				urls[shortCode].DisabledAt = &now
+				return nil
+			}
+			mockRepo.DeleteURLFunc = func(ctx context.Context, shortCode string) error {
+				now := time.Now()
+				urls[shortCode].DeletedAt = &now
+				return nil
+			}
+			mockRepo.UpdateLongURLFunc = func(ctx context.Context, shortCode, longURL, originalURL string) error {
+				urls[shortCode].LongURL = longURL
+				return nil
+			}
+			mockRepo.UpdateSettingsFunc = func(ctx context.Context, shortCode string, settings LinkSettings) error {
+				urls[shortCode].Interstitial = *settings.Interstitial
+				return nil
+			}
+			mockRepo.SetRulesFunc = func(ctx context.Context, shortCode string, rules []Rule) error {
+				urls[shortCode].Rules = rules
+				return nil
+			}
+			mockRepo.SetVariantsFunc = func(ctx context.Context, shortCode string, variants []Variant) error {
+				urls[shortCode].Variants = variants
+				return nil
+			}
+			mockRepo.UpdatePageInfoFunc = func(ctx context.Context, shortCode, longURL string, page PageInfo) error {
+				urls[shortCode].Page = page
+				return nil
+			}
+			mockRepo.RecordHealthCheckFunc = func(ctx context.Context, shortCode, longURL string, check HealthCheck) (bool, error) {
+				urls[shortCode].Health.LastStatus = check.Status
+				return false, nil
+			}
+			repo := NewCachedRepository(mockRepo, newCache(), time.Minute, time.Minute)
+
+			_, _ = repo.GetURLByShortCode(ctx, "abc")
+			if err := repo.UpdateLongURL(ctx, "abc", "https://example.com/new", "https://example.com/new"); err != nil {
+				t.Fatalf("UpdateLongURL failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.LongURL != "https://example.com/new" {
+				t.Errorf("expected the new destination after UpdateLongURL, got %+v", u)
+			}
+			interstitial := true
+			if err := repo.UpdateSettings(ctx, "abc", LinkSettings{Interstitial: &interstitial}); err != nil {
+				t.Fatalf("UpdateSettings failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || !u.Interstitial {
+				t.Errorf("expected the new settings after UpdateSettings, got %+v", u)
+			}
+			if err := repo.SetRules(ctx, "abc", []Rule{{Platforms: []string{"ios"}, LongURL: "https://apps.apple.com/app/id1"}}); err != nil {
+				t.Fatalf("SetRules failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || len(u.Rules) != 1 {
+				t.Errorf("expected the new rules after SetRules, got %+v", u)
+			}
+			if err := repo.SetVariants(ctx, "abc", []Variant{{Name: "A", LongURL: "https://example.com/a", Weight: 1}}); err != nil {
+				t.Fatalf("SetVariants failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || len(u.Variants) != 1 {
+				t.Errorf("expected the new variants after SetVariants, got %+v", u)
+			}
+			if err := repo.UpdatePageInfo(ctx, "abc", "https://example.com/new", PageInfo{Title: "New", StatusCode: 200}); err != nil {
+				t.Fatalf("UpdatePageInfo failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.Page.Title != "New" {
+				t.Errorf("expected the new page info after UpdatePageInfo, got %+v", u)
+			}
+			if _, err := repo.RecordHealthCheck(ctx, "abc", "https://example.com/new", HealthCheck{Status: 404}); err != nil {
+				t.Fatalf("RecordHealthCheck failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.Health.LastStatus != 404 {
+				t.Errorf("expected the new health status after RecordHealthCheck, got %+v", u)
+			}
+			if err := repo.DisableURL(ctx, "abc", true); err != nil {
+				t.Fatalf("DisableURL failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.DisabledAt == nil {
+				t.Errorf("expected the disabled record after DisableURL, got %+v", u)
+			}
+			if err := repo.DeleteURL(ctx, "abc"); err != nil {
+				t.Fatalf("DeleteURL failed: %v", err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.DeletedAt == nil {
+				t.Errorf("expected the deleted record after DeleteURL, got %+v", u)
+			}
+			if lookups != 9 {
+				t.Errorf("expected 9 database lookups, got %d", lookups)
+			}
+		})
+
+		t.Run(name+"/ClickLimit", func(t *testing.T) {
+			ctx := context.Background()
+			lookups := 0
+			urls := map[string]*URL{"abc": {ID: 1, LongURL: "https://example.com", ShortCode: "abc", MaxClicks: 2, ClicksRemaining: 2}}
+			mockRepo := countingRepo(urls, &lookups)
+			mockRepo.ConsumeClickFunc = func(ctx context.Context, shortCode string) (int, error) {
+				u := urls[shortCode]
+				if u.ClicksRemaining == 0 {
+					return 0, ErrNoClicksLeft
+				}
+				u.ClicksRemaining--
+				return u.ClicksRemaining, nil
+			}
+			repo := NewCachedRepository(mockRepo, newCache(), time.Minute, time.Minute)
+
+			_, _ = repo.GetURLByShortCode(ctx, "abc")
+			if remaining, err := repo.ConsumeClick(ctx, "abc"); err != nil || remaining != 1 {
+				t.Fatalf("expected 1 click left, got %d, err=%v", remaining, err)
+			}
+			// Counts are not kept up to date in the cache...
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.ClicksRemaining != 2 {
+				t.Errorf("expected the cached record, got %+v", u)
+			}
+			// ...but the record is evicted once the last click is used
+			if remaining, err := repo.ConsumeClick(ctx, "abc"); err != nil || remaining != 0 {
+				t.Fatalf("expected no clicks left, got %d, err=%v", remaining, err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.ClicksRemaining != 0 {
+				t.Errorf("expected a used up record, got %+v", u)
+			}
+			if _, err := repo.ConsumeClick(ctx, "abc"); !errors.Is(err, ErrNoClicksLeft) {
+				t.Errorf("expected ErrNoClicksLeft, got %v", err)
+			}
+			if lookups != 2 {
+				t.Errorf("expected 2 database lookups, got %d", lookups)
+			}
+		})
+
+		t.Run(name+"/WriteDuringLookup", func(t *testing.T) {
+			ctx := context.Background()
+			lookups := 0
+			urls := map[string]*URL{"abc": {ID: 1, LongURL: "https://example.com", ShortCode: "abc"}}
+			mockRepo := countingRepo(urls, &lookups)
+			mockRepo.DeleteURLFunc = func(ctx context.Context, shortCode string) error {
+				now := time.Now()
+				deleted := *urls[shortCode]
+				deleted.DeletedAt = &now
+				urls[shortCode] = &deleted
+				return nil
+			}
+			repo := NewCachedRepository(mockRepo, newCache(), time.Minute, time.Minute)
+
+			// The link is deleted, through another instance, after the
+			// first lookup read it but before it is cached
+			getURL := mockRepo.GetURLByShortCodeFunc
+			mockRepo.GetURLByShortCodeFunc = func(ctx context.Context, shortCode string) (*URL, error) {
+				u, err := getURL(ctx, shortCode)
+				if lookups == 1 {
+					_ = repo.DeleteURL(ctx, shortCode)
+				}
+				return u, err
+			}
+
+			if u, err := repo.GetURLByShortCode(ctx, "abc"); err != nil || u.DeletedAt != nil {
+				t.Fatalf("expected the record as it was read, got %+v, err=%v", u, err)
+			}
+			if u, _ := repo.GetURLByShortCode(ctx, "abc"); u == nil || u.DeletedAt == nil {
+				t.Errorf("expected the deleted record, got %+v", u)
+			}
+		})
+
+		t.Run(name+"/PasswordNotCached", func(t *testing.T) {
+			ctx := context.Background()
+			lookups := 0
+			urls := map[string]*URL{"abc": {ID: 1, LongURL: "https://example.com", ShortCode: "abc", PasswordHash: "$2a$10$hash"}}
+			c := newCache()
+			repo := NewCachedRepository(countingRepo(urls, &lookups), c, time.Minute, time.Minute)
+
+			for i := 0; i < 2; i++ {
+				if u, err := repo.GetURLByShortCode(ctx, "abc"); err != nil || u.PasswordHash != "$2a$10$hash" {
+					t.Fatalf("expected the protected record, got %+v, err=%v", u, err)
+				}
+			}
+			if lookups != 2 {
+				t.Errorf("expected 2 database lookups, got %d", lookups)
+			}
+			if _, err := c.Get(ctx, cacheKey("abc")); !errors.Is(err, cache.ErrMiss) {
+				t.Errorf("expected the protected record not to be cached, got %v", err)
+			}
+		})
+
+		t.Run(name+"/NegativeDisabled", func(t *testing.T) {
+			ctx := context.Background()
+			lookups := 0
+			repo := NewCachedRepository(countingRepo(map[string]*URL{}, &lookups), newCache(), time.Minute, 0)
+
+			for i := 0; i < 2; i++ {
+				_, _ = repo.GetURLByShortCode(ctx, "missing")
+			}
+			if lookups != 2 {
+				t.Errorf("expected 2 database lookups, got %d", lookups)
+			}
+		})
+	}
+}
+
+func TestCachedRepo_DatabaseErrorNotCached(t *testing.T) {
+	ctx := context.Background()
+	lookups := 0
+	mockRepo := &MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*URL, error) {
+			lookups++
+			return nil, errors.New("connection refused")
+		},
+	}
+	repo := NewCachedRepository(mockRepo, cache.NewLRU(10), time.Minute, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.GetURLByShortCode(ctx, "abc"); err == nil || errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected the database error, got %v", err)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("expected 2 database lookups, got %d", lookups)
+	}
+}
+
+func TestCachedRepo_CacheDown(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	c := cache.NewRedis(redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1}))
+	srv.Close()
+
+	lookups := 0
+	urls := map[string]*URL{"abc": {ID: 1, LongURL: "https://example.com", ShortCode: "abc"}}
+	repo := NewCachedRepository(countingRepo(urls, &lookups), c, time.Minute, time.Minute)
+
+	// Lookups and writes keep working, straight against the database
+	u, err := repo.GetURLByShortCode(ctx, "abc")
+	if err != nil || u.LongURL != "https://example.com" {
+		t.Errorf("expected the record from the database, got %+v, err=%v", u, err)
+	}
+	if _, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/b"}, func(id int64) string { return "b" }); err != nil {
+		t.Errorf("expected CreateURL to succeed, got %v", err)
+	}
+}
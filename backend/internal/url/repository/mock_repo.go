@@ -3,44 +3,172 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // MockRepo for testing service logic
 type MockRepo struct {
-	URLExistsFunc         func(ctx context.Context, longURL string) (bool, int64, error)
-	CreateURLFunc         func(ctx context.Context, longURL string) (int64, error)
-	GetURLByShortCodeFunc func(ctx context.Context, shortCode string) (string, error)
-	SetShortCodeFunc      func(ctx context.Context, id int64, shortCode string) error
+	CreateURLFunc         func(ctx context.Context, u *URL, encode func(id int64) string) (string, error)
+	CreateURLsFunc        func(ctx context.Context, urls []*URL, encode func(id int64) string) ([]CreateResult, error)
+	GetURLByShortCodeFunc func(ctx context.Context, shortCode string) (*URL, error)
+	DeleteURLFunc         func(ctx context.Context, shortCode string) error
+	DisableURLFunc        func(ctx context.Context, shortCode string, disabled bool) error
+	UpdateLongURLFunc     func(ctx context.Context, shortCode, longURL, originalURL string) error
+	GetURLHistoryFunc     func(ctx context.Context, shortCode string) ([]URLChange, error)
+	UpdateSettingsFunc    func(ctx context.Context, shortCode string, settings LinkSettings) error
+	SetRulesFunc          func(ctx context.Context, shortCode string, rules []Rule) error
+	SetVariantsFunc       func(ctx context.Context, shortCode string, variants []Variant) error
+	UpdatePageInfoFunc    func(ctx context.Context, shortCode, longURL string, page PageInfo) error
+	ClaimURLsForCheckFunc func(ctx context.Context, checkedBefore time.Time, limit int) ([]*URL, error)
+	RecordHealthCheckFunc func(ctx context.Context, shortCode, longURL string, check HealthCheck) (bool, error)
+	ConsumeClickFunc      func(ctx context.Context, shortCode string) (int, error)
+	GetPasswordLockFunc   func(ctx context.Context, shortCode string) (PasswordLock, error)
+	ListURLsFunc          func(ctx context.Context, filter ListFilter) ([]*URL, error)
+
+	RecordPasswordFailureFunc func(ctx context.Context, shortCode string, maxFailures int, lockFor time.Duration) (PasswordLock, error)
+	ResetPasswordFailuresFunc func(ctx context.Context, shortCode string) error
 }
 
 // CreateURL implements [URLRepository].
-func (m *MockRepo) CreateURL(ctx context.Context, longURL string) (int64, error) {
+func (m *MockRepo) CreateURL(ctx context.Context, u *URL, encode func(id int64) string) (string, error) {
 	if m.CreateURLFunc != nil {
-		return m.CreateURLFunc(ctx, longURL)
+		return m.CreateURLFunc(ctx, u, encode)
+	}
+	return "", fmt.Errorf("some error creating long url")
+}
+
+// CreateURLs implements [URLRepository].
+func (m *MockRepo) CreateURLs(ctx context.Context, urls []*URL, encode func(id int64) string) ([]CreateResult, error) {
+	if m.CreateURLsFunc != nil {
+		return m.CreateURLsFunc(ctx, urls, encode)
 	}
-	return 0, fmt.Errorf("some error creating long url")
+	return nil, fmt.Errorf("some error creating long urls")
 }
 
 // GetURLByShortCode implements [URLRepository].
-func (m *MockRepo) GetURLByShortCode(ctx context.Context, shortCode string) (string, error) {
+func (m *MockRepo) GetURLByShortCode(ctx context.Context, shortCode string) (*URL, error) {
 	if m.GetURLByShortCodeFunc != nil {
 		return m.GetURLByShortCodeFunc(ctx, shortCode)
 	}
-	return "", fmt.Errorf("some error fetching long url by short code") // database error
+	return nil, fmt.Errorf("some error fetching long url by short code") // database error
+}
+
+// DeleteURL implements [URLRepository].
+func (m *MockRepo) DeleteURL(ctx context.Context, shortCode string) error {
+	if m.DeleteURLFunc != nil {
+		return m.DeleteURLFunc(ctx, shortCode)
+	}
+	return fmt.Errorf("some error deleting url")
+}
+
+// DisableURL implements [URLRepository].
+func (m *MockRepo) DisableURL(ctx context.Context, shortCode string, disabled bool) error {
+	if m.DisableURLFunc != nil {
+		return m.DisableURLFunc(ctx, shortCode, disabled)
+	}
+	return fmt.Errorf("some error disabling url")
+}
+
+// UpdateLongURL implements [URLRepository].
+func (m *MockRepo) UpdateLongURL(ctx context.Context, shortCode, longURL, originalURL string) error {
+	if m.UpdateLongURLFunc != nil {
+		return m.UpdateLongURLFunc(ctx, shortCode, longURL, originalURL)
+	}
+	return fmt.Errorf("some error updating url")
+}
+
+// GetURLHistory implements [URLRepository].
+func (m *MockRepo) GetURLHistory(ctx context.Context, shortCode string) ([]URLChange, error) {
+	if m.GetURLHistoryFunc != nil {
+		return m.GetURLHistoryFunc(ctx, shortCode)
+	}
+	return nil, fmt.Errorf("some error fetching url history")
+}
+
+// UpdateSettings implements [URLRepository].
+func (m *MockRepo) UpdateSettings(ctx context.Context, shortCode string, settings LinkSettings) error {
+	if m.UpdateSettingsFunc != nil {
+		return m.UpdateSettingsFunc(ctx, shortCode, settings)
+	}
+	return fmt.Errorf("some error updating url settings")
+}
+
+// SetRules implements [URLRepository].
+func (m *MockRepo) SetRules(ctx context.Context, shortCode string, rules []Rule) error {
+	if m.SetRulesFunc != nil {
+		return m.SetRulesFunc(ctx, shortCode, rules)
+	}
+	return fmt.Errorf("some error setting url rules")
+}
+
+// SetVariants implements [URLRepository].
+func (m *MockRepo) SetVariants(ctx context.Context, shortCode string, variants []Variant) error {
+	if m.SetVariantsFunc != nil {
+		return m.SetVariantsFunc(ctx, shortCode, variants)
+	}
+	return fmt.Errorf("some error setting url variants")
+}
+
+// UpdatePageInfo implements [URLRepository].
+func (m *MockRepo) UpdatePageInfo(ctx context.Context, shortCode, longURL string, page PageInfo) error {
+	if m.UpdatePageInfoFunc != nil {
+		return m.UpdatePageInfoFunc(ctx, shortCode, longURL, page)
+	}
+	return fmt.Errorf("some error updating page info")
+}
+
+// ClaimURLsForCheck implements [URLRepository].
+func (m *MockRepo) ClaimURLsForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*URL, error) {
+	if m.ClaimURLsForCheckFunc != nil {
+		return m.ClaimURLsForCheckFunc(ctx, checkedBefore, limit)
+	}
+	return nil, fmt.Errorf("some error claiming urls for check")
+}
+
+// RecordHealthCheck implements [URLRepository].
+func (m *MockRepo) RecordHealthCheck(ctx context.Context, shortCode, longURL string, check HealthCheck) (bool, error) {
+	if m.RecordHealthCheckFunc != nil {
+		return m.RecordHealthCheckFunc(ctx, shortCode, longURL, check)
+	}
+	return false, fmt.Errorf("some error recording health check")
+}
+
+// ConsumeClick implements [URLRepository].
+func (m *MockRepo) ConsumeClick(ctx context.Context, shortCode string) (int, error) {
+	if m.ConsumeClickFunc != nil {
+		return m.ConsumeClickFunc(ctx, shortCode)
+	}
+	return 0, fmt.Errorf("some error consuming click")
+}
+
+// GetPasswordLock implements [URLRepository].
+func (m *MockRepo) GetPasswordLock(ctx context.Context, shortCode string) (PasswordLock, error) {
+	if m.GetPasswordLockFunc != nil {
+		return m.GetPasswordLockFunc(ctx, shortCode)
+	}
+	return PasswordLock{}, fmt.Errorf("some error reading password lockout")
+}
+
+// RecordPasswordFailure implements [URLRepository].
+func (m *MockRepo) RecordPasswordFailure(ctx context.Context, shortCode string, maxFailures int, lockFor time.Duration) (PasswordLock, error) {
+	if m.RecordPasswordFailureFunc != nil {
+		return m.RecordPasswordFailureFunc(ctx, shortCode, maxFailures, lockFor)
+	}
+	return PasswordLock{}, fmt.Errorf("some error recording password failure")
 }
 
-// SetShortCode implements [URLRepository].
-func (m *MockRepo) SetShortCode(ctx context.Context, id int64, shortCode string) error {
-	if m.SetShortCodeFunc != nil {
-		return m.SetShortCodeFunc(ctx, id, shortCode)
+// ResetPasswordFailures implements [URLRepository].
+func (m *MockRepo) ResetPasswordFailures(ctx context.Context, shortCode string) error {
+	if m.ResetPasswordFailuresFunc != nil {
+		return m.ResetPasswordFailuresFunc(ctx, shortCode)
 	}
-	return fmt.Errorf("some error setting short code") // database error
+	return fmt.Errorf("some error resetting password failures")
 }
 
-// URLExists implements [URLRepository].
-func (m *MockRepo) URLExists(ctx context.Context, longURL string) (bool, int64, error) {
-	if m.URLExistsFunc != nil {
-		return m.URLExistsFunc(ctx, longURL)
+// ListURLs implements [URLRepository].
+func (m *MockRepo) ListURLs(ctx context.Context, filter ListFilter) ([]*URL, error) {
+	if m.ListURLsFunc != nil {
+		return m.ListURLsFunc(ctx, filter)
 	}
-	return false, 0, fmt.Errorf("some error checking long url in database") // database error
+	return nil, fmt.Errorf("some error listing urls")
 }
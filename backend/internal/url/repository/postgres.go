@@ -3,8 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 	"zipit/pkg/database"
+
+	"github.com/lib/pq"
 )
 
 // postgresRepository implements the URLRepository interface using a PostgreSQL database.
@@ -12,70 +18,867 @@ type postgresRepository struct {
 	db *database.Database
 }
 
-// CreateURL inserts a new long URL into the database and returns its auto-generated ID.
-// We do this first because we need the ID to generate a unique short code.
-func (pgRepo *postgresRepository) CreateURL(ctx context.Context, longURL string) (int64, error) {
-	var id int64
-	// PostgreSQL "RETURNING id" saves us an extra query to find out what ID was assigned.
-	query := `INSERT INTO urls (long_url) VALUES ($1) RETURNING id`
+// maxInsertAttempts bounds how many sequence values CreateURL will skip
+// because a custom alias has already claimed them.
+const maxInsertAttempts = 10
+
+// CreateURL inserts a URL together with its short code in a single statement,
+// so a failed request can never leave a row without a code behind.
+// The row ID is taken from the sequence up front because generated codes are
+// derived from it. Canonical rows rely on the idx_urls_canonical_owner_long_url
+// unique index: if another request of the same owner already stored the
+// destination, the insert does nothing and the existing code is returned.
+func (pgRepo *postgresRepository) CreateURL(ctx context.Context, u *URL, encode func(id int64) string) (string, error) {
+	query := `INSERT INTO urls (id, long_url, original_url, short_code, owner, is_custom, canonical, expires_at, interstitial, redirect_type, title, description, tags, password_hash, max_clicks, clicks_remaining)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (owner, long_url) WHERE canonical DO NOTHING
+		RETURNING short_code`
+
+	for attempt := 0; attempt < maxInsertAttempts; attempt++ {
+		id := u.ID
+		if id == 0 || attempt > 0 {
+			var err error
+			if id, err = pgRepo.nextID(ctx); err != nil {
+				return "", err
+			}
+		}
+		isCustom := u.ShortCode != ""
+		shortCode := u.ShortCode
+		if !isCustom {
+			shortCode = encode(id)
+		}
+
+		var stored string
+		// QueryRowContext is for queries that return exactly one row.
+		err := pgRepo.db.Conn.QueryRowContext(ctx, query, id, u.LongURL, u.OriginalURL, shortCode, u.Owner, isCustom, u.Canonical, u.ExpiresAt, u.Interstitial, redirectType(u), u.Title, u.Description, tags(u), u.PasswordHash, u.MaxClicks, u.MaxClicks).Scan(&stored)
+		switch {
+		case err == nil:
+			return stored, nil
+		case err == sql.ErrNoRows:
+			// ON CONFLICT DO NOTHING returns no row: the destination already has a canonical code.
+			existing, err := pgRepo.canonicalShortCode(ctx, u.Owner, u.LongURL)
+			if err == sql.ErrNoRows {
+				continue // the canonical row went away in the meantime
+			}
+			return existing, err
+		case isUniqueViolation(err, "urls_pkey"):
+			// The ID is reserved by a custom alias; take the next one.
+			continue
+		case isUniqueViolation(err, "urls_short_code_key"):
+			return "", fmt.Errorf("short code %q: %w", shortCode, ErrShortCodeTaken)
+		default:
+			return "", fmt.Errorf("failed to insert long URL: %w", err)
+		}
+	}
+	return "", fmt.Errorf("failed to insert long URL: no free id after %d attempts", maxInsertAttempts)
+}
+
+// insertChunkSize bounds the rows per multi-row INSERT, keeping each statement
+// well below PostgreSQL's limit of 65535 bind parameters.
+const insertChunkSize = 500
+
+// CreateURLs inserts urls with multi-row INSERT statements of up to
+// insertChunkSize rows each. If a chunk fails outright after earlier chunks
+// were stored, its records and all later ones fail with that error.
+func (pgRepo *postgresRepository) CreateURLs(ctx context.Context, urls []*URL, encode func(id int64) string) ([]CreateResult, error) {
+	results := make([]CreateResult, len(urls))
+	for start := 0; start < len(urls); start += insertChunkSize {
+		end := min(start+insertChunkSize, len(urls))
+		if err := pgRepo.createChunk(ctx, urls[start:end], encode, results[start:end]); err != nil {
+			if start == 0 {
+				return nil, err
+			}
+			for i := start; i < len(urls); i++ {
+				results[i] = CreateResult{Err: err}
+			}
+			break
+		}
+	}
+	return results, nil
+}
+
+// pendingRow is a record of a CreateURLs call together with the id and code
+// it is being inserted with.
+type pendingRow struct {
+	index     int
+	id        int64
+	shortCode string
+}
+
+// createChunk stores urls and fills in their results. Rows that conflict with
+// a stored row are skipped with ON CONFLICT DO NOTHING rather than failing the
+// statement, then sorted out: canonical rows pick up the existing canonical
+// code, custom aliases whose code is stored fail with ErrShortCodeTaken, and
+// the rest ran into a reserved id or a generated code in use and are retried
+// with a fresh id, as in CreateURL.
+func (pgRepo *postgresRepository) createChunk(ctx context.Context, urls []*URL, encode func(id int64) string, results []CreateResult) error {
+	pending := make([]int, len(urls))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for attempt := 0; attempt < maxInsertAttempts && len(pending) > 0; attempt++ {
+		need := 0
+		for _, i := range pending {
+			if urls[i].ID == 0 || attempt > 0 {
+				need++
+			}
+		}
+		fresh, err := pgRepo.nextIDs(ctx, need)
+		if err != nil {
+			return err
+		}
+
+		// Rows repeating an id or code of an earlier row in the same
+		// statement wait for the next round, where they conflict with the
+		// stored row like any other duplicate.
+		var rows []pendingRow
+		var retry []int
+		seenIDs, seenCodes := map[int64]bool{}, map[string]bool{}
+		for _, i := range pending {
+			u := urls[i]
+			id := u.ID
+			if id == 0 || attempt > 0 {
+				id, fresh = fresh[0], fresh[1:]
+			}
+			shortCode := u.ShortCode
+			if shortCode == "" {
+				shortCode = encode(id)
+			}
+			if seenIDs[id] || seenCodes[shortCode] {
+				retry = append(retry, i)
+				continue
+			}
+			seenIDs[id], seenCodes[shortCode] = true, true
+			rows = append(rows, pendingRow{index: i, id: id, shortCode: shortCode})
+		}
+
+		inserted, err := pgRepo.insertRows(ctx, urls, rows)
+		if err != nil {
+			return err
+		}
+
+		var skipped []pendingRow
+		for _, row := range rows {
+			if inserted[row.id] {
+				results[row.index] = CreateResult{ShortCode: row.shortCode}
+			} else {
+				skipped = append(skipped, row)
+			}
+		}
+		if len(skipped) > 0 {
+			unresolved, err := pgRepo.resolveSkipped(ctx, urls, skipped, results)
+			if err != nil {
+				return err
+			}
+			retry = append(retry, unresolved...)
+		}
+		pending = retry
+	}
+
+	for _, i := range pending {
+		results[i] = CreateResult{Err: fmt.Errorf("failed to insert long URL: no free id after %d attempts", maxInsertAttempts)}
+	}
+	return nil
+}
+
+// insertRows inserts rows in one statement and returns the ids of the rows
+// that were stored.
+func (pgRepo *postgresRepository) insertRows(ctx context.Context, urls []*URL, rows []pendingRow) (map[int64]bool, error) {
+	const columns = 16
 
-	// QueryRowContext is for queries that return exactly one row.
-	err := pgRepo.db.Conn.QueryRowContext(ctx, query, longURL).Scan(&id)
+	var query strings.Builder
+	query.WriteString("INSERT INTO urls (id, long_url, original_url, short_code, owner, is_custom, canonical, expires_at, interstitial, redirect_type, title, description, tags, password_hash, max_clicks, clicks_remaining) VALUES ")
+	args := make([]any, 0, len(rows)*columns)
+	for n, row := range rows {
+		if n > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for c := 1; c <= columns; c++ {
+			if c > 1 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", n*columns+c)
+		}
+		query.WriteString(")")
+
+		u := urls[row.index]
+		args = append(args, row.id, u.LongURL, u.OriginalURL, row.shortCode, u.Owner, u.ShortCode != "", u.Canonical, u.ExpiresAt, u.Interstitial, redirectType(u), u.Title, u.Description, tags(u), u.PasswordHash, u.MaxClicks, u.MaxClicks)
+	}
+	query.WriteString(" ON CONFLICT DO NOTHING RETURNING id")
+
+	rs, err := pgRepo.db.Conn.QueryContext(ctx, query.String(), args...)
 	if err != nil {
-		return 0, fmt.Errorf("failed to insert long URL: %w", err)
+		return nil, fmt.Errorf("failed to insert long URLs: %w", err)
+	}
+	defer rs.Close()
+
+	inserted := make(map[int64]bool, len(rows))
+	for rs.Next() {
+		var id int64
+		if err := rs.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan inserted url: %w", err)
+		}
+		inserted[id] = true
+	}
+	if err := rs.Err(); err != nil {
+		return nil, fmt.Errorf("failed to insert long URLs: %w", err)
+	}
+	return inserted, nil
+}
+
+// resolveSkipped settles rows skipped by insertRows: canonical rows whose
+// destination has a canonical code get that code, and custom aliases that are
+// stored get ErrShortCodeTaken. It returns the indexes of the rows to retry.
+func (pgRepo *postgresRepository) resolveSkipped(ctx context.Context, urls []*URL, skipped []pendingRow, results []CreateResult) ([]int, error) {
+	var owners, longURLs, aliases []string
+	for _, row := range skipped {
+		u := urls[row.index]
+		switch {
+		case u.Canonical:
+			owners = append(owners, u.Owner)
+			longURLs = append(longURLs, u.LongURL)
+		case u.ShortCode != "":
+			aliases = append(aliases, u.ShortCode)
+		}
+	}
+
+	canonical := map[[2]string]string{}
+	if len(longURLs) > 0 {
+		query := `SELECT owner, long_url, short_code FROM urls
+			WHERE canonical AND (owner, long_url) IN (SELECT * FROM unnest($1::text[], $2::text[]))`
+		rs, err := pgRepo.db.Conn.QueryContext(ctx, query, pq.Array(owners), pq.Array(longURLs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch canonical urls: %w", err)
+		}
+		defer rs.Close()
+		for rs.Next() {
+			var owner, longURL, shortCode string
+			if err := rs.Scan(&owner, &longURL, &shortCode); err != nil {
+				return nil, fmt.Errorf("failed to scan canonical url: %w", err)
+			}
+			canonical[[2]string{owner, longURL}] = shortCode
+		}
+		if err := rs.Err(); err != nil {
+			return nil, fmt.Errorf("failed to fetch canonical urls: %w", err)
+		}
 	}
 
+	taken := map[string]bool{}
+	if len(aliases) > 0 {
+		query := "SELECT short_code FROM urls WHERE short_code = ANY($1)"
+		rs, err := pgRepo.db.Conn.QueryContext(ctx, query, pq.Array(aliases))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch short codes: %w", err)
+		}
+		defer rs.Close()
+		for rs.Next() {
+			var shortCode string
+			if err := rs.Scan(&shortCode); err != nil {
+				return nil, fmt.Errorf("failed to scan short code: %w", err)
+			}
+			taken[shortCode] = true
+		}
+		if err := rs.Err(); err != nil {
+			return nil, fmt.Errorf("failed to fetch short codes: %w", err)
+		}
+	}
+
+	var retry []int
+	for _, row := range skipped {
+		u := urls[row.index]
+		if shortCode, ok := canonical[[2]string{u.Owner, u.LongURL}]; ok && u.Canonical {
+			results[row.index] = CreateResult{ShortCode: shortCode}
+		} else if u.ShortCode != "" && taken[u.ShortCode] {
+			results[row.index] = CreateResult{Err: fmt.Errorf("short code %q: %w", u.ShortCode, ErrShortCodeTaken)}
+		} else {
+			retry = append(retry, row.index)
+		}
+	}
+	return retry, nil
+}
+
+// nextIDs allocates n fresh row IDs from the urls sequence.
+func (pgRepo *postgresRepository) nextIDs(ctx context.Context, n int) ([]int64, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	query := "SELECT nextval(pg_get_serial_sequence('urls', 'id')) FROM generate_series(1, $1)"
+
+	rs, err := pgRepo.db.Conn.QueryContext(ctx, query, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate url ids: %w", err)
+	}
+	defer rs.Close()
+
+	ids := make([]int64, 0, n)
+	for rs.Next() {
+		var id int64
+		if err := rs.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to allocate url ids: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rs.Err(); err != nil {
+		return nil, fmt.Errorf("failed to allocate url ids: %w", err)
+	}
+	return ids, nil
+}
+
+// nextID allocates a fresh row ID from the urls sequence.
+func (pgRepo *postgresRepository) nextID(ctx context.Context) (int64, error) {
+	var id int64
+	query := "SELECT nextval(pg_get_serial_sequence('urls', 'id'))"
+
+	if err := pgRepo.db.Conn.QueryRowContext(ctx, query).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to allocate url id: %w", err)
+	}
 	return id, nil
 }
 
-// GetURLByShortCode retrieves the original long URL for a given short code alias.
-func (pgRepo *postgresRepository) GetURLByShortCode(ctx context.Context, shortCode string) (string, error) {
-	var longURL string
-	query := "SELECT long_url FROM urls WHERE short_code = $1"
+// canonicalShortCode returns the code of the canonical row of owner for longURL.
+func (pgRepo *postgresRepository) canonicalShortCode(ctx context.Context, owner, longURL string) (string, error) {
+	var shortCode string
+	query := "SELECT short_code FROM urls WHERE owner = $1 AND long_url = $2 AND canonical"
+
+	err := pgRepo.db.Conn.QueryRowContext(ctx, query, owner, longURL).Scan(&shortCode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to fetch canonical url: %w", err)
+	}
+	return shortCode, nil
+}
+
+// urlColumns are the columns scanURL reads, in order.
+const urlColumns = `id, long_url, original_url, short_code, owner, canonical, created_at, expires_at, disabled_at, deleted_at,
+	interstitial, redirect_type, title, description, tags, password_hash, max_clicks, clicks_remaining,
+	page_title, page_og_title, page_og_image, page_status, page_fetched_at,
+	last_checked_at, last_status, consecutive_failures`
 
-	err := pgRepo.db.Conn.QueryRowContext(ctx, query, shortCode).Scan(&longURL)
+// scanURL reads a record selected with urlColumns.
+func scanURL(row interface{ Scan(dest ...any) error }) (*URL, error) {
+	u := &URL{}
+	var expiresAt, disabledAt, deletedAt, fetchedAt, checkedAt sql.NullTime
+	err := row.Scan(&u.ID, &u.LongURL, &u.OriginalURL, &u.ShortCode, &u.Owner, &u.Canonical, &u.CreatedAt, &expiresAt, &disabledAt, &deletedAt,
+		&u.Interstitial, &u.RedirectType, &u.Title, &u.Description, pq.Array(&u.Tags), &u.PasswordHash, &u.MaxClicks, &u.ClicksRemaining,
+		&u.Page.Title, &u.Page.OGTitle, &u.Page.OGImage, &u.Page.StatusCode, &fetchedAt,
+		&checkedAt, &u.Health.LastStatus, &u.Health.ConsecutiveFailures)
+	if err != nil {
+		return nil, err
+	}
+	u.ExpiresAt = nullTimePtr(expiresAt)
+	u.DisabledAt = nullTimePtr(disabledAt)
+	u.DeletedAt = nullTimePtr(deletedAt)
+	u.Page.FetchedAt = nullTimePtr(fetchedAt)
+	u.Health.LastCheckedAt = nullTimePtr(checkedAt)
+	return u, nil
+}
+
+// GetURLByShortCode retrieves the URL record for a given short code alias.
+func (pgRepo *postgresRepository) GetURLByShortCode(ctx context.Context, shortCode string) (*URL, error) {
+	query := "SELECT " + urlColumns + " FROM urls WHERE short_code = $1"
+
+	u, err := scanURL(pgRepo.db.Conn.QueryRowContext(ctx, query, shortCode))
 	if err != nil {
 		// sql.ErrNoRows means the query was valid but no matching record exists.
 		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+			return nil, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
 		}
-		return "", fmt.Errorf("failed to retrieve URL: %w", err)
+		return nil, fmt.Errorf("failed to retrieve URL: %w", err)
+	}
+	if u.Rules, err = pgRepo.rules(ctx, shortCode); err != nil {
+		return nil, err
 	}
-	return longURL, nil
+	if u.Variants, err = pgRepo.variants(ctx, shortCode); err != nil {
+		return nil, err
+	}
+	return u, nil
 }
 
-// SetShortCode updates an existing URL record with its calculated short code.
-func (pgRepo *postgresRepository) SetShortCode(ctx context.Context, id int64, shortCode string) error {
-	query := "UPDATE urls SET short_code = $1 WHERE id = $2"
+// rules returns the routing rules of shortCode in order.
+func (pgRepo *postgresRepository) rules(ctx context.Context, shortCode string) ([]Rule, error) {
+	query := "SELECT platforms, languages, countries, long_url FROM url_rules WHERE short_code = $1 ORDER BY position"
+	rows, err := pgRepo.db.Conn.QueryContext(ctx, query, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve URL rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var r Rule
+		if err := rows.Scan(pq.Array(&r.Platforms), pq.Array(&r.Languages), pq.Array(&r.Countries), &r.LongURL); err != nil {
+			return nil, fmt.Errorf("failed to scan URL rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve URL rules: %w", err)
+	}
+	return rules, nil
+}
 
-	// ExecContext is for statements that modify data but don't return rows.
-	results, err := pgRepo.db.Conn.ExecContext(ctx, query, shortCode, id)
+// variants returns the A/B variants of shortCode in order.
+func (pgRepo *postgresRepository) variants(ctx context.Context, shortCode string) ([]Variant, error) {
+	query := "SELECT name, long_url, weight FROM url_variants WHERE short_code = $1 ORDER BY position"
+	rows, err := pgRepo.db.Conn.QueryContext(ctx, query, shortCode)
 	if err != nil {
-		return fmt.Errorf("failed to update short code: %w", err)
+		return nil, fmt.Errorf("failed to retrieve URL variants: %w", err)
 	}
+	defer rows.Close()
 
-	// Verify that the update actually happened (ID might not exist).
-	rows, _ := results.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("no URL found with ID %d", id)
+	var variants []Variant
+	for rows.Next() {
+		var v Variant
+		if err := rows.Scan(&v.Name, &v.LongURL, &v.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan URL variant: %w", err)
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve URL variants: %w", err)
+	}
+	return variants, nil
+}
+
+// destinationHost extracts the lower-cased host from long_url, skipping the
+// scheme and any user info.
+const destinationHost = `lower(substring(long_url from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^/?#@]*@)?([^/:?#]+)'))`
+
+// ListURLs returns the records matching filter. Tags are matched with the
+// containment operator, which the idx_urls_tags GIN index supports. Pages are
+// fetched by id, so records created while paging never shift later pages.
+func (pgRepo *postgresRepository) ListURLs(ctx context.Context, filter ListFilter) ([]*URL, error) {
+	conds := []string{"deleted_at IS NULL"}
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if !filter.AllOwners {
+		conds = append(conds, "owner = "+arg(filter.Owner))
+	}
+	if filter.Tag != "" {
+		conds = append(conds, "tags @> "+arg(pq.Array([]string{filter.Tag})))
+	}
+	if filter.CreatedAfter != nil {
+		conds = append(conds, "created_at > "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+arg(*filter.CreatedBefore))
+	}
+	if filter.Domain != "" {
+		domain := arg(strings.ToLower(filter.Domain))
+		conds = append(conds, fmt.Sprintf("(%[1]s = %[2]s OR right(%[1]s, length(%[2]s) + 1) = '.' || %[2]s)", destinationHost, domain))
+	}
+	if filter.Search != "" {
+		conds = append(conds, "strpos(lower(long_url), lower("+arg(filter.Search)+")) > 0")
+	}
+	if filter.BeforeID > 0 {
+		conds = append(conds, "id < "+arg(filter.BeforeID))
+	}
+	query := "SELECT " + urlColumns + " FROM urls WHERE " + strings.Join(conds, " AND ") + " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := pgRepo.db.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list URLs: %w", err)
+	}
+	defer rows.Close()
+
+	urls := []*URL{}
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan URL: %w", err)
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list URLs: %w", err)
+	}
+	return urls, nil
+}
+
+// DeleteURL marks the record for shortCode as deleted. The row is kept so the
+// code is never issued again, but it stops being canonical so a later shorten
+// request for the same destination gets a working link.
+func (pgRepo *postgresRepository) DeleteURL(ctx context.Context, shortCode string) error {
+	query := `UPDATE urls SET deleted_at = now(), canonical = FALSE
+		WHERE short_code = $1 AND deleted_at IS NULL`
+
+	return pgRepo.updateOne(ctx, shortCode, query, shortCode)
+}
+
+// DisableURL disables or re-enables the record for shortCode. Disabled records
+// stop being canonical, and stay that way once re-enabled. Re-enabling gives
+// the destination a fresh start with the health checker.
+func (pgRepo *postgresRepository) DisableURL(ctx context.Context, shortCode string, disabled bool) error {
+	query := `UPDATE urls
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, now()) END,
+			canonical = canonical AND NOT $2,
+			consecutive_failures = CASE WHEN $2 THEN consecutive_failures ELSE 0 END
+		WHERE short_code = $1 AND deleted_at IS NULL`
+
+	return pgRepo.updateOne(ctx, shortCode, query, shortCode, disabled)
+}
+
+// UpdateLongURL changes the destination of shortCode and appends the change to
+// url_history in one transaction. The row is locked first so concurrent
+// updates are recorded in order with the right previous destination.
+func (pgRepo *postgresRepository) UpdateLongURL(ctx context.Context, shortCode, longURL, originalURL string) error {
+	return pgRepo.db.WithTx(ctx, func(tx *sql.Tx) error {
+		var oldLongURL string
+		query := "SELECT long_url FROM urls WHERE short_code = $1 AND deleted_at IS NULL FOR UPDATE"
+		if err := tx.QueryRowContext(ctx, query, shortCode).Scan(&oldLongURL); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+			}
+			return fmt.Errorf("failed to lock URL: %w", err)
+		}
+
+		query = "INSERT INTO url_history (short_code, old_long_url, new_long_url) VALUES ($1, $2, $3)"
+		if _, err := tx.ExecContext(ctx, query, shortCode, oldLongURL, longURL); err != nil {
+			return fmt.Errorf("failed to record URL change: %w", err)
+		}
+
+		query = `UPDATE urls SET long_url = $2, original_url = $3, canonical = FALSE,
+			page_title = '', page_og_title = '', page_og_image = '', page_status = 0, page_fetched_at = NULL,
+			last_checked_at = NULL, last_status = 0, consecutive_failures = 0
+			WHERE short_code = $1`
+		if _, err := tx.ExecContext(ctx, query, shortCode, longURL, originalURL); err != nil {
+			return fmt.Errorf("failed to update URL: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetURLHistory returns the destination changes of shortCode, newest first.
+func (pgRepo *postgresRepository) GetURLHistory(ctx context.Context, shortCode string) ([]URLChange, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM urls WHERE short_code = $1 AND deleted_at IS NULL)"
+	if err := pgRepo.db.Conn.QueryRowContext(ctx, query, shortCode).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to retrieve URL: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+	}
+
+	query = `SELECT old_long_url, new_long_url, changed_at FROM url_history
+		WHERE short_code = $1 ORDER BY changed_at DESC, id DESC`
+	rows, err := pgRepo.db.Conn.QueryContext(ctx, query, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve URL history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []URLChange{}
+	for rows.Next() {
+		var c URLChange
+		if err := rows.Scan(&c.OldLongURL, &c.NewLongURL, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan URL history: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve URL history: %w", err)
+	}
+	return changes, nil
+}
+
+// UpdateSettings applies the set fields of settings to the record for
+// shortCode in one statement. The record stops being canonical, so plain
+// shorten requests for the destination only get links with default settings.
+func (pgRepo *postgresRepository) UpdateSettings(ctx context.Context, shortCode string, settings LinkSettings) error {
+	sets := []string{"canonical = FALSE"}
+	args := []any{shortCode}
+	if settings.Interstitial != nil {
+		args = append(args, *settings.Interstitial)
+		sets = append(sets, fmt.Sprintf("interstitial = $%d", len(args)))
+	}
+	if settings.RedirectType != nil {
+		args = append(args, *settings.RedirectType)
+		sets = append(sets, fmt.Sprintf("redirect_type = $%d", len(args)))
+	}
+
+	query := "UPDATE urls SET " + strings.Join(sets, ", ") + " WHERE short_code = $1 AND deleted_at IS NULL"
+	return pgRepo.updateOne(ctx, shortCode, query, args...)
+}
+
+// SetRules replaces the rules of shortCode in one transaction. The record is
+// locked first, so concurrent calls apply one after the other and a deleted
+// record never gets new rules.
+func (pgRepo *postgresRepository) SetRules(ctx context.Context, shortCode string, rules []Rule) error {
+	return pgRepo.db.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := lockURL(ctx, tx, shortCode, len(rules) == 0); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM url_rules WHERE short_code = $1", shortCode); err != nil {
+			return fmt.Errorf("failed to remove URL rules: %w", err)
+		}
+		query := `INSERT INTO url_rules (short_code, position, platforms, languages, countries, long_url)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+		for i, r := range rules {
+			_, err := tx.ExecContext(ctx, query, shortCode, i, nonNil(r.Platforms), nonNil(r.Languages), nonNil(r.Countries), r.LongURL)
+			if err != nil {
+				return fmt.Errorf("failed to store URL rule: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// SetVariants deletes and reinserts the variants of shortCode like SetRules.
+func (pgRepo *postgresRepository) SetVariants(ctx context.Context, shortCode string, variants []Variant) error {
+	return pgRepo.db.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := lockURL(ctx, tx, shortCode, len(variants) == 0); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM url_variants WHERE short_code = $1", shortCode); err != nil {
+			return fmt.Errorf("failed to remove URL variants: %w", err)
+		}
+		query := `INSERT INTO url_variants (short_code, position, name, long_url, weight)
+			VALUES ($1, $2, $3, $4, $5)`
+		for i, v := range variants {
+			if _, err := tx.ExecContext(ctx, query, shortCode, i, v.Name, v.LongURL, v.Weight); err != nil {
+				return fmt.Errorf("failed to store URL variant: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// lockURL locks the live record for shortCode within tx before its rules or
+// variants are replaced, so concurrent replacements do not mix. The record
+// stays canonical only if it was and keepCanonical is set.
+func lockURL(ctx context.Context, tx *sql.Tx, shortCode string, keepCanonical bool) error {
+	query := `UPDATE urls SET canonical = canonical AND $2
+		WHERE short_code = $1 AND deleted_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, shortCode, keepCanonical)
+	if err != nil {
+		return fmt.Errorf("failed to lock URL: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to lock URL: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
 	}
 	return nil
 }
 
-// URLExists checks if a long URL has already been shortened to avoid duplicates.
-func (pgRepo *postgresRepository) URLExists(ctx context.Context, longURL string) (bool, int64, error) {
-	var id int64
-	query := "SELECT id FROM urls WHERE long_url = $1"
+// UpdatePageInfo stores page for shortCode. The destination is compared so
+// that a page fetched before a destination change is not stored for the new
+// destination.
+func (pgRepo *postgresRepository) UpdatePageInfo(ctx context.Context, shortCode, longURL string, page PageInfo) error {
+	query := `UPDATE urls
+		SET page_title = $3, page_og_title = $4, page_og_image = $5, page_status = $6, page_fetched_at = $7
+		WHERE short_code = $1 AND long_url = $2 AND deleted_at IS NULL`
+
+	fetchedAt := time.Now()
+	if page.FetchedAt != nil {
+		fetchedAt = *page.FetchedAt
+	}
+	return pgRepo.updateOne(ctx, shortCode, query, shortCode, longURL, page.Title, page.OGTitle, page.OGImage, page.StatusCode, fetchedAt)
+}
+
+// ClaimURLsForCheck selects and marks the records to check in one statement.
+// Rows claimed by a concurrent caller are skipped rather than waited for, so
+// several url-service instances can run health checks side by side.
+func (pgRepo *postgresRepository) ClaimURLsForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*URL, error) {
+	query := `UPDATE urls SET last_checked_at = now()
+		WHERE id IN (
+			SELECT id FROM urls
+			WHERE deleted_at IS NULL AND disabled_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+				AND (last_checked_at IS NULL OR last_checked_at < $1)
+			ORDER BY last_checked_at NULLS FIRST, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + urlColumns
+
+	rows, err := pgRepo.db.Conn.QueryContext(ctx, query, checkedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim URLs for check: %w", err)
+	}
+	defer rows.Close()
+
+	urls := []*URL{}
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan URL: %w", err)
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim URLs for check: %w", err)
+	}
+	return urls, nil
+}
+
+// RecordHealthCheck stores check for shortCode and disables the record if it
+// has failed too often, in one statement, so the count of failures cannot
+// race with a concurrent check. A record disabled this way stops being
+// canonical like one disabled by its owner.
+func (pgRepo *postgresRepository) RecordHealthCheck(ctx context.Context, shortCode, longURL string, check HealthCheck) (bool, error) {
+	query := `UPDATE urls
+		SET last_checked_at = now(), last_status = $3,
+			consecutive_failures = CASE WHEN $4 THEN 0 ELSE consecutive_failures + 1 END,
+			disabled_at = CASE WHEN NOT $4 AND $5 > 0 AND consecutive_failures + 1 >= $5
+				THEN COALESCE(disabled_at, now()) ELSE disabled_at END,
+			canonical = canonical AND ($4 OR $5 <= 0 OR consecutive_failures + 1 < $5)
+		WHERE short_code = $1 AND long_url = $2 AND deleted_at IS NULL
+		RETURNING disabled_at IS NOT NULL`
 
-	err := pgRepo.db.Conn.QueryRowContext(ctx, query, longURL).Scan(&id)
+	var disabled bool
+	err := pgRepo.db.Conn.QueryRowContext(ctx, query, shortCode, longURL, check.Status, check.Healthy, check.DisableAfter).Scan(&disabled)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// Return 0 as the ID when not found (standard Go idiom for missing values).
-			return false, 0, nil
+			return false, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
 		}
-		return false, 0, fmt.Errorf("failed to check url existence: %w", err)
+		return false, fmt.Errorf("failed to record health check: %w", err)
+	}
+	return disabled, nil
+}
+
+// ConsumeClick decrements the clicks left for shortCode with a conditional
+// UPDATE. The row lock it takes makes concurrent resolves queue up, and each
+// one re-checks clicks_remaining > 0 after the previous one committed.
+func (pgRepo *postgresRepository) ConsumeClick(ctx context.Context, shortCode string) (int, error) {
+	query := `UPDATE urls SET clicks_remaining = clicks_remaining - 1
+		WHERE short_code = $1 AND deleted_at IS NULL AND max_clicks > 0 AND clicks_remaining > 0
+		RETURNING clicks_remaining`
+
+	var remaining int
+	err := pgRepo.db.Conn.QueryRowContext(ctx, query, shortCode).Scan(&remaining)
+	if err == nil {
+		return remaining, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to consume click: %w", err)
+	}
+
+	// Nothing was updated: tell a used up link from a missing one.
+	var exists bool
+	query = "SELECT EXISTS (SELECT 1 FROM urls WHERE short_code = $1 AND deleted_at IS NULL)"
+	if err := pgRepo.db.Conn.QueryRowContext(ctx, query, shortCode).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to retrieve URL: %w", err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+	}
+	return 0, fmt.Errorf("short code %q: %w", shortCode, ErrNoClicksLeft)
+}
+
+// GetPasswordLock reads the lockout state of shortCode.
+func (pgRepo *postgresRepository) GetPasswordLock(ctx context.Context, shortCode string) (PasswordLock, error) {
+	query := "SELECT password_failures, password_locked_until FROM urls WHERE short_code = $1 AND deleted_at IS NULL"
+	return pgRepo.passwordLock(ctx, shortCode, query, shortCode)
+}
+
+// RecordPasswordFailure counts a wrong password for shortCode and locks it
+// once maxFailures are reached, in one statement that also skips locked
+// records, so concurrent guesses cannot slip past the limit.
+func (pgRepo *postgresRepository) RecordPasswordFailure(ctx context.Context, shortCode string, maxFailures int, lockFor time.Duration) (PasswordLock, error) {
+	query := `UPDATE urls
+		SET password_failures = CASE WHEN password_failures + 1 >= $2 THEN 0 ELSE password_failures + 1 END,
+			password_locked_until = CASE WHEN password_failures + 1 >= $2
+				THEN now() + make_interval(secs => $3) ELSE password_locked_until END
+		WHERE short_code = $1 AND deleted_at IS NULL
+			AND (password_locked_until IS NULL OR password_locked_until <= now())
+		RETURNING password_failures, password_locked_until`
+
+	lock, err := pgRepo.passwordLock(ctx, shortCode, query, shortCode, maxFailures, lockFor.Seconds())
+	if !errors.Is(err, sql.ErrNoRows) {
+		return lock, err
 	}
-	return true, id, nil
+
+	// Nothing was updated: tell a locked link from a missing one.
+	if lock, err = pgRepo.GetPasswordLock(ctx, shortCode); err != nil {
+		return lock, err
+	}
+	return lock, fmt.Errorf("short code %q: %w", shortCode, ErrPasswordLocked)
+}
+
+// ResetPasswordFailures clears the count of wrong passwords for shortCode,
+// and its lockout.
+func (pgRepo *postgresRepository) ResetPasswordFailures(ctx context.Context, shortCode string) error {
+	query := "UPDATE urls SET password_failures = 0, password_locked_until = NULL WHERE short_code = $1 AND deleted_at IS NULL"
+	return pgRepo.updateOne(ctx, shortCode, query, shortCode)
+}
+
+// passwordLock runs a query returning the lockout state of shortCode.
+func (pgRepo *postgresRepository) passwordLock(ctx context.Context, shortCode, query string, args ...any) (PasswordLock, error) {
+	var lock PasswordLock
+	var lockedUntil sql.NullTime
+	if err := pgRepo.db.Conn.QueryRowContext(ctx, query, args...).Scan(&lock.Failures, &lockedUntil); err != nil {
+		if err == sql.ErrNoRows {
+			return lock, fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+		}
+		return lock, fmt.Errorf("failed to read password lockout: %w", err)
+	}
+	lock.LockedUntil = nullTimePtr(lockedUntil)
+	return lock, nil
+}
+
+// updateOne runs an UPDATE for shortCode and reports sql.ErrNoRows if it matched nothing.
+func (pgRepo *postgresRepository) updateOne(ctx context.Context, shortCode, query string, args ...any) error {
+	res, err := pgRepo.db.Conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update URL: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update URL: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("short code %q does not exist: %w", shortCode, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// redirectType returns the redirect status to store for u.
+func redirectType(u *URL) int {
+	if u.RedirectType == 0 {
+		return http.StatusFound
+	}
+	return u.RedirectType
+}
+
+// tags returns the tags to store for u. The column is NOT NULL, so records
+// without tags store an empty array.
+func tags(u *URL) pq.StringArray {
+	return nonNil(u.Tags)
+}
+
+// nonNil returns values as an array for a NOT NULL column, which stores nil
+// as an empty array.
+func nonNil(values []string) pq.StringArray {
+	if values == nil {
+		return pq.StringArray{}
+	}
+	return values
+}
+
+// nullTimePtr converts a nullable column into an optional time.
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique violation on the named constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
 }
 
 // NewPostgresRepository constructor for creating a new repository instance.
@@ -2,7 +2,11 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 	"zipit/pkg/config"
@@ -49,32 +53,814 @@ func TestPostgresRepo(t *testing.T) {
 		defer cancel()
 
 		longURL := "https://example.com/some-very-long-link"
+		encode := func(id int64) string { return fmt.Sprintf("c%d", id) }
 
-		// 1. Create entry
-		id, err := repo.CreateURL(ctx, longURL)
+		// 1. Create entry, the code is derived from the allocated id
+		code, err := repo.CreateURL(ctx, &URL{LongURL: longURL, OriginalURL: "https://Example.com/some-very-long-link", Canonical: true}, encode)
 		if err != nil {
 			t.Fatalf("Create failed: %v", err)
 		}
+		if code != "c1" {
+			t.Errorf("expected code c1, got %s", code)
+		}
+
+		// 2. Creating it again returns the canonical row instead of a new one
+		again, err := repo.CreateURL(ctx, &URL{LongURL: longURL, Canonical: true}, encode)
+		if err != nil || again != code {
+			t.Errorf("expected existing code %s, got %s, err=%v", code, again, err)
+		}
+		if n := countRows(t, db, longURL); n != 1 {
+			t.Errorf("expected 1 row for %s, got %d", longURL, n)
+		}
+
+		// 3. Resolve it back from short code to long URL
+		resURL, err := repo.GetURLByShortCode(ctx, code)
+		if err != nil || resURL.LongURL != longURL {
+			t.Fatalf("GetURLByShortCode failed: expected %s, got %+v, err=%v", longURL, resURL, err)
+		}
+		if resURL.ID != 1 || !resURL.Canonical || resURL.ExpiresAt != nil {
+			t.Errorf("GetURLByShortCode returned unexpected record %+v", resURL)
+		}
+		if resURL.OriginalURL != "https://Example.com/some-very-long-link" {
+			t.Errorf("expected the submitted spelling to be kept, got %q", resURL.OriginalURL)
+		}
+	})
+
+	// Sub-test for custom aliases and the ids they reserve
+	t.Run("Custom Alias", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		aliasURL := "https://example.com/launch"
+		encode := func(id int64) string { return fmt.Sprintf("c%d", id) }
+
+		// 1. Claim id 2 for an alias, as the service does for codes the shortener could generate
+		code, err := repo.CreateURL(ctx, &URL{ID: 2, LongURL: aliasURL, ShortCode: "launch"}, nil)
+		if err != nil || code != "launch" {
+			t.Fatalf("Create alias failed: code=%s, err=%v", code, err)
+		}
+
+		// 2. Generated rows skip the reserved id
+		first, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/a", Canonical: true}, encode)
+		if err != nil || first != "c1" {
+			t.Fatalf("expected first generated code c1, got %s, err=%v", first, err)
+		}
+		second, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/b", Canonical: true}, encode)
+		if err != nil || second != "c3" {
+			t.Fatalf("expected second generated code c3, got %s, err=%v", second, err)
+		}
+
+		// 3. The same alias cannot be taken twice
+		_, err = repo.CreateURL(ctx, &URL{LongURL: "https://example.com/other", ShortCode: "launch"}, nil)
+		if !errors.Is(err, ErrShortCodeTaken) {
+			t.Errorf("expected ErrShortCodeTaken, got %v", err)
+		}
+
+		// 4. Aliases are not reused for deduplication
+		generated, err := repo.CreateURL(ctx, &URL{LongURL: aliasURL, Canonical: true}, encode)
+		if err != nil || generated == "launch" {
+			t.Errorf("expected a generated code next to the alias, got %s, err=%v", generated, err)
+		}
+
+		// 5. The alias resolves like any other code
+		resURL, err := repo.GetURLByShortCode(ctx, "launch")
+		if err != nil || resURL.LongURL != aliasURL {
+			t.Errorf("GetURLByShortCode failed: expected %s, got %+v, err=%v", aliasURL, resURL, err)
+		}
+	})
+
+	// Sub-test for links with an expiry
+	t.Run("Expiring Link", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		longURL := "https://example.com/reset-password"
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		encode := func(id int64) string { return fmt.Sprintf("e%d", id) }
+
+		code, err := repo.CreateURL(ctx, &URL{LongURL: longURL, ExpiresAt: &expiresAt}, encode)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		// Expiring links are never handed out again for deduplication
+		other, err := repo.CreateURL(ctx, &URL{LongURL: longURL, ExpiresAt: &expiresAt}, encode)
+		if err != nil || other == code {
+			t.Errorf("expected a second expiring link, got %s, err=%v", other, err)
+		}
+
+		resURL, err := repo.GetURLByShortCode(ctx, code)
+		if err != nil {
+			t.Fatalf("GetURLByShortCode failed: %v", err)
+		}
+		if resURL.ExpiresAt == nil || !resURL.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("expected expires_at %v, got %v", expiresAt, resURL.ExpiresAt)
+		}
+	})
+
+	// Sub-test for generated codes that are already taken
+	t.Run("Code Collision", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/a", ShortCode: "same"}, nil); err != nil {
+			t.Fatalf("Create alias failed: %v", err)
+		}
+		same := func(id int64) string { return "same" }
+		_, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/b", Canonical: true}, same)
+		if !errors.Is(err, ErrShortCodeTaken) {
+			t.Errorf("expected ErrShortCodeTaken, got %v", err)
+		}
+
+		// Nothing is left behind by the failed insert
+		if n := countRows(t, db, "https://example.com/b"); n != 0 {
+			t.Errorf("expected no row for the failed insert, got %d", n)
+		}
+	})
+
+	// Sub-test for disabling and deleting links
+	t.Run("Disable and Delete", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		longURL := "https://example.com/takedown"
+		encode := func(id int64) string { return fmt.Sprintf("d%d", id) }
+
+		code, err := repo.CreateURL(ctx, &URL{LongURL: longURL, Canonical: true}, encode)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		// 1. Disabling keeps the row but hands out a new code for the destination
+		if err := repo.DisableURL(ctx, code, true); err != nil {
+			t.Fatalf("DisableURL failed: %v", err)
+		}
+		resURL, err := repo.GetURLByShortCode(ctx, code)
+		if err != nil || resURL.DisabledAt == nil || resURL.Canonical {
+			t.Errorf("expected a disabled, non-canonical record, got %+v, err=%v", resURL, err)
+		}
+		fresh, err := repo.CreateURL(ctx, &URL{LongURL: longURL, Canonical: true}, encode)
+		if err != nil || fresh == code {
+			t.Errorf("expected a new code for the destination, got %s, err=%v", fresh, err)
+		}
+
+		// 2. Re-enabling clears the flag
+		if err := repo.DisableURL(ctx, code, false); err != nil {
+			t.Fatalf("DisableURL(false) failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, code); resURL == nil || resURL.DisabledAt != nil {
+			t.Errorf("expected the record to be enabled, got %+v", resURL)
+		}
+
+		// 3. Deleting keeps the row and its code
+		if err := repo.DeleteURL(ctx, code); err != nil {
+			t.Fatalf("DeleteURL failed: %v", err)
+		}
+		resURL, err = repo.GetURLByShortCode(ctx, code)
+		if err != nil || resURL.DeletedAt == nil {
+			t.Errorf("expected a deleted record, got %+v, err=%v", resURL, err)
+		}
+		if _, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/reuse", ShortCode: code}, nil); !errors.Is(err, ErrShortCodeTaken) {
+			t.Errorf("expected the deleted code to stay taken, got %v", err)
+		}
+
+		// 4. Deleted and unknown codes cannot be changed
+		if err := repo.DeleteURL(ctx, code); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows deleting twice, got %v", err)
+		}
+		if err := repo.DisableURL(ctx, code, true); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows disabling a deleted link, got %v", err)
+		}
+		if err := repo.DisableURL(ctx, "unknown", true); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows for an unknown code, got %v", err)
+		}
+	})
 
-		// 2. Mock a short code and save it
-		code := "abc123"
-		err = repo.SetShortCode(ctx, id, code)
+	// Sub-test for link settings
+	t.Run("Settings", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		longURL := "https://example.com/flagged"
+		encode := func(id int64) string { return fmt.Sprintf("s%d", id) }
+
+		// 1. Settings given at creation are stored, and defaults filled in
+		code, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/warned", Interstitial: true, RedirectType: 308}, encode)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if resURL, err := repo.GetURLByShortCode(ctx, code); err != nil || !resURL.Interstitial || resURL.RedirectType != 308 {
+			t.Errorf("expected an interstitial record redirecting with 308, got %+v, err=%v", resURL, err)
+		}
+		results, err := repo.CreateURLs(ctx, []*URL{{LongURL: "https://example.com/default"}}, encode)
+		if err != nil || results[0].Err != nil {
+			t.Fatalf("CreateURLs failed: %v %v", err, results)
+		}
+		if resURL, err := repo.GetURLByShortCode(ctx, results[0].ShortCode); err != nil || resURL.RedirectType != 302 {
+			t.Errorf("expected the default redirect type 302, got %+v, err=%v", resURL, err)
+		}
+
+		// 2. Turning the interstitial on takes the record out of deduplication
+		code, err = repo.CreateURL(ctx, &URL{LongURL: longURL, Canonical: true}, encode)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		on, off := true, false
+		if err := repo.UpdateSettings(ctx, code, LinkSettings{Interstitial: &on}); err != nil {
+			t.Fatalf("UpdateSettings failed: %v", err)
+		}
+		resURL, err := repo.GetURLByShortCode(ctx, code)
+		if err != nil || !resURL.Interstitial || resURL.Canonical {
+			t.Errorf("expected an interstitial, non-canonical record, got %+v, err=%v", resURL, err)
+		}
+		if fresh, err := repo.CreateURL(ctx, &URL{LongURL: longURL, Canonical: true}, encode); err != nil || fresh == code {
+			t.Errorf("expected a new code for the destination, got %s, err=%v", fresh, err)
+		}
+
+		// 3. Unset fields are left alone
+		permanent := 301
+		if err := repo.UpdateSettings(ctx, code, LinkSettings{RedirectType: &permanent}); err != nil {
+			t.Fatalf("UpdateSettings(301) failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, code); resURL == nil || !resURL.Interstitial || resURL.RedirectType != 301 {
+			t.Errorf("expected the interstitial to stay on with redirect type 301, got %+v", resURL)
+		}
+		if err := repo.UpdateSettings(ctx, code, LinkSettings{Interstitial: &off}); err != nil {
+			t.Fatalf("UpdateSettings(off) failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, code); resURL == nil || resURL.Interstitial {
+			t.Errorf("expected the interstitial to be off, got %+v", resURL)
+		}
+
+		// 4. Unknown codes cannot be changed
+		if err := repo.UpdateSettings(ctx, "unknown", LinkSettings{Interstitial: &on}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows for an unknown code, got %v", err)
+		}
+	})
+
+	// Sub-test for changing the destination of a link
+	t.Run("Update Destination", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		encode := func(id int64) string { return fmt.Sprintf("q%d", id) }
+		code, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/q1", Canonical: true}, encode)
 		if err != nil {
-			t.Fatalf("SetShortCode failed: %v", err)
+			t.Fatalf("Create failed: %v", err)
 		}
 
-		// 3. Verify it's there via URLExists
-		exists, existingID, err := repo.URLExists(ctx, longURL)
-		if err != nil || !exists || existingID != id {
-			t.Errorf("URLExists failed: exists=%v, expectedID=%d, gotID=%d, err=%v", exists, id, existingID, err)
+		// 1. A new link has no history
+		changes, err := repo.GetURLHistory(ctx, code)
+		if err != nil || len(changes) != 0 {
+			t.Fatalf("expected empty history, got %+v, err=%v", changes, err)
 		}
 
-		// 4. Resolve it back from short code to long URL
+		// 2. Page info is stored for the current destination only
+		page := PageInfo{Title: "Q1", OGTitle: "Q1 on Example", OGImage: "https://example.com/q1.png", StatusCode: 200}
+		if err := repo.UpdatePageInfo(ctx, code, "https://example.com/other", page); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows for another destination, got %v", err)
+		}
+		if err := repo.UpdatePageInfo(ctx, code, "https://example.com/q1", page); err != nil {
+			t.Fatalf("UpdatePageInfo failed: %v", err)
+		}
 		resURL, err := repo.GetURLByShortCode(ctx, code)
-		if err != nil || resURL != longURL {
-			t.Errorf("GetURLByShortCode failed: expected %s, got %s, err=%v", longURL, resURL, err)
+		if err != nil || resURL.Page.Title != "Q1" || resURL.Page.OGTitle != "Q1 on Example" || resURL.Page.OGImage != "https://example.com/q1.png" ||
+			resURL.Page.StatusCode != 200 || resURL.Page.FetchedAt == nil {
+			t.Errorf("expected the page info to be stored, got %+v, err=%v", resURL, err)
+		}
+
+		// 3. Each update is recorded, newest first, and clears the page info
+		for _, next := range []string{"https://example.com/q2", "https://example.com/q3"} {
+			if err := repo.UpdateLongURL(ctx, code, next, next); err != nil {
+				t.Fatalf("UpdateLongURL failed: %v", err)
+			}
+		}
+		resURL, err = repo.GetURLByShortCode(ctx, code)
+		if err != nil || resURL.LongURL != "https://example.com/q3" || resURL.Canonical || resURL.Page != (PageInfo{}) {
+			t.Errorf("expected the updated, non-canonical record, got %+v, err=%v", resURL, err)
+		}
+		changes, err = repo.GetURLHistory(ctx, code)
+		if err != nil || len(changes) != 2 {
+			t.Fatalf("expected 2 changes, got %+v, err=%v", changes, err)
+		}
+		if changes[0].OldLongURL != "https://example.com/q2" || changes[0].NewLongURL != "https://example.com/q3" ||
+			changes[1].OldLongURL != "https://example.com/q1" || changes[1].NewLongURL != "https://example.com/q2" {
+			t.Errorf("unexpected history %+v", changes)
+		}
+
+		// 4. Shortening the original destination again gives a new code
+		fresh, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/q1", Canonical: true}, encode)
+		if err != nil || fresh == code {
+			t.Errorf("expected a new code for the old destination, got %s, err=%v", fresh, err)
+		}
+
+		// 5. Unknown codes
+		if err := repo.UpdateLongURL(ctx, "unknown", "https://example.com", "https://example.com"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows updating an unknown code, got %v", err)
+		}
+		if _, err := repo.GetURLHistory(ctx, "unknown"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows for the history of an unknown code, got %v", err)
+		}
+	})
+
+	// Sub-test for links of different owners
+	t.Run("Owners", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		longURL := "https://example.com/shared"
+		encode := func(id int64) string { return fmt.Sprintf("o%d", id) }
+
+		acme, err := repo.CreateURL(ctx, &URL{LongURL: longURL, Owner: "acme", Canonical: true}, encode)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		again, _ := repo.CreateURL(ctx, &URL{LongURL: longURL, Owner: "acme", Canonical: true}, encode)
+		other, _ := repo.CreateURL(ctx, &URL{LongURL: longURL, Owner: "globex", Canonical: true}, encode)
+		anonymous, _ := repo.CreateURL(ctx, &URL{LongURL: longURL, Canonical: true}, encode)
+
+		// Deduplication only happens within an owner
+		if again != acme || other == acme || anonymous == acme || anonymous == other {
+			t.Errorf("expected one code per owner, got acme=%s again=%s globex=%s anonymous=%s", acme, again, other, anonymous)
+		}
+		resURL, err := repo.GetURLByShortCode(ctx, other)
+		if err != nil || resURL.Owner != "globex" {
+			t.Errorf("expected a record owned by globex, got %+v, err=%v", resURL, err)
+		}
+	})
+
+	// Sub-test for link metadata and searching by tag
+	t.Run("Metadata", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		encode := func(id int64) string { return fmt.Sprintf("m%d", id) }
+		launch, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/launch", Owner: "acme", Title: "Launch", Description: "Launch post", Tags: []string{"q3-campaign", "blog"}}, encode)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		resURL, err := repo.GetURLByShortCode(ctx, launch)
+		if err != nil || resURL.Title != "Launch" || resURL.Description != "Launch post" || len(resURL.Tags) != 2 || resURL.Tags[0] != "q3-campaign" {
+			t.Errorf("expected the metadata to be stored, got %+v, err=%v", resURL, err)
+		}
+
+		results, err := repo.CreateURLs(ctx, []*URL{
+			{LongURL: "https://example.com/ad", Owner: "acme", Tags: []string{"q3-campaign"}},
+			{LongURL: "https://example.com/other", Owner: "acme"},
+			{LongURL: "https://example.com/theirs", Owner: "globex", Tags: []string{"q3-campaign"}},
+			{LongURL: "https://example.com/gone", Owner: "acme", Tags: []string{"q3-campaign"}},
+		}, encode)
+		if err != nil {
+			t.Fatalf("CreateURLs failed: %v", err)
+		}
+		if err := repo.DeleteURL(ctx, results[3].ShortCode); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		// Only live links of the owner with the tag are listed, newest first
+		urls, err := repo.ListURLs(ctx, ListFilter{Owner: "acme", Tag: "q3-campaign"})
+		if err != nil {
+			t.Fatalf("ListURLs failed: %v", err)
+		}
+		if len(urls) != 2 || urls[0].ShortCode != results[0].ShortCode || urls[1].ShortCode != launch {
+			t.Errorf("expected %s and %s, got %+v", results[0].ShortCode, launch, urls)
+		}
+		if urls, _ := repo.ListURLs(ctx, ListFilter{Owner: "acme", Limit: 1}); len(urls) != 1 || urls[0].ShortCode != results[1].ShortCode {
+			t.Errorf("expected only the newest link %s, got %+v", results[1].ShortCode, urls)
+		}
+		if urls, err := repo.ListURLs(ctx, ListFilter{Owner: "acme", Tag: "unknown"}); err != nil || len(urls) != 0 {
+			t.Errorf("expected no links, got %+v, err=%v", urls, err)
 		}
 	})
+
+	// Sub-test for listing links with filters and pages
+	t.Run("List", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		encode := func(id int64) string { return fmt.Sprintf("l%d", id) }
+		results, err := repo.CreateURLs(ctx, []*URL{
+			{LongURL: "https://example.com/docs/Setup", Owner: "acme"},
+			{LongURL: "https://blog.example.com/launch", Owner: "acme"},
+			{LongURL: "https://user@notexample.com/docs", Owner: "acme"},
+			{LongURL: "https://example.org/docs", Owner: "globex"},
+		}, encode)
+		if err != nil {
+			t.Fatalf("CreateURLs failed: %v", err)
+		}
+		codes := func(urls []*URL) string {
+			var s []string
+			for _, u := range urls {
+				s = append(s, u.ShortCode)
+			}
+			return fmt.Sprint(s)
+		}
+		code := func(i int) string { return results[i].ShortCode }
+		future, past := time.Now().Add(time.Hour), time.Now().Add(-time.Hour)
+
+		tests := []struct {
+			name   string
+			filter ListFilter
+			want   []string
+		}{
+			{name: "Owner", filter: ListFilter{Owner: "acme"}, want: []string{code(2), code(1), code(0)}},
+			{name: "All Owners", filter: ListFilter{AllOwners: true, Limit: 2}, want: []string{code(3), code(2)}},
+			{name: "Domain", filter: ListFilter{Owner: "acme", Domain: "Example.com"}, want: []string{code(1), code(0)}},
+			{name: "Search", filter: ListFilter{AllOwners: true, Search: "DOCS/"}, want: []string{code(0)}},
+			{name: "Created Before", filter: ListFilter{Owner: "acme", CreatedBefore: &past}, want: nil},
+			{name: "Created Between", filter: ListFilter{Owner: "acme", CreatedAfter: &past, CreatedBefore: &future}, want: []string{code(2), code(1), code(0)}},
+			{name: "Next Page", filter: ListFilter{Owner: "acme", BeforeID: 2, Limit: 2}, want: []string{code(0)}},
+		}
+		for _, tt := range tests {
+			urls, err := repo.ListURLs(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: ListURLs failed: %v", tt.name, err)
+			}
+			if codes(urls) != fmt.Sprint(tt.want) {
+				t.Errorf("%s: expected %v, got %s", tt.name, tt.want, codes(urls))
+			}
+		}
+	})
+
+	// Sub-test for destination health checks
+	t.Run("Health", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		encode := func(id int64) string { return fmt.Sprintf("h%d", id) }
+		past := time.Now().Add(-time.Hour)
+		results, err := repo.CreateURLs(ctx, []*URL{
+			{LongURL: "https://example.com/h1"},
+			{LongURL: "https://example.com/h2"},
+			{LongURL: "https://example.com/expired", ExpiresAt: &past},
+			{LongURL: "https://example.com/gone"},
+		}, encode)
+		if err != nil {
+			t.Fatalf("CreateURLs failed: %v", err)
+		}
+		first, second := results[0].ShortCode, results[1].ShortCode
+		if err := repo.DeleteURL(ctx, results[3].ShortCode); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		// 1. Live links are claimed once until they are due again
+		claimed, err := repo.ClaimURLsForCheck(ctx, past, 10)
+		if err != nil || len(claimed) != 2 {
+			t.Fatalf("expected 2 claimed links, got %+v, err=%v", claimed, err)
+		}
+		if claimed, err := repo.ClaimURLsForCheck(ctx, past, 10); err != nil || len(claimed) != 0 {
+			t.Errorf("expected no links due, got %+v, err=%v", claimed, err)
+		}
+
+		// 2. Failed checks are counted, and the link is disabled after DisableAfter
+		check := HealthCheck{Status: 404, DisableAfter: 2}
+		if disabled, err := repo.RecordHealthCheck(ctx, first, "https://example.com/h1", check); err != nil || disabled {
+			t.Fatalf("expected the first failure to keep the link, got disabled=%v, err=%v", disabled, err)
+		}
+		resURL, err := repo.GetURLByShortCode(ctx, first)
+		if err != nil || resURL.Health.LastStatus != 404 || resURL.Health.ConsecutiveFailures != 1 || resURL.Health.LastCheckedAt == nil {
+			t.Errorf("expected one recorded failure, got %+v, err=%v", resURL, err)
+		}
+		if disabled, err := repo.RecordHealthCheck(ctx, first, "https://example.com/h1", check); err != nil || !disabled {
+			t.Fatalf("expected the second failure to disable the link, got disabled=%v, err=%v", disabled, err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, first); resURL.DisabledAt == nil || resURL.Health.ConsecutiveFailures != 2 {
+			t.Errorf("expected a disabled link, got %+v", resURL)
+		}
+
+		// 3. A healthy check resets the count, and so does re-enabling
+		_, _ = repo.RecordHealthCheck(ctx, second, "https://example.com/h2", HealthCheck{Status: 503})
+		if _, err := repo.RecordHealthCheck(ctx, second, "https://example.com/h2", HealthCheck{Status: 200, Healthy: true}); err != nil {
+			t.Fatalf("RecordHealthCheck failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, second); resURL.Health.LastStatus != 200 || resURL.Health.ConsecutiveFailures != 0 {
+			t.Errorf("expected a healthy link, got %+v", resURL.Health)
+		}
+		if err := repo.DisableURL(ctx, first, false); err != nil {
+			t.Fatalf("DisableURL failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, first); resURL.Health.ConsecutiveFailures != 0 {
+			t.Errorf("expected re-enabling to reset the failures, got %+v", resURL.Health)
+		}
+
+		// 4. Checks of a previous destination are dropped
+		if err := repo.UpdateLongURL(ctx, second, "https://example.com/h2b", "https://example.com/h2b"); err != nil {
+			t.Fatalf("UpdateLongURL failed: %v", err)
+		}
+		if _, err := repo.RecordHealthCheck(ctx, second, "https://example.com/h2", check); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows for the old destination, got %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, second); resURL.Health != (HealthStatus{}) {
+			t.Errorf("expected the new destination to be unchecked, got %+v", resURL.Health)
+		}
+
+		// 5. Unchecked links come first once links are due again
+		claimed, err = repo.ClaimURLsForCheck(ctx, time.Now().Add(time.Minute), 1)
+		if err != nil || len(claimed) != 1 || claimed[0].ShortCode != second {
+			t.Errorf("expected %s to be claimed first, got %+v, err=%v", second, claimed, err)
+		}
+	})
+
+	t.Run("Password", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shortCode, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/secret", PasswordHash: "$2a$10$hash"}, func(id int64) string { return fmt.Sprintf("p%d", id) })
+		if err != nil {
+			t.Fatalf("CreateURL failed: %v", err)
+		}
+		if resURL, err := repo.GetURLByShortCode(ctx, shortCode); err != nil || resURL.PasswordHash != "$2a$10$hash" {
+			t.Fatalf("expected the password hash to be stored, got %+v, err=%v", resURL, err)
+		}
+
+		// 1. Failures are counted until the record locks, then counted afresh
+		for i := 1; i < 3; i++ {
+			lock, err := repo.RecordPasswordFailure(ctx, shortCode, 3, time.Minute)
+			if err != nil || lock.Failures != i || lock.LockedUntil != nil {
+				t.Fatalf("failure %d: expected no lockout, got %+v, err=%v", i, lock, err)
+			}
+		}
+		lock, err := repo.RecordPasswordFailure(ctx, shortCode, 3, time.Minute)
+		if err != nil || lock.Failures != 0 || lock.LockedUntil == nil || !lock.LockedUntil.After(time.Now()) {
+			t.Fatalf("expected the third failure to lock the link, got %+v, err=%v", lock, err)
+		}
+		if lock, err := repo.GetPasswordLock(ctx, shortCode); err != nil || lock.LockedUntil == nil {
+			t.Errorf("expected the lockout to be stored, got %+v, err=%v", lock, err)
+		}
+		// Locked records count nothing more
+		if lock, err := repo.RecordPasswordFailure(ctx, shortCode, 3, time.Minute); !errors.Is(err, ErrPasswordLocked) || lock.LockedUntil == nil {
+			t.Errorf("expected ErrPasswordLocked with the lockout, got %+v, err=%v", lock, err)
+		}
+
+		// 2. A correct password clears the count and the lockout
+		if err := repo.ResetPasswordFailures(ctx, shortCode); err != nil {
+			t.Fatalf("ResetPasswordFailures failed: %v", err)
+		}
+		_, _ = repo.RecordPasswordFailure(ctx, shortCode, 3, time.Minute)
+		if err := repo.ResetPasswordFailures(ctx, shortCode); err != nil {
+			t.Fatalf("ResetPasswordFailures failed: %v", err)
+		}
+		if lock, _ := repo.GetPasswordLock(ctx, shortCode); lock.Failures != 0 || lock.LockedUntil != nil {
+			t.Errorf("expected no failures or lockout after a reset, got %+v", lock)
+		}
+
+		// 3. Unknown codes
+		if _, err := repo.GetPasswordLock(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+		if _, err := repo.RecordPasswordFailure(ctx, "missing", 3, time.Minute); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("Click Limit", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		encode := func(id int64) string { return fmt.Sprintf("m%d", id) }
+		limited, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/invite", MaxClicks: 5}, encode)
+		if err != nil {
+			t.Fatalf("CreateURL failed: %v", err)
+		}
+		if resURL, err := repo.GetURLByShortCode(ctx, limited); err != nil || resURL.MaxClicks != 5 || resURL.ClicksRemaining != 5 {
+			t.Fatalf("expected 5 of 5 clicks left, got %+v, err=%v", resURL, err)
+		}
+
+		// 1. Concurrent resolves use exactly MaxClicks clicks between them
+		const resolves = 50
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		succeeded, exhausted := 0, 0
+		for i := 0; i < resolves; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.ConsumeClick(ctx, limited)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					succeeded++
+				case errors.Is(err, ErrNoClicksLeft):
+					exhausted++
+				default:
+					t.Errorf("ConsumeClick failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if succeeded != 5 || exhausted != resolves-5 {
+			t.Errorf("expected 5 clicks and %d refusals, got %d and %d", resolves-5, succeeded, exhausted)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, limited); resURL.ClicksRemaining != 0 {
+			t.Errorf("expected no clicks left, got %d", resURL.ClicksRemaining)
+		}
+
+		// 2. Links without a limit have no clicks to consume, and unknown codes do not exist
+		open, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/open"}, encode)
+		if err != nil {
+			t.Fatalf("CreateURL failed: %v", err)
+		}
+		if _, err := repo.ConsumeClick(ctx, open); !errors.Is(err, ErrNoClicksLeft) {
+			t.Errorf("expected ErrNoClicksLeft without a limit, got %v", err)
+		}
+		if _, err := repo.ConsumeClick(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shortCode, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/app", Canonical: true}, func(id int64) string { return fmt.Sprintf("r%d", id) })
+		if err != nil {
+			t.Fatalf("CreateURL failed: %v", err)
+		}
+		if resURL, err := repo.GetURLByShortCode(ctx, shortCode); err != nil || len(resURL.Rules) != 0 {
+			t.Fatalf("expected no rules, got %+v, err=%v", resURL, err)
+		}
+
+		// 1. Rules are stored in order and take the record out of deduplication
+		rules := []Rule{
+			{Platforms: []string{"ios"}, LongURL: "https://apps.apple.com/app/id1"},
+			{Platforms: []string{"android"}, Countries: []string{"DE", "AT"}, LongURL: "https://play.google.com/store/apps/details?id=de"},
+			{Languages: []string{"fr"}, LongURL: "https://example.com/fr/app"},
+		}
+		if err := repo.SetRules(ctx, shortCode, rules); err != nil {
+			t.Fatalf("SetRules failed: %v", err)
+		}
+		resURL, err := repo.GetURLByShortCode(ctx, shortCode)
+		if err != nil {
+			t.Fatalf("GetURLByShortCode failed: %v", err)
+		}
+		if len(resURL.Rules) != 3 || resURL.Rules[1].LongURL != rules[1].LongURL || len(resURL.Rules[1].Countries) != 2 || len(resURL.Rules[1].Languages) != 0 {
+			t.Errorf("expected the rules back in order, got %+v", resURL.Rules)
+		}
+		if resURL.Canonical {
+			t.Error("expected a record with rules to stop being canonical")
+		}
+
+		// 2. Setting rules replaces them, and no rules removes them
+		if err := repo.SetRules(ctx, shortCode, rules[2:]); err != nil {
+			t.Fatalf("SetRules failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, shortCode); len(resURL.Rules) != 1 || resURL.Rules[0].LongURL != rules[2].LongURL {
+			t.Errorf("expected the rules to be replaced, got %+v", resURL.Rules)
+		}
+		if err := repo.SetRules(ctx, shortCode, nil); err != nil {
+			t.Fatalf("SetRules failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, shortCode); len(resURL.Rules) != 0 {
+			t.Errorf("expected the rules to be removed, got %+v", resURL.Rules)
+		}
+
+		// 3. Unknown and deleted codes
+		if err := repo.SetRules(ctx, "missing", rules); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+		if err := repo.DeleteURL(ctx, shortCode); err != nil {
+			t.Fatalf("DeleteURL failed: %v", err)
+		}
+		if err := repo.SetRules(ctx, shortCode, rules); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows for a deleted record, got %v", err)
+		}
+	})
+
+	t.Run("Variants", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shortCode, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/landing", Canonical: true}, func(id int64) string { return fmt.Sprintf("v%d", id) })
+		if err != nil {
+			t.Fatalf("CreateURL failed: %v", err)
+		}
+
+		// 1. Variants are stored in order and take the record out of deduplication
+		variants := []Variant{
+			{Name: "A", LongURL: "https://example.com/landing-a", Weight: 70},
+			{Name: "B", LongURL: "https://example.com/landing-b", Weight: 30},
+		}
+		if err := repo.SetVariants(ctx, shortCode, variants); err != nil {
+			t.Fatalf("SetVariants failed: %v", err)
+		}
+		resURL, err := repo.GetURLByShortCode(ctx, shortCode)
+		if err != nil {
+			t.Fatalf("GetURLByShortCode failed: %v", err)
+		}
+		if len(resURL.Variants) != 2 || resURL.Variants[0] != variants[0] || resURL.Variants[1] != variants[1] {
+			t.Errorf("expected the variants back in order, got %+v", resURL.Variants)
+		}
+		if resURL.Canonical {
+			t.Error("expected a record with variants to stop being canonical")
+		}
+
+		// 2. No variants removes them
+		if err := repo.SetVariants(ctx, shortCode, nil); err != nil {
+			t.Fatalf("SetVariants failed: %v", err)
+		}
+		if resURL, _ := repo.GetURLByShortCode(ctx, shortCode); len(resURL.Variants) != 0 {
+			t.Errorf("expected the variants to be removed, got %+v", resURL.Variants)
+		}
+
+		// 3. Unknown codes
+		if err := repo.SetVariants(ctx, "missing", variants); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("Bulk Insert", func(t *testing.T) {
+		cleanup(t, db)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		encode := func(id int64) string { return fmt.Sprintf("b%d", id) }
+		existing, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/existing", Owner: "acme", Canonical: true}, encode)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, err := repo.CreateURL(ctx, &URL{LongURL: "https://example.com/taken", ShortCode: "taken"}, nil); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		urls := []*URL{
+			{LongURL: "https://example.com/1", Owner: "acme", Canonical: true},
+			{LongURL: "https://example.com/existing", Owner: "acme", Canonical: true},
+			{LongURL: "https://example.com/1", Owner: "acme", Canonical: true},
+			{LongURL: "https://example.com/alias", Owner: "acme", ShortCode: "promo"},
+			{LongURL: "https://example.com/alias", Owner: "acme", ShortCode: "taken"},
+			{LongURL: "https://example.com/alias", Owner: "acme", ShortCode: "promo"},
+		}
+		results, err := repo.CreateURLs(ctx, urls, encode)
+		if err != nil {
+			t.Fatalf("CreateURLs failed: %v", err)
+		}
+		if len(results) != len(urls) {
+			t.Fatalf("expected %d results, got %d", len(urls), len(results))
+		}
+
+		if results[0].Err != nil || results[2].ShortCode != results[0].ShortCode {
+			t.Errorf("expected duplicates in the batch to share a code, got %+v and %+v", results[0], results[2])
+		}
+		if results[1].ShortCode != existing {
+			t.Errorf("expected the existing canonical code %s, got %+v", existing, results[1])
+		}
+		if results[3].ShortCode != "promo" {
+			t.Errorf("expected alias promo, got %+v", results[3])
+		}
+		for _, i := range []int{4, 5} {
+			if !errors.Is(results[i].Err, ErrShortCodeTaken) {
+				t.Errorf("result %d: expected ErrShortCodeTaken, got %+v", i, results[i])
+			}
+		}
+		if n := countRows(t, db, "https://example.com/1"); n != 1 {
+			t.Errorf("expected 1 row for the duplicated url, got %d", n)
+		}
+
+		// Larger batches are split into several statements
+		many := make([]*URL, insertChunkSize+10)
+		for i := range many {
+			many[i] = &URL{LongURL: fmt.Sprintf("https://example.com/many/%d", i), Canonical: true}
+		}
+		results, err = repo.CreateURLs(ctx, many, encode)
+		if err != nil {
+			t.Fatalf("CreateURLs failed: %v", err)
+		}
+		codes := map[string]bool{}
+		for i, res := range results {
+			if res.Err != nil {
+				t.Fatalf("result %d: unexpected error %v", i, res.Err)
+			}
+			codes[res.ShortCode] = true
+		}
+		if len(codes) != len(many) {
+			t.Errorf("expected %d distinct codes, got %d", len(many), len(codes))
+		}
+	})
+
+}
+
+// countRows returns how many rows store longURL.
+func countRows(t *testing.T, db *database.Database, longURL string) int {
+	var n int
+	if err := db.Conn.QueryRow("SELECT COUNT(*) FROM urls WHERE long_url = $1", longURL).Scan(&n); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return n
 }
 
 // setupSchema ensures the database table exists.
@@ -84,8 +870,62 @@ func setupSchema(t *testing.T, db *database.Database) {
 	CREATE TABLE IF NOT EXISTS urls (
 		id BIGSERIAL PRIMARY KEY,
 		long_url TEXT NOT NULL,
-		short_code VARCHAR(12) UNIQUE,
+		short_code VARCHAR(12) UNIQUE NOT NULL,
 		created_at TIMESTAMP DEFAULT NOW()
+	);
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_custom BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS canonical BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS original_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_type SMALLINT NOT NULL DEFAULT 302;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_title TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_og_title TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_og_image TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_status INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_fetched_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_status INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_failures INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_locked_until TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks_remaining INTEGER NOT NULL DEFAULT 0;
+	DROP INDEX IF EXISTS idx_urls_canonical_long_url;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_canonical_owner_long_url ON urls(owner, long_url) WHERE canonical;
+	CREATE TABLE IF NOT EXISTS url_history (
+		id BIGSERIAL PRIMARY KEY,
+		short_code VARCHAR(12) NOT NULL,
+		old_long_url TEXT NOT NULL,
+		new_long_url TEXT NOT NULL,
+		changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS url_rules (
+		id BIGSERIAL PRIMARY KEY,
+		short_code VARCHAR(12) NOT NULL,
+		position INTEGER NOT NULL,
+		platforms TEXT[] NOT NULL DEFAULT '{}',
+		languages TEXT[] NOT NULL DEFAULT '{}',
+		countries TEXT[] NOT NULL DEFAULT '{}',
+		long_url TEXT NOT NULL,
+		CONSTRAINT url_rules_short_code_position_key UNIQUE (short_code, position)
+	);
+	CREATE TABLE IF NOT EXISTS url_variants (
+		id BIGSERIAL PRIMARY KEY,
+		short_code VARCHAR(12) NOT NULL,
+		position INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		long_url TEXT NOT NULL,
+		weight INTEGER NOT NULL CHECK (weight > 0),
+		CONSTRAINT url_variants_short_code_position_key UNIQUE (short_code, position),
+		CONSTRAINT url_variants_short_code_name_key UNIQUE (short_code, name)
 	);`
 
 	_, err := db.Conn.Exec(schema)
@@ -96,7 +936,7 @@ func setupSchema(t *testing.T, db *database.Database) {
 
 // cleanup clears the urls table and resets the auto-increment ID counter.
 func cleanup(t *testing.T, db *database.Database) {
-	_, err := db.Conn.Exec("TRUNCATE TABLE urls RESTART IDENTITY")
+	_, err := db.Conn.Exec("TRUNCATE TABLE urls, url_history, url_rules, url_variants RESTART IDENTITY")
 	if err != nil {
 		t.Fatalf("failed to cleanup database: %v", err)
 	}
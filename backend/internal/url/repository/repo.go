@@ -1,28 +1,277 @@
 package repository
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrShortCodeTaken is returned when a short code is already assigned to another URL.
+var ErrShortCodeTaken = errors.New("short code already in use")
+
+// ErrNoClicksLeft is returned by ConsumeClick when a record has used up its clicks.
+var ErrNoClicksLeft = errors.New("no clicks left")
+
+// ErrPasswordLocked is returned by RecordPasswordFailure while a record is
+// locked against password attempts.
+var ErrPasswordLocked = errors.New("password attempts locked")
+
+// URL is a stored short link. Page, Health, Rules and Variants are stored
+// separately, after the record is created.
+type URL struct {
+	// ID may be set to claim a specific row id when creating a record,
+	// otherwise the database assigns one.
+	ID int64
+	// LongURL is the normalized destination, used for redirects and
+	// deduplication, and OriginalURL the destination as the caller
+	// submitted it, kept for display.
+	LongURL     string
+	OriginalURL string
+	// ShortCode is only set when creating a record for a custom alias;
+	// generated codes are derived from the row id.
+	ShortCode string
+	// Owner is the API key owner that created the record, or "" for
+	// anonymous links.
+	Owner string
+	// Canonical records are shared by every plain shorten request of the
+	// same Owner for the same LongURL.
+	Canonical bool
+	CreatedAt time.Time
+	// ExpiresAt is nil if the link never expires.
+	ExpiresAt *time.Time
+	// DisabledAt and DeletedAt are set while the link is disabled or after
+	// it has been deleted.
+	DisabledAt *time.Time
+	DeletedAt  *time.Time
+	// Interstitial links show a warning page before redirecting.
+	Interstitial bool
+	// RedirectType is the HTTP status code visitors are redirected with; 0
+	// stores the default, 302.
+	RedirectType int
+	// Title, Description and Tags are optional details for the owner to find
+	// the link by.
+	Title       string
+	Description string
+	Tags        []string
+	// PasswordHash is the bcrypt hash of the password visitors must supply to
+	// follow the link, or "" if anyone may follow it.
+	PasswordHash string
+	// MaxClicks limits how often the link resolves, 0 meaning no limit.
+	MaxClicks int
+	// ClicksRemaining counts down from MaxClicks as the link is followed, and
+	// is only read, never written, when creating a record. It may be stale
+	// when read through a cache; ConsumeClick is what decides whether a
+	// click is left.
+	ClicksRemaining int
+	// Page describes the destination page and Health the periodic checks of
+	// the destination.
+	Page   PageInfo
+	Health HealthStatus
+	// Rules route some visitors elsewhere, in the order they are tried, and
+	// Variants split the remaining visitors between several destinations.
+	// Both are only loaded by GetURLByShortCode.
+	Rules    []Rule
+	Variants []Variant
+}
+
+// Rule sends the visitors of a record who match all of its conditions to
+// LongURL instead of the record's destination. Each condition lists the
+// values it accepts, and an empty one accepts every visitor: Platforms are
+// operating systems as named by the rules package, Languages lower-case
+// language tags, and Countries upper-case ISO 3166-1 alpha-2 codes.
+type Rule struct {
+	Platforms []string
+	Languages []string
+	Countries []string
+	LongURL   string
+}
+
+// Variant is one of the destinations an A/B split sends the visitors of a
+// record to. Each variant gets a share of visitors in proportion to its
+// Weight, and Name identifies it in click events.
+type Variant struct {
+	Name    string
+	LongURL string
+	Weight  int
+}
+
+// PageInfo is what was found when fetching the destination page of a record:
+// its <title>, og:title and og:image, and the status code of the final
+// response. FetchedAt is nil until the page has been fetched.
+type PageInfo struct {
+	Title      string
+	OGTitle    string
+	OGImage    string
+	StatusCode int
+	FetchedAt  *time.Time
+}
+
+// CreateResult is the outcome of storing one record with CreateURLs: its short
+// code, or ErrShortCodeTaken or another error if it could not be stored.
+type CreateResult struct {
+	ShortCode string
+	Err       error
+}
+
+// HealthStatus is the outcome of the periodic checks of a record's
+// destination. LastCheckedAt is nil until the destination has been checked.
+// LastStatus is the status code of the last check, or 0 if it failed without
+// a response. ConsecutiveFailures counts the checks that failed since the
+// last one that succeeded.
+type HealthStatus struct {
+	LastCheckedAt       *time.Time
+	LastStatus          int
+	ConsecutiveFailures int
+}
+
+// HealthCheck is the result of checking a record's destination. If
+// DisableAfter is positive, the record is disabled once that many checks in
+// a row have failed.
+type HealthCheck struct {
+	Status       int
+	Healthy      bool
+	DisableAfter int
+}
+
+// PasswordLock is the state of a password-protected record's lockout:
+// Failures counts the wrong passwords since the last lockout or correct
+// password, and LockedUntil, if set and in the future, is when the record
+// accepts passwords again.
+type PasswordLock struct {
+	Failures    int
+	LockedUntil *time.Time
+}
+
+// LinkSettings holds changes to the settings of a stored link. Nil fields are
+// left unchanged.
+type LinkSettings struct {
+	Interstitial *bool
+	RedirectType *int
+}
+
+// ListFilter selects the records ListURLs returns. Owner is matched unless
+// AllOwners is set, so "" lists anonymous links. Other empty fields match
+// every record.
+type ListFilter struct {
+	Owner     string
+	AllOwners bool
+	// Tag matches records carrying the tag.
+	Tag string
+	// CreatedAfter and CreatedBefore bound the creation time, exclusively.
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Domain matches destinations on the host or any of its subdomains.
+	Domain string
+	// Search matches destinations containing it, ignoring case.
+	Search string
+	// BeforeID only matches records with a lower id, for fetching the page
+	// after the one ending with that id; 0 starts with the newest record.
+	BeforeID int64
+	// Limit caps the number of records returned; 0 means no limit.
+	Limit int
+}
+
+// URLChange is a recorded change of a link's destination.
+type URLChange struct {
+	OldLongURL string
+	NewLongURL string
+	ChangedAt  time.Time
+}
 
 // URLRepository provides an abstraction for persisting and retrieving URL
 // records used by the URL-shortening service. Implementations handle storage
 // details (e.g., database access), transactional concerns, and concurrency.
 //
-// CreateURL creates a new record for the provided longURL and returns the
-// generated record ID, or an error if creation fails.
+// CreateURL atomically stores u and returns its short code. Records without a
+// ShortCode get encode(id) as their code. If u is canonical and a canonical
+// record for the same Owner and LongURL already exists, nothing is written and
+// the existing code is returned, so concurrent calls never create duplicates.
+// If the code is already in use, ErrShortCodeTaken is returned.
+//
+// CreateURLs stores many records the way CreateURL stores one, in bulk, and
+// returns one result per record in the same order. Records succeed or fail
+// independently; the returned error is only set if nothing could be stored.
+//
+// GetURLByShortCode returns the record associated with the given shortCode,
+// whether or not it has expired, been disabled or been deleted. If no record
+// is found, the implementation should return a non-nil error describing the
+// condition.
+//
+// UpdateLongURL points the record for shortCode at longURL, submitted as
+// originalURL, and records the change in its history, atomically. The record
+// stops being canonical and its PageInfo and HealthStatus are cleared. It
+// returns an error wrapping sql.ErrNoRows if there is no record or it is
+// deleted. GetURLHistory returns the recorded changes, newest first, and an
+// error wrapping sql.ErrNoRows if there is no record.
+//
+// UpdateSettings applies settings to the record for shortCode. Records whose
+// settings change stop being canonical. It returns an error wrapping
+// sql.ErrNoRows if there is no record or it is deleted.
+//
+// SetRules replaces the routing rules of the record for shortCode with
+// rules, in order; an empty rules removes them. Records with rules stop
+// being canonical. It returns an error wrapping sql.ErrNoRows if there is no
+// record or it is deleted.
+//
+// SetVariants replaces the A/B variants of the record for shortCode with
+// variants, in order; an empty variants removes them. Records with variants
+// stop being canonical. It returns an error wrapping sql.ErrNoRows if there
+// is no record or it is deleted.
+//
+// UpdatePageInfo stores page as the PageInfo of the record for shortCode, if
+// the record still points at longURL. It returns an error wrapping
+// sql.ErrNoRows if there is no such record or it is deleted.
+//
+// ClaimURLsForCheck returns up to limit live, unexpired records whose
+// destination has not been checked since checkedBefore, least recently
+// checked first, and marks them as checked now so that concurrent callers
+// claim other records. RecordHealthCheck stores the result of a check for the
+// record for shortCode, if it still points at longURL, and reports whether
+// the record is disabled afterwards. It returns an error wrapping
+// sql.ErrNoRows if there is no such record or it is deleted.
+//
+// ConsumeClick uses up one of the clicks of the record for shortCode, which
+// must have a MaxClicks limit, and returns how many are left afterwards. The
+// decrement is atomic, so concurrent calls never use more than MaxClicks
+// clicks between them. It returns ErrNoClicksLeft once none are left, and an
+// error wrapping sql.ErrNoRows if there is no record or it is deleted.
 //
-// GetURLByShortCode returns the original long URL associated with the given
-// shortCode. If no record is found, the implementation should return a
-// non-nil error describing the condition.
+// GetPasswordLock returns the lockout state of the record for shortCode.
+// RecordPasswordFailure counts a wrong password for it and, once maxFailures
+// have been counted, locks it for lockFor and starts counting afresh; it
+// returns the state afterwards. While the record is locked, it counts nothing
+// and returns ErrPasswordLocked instead; checking and counting is atomic, so
+// concurrent callers never count more than maxFailures between them.
+// ResetPasswordFailures clears the count and any lockout after a correct
+// password. All three read and write the database directly, even through a
+// cache, so every instance sees the same lockout. They return an error
+// wrapping sql.ErrNoRows if there is no record or it is deleted.
 //
-// URLExists checks whether the given longURL already exists in storage.
-// It returns a boolean indicating existence, the existing record ID (0 if not
-// found), and an error if the existence check could not be performed.
+// ListURLs returns the records matching filter, newest first, which is in
+// descending id order. Deleted records are never listed.
 //
-// SetShortCode associates the provided shortCode with an existing record
-// identified by id. It returns an error if the update fails or if the id does
-// not correspond to an existing record.
+// DeleteURL soft-deletes the record for shortCode and DisableURL disables or
+// re-enables it. Neither removes the row, so the code is never reissued, and
+// both take the record out of deduplication. Both return an error wrapping
+// sql.ErrNoRows if there is no record or it is already deleted. Re-enabling
+// a record resets its count of failed health checks.
 type URLRepository interface {
-	CreateURL(ctx context.Context, longURL string) (int64, error)
-	GetURLByShortCode(ctx context.Context, shortCode string) (string, error)
-	URLExists(ctx context.Context, longURL string) (bool, int64, error)
-	SetShortCode(ctx context.Context, id int64, shortCode string) error
+	CreateURL(ctx context.Context, u *URL, encode func(id int64) string) (string, error)
+	CreateURLs(ctx context.Context, urls []*URL, encode func(id int64) string) ([]CreateResult, error)
+	GetURLByShortCode(ctx context.Context, shortCode string) (*URL, error)
+	DeleteURL(ctx context.Context, shortCode string) error
+	DisableURL(ctx context.Context, shortCode string, disabled bool) error
+	UpdateLongURL(ctx context.Context, shortCode, longURL, originalURL string) error
+	GetURLHistory(ctx context.Context, shortCode string) ([]URLChange, error)
+	UpdateSettings(ctx context.Context, shortCode string, settings LinkSettings) error
+	SetRules(ctx context.Context, shortCode string, rules []Rule) error
+	SetVariants(ctx context.Context, shortCode string, variants []Variant) error
+	UpdatePageInfo(ctx context.Context, shortCode, longURL string, page PageInfo) error
+	ClaimURLsForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*URL, error)
+	RecordHealthCheck(ctx context.Context, shortCode, longURL string, check HealthCheck) (bool, error)
+	ConsumeClick(ctx context.Context, shortCode string) (int, error)
+	GetPasswordLock(ctx context.Context, shortCode string) (PasswordLock, error)
+	RecordPasswordFailure(ctx context.Context, shortCode string, maxFailures int, lockFor time.Duration) (PasswordLock, error)
+	ResetPasswordFailures(ctx context.Context, shortCode string) error
+	ListURLs(ctx context.Context, filter ListFilter) ([]*URL, error)
 }
@@ -0,0 +1,193 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"zipit/internal/url/repository"
+	"zipit/pkg/geoip"
+)
+
+// Platforms visitors can be routed by, as detected from their User-Agent.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// MaxRules is the most rules a link may have, and MaxValues the most values
+// one condition of a rule may list.
+const (
+	MaxRules  = 20
+	MaxValues = 50
+)
+
+var (
+	validLanguage = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+	validCountry  = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// Visitor holds what a resolve request tells about the visitor following a
+// link: its User-Agent and Accept-Language headers and its IP address.
+// Fields the request did not carry are left empty and match no condition.
+type Visitor struct {
+	UserAgent      string
+	AcceptLanguage string
+	IP             string
+}
+
+// Engine picks the destination of a visitor from the rules of a link. It
+// is safe for concurrent use.
+type Engine struct {
+	locator geoip.Locator
+}
+
+// NewEngine creates an Engine that finds visitors' countries with locator.
+// A nil locator knows no countries, so rules by country never match.
+func NewEngine(locator geoip.Locator) *Engine {
+	return &Engine{locator: locator}
+}
+
+// Route returns the destination of the first of rules that v matches. A
+// rule matches if v matches every condition it sets: its platform is one of
+// Platforms, its preferred language one of Languages or a more specific tag
+// of one of them ("en" matches "en-GB"), and its country one of Countries.
+// ok is false if no rule matches, in which case visitors go to the link's
+// own destination.
+func (e *Engine) Route(rules []repository.Rule, v Visitor) (longURL string, ok bool) {
+	if len(rules) == 0 {
+		return "", false
+	}
+	platform := Platform(v.UserAgent)
+	language := PreferredLanguage(v.AcceptLanguage)
+	country, located := "", false
+
+	for _, r := range rules {
+		if len(r.Platforms) > 0 && !slices.Contains(r.Platforms, platform) {
+			continue
+		}
+		if len(r.Languages) > 0 && !slices.ContainsFunc(r.Languages, func(tag string) bool { return languageMatches(language, tag) }) {
+			continue
+		}
+		if len(r.Countries) > 0 {
+			// Only links routing by country pay for the lookup.
+			if !located {
+				country, located = e.country(v.IP), true
+			}
+			if !slices.Contains(r.Countries, country) {
+				continue
+			}
+		}
+		return r.LongURL, true
+	}
+	return "", false
+}
+
+// country returns the country of the IP address ip, or "" if it is unknown.
+func (e *Engine) country(ip string) string {
+	if e.locator == nil {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	return e.locator.Country(addr)
+}
+
+// Platform returns the operating system a User-Agent header names, as one of
+// the Platform constants, or "" if it names none of them. iPadOS Safari
+// claims to be macOS by default, so those visitors count as macOS.
+func Platform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		return PlatformAndroid
+	case strings.Contains(userAgent, "Windows"):
+		return PlatformWindows
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return PlatformMacOS
+	case strings.Contains(userAgent, "Linux"):
+		return PlatformLinux
+	}
+	return ""
+}
+
+// PreferredLanguage returns the language tag an Accept-Language header
+// prefers, in lower case: the one with the highest quality, the first of
+// them on a tie. It returns "" if the header accepts no specific language.
+func PreferredLanguage(acceptLanguage string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
+
+// languageMatches reports whether the language tag language is tag or a more
+// specific tag of it.
+func languageMatches(language, tag string) bool {
+	return language == tag || strings.HasPrefix(language, tag+"-")
+}
+
+// Normalize checks the conditions of rule and returns it with platforms and
+// languages in lower case and countries in upper case. The destination is
+// left to the caller to check. A rule must set at least one condition,
+// since one matching every visitor would make the link's own destination
+// unreachable.
+func Normalize(rule repository.Rule) (repository.Rule, error) {
+	if len(rule.Platforms) == 0 && len(rule.Languages) == 0 && len(rule.Countries) == 0 {
+		return rule, errors.New("rule must have a platform, language or country condition")
+	}
+	if len(rule.Platforms) > MaxValues || len(rule.Languages) > MaxValues || len(rule.Countries) > MaxValues {
+		return rule, fmt.Errorf("rule conditions may list at most %d values", MaxValues)
+	}
+
+	out := repository.Rule{LongURL: rule.LongURL}
+	for _, p := range rule.Platforms {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch p {
+		case PlatformIOS, PlatformAndroid, PlatformWindows, PlatformMacOS, PlatformLinux:
+		default:
+			return rule, fmt.Errorf("unknown platform %q", p)
+		}
+		out.Platforms = append(out.Platforms, p)
+	}
+	for _, l := range rule.Languages {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if !validLanguage.MatchString(l) {
+			return rule, fmt.Errorf("invalid language tag %q", l)
+		}
+		out.Languages = append(out.Languages, l)
+	}
+	for _, c := range rule.Countries {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if !validCountry.MatchString(c) {
+			return rule, fmt.Errorf("invalid country code %q", c)
+		}
+		out.Countries = append(out.Countries, c)
+	}
+	return out, nil
+}
@@ -0,0 +1,151 @@
+package rules
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"zipit/internal/url/repository"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+	windowsUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	macUA     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15"
+	linuxUA   = "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+)
+
+func TestPlatform(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{iPhoneUA, PlatformIOS},
+		{"Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X)", PlatformIOS},
+		{androidUA, PlatformAndroid},
+		{windowsUA, PlatformWindows},
+		{macUA, PlatformMacOS},
+		{linuxUA, PlatformLinux},
+		{"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0)", ""},
+		{"curl/8.5.0", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Platform(tt.userAgent); got != tt.want {
+			t.Errorf("Platform(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", "fr-ch"},
+		{"en;q=0.5, de", "de"},
+		{"de;q=0.8, en;q=0.8", "de"},
+		{"*, es;q=0.3", "es"},
+		{"en;q=0", ""},
+		{"en;q=abc, pt-BR;q=0.2", "pt-br"},
+		{"*", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := PreferredLanguage(tt.header); got != tt.want {
+			t.Errorf("PreferredLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// locatorFunc adapts a function to the geoip.Locator interface.
+type locatorFunc func(addr netip.Addr) string
+
+func (f locatorFunc) Country(addr netip.Addr) string { return f(addr) }
+
+func TestEngine_Route(t *testing.T) {
+	lookups := 0
+	engine := NewEngine(locatorFunc(func(addr netip.Addr) string {
+		lookups++
+		if addr == netip.MustParseAddr("203.0.113.7") {
+			return "DE"
+		}
+		return "US"
+	}))
+	rules := []repository.Rule{
+		{Platforms: []string{PlatformIOS}, LongURL: "https://apps.apple.com/app/id1"},
+		{Platforms: []string{PlatformAndroid}, Countries: []string{"DE", "AT"}, LongURL: "https://play.google.com/store/apps/details?id=de"},
+		{Platforms: []string{PlatformAndroid}, LongURL: "https://play.google.com/store/apps/details?id=app"},
+		{Languages: []string{"pt"}, LongURL: "https://example.com/pt"},
+	}
+
+	tests := []struct {
+		name    string
+		visitor Visitor
+		want    string
+	}{
+		{"iOS", Visitor{UserAgent: iPhoneUA, IP: "203.0.113.7"}, "https://apps.apple.com/app/id1"},
+		{"Android In Germany", Visitor{UserAgent: androidUA, IP: "203.0.113.7"}, "https://play.google.com/store/apps/details?id=de"},
+		{"Android Elsewhere", Visitor{UserAgent: androidUA, IP: "198.51.100.1"}, "https://play.google.com/store/apps/details?id=app"},
+		{"Android Without IP", Visitor{UserAgent: androidUA}, "https://play.google.com/store/apps/details?id=app"},
+		{"Language Subtag", Visitor{UserAgent: windowsUA, AcceptLanguage: "pt-BR,en;q=0.5"}, "https://example.com/pt"},
+		{"Less Preferred Language", Visitor{UserAgent: windowsUA, AcceptLanguage: "en,pt;q=0.5"}, ""},
+		{"No Match", Visitor{UserAgent: macUA}, ""},
+		{"Nothing Known", Visitor{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := engine.Route(rules, tt.visitor)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("expected %q, got %q (ok=%v)", tt.want, got, ok)
+			}
+		})
+	}
+
+	// Countries are only looked up for rules that need them
+	lookups = 0
+	_, _ = engine.Route(rules, Visitor{UserAgent: iPhoneUA, IP: "203.0.113.7"})
+	_, _ = engine.Route(rules[3:], Visitor{UserAgent: androidUA, IP: "203.0.113.7"})
+	if lookups != 0 {
+		t.Errorf("expected no country lookups, got %d", lookups)
+	}
+
+	// Without a locator, rules by country never match
+	if got, ok := NewEngine(nil).Route(rules[1:2], Visitor{UserAgent: androidUA, IP: "203.0.113.7"}); ok {
+		t.Errorf("expected no match without a locator, got %q", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	rule, err := Normalize(repository.Rule{
+		Platforms: []string{" iOS", "Android"},
+		Languages: []string{"EN-gb"},
+		Countries: []string{"de"},
+		LongURL:   "https://example.com",
+	})
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if strings.Join(rule.Platforms, ",") != "ios,android" || rule.Languages[0] != "en-gb" || rule.Countries[0] != "DE" || rule.LongURL != "https://example.com" {
+		t.Errorf("unexpected normalized rule %+v", rule)
+	}
+
+	tests := []struct {
+		name    string
+		rule    repository.Rule
+		wantErr string
+	}{
+		{"No Condition", repository.Rule{LongURL: "https://example.com"}, "must have a platform, language or country"},
+		{"Unknown Platform", repository.Rule{Platforms: []string{"symbian"}}, `unknown platform "symbian"`},
+		{"Invalid Language", repository.Rule{Languages: []string{"english!"}}, `invalid language tag "english!"`},
+		{"Invalid Country", repository.Rule{Countries: []string{"DEU"}}, `invalid country code "DEU"`},
+		{"Too Many Values", repository.Rule{Countries: make([]string, MaxValues+1)}, "at most 50 values"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Normalize(tt.rule); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+// Counters counts links created and followed, for monitoring. Calls must not
+// block.
+type Counters interface {
+	// Shortened counts n links handed out.
+	Shortened(n int)
+	// Resolved counts an attempt to follow a link: "ok", or why it failed.
+	Resolved(result string)
+}
+
+// countingSvc reports links created by ShortenURL and ShortenURLs, and links
+// followed with GetLongURL, to Counters. Previews are not counted.
+type countingSvc struct {
+	URLService
+	counters Counters
+}
+
+// ShortenURL implements [URLService].
+func (svc *countingSvc) ShortenURL(ctx context.Context, longURL string, opts ShortenOptions) (string, error) {
+	shortCode, err := svc.URLService.ShortenURL(ctx, longURL, opts)
+	if err == nil {
+		svc.counters.Shortened(1)
+	}
+	return shortCode, err
+}
+
+// ShortenURLs implements [URLService].
+func (svc *countingSvc) ShortenURLs(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	results, err := svc.URLService.ShortenURLs(ctx, items)
+	shortened := 0
+	for _, res := range results {
+		if res.Err == nil {
+			shortened++
+		}
+	}
+	if shortened > 0 {
+		svc.counters.Shortened(shortened)
+	}
+	return results, err
+}
+
+// GetLongURL implements [URLService].
+func (svc *countingSvc) GetLongURL(ctx context.Context, shortCode string, opts ResolveOptions) (*Link, error) {
+	link, err := svc.URLService.GetLongURL(ctx, shortCode, opts)
+	svc.counters.Resolved(resolveResult(err))
+	return link, err
+}
+
+// resolveResult names the outcome of GetLongURL for Counters.
+func resolveResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrExpired):
+		return "expired"
+	case errors.Is(err, ErrDisabled):
+		return "disabled"
+	case errors.Is(err, ErrExhausted):
+		return "exhausted"
+	case errors.Is(err, ErrPasswordRequired):
+		return "password_required"
+	case errors.Is(err, ErrWrongPassword):
+		return "wrong_password"
+	case errors.Is(err, ErrPasswordLocked):
+		return "password_locked"
+	}
+	return "error"
+}
+
+// WithCounters wraps svc so that the links it creates and follows are counted
+// by counters.
+func WithCounters(svc URLService, counters Counters) URLService {
+	return &countingSvc{URLService: svc, counters: counters}
+}
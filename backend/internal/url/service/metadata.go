@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on the metadata of a link.
+const (
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 1000
+	MaxTags              = 10
+)
+
+// validTag matches a stored tag: up to 50 letters, digits and the separators
+// "-", "_", "." and ":", starting with a letter or digit.
+var validTag = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}][\p{Ll}\p{Lo}\p{N}_.:-]{0,49}$`)
+
+// NormalizeTag returns tag as it is stored and searched for: trimmed and
+// lower-case, so "Q3-Campaign" and "q3-campaign" are the same tag.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// linkMetadata validates the title, description and tags of a new link and
+// returns them in the form they are stored in. Duplicate tags are dropped.
+func linkMetadata(opts ShortenOptions) (title, description string, tags []string, err error) {
+	title, description = strings.TrimSpace(opts.Title), strings.TrimSpace(opts.Description)
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return "", "", nil, fmt.Errorf("%w: title is longer than %d characters", ErrInvalidMetadata, MaxTitleLength)
+	}
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return "", "", nil, fmt.Errorf("%w: description is longer than %d characters", ErrInvalidMetadata, MaxDescriptionLength)
+	}
+
+	seen := map[string]bool{}
+	for _, tag := range opts.Tags {
+		tag = NormalizeTag(tag)
+		if !validTag.MatchString(tag) {
+			return "", "", nil, fmt.Errorf("%w: invalid tag %q", ErrInvalidMetadata, tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxTags {
+		return "", "", nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidMetadata, MaxTags)
+	}
+	return title, description, tags, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Normalizer canonicalizes URLs before they are deduplicated and stored, so
+// that spellings of the same address share a short code. It always lowercases
+// the scheme and host, drops default ports and resolves dot segments in the
+// path, none of which change the resource addressed. The remaining rules can
+// change what the destination sees and are opt-in.
+type Normalizer struct {
+	// SortQuery orders query parameters by name, keeping the order of
+	// repeated parameters.
+	SortQuery bool
+	// StripTracking drops utm_* tracking parameters from the query.
+	StripTracking bool
+}
+
+// defaultPorts are the ports implied by each scheme.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// Normalize returns the canonical form of rawURL, which must be an absolute URL.
+// Query parameters and the path keep their original escaping.
+func (n Normalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if _, port, err := net.SplitHostPort(u.Host); err == nil && (port == "" || port == defaultPorts[u.Scheme]) {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	escapedPath := removeDotSegments(u.EscapedPath())
+	if escapedPath == "" {
+		escapedPath = "/"
+	}
+	if u.Path, err = url.PathUnescape(escapedPath); err != nil {
+		return "", err
+	}
+	u.RawPath = escapedPath
+
+	if n.SortQuery || n.StripTracking {
+		u.RawQuery = n.normalizeQuery(u.RawQuery)
+		u.ForceQuery = false
+	}
+	return u.String(), nil
+}
+
+// normalizeQuery applies the optional query rules to rawQuery.
+func (n Normalizer) normalizeQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type param struct{ name, raw string }
+	var params []param
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(raw, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if n.StripTracking && strings.HasPrefix(strings.ToLower(name), "utm_") {
+			continue
+		}
+		params = append(params, param{name: name, raw: raw})
+	}
+
+	if n.SortQuery {
+		sort.SliceStable(params, func(i, j int) bool { return params[i].name < params[j].name })
+	}
+	raw := make([]string, len(params))
+	for i, p := range params {
+		raw[i] = p.raw
+	}
+	return strings.Join(raw, "&")
+}
+
+// removeDotSegments resolves "." and ".." segments in an absolute path, as
+// described in RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	if path == "" {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+		case "..":
+			// out[0] is the empty segment before the leading slash
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, segment)
+			continue
+		}
+		// A path ending in a dot segment refers to a directory
+		if last {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "/")
+}
@@ -0,0 +1,67 @@
+package service
+
+import "testing"
+
+func TestNormalizer_Normalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer Normalizer
+		input      string
+		want       string
+	}{
+		{name: "Already Canonical", input: "https://example.com/a?b=1#top", want: "https://example.com/a?b=1#top"},
+		{name: "Empty Path", input: "https://example.com", want: "https://example.com/"},
+		{name: "Uppercase Scheme And Host", input: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "Default HTTPS Port", input: "https://example.com:443/", want: "https://example.com/"},
+		{name: "Default HTTP Port", input: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "Empty Port", input: "https://example.com:/a", want: "https://example.com/a"},
+		{name: "Other Port Kept", input: "https://example.com:8443/", want: "https://example.com:8443/"},
+		{name: "HTTP Port On HTTPS Kept", input: "https://example.com:80/", want: "https://example.com:80/"},
+		{name: "IPv6 Host", input: "https://[::1]:443/a", want: "https://[::1]/a"},
+		{name: "Dot Segments", input: "https://example.com/a/./b/../c", want: "https://example.com/a/c"},
+		{name: "Trailing Dot Segment", input: "https://example.com/a/b/..", want: "https://example.com/a/"},
+		{name: "Dot Segments Above Root", input: "https://example.com/../../a", want: "https://example.com/a"},
+		{name: "Escaping Kept", input: "https://example.com/a%2Fb/c%20d", want: "https://example.com/a%2Fb/c%20d"},
+		{name: "Query Order Kept By Default", input: "https://example.com/?b=2&a=1&utm_source=x", want: "https://example.com/?b=2&a=1&utm_source=x"},
+		{
+			name:       "Sorted Query",
+			normalizer: Normalizer{SortQuery: true},
+			input:      "https://example.com/?b=2&a=1&b=1&q=a+b",
+			want:       "https://example.com/?a=1&b=2&b=1&q=a+b",
+		},
+		{
+			name:       "Tracking Stripped",
+			normalizer: Normalizer{StripTracking: true},
+			input:      "https://example.com/?utm_source=news&id=7&UTM_Campaign=x&utmost=1",
+			want:       "https://example.com/?id=7&utmost=1",
+		},
+		{
+			name:       "Only Tracking Parameters",
+			normalizer: Normalizer{StripTracking: true},
+			input:      "https://example.com/a?utm_source=news",
+			want:       "https://example.com/a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.normalizer.Normalize(tt.input)
+			if err != nil {
+				t.Fatalf("Normalize(%q) failed: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizer_SpellingsShareForm(t *testing.T) {
+	var n Normalizer
+	want, _ := n.Normalize("https://example.com")
+	for _, input := range []string{"https://Example.com/", "https://example.com:443/", "HTTPS://EXAMPLE.COM/./"} {
+		if got, _ := n.Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
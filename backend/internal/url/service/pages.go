@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+)
+
+// PageQueue schedules fetching the destination page of a link in the
+// background. Enqueue must not block.
+type PageQueue interface {
+	Enqueue(shortCode string)
+}
+
+// pageFetchingSvc queues the destination page of links for fetching whenever
+// a link is created or its destination changes.
+type pageFetchingSvc struct {
+	URLService
+	queue PageQueue
+}
+
+// ShortenURL implements [URLService]. Canonical links handed out again are
+// queued too; the fetcher skips pages it already fetched.
+func (svc *pageFetchingSvc) ShortenURL(ctx context.Context, longURL string, opts ShortenOptions) (string, error) {
+	shortCode, err := svc.URLService.ShortenURL(ctx, longURL, opts)
+	if err == nil {
+		svc.queue.Enqueue(shortCode)
+	}
+	return shortCode, err
+}
+
+// ShortenURLs implements [URLService].
+func (svc *pageFetchingSvc) ShortenURLs(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	results, err := svc.URLService.ShortenURLs(ctx, items)
+	for _, res := range results {
+		if res.Err == nil {
+			svc.queue.Enqueue(res.ShortCode)
+		}
+	}
+	return results, err
+}
+
+// UpdateURL implements [URLService].
+func (svc *pageFetchingSvc) UpdateURL(ctx context.Context, owner, shortCode, longURL string) error {
+	if err := svc.URLService.UpdateURL(ctx, owner, shortCode, longURL); err != nil {
+		return err
+	}
+	svc.queue.Enqueue(shortCode)
+	return nil
+}
+
+// WithPageFetching wraps svc so that the destination pages of new links, and
+// of links pointed at a new destination, are queued on queue.
+func WithPageFetching(svc URLService, queue PageQueue) URLService {
+	return &pageFetchingSvc{URLService: svc, queue: queue}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"zipit/internal/url/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordLength is the longest link password in bytes, the most bcrypt
+// hashes.
+const MaxPasswordLength = 72
+
+// Wrong passwords lock a link against further attempts: after
+// MaxPasswordFailures in a row, passwords are refused for PasswordLockout.
+const (
+	MaxPasswordFailures = 5
+	PasswordLockout     = 15 * time.Minute
+)
+
+// hashPassword returns the hash to store for the password of a new link, or
+// "" if the link has no password.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > MaxPasswordLength {
+		return "", fmt.Errorf("%w: longer than %d bytes", ErrInvalidPassword, MaxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidPassword, err)
+	}
+	return string(hash), nil
+}
+
+// checkPassword checks the password supplied to follow u, which is password
+// protected. Every attempt is counted as a failure before the password is
+// compared, and taken back if it was correct: concurrent guesses then cannot
+// all get past the lockout before any of them is counted, and a locked link
+// does not confirm a correct guess either.
+func (svc *urlSvc) checkPassword(ctx context.Context, u *repository.URL, password string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	lock, err := svc.repo.RecordPasswordFailure(ctx, u.ShortCode, MaxPasswordFailures, PasswordLockout)
+	if errors.Is(err, repository.ErrPasswordLocked) {
+		return ErrPasswordLocked
+	}
+	if err != nil {
+		return ErrDatabaseWrite
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if lock.LockedUntil != nil && time.Now().Before(*lock.LockedUntil) {
+			slog.Warn("link locked after repeated wrong passwords", "alias", u.ShortCode, "until", *lock.LockedUntil)
+			return ErrPasswordLocked
+		}
+		return ErrWrongPassword
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check password: %w", err)
+	}
+
+	if err := svc.repo.ResetPasswordFailures(ctx, u.ShortCode); err != nil {
+		slog.Warn("failed to reset password failures", "alias", u.ShortCode, "error", err)
+	}
+	return nil
+}
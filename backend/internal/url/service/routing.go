@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"zipit/internal/url/repository"
+	"zipit/internal/url/rules"
+)
+
+// SetRules implements [URLService]. Rule destinations are normalized and
+// checked against the URL policy like the link's own destination.
+func (svc *urlSvc) SetRules(ctx context.Context, owner, shortCode string, linkRules []repository.Rule) error {
+	if len(linkRules) > rules.MaxRules {
+		return fmt.Errorf("%w: at most %d rules are allowed", ErrInvalidRules, rules.MaxRules)
+	}
+	normalized := make([]repository.Rule, 0, len(linkRules))
+	for i, r := range linkRules {
+		r, err := rules.Normalize(r)
+		if err != nil {
+			return fmt.Errorf("%w: rule %d: %w", ErrInvalidRules, i+1, err)
+		}
+		if r.LongURL, err = svc.normalize(ctx, r.LongURL); err != nil {
+			if errors.Is(err, ErrInvalidURL) {
+				return fmt.Errorf("%w: rule %d: invalid URL", ErrInvalidRules, i+1)
+			}
+			return err
+		}
+		normalized = append(normalized, r)
+	}
+
+	if _, err := svc.owned(ctx, owner, shortCode); err != nil {
+		return err
+	}
+	return writeErr(svc.repo.SetRules(ctx, shortCode, normalized))
+}
+
+// GetRules implements [URLService].
+func (svc *urlSvc) GetRules(ctx context.Context, owner, shortCode string) ([]repository.Rule, error) {
+	u, err := svc.owned(ctx, owner, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if u.Rules == nil {
+		return []repository.Rule{}, nil
+	}
+	return u.Rules, nil
+}
@@ -3,6 +3,10 @@ package service
 import (
 	"context"
 	"errors"
+	"time"
+	"zipit/internal/url/repository"
+	"zipit/internal/url/rules"
+	"zipit/pkg/redirect"
 )
 
 var (
@@ -10,8 +14,153 @@ var (
 	ErrInvalidURL    = errors.New("invalid url")
 	ErrDatabaseRead  = errors.New("error reading from database")
 	ErrDatabaseWrite = errors.New("error writing to database")
+	ErrInvalidAlias  = errors.New("invalid custom alias")
+	ErrAliasTaken    = errors.New("custom alias already in use")
+	ErrInvalidExpiry = errors.New("expiry must be in the future")
+	ErrExpired       = errors.New("url has expired")
+	ErrDisabled      = errors.New("url has been disabled")
+	ErrExhausted     = errors.New("url has reached its click limit")
+	ErrBatchTooLarge = errors.New("too many urls in batch")
+	ErrBlockedURL    = errors.New("url not allowed")
+	ErrPolicyCheck   = errors.New("failed to check url policy")
+
+	ErrInvalidRedirectType = errors.New("invalid redirect type")
+	ErrInvalidMetadata     = errors.New("invalid link metadata")
+	ErrInvalidFilter       = errors.New("invalid list filter")
+	ErrInvalidMaxClicks    = errors.New("max clicks must not be negative")
+	ErrInvalidRules        = errors.New("invalid routing rules")
+	ErrInvalidVariants     = errors.New("invalid link variants")
+
+	ErrInvalidPassword  = errors.New("invalid link password")
+	ErrPasswordRequired = errors.New("url requires a password")
+	ErrWrongPassword    = errors.New("incorrect password")
+	ErrPasswordLocked   = errors.New("too many incorrect passwords")
+)
+
+// MaxBatchSize is the largest number of items ShortenURLs accepts at once.
+const MaxBatchSize = 1000
+
+// DefaultListLimit is how many links ListURLs returns if the filter sets no
+// limit, and MaxListLimit the most it returns at once.
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 100
+)
+
+// ShortenOptions holds optional settings for a new short link.
+type ShortenOptions struct {
+	// CustomAlias is used as the short code instead of a generated one.
+	CustomAlias string
+	// ExpiresAt, if set, is when the link stops resolving.
+	ExpiresAt *time.Time
+	// Owner is the authenticated caller creating the link, or "" if anonymous.
+	Owner string
+	// Interstitial shows a warning page with the destination before redirecting.
+	Interstitial bool
+	// RedirectType is the HTTP status visitors are redirected with, one of
+	// 301, 302, 307 or 308. 0 means DefaultRedirectType.
+	RedirectType int
+	// Title, Description and Tags help the owner find the link again. Tags
+	// are stored normalized with NormalizeTag.
+	Title       string
+	Description string
+	Tags        []string
+	// Password, if set, must be supplied to follow the link. It is stored
+	// hashed and may be at most MaxPasswordLength bytes long.
+	Password string
+	// MaxClicks, if positive, is how many times the link resolves before it
+	// stops working.
+	MaxClicks int
+}
+
+// ResolveOptions holds what a visitor supplies to follow a link.
+type ResolveOptions struct {
+	// Password unlocks password-protected links.
+	Password string
+	// Visitor describes who follows the link, for the link's routing rules.
+	Visitor rules.Visitor
+	// VisitorID identifies the visitor across visits, so that it keeps
+	// getting the same A/B variant of a link. Visitors without one get a
+	// random variant.
+	VisitorID string
+	// LookupOnly is set if the caller shows the destination instead of
+	// redirecting the visitor to it, so no click is used up.
+	LookupOnly bool
+	// Confirmed is set once the visitor went past the warning page of a
+	// link with an interstitial. Until then, flagged links use up no click.
+	Confirmed bool
+}
+
+// DefaultRedirectType is the redirect status of links that do not set one.
+const DefaultRedirectType = redirect.Default
+
+// Safety statuses of a link's destination.
+const (
+	// SafetyOK destinations are followed right away.
+	SafetyOK = "ok"
+	// SafetyFlagged destinations were flagged by the link's owner.
+	SafetyFlagged = "flagged"
+	// SafetyBlocked destinations are rejected by the current URL policy,
+	// which may have changed since the link was created.
+	SafetyBlocked = "blocked"
 )
 
+// Link is a short link that can be followed, as seen by visitors.
+type Link struct {
+	LongURL   string
+	CreatedAt time.Time
+	// ExpiresAt is nil if the link never expires.
+	ExpiresAt *time.Time
+	// Safety is one of SafetyOK, SafetyFlagged or SafetyBlocked, and
+	// SafetyReason says why the destination is flagged or blocked.
+	Safety       string
+	SafetyReason string
+	// RedirectType is the HTTP status to redirect visitors with.
+	RedirectType int
+	Title        string
+	Description  string
+	Tags         []string
+	// Page describes the destination page, once it has been fetched.
+	Page repository.PageInfo
+	// Health is the outcome of the periodic checks of the destination.
+	Health repository.HealthStatus
+	// Routed is set if the link has routing rules or variants, so other
+	// visitors may get another LongURL. Page and Health are left out for
+	// visitors who were sent elsewhere, since they describe the link's own
+	// destination.
+	Routed bool
+	// Variant names the A/B variant the visitor was sent to, or is "" if
+	// the link has no variants or a routing rule matched.
+	Variant string
+}
+
+// Interstitial reports whether visitors should see a warning page before
+// being redirected.
+func (l *Link) Interstitial() bool {
+	return l.Safety != SafetyOK
+}
+
+// ListPage is one page of links returned by ListURLs.
+type ListPage struct {
+	URLs []*repository.URL
+	// NextCursor is the BeforeID of the filter for the next page, or 0 if
+	// this is the last page.
+	NextCursor int64
+}
+
+// BatchItem is one link to create with ShortenURLs.
+type BatchItem struct {
+	LongURL string
+	Opts    ShortenOptions
+}
+
+// BatchResult is the outcome of one BatchItem: its short code, or the error
+// ShortenURL would have returned for it.
+type BatchResult struct {
+	ShortCode string
+	Err       error
+}
+
 // URLService defines the interface for URL shortening operations.
 // It provides methods to create shortened URLs and retrieve original URLs
 // from their short code representations.
@@ -20,20 +169,90 @@ var (
 // Parameters:
 //   - ctx: Context for request cancellation and timeouts
 //   - longURL: The original URL to be shortened
+//   - opts: Optional settings such as a custom alias or expiry
 //
 // Returns:
 //   - string: The generated short code or shortened URL
-//   - error: An error if the URL is invalid or the operation fails
+//   - error: An error if the URL, alias, expiry or metadata is invalid, the alias is taken, or the operation fails.
+//     Destinations rejected by the URL policy return an error wrapping both
+//     ErrBlockedURL and the *policy.Violation explaining why.
 //
-// GetLongURL retrieves the original URL associated with a given short code.
+// ShortenURLs creates the links for items as ShortenURL would one by one, and
+// returns one result per item in the same order. Items succeed or fail
+// independently, so one invalid URL does not fail the batch. The returned
+// error is only set if there are more than MaxBatchSize items
+// (ErrBatchTooLarge) or nothing could be stored.
+//
+// GetLongURL retrieves the link to follow for a given short code.
 // Parameters:
 //   - ctx: Context for request cancellation and timeouts
 //   - shortCode: The short code identifier for the URL
+//   - opts: What the visitor supplied, such as the link's password
 //
 // Returns:
-//   - string: The original long URL
-//   - error: An error if the short code is not found or the operation fails
+//   - *Link: The long URL, with the safety status of the destination. Links
+//     with routing rules return the destination of the first rule the
+//     visitor in opts matches; other visitors of links with variants get
+//     the variant picked for opts.VisitorID.
+//   - error: An error if the short code is not found or deleted, has expired
+//     or been disabled, has used up its clicks (ErrExhausted), or the
+//     operation fails. Calls that redirect the visitor use up one click of
+//     links with a MaxClicks limit; see ResolveOptions for those that do not.
+//     Password-protected links return ErrPasswordRequired without a
+//     password, ErrWrongPassword with a wrong one, and ErrPasswordLocked
+//     after MaxPasswordFailures wrong passwords in a row, until
+//     PasswordLockout has passed.
+//
+// PreviewURL returns the same link as GetLongURL, for showing it to a visitor
+// instead of following it. It needs the password of protected links too, but
+// does not use up clicks.
+//
+// The remaining methods manage an existing link and only act on links created
+// by owner; links of other owners are reported as ErrNotFound, as are short
+// codes that do not exist or are deleted.
+//
+// UpdateURL points an existing short code at a new, valid long URL, which is
+// subject to the URL policy like a new link. The previous destination is kept
+// in the link's history, which GetURLHistory returns newest first.
+//
+// UpdateSettings changes the settings of an existing link. Settings are
+// validated like the matching ShortenOptions.
+//
+// SetRules replaces the routing rules of an existing link, which GetRules
+// returns in order. At most rules.MaxRules rules are allowed, each checked
+// with rules.Normalize and with a destination subject to the URL policy;
+// invalid rules return an error wrapping ErrInvalidRules that says which.
+//
+// SetVariants replaces the A/B variants of an existing link, which
+// GetVariants returns in order. Visitors who match no routing rule are split
+// between the variants by weight instead of going to the link's own
+// destination. A split needs two to MaxVariants variants, each with a weight
+// of 1 to MaxVariantWeight and a destination subject to the URL policy.
+// Variants without a name are named by their position: "A", "B" and so on.
+// Invalid variants return an error wrapping ErrInvalidVariants that says
+// which. No variants removes the split.
+//
+// ListURLs returns a page of the links matching filter, newest first. It
+// lists whichever owner the filter selects, so callers must check that the
+// caller may see that owner's links. The filter's Tag is normalized with
+// NormalizeTag, and its Limit defaults to DefaultListLimit and is capped at
+// MaxListLimit. An empty creation time range returns ErrInvalidFilter.
+//
+// DeleteURL takes a link down for good. The short code is never reissued.
+// DisableURL takes a link down until it is re-enabled with disabled=false.
 type URLService interface {
-	ShortenURL(ctx context.Context, longURL string) (string, error)
-	GetLongURL(ctx context.Context, shortCode string) (string, error)
+	ShortenURL(ctx context.Context, longURL string, opts ShortenOptions) (string, error)
+	ShortenURLs(ctx context.Context, items []BatchItem) ([]BatchResult, error)
+	GetLongURL(ctx context.Context, shortCode string, opts ResolveOptions) (*Link, error)
+	PreviewURL(ctx context.Context, shortCode string, opts ResolveOptions) (*Link, error)
+	DeleteURL(ctx context.Context, owner, shortCode string) error
+	DisableURL(ctx context.Context, owner, shortCode string, disabled bool) error
+	UpdateURL(ctx context.Context, owner, shortCode, longURL string) error
+	UpdateSettings(ctx context.Context, owner, shortCode string, settings repository.LinkSettings) error
+	SetRules(ctx context.Context, owner, shortCode string, linkRules []repository.Rule) error
+	GetRules(ctx context.Context, owner, shortCode string) ([]repository.Rule, error)
+	SetVariants(ctx context.Context, owner, shortCode string, variants []repository.Variant) error
+	GetVariants(ctx context.Context, owner, shortCode string) ([]repository.Variant, error)
+	GetURLHistory(ctx context.Context, owner, shortCode string) ([]repository.URLChange, error)
+	ListURLs(ctx context.Context, filter repository.ListFilter) (*ListPage, error)
 }
@@ -4,62 +4,473 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"zipit/internal/url/policy"
 	"zipit/internal/url/repository"
+	"zipit/internal/url/rules"
+	"zipit/pkg/alias"
+	"zipit/pkg/redirect"
 	"zipit/pkg/shortener"
 )
 
+// validAlias matches the short codes the gateway accepts on resolve.
+var validAlias = regexp.MustCompile(`^[0-9a-zA-Z]{1,12}$`)
+
+// maxCodeAttempts bounds how many codes are tried for a new URL before giving up.
+const maxCodeAttempts = 5
+
 type urlSvc struct {
-	repo      repository.URLRepository
-	shortener shortener.Shortener
+	repo       repository.URLRepository
+	shortener  shortener.Shortener
+	normalizer Normalizer
+	policy     policy.Policy
+	router     *rules.Engine
+}
+
+// GetLongURL implements [URLService]. Links with a click limit use up a
+// click once every other check has passed, if the visitor is redirected;
+// otherwise their destination is left out. The click is counted by the
+// repository, never from the looked up record, which may come from a cache.
+func (svc *urlSvc) GetLongURL(ctx context.Context, shortCode string, opts ResolveOptions) (*Link, error) {
+	u, err := svc.lookup(ctx, shortCode, opts)
+	if err != nil {
+		return nil, err
+	}
+	link := svc.link(ctx, u, opts)
+	if u.MaxClicks > 0 && !redirects(link, opts) {
+		hideDestination(link)
+	} else if u.MaxClicks > 0 {
+		if _, err := svc.repo.ConsumeClick(ctx, shortCode); err != nil {
+			switch {
+			case errors.Is(err, repository.ErrNoClicksLeft):
+				return nil, ErrExhausted
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, ErrNotFound
+			}
+			return nil, ErrDatabaseWrite
+		}
+	}
+	return link, nil
+}
+
+// redirects reports whether the visitor in opts is redirected to link, rather
+// than shown its destination or the warning page in front of it.
+func redirects(link *Link, opts ResolveOptions) bool {
+	return !opts.LookupOnly && (!link.Interstitial() || opts.Confirmed)
+}
+
+// hideDestination leaves out what link reveals about its destination, for
+// links with a click limit that are described without using up a click.
+func hideDestination(link *Link) {
+	link.LongURL, link.Page = "", repository.PageInfo{}
+}
+
+// PreviewURL implements [URLService]. Previews do not use up clicks, and
+// show visitors the destination following the link would take them to,
+// unless the link has a click limit.
+func (svc *urlSvc) PreviewURL(ctx context.Context, shortCode string, opts ResolveOptions) (*Link, error) {
+	u, err := svc.lookup(ctx, shortCode, opts)
+	if err != nil {
+		return nil, err
+	}
+	link := svc.link(ctx, u, opts)
+	if u.MaxClicks > 0 {
+		hideDestination(link)
+	}
+	return link, nil
 }
 
-// GetLongURL implements [URLService].
-func (svc *urlSvc) GetLongURL(ctx context.Context, shortCode string) (string, error) {
-	longURL, err := svc.repo.GetURLByShortCode(ctx, shortCode)
+// lookup returns the live record for shortCode, unlocked with the visitor's
+// password if it has one.
+func (svc *urlSvc) lookup(ctx context.Context, shortCode string, opts ResolveOptions) (*repository.URL, error) {
+	u, err := svc.repo.GetURLByShortCode(ctx, shortCode)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", ErrNotFound
+			return nil, ErrNotFound
 		}
-		return "", ErrDatabaseRead
+		return nil, ErrDatabaseRead
 	}
-	return longURL, nil
+	if u.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if u.DisabledAt != nil {
+		return nil, ErrDisabled
+	}
+	if u.ExpiresAt != nil && !time.Now().Before(*u.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	// Cached records may not know yet that the last click was used, in
+	// which case ConsumeClick finds out.
+	if u.MaxClicks > 0 && u.ClicksRemaining <= 0 {
+		return nil, ErrExhausted
+	}
+	if u.PasswordHash != "" {
+		if err := svc.checkPassword(ctx, u, opts.Password); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
 }
 
-// ShortenURL implements [URLService].
-func (svc *urlSvc) ShortenURL(ctx context.Context, longURL string) (string, error) {
-	if !isValidURL(longURL) {
-		return "", ErrInvalidURL
+// link describes the record u to the visitor in opts, routed by its rules or
+// else sent to one of its variants, and rates the destination.
+func (svc *urlSvc) link(ctx context.Context, u *repository.URL, opts ResolveOptions) *Link {
+	link := &Link{
+		LongURL:      u.LongURL,
+		CreatedAt:    u.CreatedAt,
+		ExpiresAt:    u.ExpiresAt,
+		Safety:       SafetyOK,
+		RedirectType: u.RedirectType,
+		Title:        u.Title,
+		Description:  u.Description,
+		Tags:         u.Tags,
+		Page:         u.Page,
+		Health:       u.Health,
+		Routed:       len(u.Rules) > 0 || len(u.Variants) > 0,
+	}
+	if longURL, ok := svc.router.Route(u.Rules, opts.Visitor); ok {
+		link.LongURL, link.Page, link.Health = longURL, repository.PageInfo{}, repository.HealthStatus{}
+	} else if len(u.Variants) > 0 {
+		variant := pickVariant(u.Variants, u.ShortCode, opts.VisitorID)
+		link.LongURL, link.Variant = variant.LongURL, variant.Name
+		link.Page, link.Health = repository.PageInfo{}, repository.HealthStatus{}
+	}
+	if link.RedirectType == 0 {
+		link.RedirectType = DefaultRedirectType
+	}
+	if u.Interstitial {
+		link.Safety, link.SafetyReason = SafetyFlagged, "flagged by the link owner"
+	}
+	svc.rate(ctx, link)
+	return link
+}
+
+// rate checks the destination of link against the current URL policy, so
+// that links created before a domain was blocked warn their visitors. Policy
+// errors other than violations leave the link as it is.
+func (svc *urlSvc) rate(ctx context.Context, link *Link) {
+	if svc.policy == nil {
+		return
+	}
+	u, err := url.Parse(link.LongURL)
+	if err != nil {
+		return
+	}
+	var violation *policy.Violation
+	if err := svc.policy.Check(ctx, u); errors.As(err, &violation) {
+		link.Safety, link.SafetyReason = SafetyBlocked, violation.Reason
+	}
+}
+
+// DeleteURL implements [URLService].
+func (svc *urlSvc) DeleteURL(ctx context.Context, owner, shortCode string) error {
+	if err := svc.authorize(ctx, owner, shortCode); err != nil {
+		return err
 	}
+	return writeErr(svc.repo.DeleteURL(ctx, shortCode))
+}
+
+// DisableURL implements [URLService].
+func (svc *urlSvc) DisableURL(ctx context.Context, owner, shortCode string, disabled bool) error {
+	if err := svc.authorize(ctx, owner, shortCode); err != nil {
+		return err
+	}
+	return writeErr(svc.repo.DisableURL(ctx, shortCode, disabled))
+}
 
-	urlExists, id, err := svc.repo.URLExists(ctx, longURL)
+// UpdateURL implements [URLService].
+func (svc *urlSvc) UpdateURL(ctx context.Context, owner, shortCode, longURL string) error {
+	normalized, err := svc.normalize(ctx, longURL)
 	if err != nil {
-		return "", ErrDatabaseRead
+		return err
 	}
+	if err := svc.authorize(ctx, owner, shortCode); err != nil {
+		return err
+	}
+	return writeErr(svc.repo.UpdateLongURL(ctx, shortCode, normalized, longURL))
+}
 
-	// create new entry
-	if urlExists {
-		return svc.shortener.Encode(id), nil
+// UpdateSettings implements [URLService].
+func (svc *urlSvc) UpdateSettings(ctx context.Context, owner, shortCode string, settings repository.LinkSettings) error {
+	if settings.RedirectType != nil && !redirect.Valid(*settings.RedirectType) {
+		return ErrInvalidRedirectType
 	}
+	if err := svc.authorize(ctx, owner, shortCode); err != nil {
+		return err
+	}
+	return writeErr(svc.repo.UpdateSettings(ctx, shortCode, settings))
+}
 
-	id, err = svc.repo.CreateURL(ctx, longURL)
+// GetURLHistory implements [URLService].
+func (svc *urlSvc) GetURLHistory(ctx context.Context, owner, shortCode string) ([]repository.URLChange, error) {
+	if err := svc.authorize(ctx, owner, shortCode); err != nil {
+		return nil, err
+	}
+	changes, err := svc.repo.GetURLHistory(ctx, shortCode)
 	if err != nil {
-		return "", ErrDatabaseWrite
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrDatabaseRead
 	}
-	shortCode := svc.shortener.Encode(id)
-	err = svc.repo.SetShortCode(ctx, id, shortCode)
+	return changes, nil
+}
+
+// ListURLs implements [URLService]. One more record than the page holds is
+// fetched to tell whether there is a next page.
+func (svc *urlSvc) ListURLs(ctx context.Context, filter repository.ListFilter) (*ListPage, error) {
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return nil, ErrInvalidFilter
+	}
+	filter.Tag = NormalizeTag(filter.Tag)
+	filter.Domain = strings.ToLower(strings.TrimSpace(filter.Domain))
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+	filter.Limit = limit + 1
+
+	urls, err := svc.repo.ListURLs(ctx, filter)
 	if err != nil {
-		return "", ErrDatabaseWrite
+		return nil, ErrDatabaseRead
 	}
+	page := &ListPage{URLs: urls}
+	if len(urls) > limit {
+		page.URLs = urls[:limit]
+		page.NextCursor = urls[limit-1].ID
+	}
+	return page, nil
+}
 
-	return shortCode, nil
+// authorize checks that shortCode exists and belongs to owner. Links of other
+// owners are reported as ErrNotFound so their codes cannot be probed. Owners
+// never change, so the check cannot go stale before the following write.
+func (svc *urlSvc) authorize(ctx context.Context, owner, shortCode string) error {
+	_, err := svc.owned(ctx, owner, shortCode)
+	return err
 }
 
-func NewUrlSvc(repo repository.URLRepository, shortener shortener.Shortener) URLService {
+// owned returns the record for shortCode after checking it as authorize does.
+func (svc *urlSvc) owned(ctx context.Context, owner, shortCode string) (*repository.URL, error) {
+	u, err := svc.repo.GetURLByShortCode(ctx, shortCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrDatabaseRead
+	}
+	if u.DeletedAt != nil || u.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+// writeErr maps a repository error from an update of an existing link.
+func writeErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return ErrDatabaseWrite
+}
+
+// ShortenURL implements [URLService].
+func (svc *urlSvc) ShortenURL(ctx context.Context, longURL string, opts ShortenOptions) (string, error) {
+	u, err := svc.newURL(ctx, longURL, opts)
+	if err != nil {
+		return "", err
+	}
+	if u.ShortCode != "" {
+		return svc.createAlias(ctx, u)
+	}
+	return svc.createGenerated(ctx, u)
+}
+
+// ShortenURLs implements [URLService]. Valid items are stored with a single
+// bulk insert.
+func (svc *urlSvc) ShortenURLs(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	if len(items) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]BatchResult, len(items))
+	var urls []*repository.URL
+	var indexes []int
+	for i, item := range items {
+		u, err := svc.newURL(ctx, item.LongURL, item.Opts)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		urls = append(urls, u)
+		indexes = append(indexes, i)
+	}
+	if len(urls) == 0 {
+		return results, nil
+	}
+
+	created, err := svc.repo.CreateURLs(ctx, urls, svc.shortener.Encode)
+	if err != nil {
+		return nil, ErrDatabaseWrite
+	}
+	for n, res := range created {
+		i := indexes[n]
+		switch {
+		case res.Err == nil:
+			results[i].ShortCode = res.ShortCode
+		case errors.Is(res.Err, repository.ErrShortCodeTaken) && urls[n].ShortCode != "":
+			results[i].Err = ErrAliasTaken
+		default:
+			results[i].Err = ErrDatabaseWrite
+		}
+	}
+	return results, nil
+}
+
+// newURL validates a shorten request and builds the record to store for it.
+// The destination is stored normalized, next to the submitted spelling.
+func (svc *urlSvc) newURL(ctx context.Context, longURL string, opts ShortenOptions) (*repository.URL, error) {
+	normalized, err := svc.normalize(ctx, longURL)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+	redirectType := opts.RedirectType
+	if redirectType == 0 {
+		redirectType = DefaultRedirectType
+	}
+	if !redirect.Valid(redirectType) {
+		return nil, ErrInvalidRedirectType
+	}
+	title, description, tags, err := linkMetadata(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxClicks < 0 {
+		return nil, ErrInvalidMaxClicks
+	}
+	passwordHash, err := hashPassword(opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &repository.URL{
+		LongURL:      normalized,
+		OriginalURL:  longURL,
+		Owner:        opts.Owner,
+		ExpiresAt:    opts.ExpiresAt,
+		Interstitial: opts.Interstitial,
+		RedirectType: redirectType,
+		Title:        title,
+		Description:  description,
+		Tags:         tags,
+		PasswordHash: passwordHash,
+		MaxClicks:    opts.MaxClicks,
+	}
+	if opts.CustomAlias == "" {
+		// Plain links share one canonical row per owner and destination.
+		// Links with settings of their own, or metadata describing the one
+		// link rather than the destination, are never handed out again.
+		u.Canonical = opts.ExpiresAt == nil && !opts.Interstitial && redirectType == DefaultRedirectType &&
+			title == "" && description == "" && len(tags) == 0 && passwordHash == "" && opts.MaxClicks == 0
+		return u, nil
+	}
+
+	code := opts.CustomAlias
+	if !validAlias.MatchString(code) || alias.Reserved(code) {
+		return nil, ErrInvalidAlias
+	}
+	u.ShortCode = code
+	// If the shortener could generate the alias itself, the row claims the
+	// matching id so that the code is never handed out again.
+	if id, err := svc.shortener.Decode(code); err == nil && svc.shortener.Encode(id) == code {
+		u.ID = id
+	}
+	return u, nil
+}
+
+// createGenerated stores u with a short code from the shortener, or returns
+// the existing code if u is canonical and already stored. Random shorteners
+// may hit codes that are already in use, so the insert is retried with a
+// fresh code until one sticks.
+func (svc *urlSvc) createGenerated(ctx context.Context, u *repository.URL) (string, error) {
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		shortCode, err := svc.repo.CreateURL(ctx, u, svc.shortener.Encode)
+		if errors.Is(err, repository.ErrShortCodeTaken) {
+			continue
+		}
+		if err != nil {
+			return "", ErrDatabaseWrite
+		}
+		return shortCode, nil
+	}
+	return "", ErrDatabaseWrite
+}
+
+// createAlias stores u under its caller-chosen short code.
+func (svc *urlSvc) createAlias(ctx context.Context, u *repository.URL) (string, error) {
+	if _, err := svc.repo.CreateURL(ctx, u, nil); err != nil {
+		if errors.Is(err, repository.ErrShortCodeTaken) {
+			return "", ErrAliasTaken
+		}
+		return "", ErrDatabaseWrite
+	}
+	return u.ShortCode, nil
+}
+
+// NewUrlSvc creates a URLService storing links in repo, with short codes from
+// shortener and destinations canonicalized by normalizer. Destinations must
+// pass urlPolicy; a nil urlPolicy allows every valid URL. Visitors are routed
+// by links' rules with router; a nil router routes without knowing
+// visitors' countries.
+func NewUrlSvc(repo repository.URLRepository, shortener shortener.Shortener, normalizer Normalizer, urlPolicy policy.Policy, router *rules.Engine) URLService {
+	if router == nil {
+		router = rules.NewEngine(nil)
+	}
 	return &urlSvc{
-		repo:      repo,
-		shortener: shortener,
+		repo:       repo,
+		shortener:  shortener,
+		normalizer: normalizer,
+		policy:     urlPolicy,
+		router:     router,
+	}
+}
+
+// normalize validates longURL, returns its canonical form and checks that
+// form against the URL policy.
+func (svc *urlSvc) normalize(ctx context.Context, longURL string) (string, error) {
+	if !isValidURL(longURL) {
+		return "", ErrInvalidURL
+	}
+	normalized, err := svc.normalizer.Normalize(longURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	if svc.policy == nil {
+		return normalized, nil
+	}
+
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	if err := svc.policy.Check(ctx, u); err != nil {
+		var violation *policy.Violation
+		if errors.As(err, &violation) {
+			return "", fmt.Errorf("%w: %w", ErrBlockedURL, violation)
+		}
+		return "", ErrPolicyCheck
 	}
+	return normalized, nil
 }
 
 func isValidURL(rawURL string) bool {
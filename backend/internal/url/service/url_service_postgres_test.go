@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	"zipit/internal/url/repository"
+	"zipit/pkg/config"
+	"zipit/pkg/database"
+	"zipit/pkg/shortener"
+)
+
+var keyMap = [][]string{
+	{"DB_PORT", "5432"},
+	{"DB_USER", "test_user"},
+	{"DB_PASSWORD", "test_password"},
+	{"DB_NAME", "test_db"},
+	{"DB_HOST", "localhost"},
+}
+
+func TestUrlSvc_ShortenURL_Concurrent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping Postgres test in short mode")
+	}
+
+	// setup test env variables
+	os.Clearenv()
+	for _, envPair := range keyMap {
+		os.Setenv(envPair[0], envPair[1])
+	}
+	cfg, err := config.NewDBConfig()
+	if err != nil {
+		t.Fatalf("failed to setup test env %v", err)
+	}
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("failed to setup test db %v", err)
+	}
+	defer db.Close()
+
+	applyMigrations(t, db)
+	if _, err := db.Conn.Exec("TRUNCATE TABLE urls RESTART IDENTITY"); err != nil {
+		t.Fatalf("failed to cleanup table: %v", err)
+	}
+
+	svc := NewUrlSvc(repository.NewPostgresRepository(db), shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	const (
+		urlCount = 5
+		workers  = 50
+	)
+	codes := make([][]string, urlCount)
+	errs := make(chan error, urlCount*workers)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Every worker shortens every URL, so each URL is requested concurrently
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < urlCount; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				code, err := svc.ShortenURL(ctx, fmt.Sprintf("https://example.com/page/%d", i), ShortenOptions{})
+				cancel()
+				if err != nil {
+					errs <- err
+					continue
+				}
+				mu.Lock()
+				codes[i] = append(codes[i], code)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("ShortenURL failed: %v", err)
+	}
+
+	// All callers must get the same code for the same URL
+	for i, got := range codes {
+		for _, code := range got {
+			if code != got[0] {
+				t.Errorf("url %d: expected every caller to get %s, got %s", i, got[0], code)
+				break
+			}
+		}
+	}
+
+	// And the table must hold exactly one row per URL, none without a code
+	rows, err := db.Conn.Query("SELECT long_url, COUNT(*) FROM urls GROUP BY long_url")
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	defer rows.Close()
+
+	seen := 0
+	for rows.Next() {
+		var longURL string
+		var n int
+		if err := rows.Scan(&longURL, &n); err != nil {
+			t.Fatalf("failed to scan row: %v", err)
+		}
+		seen++
+		if n != 1 {
+			t.Errorf("expected 1 row for %s, got %d", longURL, n)
+		}
+	}
+	if seen != urlCount {
+		t.Errorf("expected %d distinct urls, got %d", urlCount, seen)
+	}
+}
+
+// applyMigrations resets the test database and runs every up migration in
+// order. The migrations cannot be run twice, so the schema is dropped first.
+func applyMigrations(t *testing.T, db *database.Database) {
+	files, err := filepath.Glob("../../../migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to find migrations: %v", err)
+	}
+	sort.Strings(files)
+
+	if _, err := db.Conn.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+		t.Fatalf("failed to reset schema: %v", err)
+	}
+
+	for _, f := range files {
+		query, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f, err)
+		}
+		if _, err := db.Conn.Exec(string(query)); err != nil {
+			t.Fatalf("failed to apply %s: %v", f, err)
+		}
+	}
+}
@@ -4,28 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"zipit/internal/url/policy"
 	"zipit/internal/url/repository"
+	"zipit/internal/url/rules"
+	"zipit/pkg/cache"
 	"zipit/pkg/shortener"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUrlSvc_ShortenURL_Success_NewURL(t *testing.T) {
 	// 1. Setup mocks
+	var created *repository.URL
 	mockRepo := &repository.MockRepo{
-		URLExistsFunc: func(ctx context.Context, longURL string) (bool, int64, error) {
-			return false, 0, nil // URL doesn't exist
-		},
-		CreateURLFunc: func(ctx context.Context, longURL string) (int64, error) {
-			return 12345, nil // Return a fake database ID
-		},
-		SetShortCodeFunc: func(ctx context.Context, id int64, shortCode string) error {
-			return nil // Simulate successful update
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = u
+			return encode(12345), nil // Encode a fake database ID
 		},
 	}
 
 	// We'll use the real shortener since it's a pure function (no side effects)
 	realShortener := shortener.NewBase62Shortener()
-	svc := NewUrlSvc(mockRepo, realShortener)
+	svc := NewUrlSvc(mockRepo, realShortener, Normalizer{}, nil, nil)
 
 	// 2. Define input
 	longURL := "https://example.com"
@@ -33,7 +40,7 @@ func TestUrlSvc_ShortenURL_Success_NewURL(t *testing.T) {
 
 	// 3. Execute
 	ctx := context.Background()
-	code, err := svc.ShortenURL(ctx, longURL)
+	code, err := svc.ShortenURL(ctx, longURL, ShortenOptions{})
 
 	// 4. Verification
 	if err != nil {
@@ -43,19 +50,22 @@ func TestUrlSvc_ShortenURL_Success_NewURL(t *testing.T) {
 	if code != expectedCode {
 		t.Errorf("Expected code %s, got %s", expectedCode, code)
 	}
+	if !created.Canonical {
+		t.Error("Expected plain links to be stored as canonical")
+	}
 }
 
 func TestUrlSvc_ShortenURL_AlreadyExists(t *testing.T) {
+	realShortener := shortener.NewBase62Shortener()
+	expectedCode := realShortener.Encode(999)
 	mockRepo := &repository.MockRepo{
-		URLExistsFunc: func(ctx context.Context, longURL string) (bool, int64, error) {
-			return true, 999, nil
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			return expectedCode, nil // The canonical row already exists
 		},
 	}
-	realShortener := shortener.NewBase62Shortener()
-	svc := NewUrlSvc(mockRepo, realShortener)
+	svc := NewUrlSvc(mockRepo, realShortener, Normalizer{}, nil, nil)
 
-	expectedCode := realShortener.Encode(999)
-	code, err := svc.ShortenURL(context.Background(), "https://existing.com")
+	code, err := svc.ShortenURL(context.Background(), "https://existing.com", ShortenOptions{})
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -66,8 +76,8 @@ func TestUrlSvc_ShortenURL_AlreadyExists(t *testing.T) {
 }
 
 func TestUrlSvc_ShortenURL_EmptyURL(t *testing.T) {
-	svc := NewUrlSvc(&repository.MockRepo{}, shortener.NewBase62Shortener())
-	_, err := svc.ShortenURL(context.Background(), "")
+	svc := NewUrlSvc(&repository.MockRepo{}, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.ShortenURL(context.Background(), "", ShortenOptions{})
 	if err == nil {
 		t.Error("Expected error for empty URL, got nil")
 		return
@@ -78,7 +88,7 @@ func TestUrlSvc_ShortenURL_EmptyURL(t *testing.T) {
 }
 
 func TestUrlSvc_ShortenURL_InvalidURLs(t *testing.T) {
-	svc := NewUrlSvc(&repository.MockRepo{}, shortener.NewBase62Shortener())
+	svc := NewUrlSvc(&repository.MockRepo{}, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
 
 	invalidURLs := []struct {
 		name string
@@ -93,7 +103,7 @@ func TestUrlSvc_ShortenURL_InvalidURLs(t *testing.T) {
 
 	for _, tt := range invalidURLs {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := svc.ShortenURL(context.Background(), tt.url)
+			_, err := svc.ShortenURL(context.Background(), tt.url, ShortenOptions{})
 			if err == nil {
 				t.Errorf("Expected ErrInvalidURL for %q, got nil", tt.url)
 				return
@@ -105,16 +115,53 @@ func TestUrlSvc_ShortenURL_InvalidURLs(t *testing.T) {
 	}
 }
 
-func TestUrlSvc_ShortenURL_URLExistsError(t *testing.T) {
+func TestUrlSvc_ShortenURL_CreateURLError(t *testing.T) {
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			return "", context.DeadlineExceeded
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{})
+	if err == nil {
+		t.Error("Expected error from CreateURL, got nil")
+		return
+	}
+	if !errors.Is(err, ErrDatabaseWrite) {
+		t.Errorf("Expected ErrDatabaseWrite, got %v", err)
+	}
+}
+
+func TestUrlSvc_GetLongURL_Success(t *testing.T) {
+	expectedURL := "https://example.com"
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{LongURL: expectedURL, ShortCode: shortCode}, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	link, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if link.LongURL != expectedURL {
+		t.Errorf("Expected %s, got %s", expectedURL, link.LongURL)
+	}
+	if link.Safety != SafetyOK || link.Interstitial() {
+		t.Errorf("Expected a safe link, got %+v", link)
+	}
+}
+
+func TestUrlSvc_GetLongURL_Error(t *testing.T) {
 	mockRepo := &repository.MockRepo{
-		URLExistsFunc: func(ctx context.Context, longURL string) (bool, int64, error) {
-			return false, 0, context.DeadlineExceeded
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return nil, context.DeadlineExceeded
 		},
 	}
-	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener())
-	_, err := svc.ShortenURL(context.Background(), "https://example.com")
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{})
 	if err == nil {
-		t.Error("Expected error from URLExists, got nil")
+		t.Error("Expected error from GetURLByShortCode, got nil")
 		return
 	}
 	if !errors.Is(err, ErrDatabaseRead) {
@@ -122,96 +169,1685 @@ func TestUrlSvc_ShortenURL_URLExistsError(t *testing.T) {
 	}
 }
 
-func TestUrlSvc_ShortenURL_CreateURLError(t *testing.T) {
+func TestUrlSvc_GetLongURL_NotFound(t *testing.T) {
 	mockRepo := &repository.MockRepo{
-		URLExistsFunc: func(ctx context.Context, longURL string) (bool, int64, error) {
-			return false, 0, nil
-		},
-		CreateURLFunc: func(ctx context.Context, longURL string) (int64, error) {
-			return 0, context.DeadlineExceeded
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return nil, sql.ErrNoRows
 		},
 	}
-	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener())
-	_, err := svc.ShortenURL(context.Background(), "https://example.com")
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.GetLongURL(context.Background(), "missing", ResolveOptions{})
 	if err == nil {
-		t.Error("Expected error from CreateURL, got nil")
+		t.Error("Expected ErrNotFound, got nil")
 		return
 	}
-	if !errors.Is(err, ErrDatabaseWrite) {
-		t.Errorf("Expected ErrDatabaseWrite, got %v", err)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
 	}
 }
 
-func TestUrlSvc_ShortenURL_SetShortCodeError(t *testing.T) {
+func TestUrlSvc_ShortenURL_CustomAlias(t *testing.T) {
+	var created *repository.URL
 	mockRepo := &repository.MockRepo{
-		URLExistsFunc: func(ctx context.Context, longURL string) (bool, int64, error) {
-			return false, 0, nil
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = u
+			return u.ShortCode, nil
 		},
-		CreateURLFunc: func(ctx context.Context, longURL string) (int64, error) {
-			return 123, nil
+	}
+	realShortener := shortener.NewBase62Shortener()
+	svc := NewUrlSvc(mockRepo, realShortener, Normalizer{}, nil, nil)
+
+	code, err := svc.ShortenURL(context.Background(), "https://example.com/launch", ShortenOptions{CustomAlias: "launch2026"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if code != "launch2026" {
+		t.Errorf("Expected code launch2026, got %s", code)
+	}
+	if created.ShortCode != "launch2026" || created.LongURL != "https://example.com/launch" {
+		t.Errorf("Unexpected record passed to repository: %+v", created)
+	}
+
+	// The alias is a valid base62 code, so it must reserve the id it decodes to.
+	wantID, _ := realShortener.Decode("launch2026")
+	if created.ID != wantID {
+		t.Errorf("Expected alias to reserve id %d, got %d", wantID, created.ID)
+	}
+}
+
+func TestUrlSvc_ShortenURL_CustomAliasNotGeneratable(t *testing.T) {
+	var created *repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = u
+			return u.ShortCode, nil
 		},
-		SetShortCodeFunc: func(ctx context.Context, id int64, shortCode string) error {
-			return context.DeadlineExceeded
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	// Leading zeros are never produced by the base62 encoder, so nothing needs reserving.
+	if _, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{CustomAlias: "007"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.ID != 0 {
+		t.Errorf("Expected no reserved id, got %d", created.ID)
+	}
+}
+
+func TestUrlSvc_ShortenURL_InvalidAlias(t *testing.T) {
+	svc := NewUrlSvc(&repository.MockRepo{}, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	invalidAliases := []struct {
+		name  string
+		alias string
+	}{
+		{"Too long", "abcdefghijklm"},
+		{"Hyphen", "launch-2026"},
+		{"Path traversal", "../etc"},
+		{"Whitespace", "my link"},
+		{"Gateway route", "links"},
+		{"Health check", "health"},
+	}
+
+	for _, tt := range invalidAliases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{CustomAlias: tt.alias})
+			if !errors.Is(err, ErrInvalidAlias) {
+				t.Errorf("Expected ErrInvalidAlias for %q, got %v", tt.alias, err)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_ShortenURL_AliasTaken(t *testing.T) {
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			return "", repository.ErrShortCodeTaken
 		},
 	}
-	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener())
-	_, err := svc.ShortenURL(context.Background(), "https://example.com")
-	if err == nil {
-		t.Error("Expected error from SetShortCode, got nil")
-		return
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{CustomAlias: "taken"})
+	if !errors.Is(err, ErrAliasTaken) {
+		t.Errorf("Expected ErrAliasTaken, got %v", err)
 	}
+}
+
+func TestUrlSvc_ShortenURL_AliasCreateError(t *testing.T) {
+	svc := NewUrlSvc(&repository.MockRepo{}, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{CustomAlias: "launch"})
 	if !errors.Is(err, ErrDatabaseWrite) {
 		t.Errorf("Expected ErrDatabaseWrite, got %v", err)
 	}
 }
 
-func TestUrlSvc_GetLongURL_Success(t *testing.T) {
-	expectedURL := "https://example.com"
+func TestUrlSvc_ShortenURL_WithExpiry(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	var created *repository.URL
 	mockRepo := &repository.MockRepo{
-		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (string, error) {
-			return expectedURL, nil
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = u
+			return encode(7), nil
 		},
 	}
-	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener())
-	url, err := svc.GetLongURL(context.Background(), "abc")
+	realShortener := shortener.NewBase62Shortener()
+	svc := NewUrlSvc(mockRepo, realShortener, Normalizer{}, nil, nil)
+
+	code, err := svc.ShortenURL(context.Background(), "https://example.com/reset", ShortenOptions{ExpiresAt: &expiresAt})
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
-	if url != expectedURL {
-		t.Errorf("Expected %s, got %s", expectedURL, url)
+	if code != realShortener.Encode(7) {
+		t.Errorf("Expected code %s, got %s", realShortener.Encode(7), code)
+	}
+	if created.ExpiresAt == nil || !created.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected expires_at %v, got %v", expiresAt, created.ExpiresAt)
+	}
+	if created.Canonical {
+		t.Error("Expected expiring links not to be shared with other requests")
 	}
 }
 
-func TestUrlSvc_GetLongURL_Error(t *testing.T) {
+func TestUrlSvc_ShortenURL_ExpiryInPast(t *testing.T) {
+	svc := NewUrlSvc(&repository.MockRepo{}, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	expiresAt := time.Now().Add(-time.Minute)
+	_, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{ExpiresAt: &expiresAt})
+	if !errors.Is(err, ErrInvalidExpiry) {
+		t.Errorf("Expected ErrInvalidExpiry, got %v", err)
+	}
+}
+
+func TestUrlSvc_GetLongURL_Expired(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Second)
 	mockRepo := &repository.MockRepo{
-		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (string, error) {
-			return "", context.DeadlineExceeded
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{LongURL: "https://example.com", ShortCode: shortCode, ExpiresAt: &expiredAt}, nil
 		},
 	}
-	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener())
-	_, err := svc.GetLongURL(context.Background(), "abc")
-	if err == nil {
-		t.Error("Expected error from GetURLByShortCode, got nil")
-		return
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{})
+	if !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
 	}
-	if !errors.Is(err, ErrDatabaseRead) {
-		t.Errorf("Expected ErrDatabaseRead, got %v", err)
+}
+
+func TestUrlSvc_GetLongURL_NotYetExpired(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{LongURL: "https://example.com", ShortCode: shortCode, ExpiresAt: &expiresAt}, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	link, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if link.LongURL != "https://example.com" {
+		t.Errorf("Expected https://example.com, got %s", link.LongURL)
+	}
+	if link.ExpiresAt == nil || !link.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected expiry %v, got %v", expiresAt, link.ExpiresAt)
 	}
 }
 
-func TestUrlSvc_GetLongURL_NotFound(t *testing.T) {
+func TestUrlSvc_ShortenURL_RetriesCodeCollision(t *testing.T) {
+	var attempts []string
 	mockRepo := &repository.MockRepo{
-		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (string, error) {
-			return "", sql.ErrNoRows
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			attempts = append(attempts, encode(1))
+			if len(attempts) < 3 {
+				return "", repository.ErrShortCodeTaken
+			}
+			return attempts[len(attempts)-1], nil
 		},
 	}
-	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener())
-	_, err := svc.GetLongURL(context.Background(), "missing")
-	if err == nil {
-		t.Error("Expected ErrNotFound, got nil")
-		return
+	svc := NewUrlSvc(mockRepo, shortener.NewRandomShortener(8), Normalizer{}, nil, nil)
+
+	code, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(attempts))
+	}
+	if code != attempts[2] {
+		t.Errorf("Expected the code that was stored (%s), got %s", attempts[2], code)
+	}
+}
+
+func TestUrlSvc_ShortenURL_GivesUpAfterCollisions(t *testing.T) {
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			return "", repository.ErrShortCodeTaken
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewRandomShortener(8), Normalizer{}, nil, nil)
+
+	_, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{})
+	if !errors.Is(err, ErrDatabaseWrite) {
+		t.Errorf("Expected ErrDatabaseWrite, got %v", err)
+	}
+}
+
+func TestUrlSvc_ShortenURL_CustomAliasWithRandomShortener(t *testing.T) {
+	var created *repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = u
+			return u.ShortCode, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewRandomShortener(8), Normalizer{}, nil, nil)
+
+	if _, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{CustomAlias: "launch"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Random codes are not derived from ids, so there is nothing to reserve.
+	if created.ID != 0 {
+		t.Errorf("Expected no reserved id, got %d", created.ID)
+	}
+}
+
+func TestUrlSvc_GetLongURL_Disabled(t *testing.T) {
+	disabledAt := time.Now()
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{LongURL: "https://example.com", ShortCode: shortCode, DisabledAt: &disabledAt}, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{})
+	if !errors.Is(err, ErrDisabled) {
+		t.Errorf("Expected ErrDisabled, got %v", err)
+	}
+}
+
+func TestUrlSvc_GetLongURL_Deleted(t *testing.T) {
+	deletedAt := time.Now()
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			// Deleted wins over disabled
+			return &repository.URL{LongURL: "https://example.com", ShortCode: shortCode, DisabledAt: &deletedAt, DeletedAt: &deletedAt}, nil
+		},
 	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	_, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{})
 	if !errors.Is(err, ErrNotFound) {
 		t.Errorf("Expected ErrNotFound, got %v", err)
 	}
 }
+
+func TestUrlSvc_DeleteURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoErr error
+		wantErr error
+	}{
+		{name: "Success"},
+		{name: "Not found", repoErr: fmt.Errorf("wrapped: %w", sql.ErrNoRows), wantErr: ErrNotFound},
+		{name: "Database error", repoErr: errors.New("connection refused"), wantErr: ErrDatabaseWrite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted string
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: ownedBy("acme"),
+				DeleteURLFunc: func(ctx context.Context, shortCode string) error {
+					deleted = shortCode
+					return tt.repoErr
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+			err := svc.DeleteURL(context.Background(), "acme", "abc")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if deleted != "abc" {
+				t.Errorf("Expected abc to be deleted, got %q", deleted)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_DisableURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		disabled bool
+		repoErr  error
+		wantErr  error
+	}{
+		{name: "Disable", disabled: true},
+		{name: "Enable", disabled: false},
+		{name: "Not found", disabled: true, repoErr: fmt.Errorf("wrapped: %w", sql.ErrNoRows), wantErr: ErrNotFound},
+		{name: "Database error", disabled: true, repoErr: errors.New("connection refused"), wantErr: ErrDatabaseWrite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotDisabled bool
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: ownedBy("acme"),
+				DisableURLFunc: func(ctx context.Context, shortCode string, disabled bool) error {
+					gotDisabled = disabled
+					return tt.repoErr
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+			err := svc.DisableURL(context.Background(), "acme", "abc", tt.disabled)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if gotDisabled != tt.disabled {
+				t.Errorf("Expected disabled=%v to be passed on, got %v", tt.disabled, gotDisabled)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_UpdateSettings(t *testing.T) {
+	var got repository.LinkSettings
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: ownedBy("acme"),
+		UpdateSettingsFunc: func(ctx context.Context, shortCode string, settings repository.LinkSettings) error {
+			got = settings
+			if shortCode == "miss" {
+				return fmt.Errorf("wrapped: %w", sql.ErrNoRows)
+			}
+			return nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	interstitial := true
+	if err := svc.UpdateSettings(context.Background(), "acme", "abc", repository.LinkSettings{Interstitial: &interstitial}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.Interstitial == nil || !*got.Interstitial {
+		t.Errorf("Expected the settings to be passed on, got %+v", got)
+	}
+	if err := svc.UpdateSettings(context.Background(), "acme", "miss", repository.LinkSettings{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUrlSvc_UpdateURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		longURL    string
+		repoErr    error
+		wantErr    error
+		wantUpdate bool
+	}{
+		{name: "Success", longURL: "https://example.com/q2", wantUpdate: true},
+		{name: "Normalized", longURL: "HTTPS://Example.com:443/a/../q2", wantUpdate: true},
+		{name: "Invalid URL", longURL: "ftp://example.com", wantErr: ErrInvalidURL},
+		{name: "Empty URL", longURL: "", wantErr: ErrInvalidURL},
+		{name: "Not found", longURL: "https://example.com", repoErr: fmt.Errorf("wrapped: %w", sql.ErrNoRows), wantErr: ErrNotFound, wantUpdate: true},
+		{name: "Database error", longURL: "https://example.com", repoErr: errors.New("connection refused"), wantErr: ErrDatabaseWrite, wantUpdate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: ownedBy("acme"),
+				UpdateLongURLFunc: func(ctx context.Context, shortCode, longURL, originalURL string) error {
+					updated = true
+					if shortCode != "abc" || originalURL != tt.longURL {
+						t.Errorf("unexpected update of %s to %s", shortCode, originalURL)
+					}
+					if tt.wantErr == nil && longURL != "https://example.com/q2" {
+						t.Errorf("expected the normalized destination, got %s", longURL)
+					}
+					return tt.repoErr
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+			err := svc.UpdateURL(context.Background(), "acme", "abc", tt.longURL)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if updated != tt.wantUpdate {
+				t.Errorf("Expected update=%v, got %v", tt.wantUpdate, updated)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_GetURLHistory(t *testing.T) {
+	changedAt := time.Now()
+	tests := []struct {
+		name    string
+		changes []repository.URLChange
+		repoErr error
+		wantErr error
+	}{
+		{name: "Success", changes: []repository.URLChange{{OldLongURL: "https://a.com", NewLongURL: "https://b.com", ChangedAt: changedAt}}},
+		{name: "Not found", repoErr: fmt.Errorf("wrapped: %w", sql.ErrNoRows), wantErr: ErrNotFound},
+		{name: "Database error", repoErr: errors.New("connection refused"), wantErr: ErrDatabaseRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: ownedBy("acme"),
+				GetURLHistoryFunc: func(ctx context.Context, shortCode string) ([]repository.URLChange, error) {
+					return tt.changes, tt.repoErr
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+			changes, err := svc.GetURLHistory(context.Background(), "acme", "abc")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if len(changes) != len(tt.changes) {
+				t.Errorf("Expected %d changes, got %d", len(tt.changes), len(changes))
+			}
+		})
+	}
+}
+
+// ownedBy returns a GetURLByShortCodeFunc serving live links of owner.
+func ownedBy(owner string) func(ctx context.Context, shortCode string) (*repository.URL, error) {
+	return func(ctx context.Context, shortCode string) (*repository.URL, error) {
+		return &repository.URL{LongURL: "https://example.com", ShortCode: shortCode, Owner: owner}, nil
+	}
+}
+
+func TestUrlSvc_ManageURL_Authorization(t *testing.T) {
+	deletedAt := time.Now()
+	tests := []struct {
+		name    string
+		lookup  func(ctx context.Context, shortCode string) (*repository.URL, error)
+		wantErr error
+	}{
+		{name: "Other owner", lookup: ownedBy("globex"), wantErr: ErrNotFound},
+		{name: "Anonymous link", lookup: ownedBy(""), wantErr: ErrNotFound},
+		{
+			name: "Deleted link",
+			lookup: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+				return &repository.URL{ShortCode: shortCode, Owner: "acme", DeletedAt: &deletedAt}, nil
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "Unknown code",
+			lookup: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+				return nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "Lookup error",
+			lookup: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+				return nil, errors.New("connection refused")
+			},
+			wantErr: ErrDatabaseRead,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The zero MockRepo fails every write, so reaching one shows up as ErrDatabaseWrite
+			mockRepo := &repository.MockRepo{GetURLByShortCodeFunc: tt.lookup}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+			ctx := context.Background()
+
+			if err := svc.DeleteURL(ctx, "acme", "abc"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DeleteURL: expected %v, got %v", tt.wantErr, err)
+			}
+			if err := svc.DisableURL(ctx, "acme", "abc", true); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DisableURL: expected %v, got %v", tt.wantErr, err)
+			}
+			if err := svc.UpdateURL(ctx, "acme", "abc", "https://example.com/new"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateURL: expected %v, got %v", tt.wantErr, err)
+			}
+			if _, err := svc.GetURLHistory(ctx, "acme", "abc"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetURLHistory: expected %v, got %v", tt.wantErr, err)
+			}
+			if err := svc.UpdateSettings(ctx, "acme", "abc", repository.LinkSettings{}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateSettings: expected %v, got %v", tt.wantErr, err)
+			}
+			if err := svc.SetRules(ctx, "acme", "abc", nil); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetRules: expected %v, got %v", tt.wantErr, err)
+			}
+			if _, err := svc.GetRules(ctx, "acme", "abc"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetRules: expected %v, got %v", tt.wantErr, err)
+			}
+			if err := svc.SetVariants(ctx, "acme", "abc", nil); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetVariants: expected %v, got %v", tt.wantErr, err)
+			}
+			if _, err := svc.GetVariants(ctx, "acme", "abc"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetVariants: expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_ShortenURL_StoresOwner(t *testing.T) {
+	var created []*repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = append(created, u)
+			return "abc", nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	_, _ = svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{Owner: "acme"})
+	_, _ = svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{Owner: "acme", CustomAlias: "launch"})
+	if len(created) != 2 || created[0].Owner != "acme" || created[1].Owner != "acme" {
+		t.Errorf("Expected both links to be owned by acme, got %+v", created)
+	}
+}
+
+func TestUrlSvc_ShortenURLs(t *testing.T) {
+	var stored []*repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLsFunc: func(ctx context.Context, urls []*repository.URL, encode func(id int64) string) ([]repository.CreateResult, error) {
+			stored = urls
+			results := make([]repository.CreateResult, len(urls))
+			for i, u := range urls {
+				switch {
+				case u.ShortCode == "taken":
+					results[i].Err = repository.ErrShortCodeTaken
+				case u.ShortCode != "":
+					results[i].ShortCode = u.ShortCode
+				default:
+					results[i].ShortCode = encode(int64(i + 1))
+				}
+			}
+			return results, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	past := time.Now().Add(-time.Hour)
+	items := []BatchItem{
+		{LongURL: "https://example.com/a", Opts: ShortenOptions{Owner: "acme"}},
+		{LongURL: "not a url"},
+		{LongURL: "https://example.com/b", Opts: ShortenOptions{CustomAlias: "promo"}},
+		{LongURL: "https://example.com/c", Opts: ShortenOptions{CustomAlias: "bad-alias"}},
+		{LongURL: "https://example.com/d", Opts: ShortenOptions{CustomAlias: "taken"}},
+		{LongURL: "https://example.com/e", Opts: ShortenOptions{ExpiresAt: &past}},
+	}
+	results, err := svc.ShortenURLs(context.Background(), items)
+	if err != nil {
+		t.Fatalf("ShortenURLs failed: %v", err)
+	}
+
+	want := []BatchResult{
+		{ShortCode: "1"},
+		{Err: ErrInvalidURL},
+		{ShortCode: "promo"},
+		{Err: ErrInvalidAlias},
+		{Err: ErrAliasTaken},
+		{Err: ErrInvalidExpiry},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %d", len(want), len(results))
+	}
+	for i := range want {
+		if results[i].ShortCode != want[i].ShortCode || !errors.Is(results[i].Err, want[i].Err) {
+			t.Errorf("result %d: expected %+v, got %+v", i, want[i], results[i])
+		}
+	}
+
+	// Only valid items reach the repository, in one call
+	if len(stored) != 3 || !stored[0].Canonical || stored[0].Owner != "acme" || stored[1].ShortCode != "promo" {
+		t.Errorf("Unexpected records stored: %+v", stored)
+	}
+}
+
+func TestUrlSvc_ShortenURLs_Errors(t *testing.T) {
+	mockRepo := &repository.MockRepo{
+		CreateURLsFunc: func(ctx context.Context, urls []*repository.URL, encode func(id int64) string) ([]repository.CreateResult, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	if _, err := svc.ShortenURLs(context.Background(), []BatchItem{{LongURL: "https://example.com"}}); !errors.Is(err, ErrDatabaseWrite) {
+		t.Errorf("Expected ErrDatabaseWrite, got %v", err)
+	}
+	if _, err := svc.ShortenURLs(context.Background(), make([]BatchItem, MaxBatchSize+1)); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("Expected ErrBatchTooLarge, got %v", err)
+	}
+
+	// A batch without valid items does not touch the database
+	results, err := svc.ShortenURLs(context.Background(), []BatchItem{{LongURL: ""}})
+	if err != nil || len(results) != 1 || !errors.Is(results[0].Err, ErrInvalidURL) {
+		t.Errorf("Expected a single ErrInvalidURL result, got %+v, err=%v", results, err)
+	}
+}
+
+func TestUrlSvc_ShortenURL_Normalizes(t *testing.T) {
+	var created []*repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = append(created, u)
+			return "abc", nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{StripTracking: true}, nil, nil)
+
+	for _, longURL := range []string{"https://Example.com:443", "https://example.com/?utm_source=news"} {
+		if _, err := svc.ShortenURL(context.Background(), longURL, ShortenOptions{}); err != nil {
+			t.Fatalf("ShortenURL(%q) failed: %v", longURL, err)
+		}
+	}
+
+	// Both spellings are deduplicated on the same destination, and keep their own spelling
+	if len(created) != 2 || created[0].LongURL != "https://example.com/" || created[1].LongURL != "https://example.com/" {
+		t.Errorf("Expected both links to be stored as https://example.com/, got %+v", created)
+	}
+	if created[0].OriginalURL != "https://Example.com:443" || created[1].OriginalURL != "https://example.com/?utm_source=news" {
+		t.Errorf("Expected the submitted spellings to be kept, got %+v", created)
+	}
+}
+
+// policyFunc adapts a function to a URL policy.
+type policyFunc func(ctx context.Context, u *url.URL) error
+
+func (f policyFunc) Check(ctx context.Context, u *url.URL) error { return f(ctx, u) }
+
+func TestUrlSvc_URLPolicy(t *testing.T) {
+	created := 0
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created++
+			return "abc", nil
+		},
+		GetURLByShortCodeFunc: ownedBy("acme"),
+		UpdateLongURLFunc: func(ctx context.Context, shortCode, longURL, originalURL string) error {
+			t.Errorf("unexpected update to %s", longURL)
+			return nil
+		},
+	}
+	blockEvil := policyFunc(func(ctx context.Context, u *url.URL) error {
+		if u.Hostname() == "evil.com" {
+			return &policy.Violation{Reason: "destination domain is blocked"}
+		}
+		return nil
+	})
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, blockEvil, nil)
+	ctx := context.Background()
+
+	if _, err := svc.ShortenURL(ctx, "https://example.com", ShortenOptions{}); err != nil {
+		t.Errorf("Expected an allowed destination to be shortened, got %v", err)
+	}
+
+	// The policy sees the normalized destination
+	_, err := svc.ShortenURL(ctx, "https://EVIL.com:443/", ShortenOptions{})
+	var violation *policy.Violation
+	if !errors.Is(err, ErrBlockedURL) || !errors.As(err, &violation) || violation.Reason != "destination domain is blocked" {
+		t.Errorf("Expected ErrBlockedURL with the reason, got %v", err)
+	}
+	if err := svc.UpdateURL(ctx, "acme", "abc", "https://evil.com/x"); !errors.Is(err, ErrBlockedURL) {
+		t.Errorf("Expected UpdateURL to apply the policy, got %v", err)
+	}
+	results, _ := svc.ShortenURLs(ctx, []BatchItem{{LongURL: "https://evil.com"}})
+	if len(results) != 1 || !errors.Is(results[0].Err, ErrBlockedURL) {
+		t.Errorf("Expected ShortenURLs to apply the policy, got %+v", results)
+	}
+	if created != 1 {
+		t.Errorf("Expected only the allowed link to be stored, got %d", created)
+	}
+
+	// Policies that fail to decide block the request without a reason
+	failing := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, policyFunc(func(ctx context.Context, u *url.URL) error {
+		return errors.New("lookup failed")
+	}), nil)
+	if _, err := failing.ShortenURL(ctx, "https://example.com", ShortenOptions{}); !errors.Is(err, ErrPolicyCheck) {
+		t.Errorf("Expected ErrPolicyCheck, got %v", err)
+	}
+}
+
+func TestUrlSvc_ShortenURL_Interstitial(t *testing.T) {
+	var created *repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			created = u
+			return "abc", nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	if _, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{Interstitial: true}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Other requests for the destination must not get a link that warns
+	if !created.Interstitial || created.Canonical {
+		t.Errorf("Expected an interstitial, non-canonical record, got %+v", created)
+	}
+}
+
+func TestUrlSvc_LinkSafety(t *testing.T) {
+	blockEvil := policyFunc(func(ctx context.Context, u *url.URL) error {
+		switch u.Hostname() {
+		case "evil.com":
+			return &policy.Violation{Reason: "destination domain is blocked"}
+		case "unknown.com":
+			return errors.New("lookup failed")
+		}
+		return nil
+	})
+	tests := []struct {
+		name             string
+		url              *repository.URL
+		wantSafety       string
+		wantReason       string
+		wantInterstitial bool
+	}{
+		{name: "Safe", url: &repository.URL{LongURL: "https://example.com/"}, wantSafety: SafetyOK},
+		{
+			name:             "Flagged by owner",
+			url:              &repository.URL{LongURL: "https://example.com/", Interstitial: true},
+			wantSafety:       SafetyFlagged,
+			wantReason:       "flagged by the link owner",
+			wantInterstitial: true,
+		},
+		{
+			name:             "Blocked since creation",
+			url:              &repository.URL{LongURL: "https://evil.com/", Interstitial: true},
+			wantSafety:       SafetyBlocked,
+			wantReason:       "destination domain is blocked",
+			wantInterstitial: true,
+		},
+		{name: "Policy error", url: &repository.URL{LongURL: "https://unknown.com/"}, wantSafety: SafetyOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+					return tt.url, nil
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, blockEvil, nil)
+
+			for _, lookup := range []func(context.Context, string, ResolveOptions) (*Link, error){svc.GetLongURL, svc.PreviewURL} {
+				link, err := lookup(context.Background(), "abc", ResolveOptions{})
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if link.Safety != tt.wantSafety || link.SafetyReason != tt.wantReason || link.Interstitial() != tt.wantInterstitial {
+					t.Errorf("Expected safety %s (%q), interstitial=%v, got %+v", tt.wantSafety, tt.wantReason, tt.wantInterstitial, link)
+				}
+			}
+		})
+	}
+}
+
+func TestUrlSvc_PreviewURL_Unavailable(t *testing.T) {
+	disabledAt := time.Now()
+	tests := []struct {
+		name    string
+		url     *repository.URL
+		repoErr error
+		wantErr error
+	}{
+		{name: "Not found", repoErr: fmt.Errorf("wrapped: %w", sql.ErrNoRows), wantErr: ErrNotFound},
+		{name: "Disabled", url: &repository.URL{LongURL: "https://example.com", DisabledAt: &disabledAt}, wantErr: ErrDisabled},
+		{name: "Expired", url: &repository.URL{LongURL: "https://example.com", ExpiresAt: &disabledAt}, wantErr: ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+					return tt.url, tt.repoErr
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+			if _, err := svc.PreviewURL(context.Background(), "abc", ResolveOptions{}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_ShortenURL_Password(t *testing.T) {
+	var stored *repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			stored = u
+			return "abc", nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	if _, err := svc.ShortenURL(context.Background(), "https://example.com/doc", ShortenOptions{Password: "s3cret"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stored.Canonical {
+		t.Error("Expected a password-protected link not to be canonical")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("s3cret")); err != nil {
+		t.Errorf("Expected the password to be stored hashed, got %q: %v", stored.PasswordHash, err)
+	}
+
+	long := strings.Repeat("x", MaxPasswordLength+1)
+	if _, err := svc.ShortenURL(context.Background(), "https://example.com/doc", ShortenOptions{Password: long}); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Expected ErrInvalidPassword, got %v", err)
+	}
+}
+
+func TestUrlSvc_GetLongURL_Password(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockedUntil := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name       string
+		password   string
+		failure    repository.PasswordLock // returned by RecordPasswordFailure
+		failureErr error
+		wantErr    error
+		wantFailed bool
+		wantReset  bool
+	}{
+		{name: "Missing password", wantErr: ErrPasswordRequired},
+		{name: "Correct password", password: "s3cret", failure: repository.PasswordLock{Failures: 1}, wantFailed: true, wantReset: true},
+		{name: "Correct password at the limit", password: "s3cret", failure: repository.PasswordLock{LockedUntil: &lockedUntil}, wantFailed: true, wantReset: true},
+		{name: "Wrong password", password: "guess", failure: repository.PasswordLock{Failures: 1}, wantErr: ErrWrongPassword, wantFailed: true},
+		{name: "Wrong password locks", password: "guess", failure: repository.PasswordLock{LockedUntil: &lockedUntil}, wantErr: ErrPasswordLocked, wantFailed: true},
+		{name: "Locked", password: "s3cret", failureErr: repository.ErrPasswordLocked, wantErr: ErrPasswordLocked, wantFailed: true},
+		{name: "Database error", password: "s3cret", failureErr: errors.New("connection refused"), wantErr: ErrDatabaseWrite, wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failed, reset bool
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+					return &repository.URL{ShortCode: shortCode, LongURL: "https://example.com/doc", PasswordHash: string(hash)}, nil
+				},
+				RecordPasswordFailureFunc: func(ctx context.Context, shortCode string, maxFailures int, lockFor time.Duration) (repository.PasswordLock, error) {
+					if maxFailures != MaxPasswordFailures || lockFor != PasswordLockout {
+						t.Errorf("Expected a lockout after %d failures for %v, got %d for %v", MaxPasswordFailures, PasswordLockout, maxFailures, lockFor)
+					}
+					failed = true
+					return tt.failure, tt.failureErr
+				},
+				ResetPasswordFailuresFunc: func(ctx context.Context, shortCode string) error {
+					reset = true
+					return nil
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+			link, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{Password: tt.password})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && link.LongURL != "https://example.com/doc" {
+				t.Errorf("Expected the destination, got %+v", link)
+			}
+			if failed != tt.wantFailed || reset != tt.wantReset {
+				t.Errorf("Expected failure recorded=%v and reset=%v, got %v and %v", tt.wantFailed, tt.wantReset, failed, reset)
+			}
+		})
+	}
+}
+
+// TestUrlSvc_GetLongURL_Password_Concurrent guesses the password of a link
+// from many goroutines at once and checks that no more than
+// MaxPasswordFailures guesses are compared before the link locks.
+func TestUrlSvc_GetLongURL_Password_Concurrent(t *testing.T) {
+	const guesses = 20
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var lock repository.PasswordLock
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{ShortCode: shortCode, LongURL: "https://example.com/doc", PasswordHash: string(hash)}, nil
+		},
+		// Like the conditional UPDATE of the PostgreSQL repository.
+		RecordPasswordFailureFunc: func(ctx context.Context, shortCode string, maxFailures int, lockFor time.Duration) (repository.PasswordLock, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if lock.LockedUntil != nil && time.Now().Before(*lock.LockedUntil) {
+				return lock, repository.ErrPasswordLocked
+			}
+			if lock.Failures++; lock.Failures >= maxFailures {
+				lockedUntil := time.Now().Add(lockFor)
+				lock = repository.PasswordLock{LockedUntil: &lockedUntil}
+			}
+			return lock, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	var wg sync.WaitGroup
+	var wrong, locked atomic.Int32
+	start := make(chan struct{})
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{Password: "guess"})
+			switch {
+			case errors.Is(err, ErrWrongPassword):
+				wrong.Add(1)
+			case errors.Is(err, ErrPasswordLocked):
+				locked.Add(1)
+			default:
+				t.Errorf("Expected ErrWrongPassword or ErrPasswordLocked, got %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if wrong.Load() != MaxPasswordFailures-1 || locked.Load() != guesses-(MaxPasswordFailures-1) {
+		t.Errorf("Expected %d wrong passwords and %d refusals, got %d and %d", MaxPasswordFailures-1, guesses-(MaxPasswordFailures-1), wrong.Load(), locked.Load())
+	}
+	if _, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{Password: "s3cret"}); !errors.Is(err, ErrPasswordLocked) {
+		t.Errorf("Expected the correct password to be refused while locked, got %v", err)
+	}
+}
+
+func TestUrlSvc_ShortenURL_MaxClicks(t *testing.T) {
+	var stored *repository.URL
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			stored = u
+			return "abc", nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	if _, err := svc.ShortenURL(context.Background(), "https://example.com/invite", ShortenOptions{MaxClicks: 1}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stored.MaxClicks != 1 || stored.Canonical {
+		t.Errorf("Expected a non-canonical link limited to 1 click, got %+v", stored)
+	}
+	if _, err := svc.ShortenURL(context.Background(), "https://example.com/invite", ShortenOptions{MaxClicks: -1}); !errors.Is(err, ErrInvalidMaxClicks) {
+		t.Errorf("Expected ErrInvalidMaxClicks, got %v", err)
+	}
+}
+
+func TestUrlSvc_GetLongURL_MaxClicks_Redirects(t *testing.T) {
+	tests := []struct {
+		name         string
+		interstitial bool
+		opts         ResolveOptions
+		wantConsumed bool
+	}{
+		{name: "Redirect", wantConsumed: true},
+		{name: "Lookup only", opts: ResolveOptions{LookupOnly: true}},
+		{name: "Warning page", interstitial: true},
+		{name: "Confirmed warning page", interstitial: true, opts: ResolveOptions{Confirmed: true}, wantConsumed: true},
+		{name: "Lookup only of flagged link", interstitial: true, opts: ResolveOptions{LookupOnly: true, Confirmed: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var consumed bool
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+					return &repository.URL{ShortCode: shortCode, LongURL: "https://example.com/invite", Interstitial: tt.interstitial, MaxClicks: 1, ClicksRemaining: 1}, nil
+				},
+				ConsumeClickFunc: func(ctx context.Context, shortCode string) (int, error) {
+					consumed = true
+					return 0, nil
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+			link, err := svc.GetLongURL(context.Background(), "abc", tt.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if consumed != tt.wantConsumed {
+				t.Errorf("Expected click used up=%v, got %v", tt.wantConsumed, consumed)
+			}
+			// The destination is only revealed by using up a click.
+			if (link.LongURL != "") != tt.wantConsumed {
+				t.Errorf("Expected destination revealed=%v, got %q", tt.wantConsumed, link.LongURL)
+			}
+		})
+	}
+}
+
+// TestUrlSvc_MaxClicks_Exhausted checks that a link limited to one click
+// cannot be read without using it up, nor at all once it is used up.
+func TestUrlSvc_MaxClicks_Exhausted(t *testing.T) {
+	fetchedAt := time.Now()
+	u := &repository.URL{ShortCode: "abc", LongURL: "https://example.com/invite", MaxClicks: 1, ClicksRemaining: 1,
+		Page: repository.PageInfo{Title: "Invite", FetchedAt: &fetchedAt}}
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			copied := *u
+			return &copied, nil
+		},
+		ConsumeClickFunc: func(ctx context.Context, shortCode string) (int, error) {
+			if u.ClicksRemaining <= 0 {
+				return 0, repository.ErrNoClicksLeft
+			}
+			u.ClicksRemaining--
+			return u.ClicksRemaining, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	lookup, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{LookupOnly: true})
+	if err != nil || lookup.LongURL != "" || lookup.Page.Title != "" {
+		t.Fatalf("Expected a lookup without the destination, got %+v and %v", lookup, err)
+	}
+	preview, err := svc.PreviewURL(context.Background(), "abc", ResolveOptions{})
+	if err != nil || preview.LongURL != "" || preview.Page.Title != "" {
+		t.Fatalf("Expected a preview without the destination, got %+v and %v", preview, err)
+	}
+	if link, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{}); err != nil || link.LongURL != u.LongURL {
+		t.Fatalf("Expected the redirect to reveal the destination, got %+v and %v", link, err)
+	}
+
+	if _, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{LookupOnly: true}); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Expected a lookup of the used up link to fail with ErrExhausted, got %v", err)
+	}
+	if _, err := svc.PreviewURL(context.Background(), "abc", ResolveOptions{}); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Expected a preview of the used up link to fail with ErrExhausted, got %v", err)
+	}
+}
+
+// TestUrlSvc_GetLongURL_MaxClicks_Concurrent resolves a link limited to a few
+// clicks from many goroutines at once, through a cache that keeps serving the
+// record with its initial count, and checks that exactly that many resolves
+// succeed.
+func TestUrlSvc_GetLongURL_MaxClicks_Concurrent(t *testing.T) {
+	const maxClicks, resolves = 5, 50
+
+	var mu sync.Mutex
+	u := &repository.URL{ShortCode: "abc", LongURL: "https://example.com/invite", MaxClicks: maxClicks, ClicksRemaining: maxClicks}
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			copied := *u
+			return &copied, nil
+		},
+		// Like the conditional UPDATE of the PostgreSQL repository.
+		ConsumeClickFunc: func(ctx context.Context, shortCode string) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if u.ClicksRemaining <= 0 {
+				return 0, repository.ErrNoClicksLeft
+			}
+			u.ClicksRemaining--
+			return u.ClicksRemaining, nil
+		},
+	}
+	repo := repository.NewCachedRepository(mockRepo, cache.NewLRU(10), time.Minute, 0)
+	svc := NewUrlSvc(repo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	// Previews do not count, since they leave the destination out.
+	if link, err := svc.PreviewURL(context.Background(), "abc", ResolveOptions{}); err != nil || link.LongURL != "" {
+		t.Fatalf("Expected a preview without the destination, got %+v and %v", link, err)
+	}
+
+	var wg sync.WaitGroup
+	var succeeded, exhausted atomic.Int32
+	start := make(chan struct{})
+	for i := 0; i < resolves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := svc.GetLongURL(context.Background(), "abc", ResolveOptions{})
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case errors.Is(err, ErrExhausted):
+				exhausted.Add(1)
+			default:
+				t.Errorf("Expected ErrExhausted, got %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if succeeded.Load() != maxClicks || exhausted.Load() != resolves-maxClicks {
+		t.Errorf("Expected %d resolves and %d refusals, got %d and %d", maxClicks, resolves-maxClicks, succeeded.Load(), exhausted.Load())
+	}
+	if _, err := svc.PreviewURL(context.Background(), "abc", ResolveOptions{}); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Expected a used up link to stay used up, got %v", err)
+	}
+}
+
+func TestUrlSvc_SetRules(t *testing.T) {
+	blockEvil := policyFunc(func(ctx context.Context, u *url.URL) error {
+		if u.Hostname() == "evil.com" {
+			return &policy.Violation{Reason: "destination domain is blocked"}
+		}
+		return nil
+	})
+	tooMany := make([]repository.Rule, rules.MaxRules+1)
+	for i := range tooMany {
+		tooMany[i] = repository.Rule{Platforms: []string{"ios"}, LongURL: "https://example.com"}
+	}
+
+	tests := []struct {
+		name      string
+		rules     []repository.Rule
+		repoErr   error
+		wantErr   error
+		wantRules []repository.Rule
+	}{
+		{
+			name:      "Success",
+			rules:     []repository.Rule{{Platforms: []string{"iOS"}, Countries: []string{"us"}, LongURL: "HTTPS://Apps.Apple.com/app/id1"}},
+			wantRules: []repository.Rule{{Platforms: []string{"ios"}, Countries: []string{"US"}, LongURL: "https://apps.apple.com/app/id1"}},
+		},
+		{name: "Remove", wantRules: []repository.Rule{}},
+		{name: "Too Many", rules: tooMany, wantErr: ErrInvalidRules},
+		{name: "No Condition", rules: []repository.Rule{{LongURL: "https://example.com"}}, wantErr: ErrInvalidRules},
+		{name: "Invalid Destination", rules: []repository.Rule{{Platforms: []string{"ios"}, LongURL: "ftp://example.com"}}, wantErr: ErrInvalidRules},
+		{name: "Blocked Destination", rules: []repository.Rule{{Platforms: []string{"ios"}, LongURL: "https://evil.com"}}, wantErr: ErrBlockedURL},
+		{name: "Database error", repoErr: errors.New("connection refused"), wantErr: ErrDatabaseWrite, wantRules: []repository.Rule{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored []repository.Rule
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: ownedBy("acme"),
+				SetRulesFunc: func(ctx context.Context, shortCode string, linkRules []repository.Rule) error {
+					stored = linkRules
+					return tt.repoErr
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, blockEvil, nil)
+			err := svc.SetRules(context.Background(), "acme", "abc", tt.rules)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantRules == nil {
+				if stored != nil {
+					t.Errorf("expected no rules to be stored, got %+v", stored)
+				}
+				return
+			}
+			if fmt.Sprint(stored) != fmt.Sprint(tt.wantRules) {
+				t.Errorf("expected %+v to be stored, got %+v", tt.wantRules, stored)
+			}
+		})
+	}
+
+	// The error says which rule is invalid
+	svc := NewUrlSvc(&repository.MockRepo{GetURLByShortCodeFunc: ownedBy("acme")}, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	err := svc.SetRules(context.Background(), "acme", "abc", []repository.Rule{
+		{Platforms: []string{"ios"}, LongURL: "https://example.com"},
+		{Platforms: []string{"symbian"}, LongURL: "https://example.com"},
+	})
+	if err == nil || err.Error() != `invalid routing rules: rule 2: unknown platform "symbian"` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestUrlSvc_GetLongURL_Rules(t *testing.T) {
+	checkedAt := time.Now()
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{
+				LongURL:   "https://example.com/app",
+				ShortCode: shortCode,
+				Owner:     "acme",
+				Page:      repository.PageInfo{Title: "Our App"},
+				Health:    repository.HealthStatus{LastCheckedAt: &checkedAt, LastStatus: 200},
+				Rules: []repository.Rule{
+					{Platforms: []string{rules.PlatformIOS}, LongURL: "https://apps.apple.com/app/id1"},
+					{Platforms: []string{rules.PlatformAndroid}, LongURL: "https://evil.com/app.apk"},
+				},
+			}, nil
+		},
+	}
+	blockEvil := policyFunc(func(ctx context.Context, u *url.URL) error {
+		if u.Hostname() == "evil.com" {
+			return &policy.Violation{Reason: "destination domain is blocked"}
+		}
+		return nil
+	})
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, blockEvil, rules.NewEngine(nil))
+	ctx := context.Background()
+
+	// 1. Visitors matching a rule go to its destination
+	iPhone := rules.Visitor{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X)"}
+	link, err := svc.GetLongURL(ctx, "app", ResolveOptions{Visitor: iPhone})
+	if err != nil {
+		t.Fatalf("GetLongURL failed: %v", err)
+	}
+	if link.LongURL != "https://apps.apple.com/app/id1" || !link.Routed {
+		t.Errorf("expected the App Store destination, got %+v", link)
+	}
+	if link.Page.Title != "" || link.Health.LastCheckedAt != nil {
+		t.Errorf("expected no page or health of the link's own destination, got %+v", link)
+	}
+	if preview, _ := svc.PreviewURL(ctx, "app", ResolveOptions{Visitor: iPhone}); preview == nil || preview.LongURL != link.LongURL {
+		t.Errorf("expected the preview to show the routed destination, got %+v", preview)
+	}
+
+	// 2. Everyone else goes to the link's destination
+	link, err = svc.GetLongURL(ctx, "app", ResolveOptions{})
+	if err != nil {
+		t.Fatalf("GetLongURL failed: %v", err)
+	}
+	if link.LongURL != "https://example.com/app" || !link.Routed || link.Page.Title != "Our App" {
+		t.Errorf("expected the link's own destination, got %+v", link)
+	}
+
+	// 3. Routed destinations are rated against the current URL policy
+	link, err = svc.GetLongURL(ctx, "app", ResolveOptions{Visitor: rules.Visitor{UserAgent: "Mozilla/5.0 (Linux; Android 14)"}})
+	if err != nil {
+		t.Fatalf("GetLongURL failed: %v", err)
+	}
+	if link.Safety != SafetyBlocked {
+		t.Errorf("expected the routed destination to be blocked, got %+v", link)
+	}
+}
+
+func TestUrlSvc_SetVariants(t *testing.T) {
+	blockEvil := policyFunc(func(ctx context.Context, u *url.URL) error {
+		if u.Hostname() == "evil.com" {
+			return &policy.Violation{Reason: "destination domain is blocked"}
+		}
+		return nil
+	})
+	tooMany := make([]repository.Variant, MaxVariants+1)
+	for i := range tooMany {
+		tooMany[i] = repository.Variant{LongURL: "https://example.com", Weight: 1}
+	}
+
+	tests := []struct {
+		name         string
+		variants     []repository.Variant
+		repoErr      error
+		wantErr      string
+		wantVariants []repository.Variant
+	}{
+		{
+			name: "Success",
+			variants: []repository.Variant{
+				{LongURL: "HTTPS://Example.com/a", Weight: 70},
+				{Name: " control ", LongURL: "https://example.com/b", Weight: 30},
+				{LongURL: "https://example.com/c", Weight: 5},
+			},
+			wantVariants: []repository.Variant{
+				{Name: "A", LongURL: "https://example.com/a", Weight: 70},
+				{Name: "control", LongURL: "https://example.com/b", Weight: 30},
+				{Name: "C", LongURL: "https://example.com/c", Weight: 5},
+			},
+		},
+		{name: "Remove", wantVariants: []repository.Variant{}},
+		{name: "Single Variant", variants: tooMany[:1], wantErr: "a split needs 2 to 10 variants"},
+		{name: "Too Many", variants: tooMany, wantErr: "a split needs 2 to 10 variants"},
+		{
+			name:     "Zero Weight",
+			variants: []repository.Variant{{LongURL: "https://example.com/a", Weight: 1}, {LongURL: "https://example.com/b"}},
+			wantErr:  "variant 2: weight must be between 1 and 1000",
+		},
+		{
+			name:     "Invalid Name",
+			variants: []repository.Variant{{Name: "new design", LongURL: "https://example.com/a", Weight: 1}, {LongURL: "https://example.com/b", Weight: 1}},
+			wantErr:  `variant 1: invalid name "new design"`,
+		},
+		{
+			name:     "Duplicate Name",
+			variants: []repository.Variant{{Name: "B", LongURL: "https://example.com/a", Weight: 1}, {LongURL: "https://example.com/b", Weight: 1}},
+			wantErr:  `variant 2: duplicate name "B"`,
+		},
+		{
+			name:     "Invalid Destination",
+			variants: []repository.Variant{{LongURL: "https://example.com/a", Weight: 1}, {LongURL: "ftp://example.com", Weight: 1}},
+			wantErr:  "variant 2: invalid URL",
+		},
+		{
+			name:     "Blocked Destination",
+			variants: []repository.Variant{{LongURL: "https://example.com/a", Weight: 1}, {LongURL: "https://evil.com", Weight: 1}},
+			wantErr:  ErrBlockedURL.Error(),
+		},
+		{name: "Database error", repoErr: errors.New("connection refused"), wantErr: ErrDatabaseWrite.Error(), wantVariants: []repository.Variant{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored []repository.Variant
+			mockRepo := &repository.MockRepo{
+				GetURLByShortCodeFunc: ownedBy("acme"),
+				SetVariantsFunc: func(ctx context.Context, shortCode string, variants []repository.Variant) error {
+					stored = variants
+					return tt.repoErr
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, blockEvil, nil)
+			err := svc.SetVariants(context.Background(), "acme", "abc", tt.variants)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+			if tt.wantVariants == nil {
+				if stored != nil {
+					t.Errorf("expected no variants to be stored, got %+v", stored)
+				}
+				return
+			}
+			if fmt.Sprint(stored) != fmt.Sprint(tt.wantVariants) {
+				t.Errorf("expected %+v to be stored, got %+v", tt.wantVariants, stored)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_GetLongURL_Variants(t *testing.T) {
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{
+				LongURL:   "https://example.com/landing",
+				ShortCode: shortCode,
+				Page:      repository.PageInfo{Title: "Landing"},
+				Rules:     []repository.Rule{{Platforms: []string{rules.PlatformIOS}, LongURL: "https://apps.apple.com/app/id1"}},
+				Variants: []repository.Variant{
+					{Name: "A", LongURL: "https://example.com/a", Weight: 70},
+					{Name: "B", LongURL: "https://example.com/b", Weight: 30},
+				},
+			}, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	ctx := context.Background()
+
+	// 1. Visitors are split by weight, and keep their variant
+	counts := map[string]int{}
+	for i := range 1000 {
+		opts := ResolveOptions{VisitorID: fmt.Sprintf("visitor-%d", i)}
+		link, err := svc.GetLongURL(ctx, "ab", opts)
+		if err != nil {
+			t.Fatalf("GetLongURL failed: %v", err)
+		}
+		if link.LongURL != "https://example.com/"+strings.ToLower(link.Variant) || !link.Routed || link.Page.Title != "" {
+			t.Fatalf("expected a variant destination, got %+v", link)
+		}
+		if again, _ := svc.GetLongURL(ctx, "ab", opts); again.Variant != link.Variant {
+			t.Fatalf("expected visitor %d to keep variant %s, got %s", i, link.Variant, again.Variant)
+		}
+		counts[link.Variant]++
+	}
+	if counts["A"] < 620 || counts["A"] > 780 || counts["A"]+counts["B"] != 1000 {
+		t.Errorf("expected about a 70/30 split, got %v", counts)
+	}
+
+	// 2. Routing rules come first
+	link, err := svc.GetLongURL(ctx, "ab", ResolveOptions{Visitor: rules.Visitor{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X)"}})
+	if err != nil {
+		t.Fatalf("GetLongURL failed: %v", err)
+	}
+	if link.LongURL != "https://apps.apple.com/app/id1" || link.Variant != "" {
+		t.Errorf("expected the routed destination without a variant, got %+v", link)
+	}
+
+	// 3. Visitors without an ID still get a variant
+	if link, _ := svc.GetLongURL(ctx, "ab", ResolveOptions{}); link == nil || link.Variant == "" {
+		t.Errorf("expected a variant, got %+v", link)
+	}
+}
+
+func TestUrlSvc_ShortenURL_RedirectType(t *testing.T) {
+	tests := []struct {
+		name          string
+		redirectType  int
+		wantErr       error
+		wantStored    int
+		wantCanonical bool
+	}{
+		{name: "Default", redirectType: 0, wantStored: 302, wantCanonical: true},
+		{name: "Explicit default", redirectType: 302, wantStored: 302, wantCanonical: true},
+		{name: "Moved permanently", redirectType: 301, wantStored: 301},
+		{name: "Temporary redirect", redirectType: 307, wantStored: 307},
+		{name: "Permanent redirect", redirectType: 308, wantStored: 308},
+		{name: "See other", redirectType: 303, wantErr: ErrInvalidRedirectType},
+		{name: "Not a redirect", redirectType: 200, wantErr: ErrInvalidRedirectType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *repository.URL
+			mockRepo := &repository.MockRepo{
+				CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+					created = u
+					return "abc", nil
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+			_, err := svc.ShortenURL(context.Background(), "https://example.com", ShortenOptions{RedirectType: tt.redirectType})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if created.RedirectType != tt.wantStored || created.Canonical != tt.wantCanonical {
+				t.Errorf("Expected redirect type %d, canonical=%v, got %+v", tt.wantStored, tt.wantCanonical, created)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_GetLongURL_RedirectType(t *testing.T) {
+	stored := map[string]int{"perm": 308, "old": 0}
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			return &repository.URL{LongURL: "https://example.com", ShortCode: shortCode, RedirectType: stored[shortCode]}, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	if link, err := svc.GetLongURL(context.Background(), "perm", ResolveOptions{}); err != nil || link.RedirectType != 308 {
+		t.Errorf("Expected redirect type 308, got %+v, err=%v", link, err)
+	}
+	// Records without a redirect type, such as cache entries written before
+	// the setting existed, use the default
+	if link, err := svc.GetLongURL(context.Background(), "old", ResolveOptions{}); err != nil || link.RedirectType != DefaultRedirectType {
+		t.Errorf("Expected the default redirect type, got %+v, err=%v", link, err)
+	}
+}
+
+func TestUrlSvc_UpdateSettings_InvalidRedirectType(t *testing.T) {
+	mockRepo := &repository.MockRepo{
+		GetURLByShortCodeFunc: ownedBy("acme"),
+		UpdateSettingsFunc: func(ctx context.Context, shortCode string, settings repository.LinkSettings) error {
+			t.Error("invalid settings should not be stored")
+			return nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+	redirectType := 304
+	err := svc.UpdateSettings(context.Background(), "acme", "abc", repository.LinkSettings{RedirectType: &redirectType})
+	if !errors.Is(err, ErrInvalidRedirectType) {
+		t.Errorf("Expected ErrInvalidRedirectType, got %v", err)
+	}
+}
+
+func TestUrlSvc_ShortenURL_Metadata(t *testing.T) {
+	longTitle := strings.Repeat("t", MaxTitleLength+1)
+	manyTags := make([]string, MaxTags+1)
+	for i := range manyTags {
+		manyTags[i] = fmt.Sprintf("tag%d", i)
+	}
+	tests := []struct {
+		name      string
+		opts      ShortenOptions
+		wantErr   error
+		wantTitle string
+		wantTags  []string
+	}{
+		{
+			name:      "Stored normalized",
+			opts:      ShortenOptions{Title: " Launch ", Description: "Launch post", Tags: []string{"Q3-Campaign", "blog", "q3-campaign"}},
+			wantTitle: "Launch",
+			wantTags:  []string{"q3-campaign", "blog"},
+		},
+		{name: "Title too long", opts: ShortenOptions{Title: longTitle}, wantErr: ErrInvalidMetadata},
+		{name: "Invalid tag", opts: ShortenOptions{Tags: []string{"two words"}}, wantErr: ErrInvalidMetadata},
+		{name: "Empty tag", opts: ShortenOptions{Tags: []string{" "}}, wantErr: ErrInvalidMetadata},
+		{name: "Too many tags", opts: ShortenOptions{Tags: manyTags}, wantErr: ErrInvalidMetadata},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *repository.URL
+			mockRepo := &repository.MockRepo{
+				CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+					created = u
+					return "abc", nil
+				},
+			}
+			svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+
+			_, err := svc.ShortenURL(context.Background(), "https://example.com", tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// Metadata describes this link, so it is not shared with other requests
+			if created.Title != tt.wantTitle || fmt.Sprint(created.Tags) != fmt.Sprint(tt.wantTags) || created.Canonical {
+				t.Errorf("Expected a non-canonical record titled %q with tags %v, got %+v", tt.wantTitle, tt.wantTags, created)
+			}
+		})
+	}
+}
+
+func TestUrlSvc_ListURLs(t *testing.T) {
+	var got repository.ListFilter
+	stored := 3
+	mockRepo := &repository.MockRepo{
+		ListURLsFunc: func(ctx context.Context, filter repository.ListFilter) ([]*repository.URL, error) {
+			got = filter
+			if filter.Tag == "broken" {
+				return nil, errors.New("connection reset")
+			}
+			var urls []*repository.URL
+			for id := int64(stored); id > 0 && len(urls) < filter.Limit; id-- {
+				urls = append(urls, &repository.URL{ID: id, ShortCode: fmt.Sprintf("c%d", id), Owner: filter.Owner})
+			}
+			return urls, nil
+		},
+	}
+	svc := NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil)
+	ctx := context.Background()
+
+	// Filters are normalized, and one extra record is fetched to detect a next page
+	page, err := svc.ListURLs(ctx, repository.ListFilter{Owner: "acme", Tag: " Q3-Campaign", Domain: "Example.COM ", Limit: 2})
+	if err != nil || len(page.URLs) != 2 || page.NextCursor != 2 {
+		t.Fatalf("Expected two links and cursor 2, got %+v, err=%v", page, err)
+	}
+	if want := (repository.ListFilter{Owner: "acme", Tag: "q3-campaign", Domain: "example.com", Limit: 3}); got != want {
+		t.Errorf("Expected filter %+v, got %+v", want, got)
+	}
+
+	// The last page has no cursor
+	page, err = svc.ListURLs(ctx, repository.ListFilter{Owner: "acme"})
+	if err != nil || len(page.URLs) != 3 || page.NextCursor != 0 {
+		t.Errorf("Expected all three links without a cursor, got %+v, err=%v", page, err)
+	}
+	if got.Limit != DefaultListLimit+1 {
+		t.Errorf("Expected the default limit, got %d", got.Limit)
+	}
+
+	if _, err := svc.ListURLs(ctx, repository.ListFilter{Limit: MaxListLimit + 1}); err != nil || got.Limit != MaxListLimit+1 {
+		t.Errorf("Expected the limit to be capped at %d, got %d, err=%v", MaxListLimit, got.Limit-1, err)
+	}
+	if _, err := svc.ListURLs(ctx, repository.ListFilter{Tag: "broken"}); !errors.Is(err, ErrDatabaseRead) {
+		t.Errorf("Expected ErrDatabaseRead, got %v", err)
+	}
+	now := time.Now()
+	if _, err := svc.ListURLs(ctx, repository.ListFilter{CreatedAfter: &now, CreatedBefore: &now}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expected ErrInvalidFilter, got %v", err)
+	}
+}
+
+// queueFunc adapts a function to [PageQueue].
+type queueFunc func(shortCode string)
+
+func (f queueFunc) Enqueue(shortCode string) { f(shortCode) }
+
+func TestUrlSvc_WithPageFetching(t *testing.T) {
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			if u.LongURL == "https://example.com/fail" {
+				return "", errors.New("connection refused")
+			}
+			return encode(1), nil
+		},
+		CreateURLsFunc: func(ctx context.Context, urls []*repository.URL, encode func(id int64) string) ([]repository.CreateResult, error) {
+			results := make([]repository.CreateResult, len(urls))
+			for i := range urls {
+				results[i].ShortCode = encode(int64(i + 2))
+			}
+			return results, nil
+		},
+		GetURLByShortCodeFunc: ownedBy("acme"),
+		UpdateLongURLFunc: func(ctx context.Context, shortCode, longURL, originalURL string) error {
+			return nil
+		},
+		UpdateSettingsFunc: func(ctx context.Context, shortCode string, settings repository.LinkSettings) error {
+			return nil
+		},
+	}
+	var queued []string
+	svc := WithPageFetching(NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil), queueFunc(func(shortCode string) {
+		queued = append(queued, shortCode)
+	}))
+	ctx := context.Background()
+
+	if _, err := svc.ShortenURL(ctx, "https://example.com/a", ShortenOptions{}); err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if _, err := svc.ShortenURL(ctx, "https://example.com/fail", ShortenOptions{}); err == nil {
+		t.Fatal("expected ShortenURL to fail")
+	}
+	if _, err := svc.ShortenURLs(ctx, []BatchItem{{LongURL: "https://example.com/b"}, {LongURL: "not a url"}, {LongURL: "https://example.com/c"}}); err != nil {
+		t.Fatalf("ShortenURLs failed: %v", err)
+	}
+	if err := svc.UpdateURL(ctx, "acme", "abc", "https://example.com/d"); err != nil {
+		t.Fatalf("UpdateURL failed: %v", err)
+	}
+	if err := svc.UpdateURL(ctx, "globex", "abc", "https://example.com/e"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another owner, got %v", err)
+	}
+	if err := svc.UpdateSettings(ctx, "acme", "abc", repository.LinkSettings{}); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	// Only links that were created or changed destination are queued
+	if want := "[1 2 3 abc]"; fmt.Sprint(queued) != want {
+		t.Errorf("expected %s to be queued, got %v", want, queued)
+	}
+}
+
+// recordingCounters records what is counted through [Counters].
+type recordingCounters struct {
+	shortened int
+	resolved  []string
+}
+
+func (c *recordingCounters) Shortened(n int)        { c.shortened += n }
+func (c *recordingCounters) Resolved(result string) { c.resolved = append(c.resolved, result) }
+
+func TestUrlSvc_WithCounters(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Hour)
+	mockRepo := &repository.MockRepo{
+		CreateURLFunc: func(ctx context.Context, u *repository.URL, encode func(id int64) string) (string, error) {
+			return encode(1), nil
+		},
+		CreateURLsFunc: func(ctx context.Context, urls []*repository.URL, encode func(id int64) string) ([]repository.CreateResult, error) {
+			results := make([]repository.CreateResult, len(urls))
+			for i := range urls {
+				results[i].ShortCode = encode(int64(i + 2))
+			}
+			return results, nil
+		},
+		GetURLByShortCodeFunc: func(ctx context.Context, shortCode string) (*repository.URL, error) {
+			switch shortCode {
+			case "miss":
+				return nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)
+			case "old":
+				return &repository.URL{ShortCode: shortCode, LongURL: "https://example.com", ExpiresAt: &expiredAt}, nil
+			case "down":
+				return nil, errors.New("connection refused")
+			}
+			return &repository.URL{ShortCode: shortCode, LongURL: "https://example.com"}, nil
+		},
+	}
+	counters := &recordingCounters{}
+	svc := WithCounters(NewUrlSvc(mockRepo, shortener.NewBase62Shortener(), Normalizer{}, nil, nil), counters)
+	ctx := context.Background()
+
+	_, _ = svc.ShortenURL(ctx, "https://example.com/a", ShortenOptions{})
+	_, _ = svc.ShortenURL(ctx, "not a url", ShortenOptions{})
+	_, _ = svc.ShortenURLs(ctx, []BatchItem{{LongURL: "https://example.com/b"}, {LongURL: "not a url"}, {LongURL: "https://example.com/c"}})
+	for _, code := range []string{"abc", "miss", "old", "down"} {
+		_, _ = svc.GetLongURL(ctx, code, ResolveOptions{})
+	}
+	_, _ = svc.PreviewURL(ctx, "abc", ResolveOptions{})
+
+	if counters.shortened != 3 {
+		t.Errorf("expected 3 links to be counted as shortened, got %d", counters.shortened)
+	}
+	if want := "[ok not_found expired error]"; fmt.Sprint(counters.resolved) != want {
+		t.Errorf("expected resolves %s, got %v", want, counters.resolved)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"zipit/internal/url/repository"
+)
+
+// MaxVariants is the most variants a link may split its visitors between,
+// and MaxVariantWeight the largest weight one of them may have.
+const (
+	MaxVariants      = 10
+	MaxVariantWeight = 1000
+)
+
+var validVariantName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// SetVariants implements [URLService]. Variant destinations are normalized
+// and checked against the URL policy like the link's own destination.
+func (svc *urlSvc) SetVariants(ctx context.Context, owner, shortCode string, variants []repository.Variant) error {
+	if len(variants) == 1 || len(variants) > MaxVariants {
+		return fmt.Errorf("%w: a split needs 2 to %d variants", ErrInvalidVariants, MaxVariants)
+	}
+	normalized := make([]repository.Variant, 0, len(variants))
+	seen := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		v.Name = strings.TrimSpace(v.Name)
+		if v.Name == "" {
+			v.Name = string(rune('A' + i))
+		}
+		if !validVariantName.MatchString(v.Name) {
+			return fmt.Errorf("%w: variant %d: invalid name %q", ErrInvalidVariants, i+1, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: variant %d: duplicate name %q", ErrInvalidVariants, i+1, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight < 1 || v.Weight > MaxVariantWeight {
+			return fmt.Errorf("%w: variant %d: weight must be between 1 and %d", ErrInvalidVariants, i+1, MaxVariantWeight)
+		}
+		var err error
+		if v.LongURL, err = svc.normalize(ctx, v.LongURL); err != nil {
+			if errors.Is(err, ErrInvalidURL) {
+				return fmt.Errorf("%w: variant %d: invalid URL", ErrInvalidVariants, i+1)
+			}
+			return err
+		}
+		normalized = append(normalized, v)
+	}
+
+	if _, err := svc.owned(ctx, owner, shortCode); err != nil {
+		return err
+	}
+	return writeErr(svc.repo.SetVariants(ctx, shortCode, normalized))
+}
+
+// GetVariants implements [URLService].
+func (svc *urlSvc) GetVariants(ctx context.Context, owner, shortCode string) ([]repository.Variant, error) {
+	u, err := svc.owned(ctx, owner, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if u.Variants == nil {
+		return []repository.Variant{}, nil
+	}
+	return u.Variants, nil
+}
+
+// pickVariant picks one of variants with a chance proportional to its
+// weight. A visitor with an ID always gets the same variant of a link for as
+// long as the variants stay the same; the short code goes into the hash so
+// that the visitor's variants of different links are independent.
+func pickVariant(variants []repository.Variant, shortCode, visitorID string) repository.Variant {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	var n int
+	if visitorID == "" {
+		n = rand.IntN(total)
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(shortCode))
+		h.Write([]byte{0})
+		h.Write([]byte(visitorID))
+		n = int(h.Sum64() % uint64(total))
+	}
+	for _, v := range variants {
+		if n < v.Weight {
+			return v
+		}
+		n -= v.Weight
+	}
+	return variants[len(variants)-1]
+}
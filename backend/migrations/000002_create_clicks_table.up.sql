@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Per-link reports always filter on the short code and usually on a time range
+CREATE INDEX IF NOT EXISTS idx_clicks_short_code_clicked_at ON clicks(short_code, clicked_at);
@@ -0,0 +1,2 @@
+-- Custom aliases are chosen by the caller instead of being derived from the row id
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_custom BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Links without an expiry stay valid forever
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
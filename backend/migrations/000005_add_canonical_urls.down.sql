@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_urls_canonical_long_url;
+ALTER TABLE urls DROP COLUMN IF EXISTS canonical;
+ALTER TABLE urls ALTER COLUMN short_code DROP NOT NULL;
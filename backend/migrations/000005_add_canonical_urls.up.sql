@@ -0,0 +1,14 @@
+-- Rows left without a short code by failed shorten requests can never be resolved
+DELETE FROM urls WHERE short_code IS NULL;
+ALTER TABLE urls ALTER COLUMN short_code SET NOT NULL;
+
+-- The canonical row for a destination is the one returned to every plain
+-- shorten request for it. Older duplicates keep their codes but are no longer
+-- handed out.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS canonical BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE urls SET canonical = TRUE WHERE id IN (
+    SELECT MIN(id) FROM urls WHERE NOT is_custom AND expires_at IS NULL GROUP BY long_url
+);
+
+-- Backs INSERT ... ON CONFLICT so concurrent requests cannot create duplicates
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_canonical_long_url ON urls(long_url) WHERE canonical;
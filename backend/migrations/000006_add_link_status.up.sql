@@ -0,0 +1,4 @@
+-- Disabled links answer 410 and can be re-enabled; deleted links answer 404.
+-- Rows are never removed so their short codes are never issued again.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
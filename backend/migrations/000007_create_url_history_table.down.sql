@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_url_history_short_code_changed_at;
+DROP TABLE IF EXISTS url_history;
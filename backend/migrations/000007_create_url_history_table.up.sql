@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS url_history (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL,
+    old_long_url TEXT NOT NULL,
+    new_long_url TEXT NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- History is always read per link, newest first
+CREATE INDEX IF NOT EXISTS idx_url_history_short_code_changed_at ON url_history(short_code, changed_at);
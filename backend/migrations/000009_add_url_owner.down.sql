@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_urls_canonical_owner_long_url;
+-- Only one owner's row per destination can stay canonical
+UPDATE urls SET canonical = FALSE WHERE canonical AND id NOT IN (
+    SELECT MIN(id) FROM urls WHERE canonical GROUP BY long_url
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_canonical_long_url ON urls(long_url) WHERE canonical;
+ALTER TABLE urls DROP COLUMN IF EXISTS owner;
@@ -0,0 +1,6 @@
+-- Links created without an API key have an empty owner
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+
+-- Deduplication is per owner, so every owner can manage the links it is handed
+DROP INDEX IF EXISTS idx_urls_canonical_long_url;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_canonical_owner_long_url ON urls(owner, long_url) WHERE canonical;
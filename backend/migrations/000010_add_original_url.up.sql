@@ -0,0 +1,4 @@
+-- long_url holds the normalized destination used for redirects and
+-- deduplication; original_url keeps the destination as it was submitted.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS original_url TEXT NOT NULL DEFAULT '';
+UPDATE urls SET original_url = long_url WHERE original_url = '';
@@ -0,0 +1,3 @@
+-- Interstitial links show a warning page with the destination before
+-- redirecting, for destinations their owner has flagged.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT FALSE;
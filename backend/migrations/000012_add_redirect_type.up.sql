@@ -0,0 +1,3 @@
+-- The HTTP status links redirect with: 301 or 308 for permanent links that
+-- search engines and browsers may cache, 302 or 307 for temporary ones.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_type SMALLINT NOT NULL DEFAULT 302;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_urls_tags;
+ALTER TABLE urls DROP COLUMN IF EXISTS tags;
+ALTER TABLE urls DROP COLUMN IF EXISTS description;
+ALTER TABLE urls DROP COLUMN IF EXISTS title;
@@ -0,0 +1,8 @@
+-- Optional details owners give their links to find them again: a title, a
+-- description and free-form tags, stored lower-case.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Links are searched by tag with tags @> ARRAY[...]
+CREATE INDEX IF NOT EXISTS idx_urls_tags ON urls USING GIN (tags);
@@ -0,0 +1,2 @@
+-- Owners page through their live links newest first, by id
+CREATE INDEX IF NOT EXISTS idx_urls_owner_id ON urls(owner, id DESC) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS page_fetched_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS page_status;
+ALTER TABLE urls DROP COLUMN IF EXISTS page_og_image;
+ALTER TABLE urls DROP COLUMN IF EXISTS page_og_title;
+ALTER TABLE urls DROP COLUMN IF EXISTS page_title;
@@ -0,0 +1,9 @@
+-- Details fetched from the destination page in the background: its <title>,
+-- og:title and og:image, and the status code of the final response.
+-- page_fetched_at is NULL until the page has been fetched, and is reset when
+-- the destination changes.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_title TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_og_title TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_og_image TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_fetched_at TIMESTAMP WITH TIME ZONE;
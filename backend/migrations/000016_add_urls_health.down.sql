@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_urls_last_checked_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS consecutive_failures;
+ALTER TABLE urls DROP COLUMN IF EXISTS last_status;
+ALTER TABLE urls DROP COLUMN IF EXISTS last_checked_at;
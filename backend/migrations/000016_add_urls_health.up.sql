@@ -0,0 +1,10 @@
+-- Periodic checks of link destinations: when the destination was last
+-- checked, the status code it answered with (0 without a response) and how
+-- many checks in a row have failed.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
+
+-- The checker claims the least recently checked live links first
+CREATE INDEX IF NOT EXISTS idx_urls_last_checked_at ON urls(last_checked_at NULLS FIRST, id)
+    WHERE deleted_at IS NULL AND disabled_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS password_locked_until;
+ALTER TABLE urls DROP COLUMN IF EXISTS password_failures;
+ALTER TABLE urls DROP COLUMN IF EXISTS password_hash;
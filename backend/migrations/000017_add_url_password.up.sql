@@ -0,0 +1,6 @@
+-- Optional password visitors must supply to follow a link, stored as a
+-- bcrypt hash ('' for open links), and the count of wrong passwords that
+-- locks the link against further attempts until password_locked_until.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_locked_until TIMESTAMP WITH TIME ZONE;
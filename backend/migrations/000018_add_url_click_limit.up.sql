@@ -0,0 +1,4 @@
+-- Links that stop working after max_clicks resolves (0 for no limit).
+-- clicks_remaining starts at max_clicks and is decremented on every resolve.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks_remaining INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS url_rules (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL,
+    position INTEGER NOT NULL,
+    platforms TEXT[] NOT NULL DEFAULT '{}',
+    languages TEXT[] NOT NULL DEFAULT '{}',
+    countries TEXT[] NOT NULL DEFAULT '{}',
+    long_url TEXT NOT NULL,
+    -- Rules are always read per link, in order, through this constraint's index
+    CONSTRAINT url_rules_short_code_position_key UNIQUE (short_code, position)
+);
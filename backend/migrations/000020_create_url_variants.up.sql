@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS url_variants (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL,
+    position INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    long_url TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight > 0),
+    -- Variants are always read per link, in order, through this constraint's index
+    CONSTRAINT url_variants_short_code_position_key UNIQUE (short_code, position),
+    CONSTRAINT url_variants_short_code_name_key UNIQUE (short_code, name)
+);
@@ -0,0 +1,2 @@
+-- The A/B variant of the link a click was sent to, or '' for links without variants
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,20 @@
+// Package alias defines the short codes links may not be created under,
+// because the gateway serves a route of its own at that path. The url
+// service checks them when links are stored, and the gateway before asking
+// it to.
+package alias
+
+// reserved are the first path segments of the gateway's own routes, and of
+// routes it has served or may serve, such as the metrics endpoint.
+var reserved = map[string]bool{
+	"health":  true,
+	"links":   true,
+	"metrics": true,
+	"shorten": true,
+}
+
+// Reserved reports whether code is taken by one of the gateway's routes, so
+// that a link created under it could never be followed.
+func Reserved(code string) bool {
+	return reserved[code]
+}
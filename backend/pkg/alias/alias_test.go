@@ -0,0 +1,25 @@
+package alias
+
+import "testing"
+
+func TestReserved(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"health", true},
+		{"links", true},
+		{"metrics", true},
+		{"shorten", true},
+		{"Links", false},
+		{"linkss", false},
+		{"abc123", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := Reserved(tt.code); got != tt.want {
+			t.Errorf("Reserved(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"zipit/pkg/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMiss is returned by Get when the key is not cached or has expired.
+var ErrMiss = errors.New("cache miss")
+
+// Cache is a key/value store whose entries expire after a per-entry TTL.
+// Add stores a value like Set, but only if the key is not cached yet, and
+// reports whether it did. Implementations must be safe for concurrent use,
+// and Add must be atomic.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewCache builds the Cache selected by cfg. It returns nil when caching is disabled.
+func NewCache(cfg *config.CacheConfig) (Cache, error) {
+	switch cfg.Backend {
+	case config.CacheNone:
+		return nil, nil
+	case config.CacheMemory:
+		return NewLRU(cfg.Size), nil
+	case config.CacheRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("no response received from redis: %w", err)
+		}
+		return NewRedis(client), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// lruEntry is a cached value together with its key, so evictions can remove it from the index.
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRU is an in-process Cache holding at most a fixed number of entries.
+// When full, the least recently used entry is evicted.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is the most recently used entry
+	items    map[string]*list.Element
+	now      func() time.Time
+}
+
+// NewLRU creates an LRU cache holding up to capacity entries.
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get implements [Cache].
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*lruEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, ErrMiss
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, nil
+}
+
+// Set implements [Cache].
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	if c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Add implements [Cache]. Expired entries count as not cached.
+func (c *LRU) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		if c.now().Before(elem.Value.(*lruEntry).expiresAt) {
+			return false, nil
+		}
+		c.remove(elem)
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: c.now().Add(ttl)})
+	if c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return true, nil
+}
+
+// Delete implements [Cache].
+func (c *LRU) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	return nil
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove drops elem from both the list and the index. The caller must hold mu.
+func (c *LRU) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
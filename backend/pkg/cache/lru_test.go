@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRU_GetSetDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("expected ErrMiss for an empty cache, got %v", err)
+	}
+
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	if got, err := c.Get(ctx, "a"); err != nil || string(got) != "1" {
+		t.Fatalf("expected 1, got %q, err=%v", got, err)
+	}
+
+	// Overwriting keeps a single entry
+	_ = c.Set(ctx, "a", []byte("2"), time.Minute)
+	if got, _ := c.Get(ctx, "a"); string(got) != "2" || c.Len() != 1 {
+		t.Errorf("expected a single entry with value 2, got %q and %d entries", got, c.Len())
+	}
+
+	_ = c.Delete(ctx, "a")
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected ErrMiss after Delete, got %v", err)
+	}
+}
+
+func TestLRU_Add(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	if added, err := c.Add(ctx, "a", []byte("1"), time.Minute); err != nil || !added {
+		t.Fatalf("expected Add to store a new key, got %v, err=%v", added, err)
+	}
+	if added, _ := c.Add(ctx, "a", []byte("2"), time.Minute); added {
+		t.Error("expected Add to keep a cached key")
+	}
+	if got, _ := c.Get(ctx, "a"); string(got) != "1" {
+		t.Errorf("expected 1, got %q", got)
+	}
+
+	now = now.Add(time.Minute)
+	if added, _ := c.Add(ctx, "a", []byte("3"), time.Minute); !added {
+		t.Error("expected Add to replace an expired key")
+	}
+	if got, _ := c.Get(ctx, "a"); string(got) != "3" || c.Len() != 1 {
+		t.Errorf("expected a single entry with value 3, got %q and %d entries", got, c.Len())
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+
+	now = now.Add(59 * time.Second)
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatalf("expected a hit before the TTL, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("expected ErrMiss once the TTL passed, got %v", err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected the expired entry to be dropped, got %d entries", c.Len())
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = c.Set(ctx, "b", []byte("2"), time.Minute)
+	_, _ = c.Get(ctx, "a") // "b" is now the least recently used
+	_ = c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected b to be evicted, got %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("expected %s to be cached, got %v", key, err)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+}
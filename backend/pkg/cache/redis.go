@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces our keys so the Redis instance can be shared with other applications.
+const keyPrefix = "zipit:"
+
+// Redis is a Cache backed by a Redis server, shared by every service instance.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis creates a Cache that stores entries through client.
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+// Get implements [Cache].
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, keyPrefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrMiss
+		}
+		return nil, fmt.Errorf("failed to read %q from redis: %w", key, err)
+	}
+	return value, nil
+}
+
+// Set implements [Cache].
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.client.Set(ctx, keyPrefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to write %q to redis: %w", key, err)
+	}
+	return nil
+}
+
+// Add implements [Cache].
+func (c *Redis) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	added, err := c.client.SetNX(ctx, keyPrefix+key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to write %q to redis: %w", key, err)
+	}
+	return added, nil
+}
+
+// Delete implements [Cache].
+func (c *Redis) Delete(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, keyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to delete %q from redis: %w", key, err)
+	}
+	return nil
+}
+
+// Close releases the connections to the Redis server.
+func (c *Redis) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"zipit/pkg/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedis_GetSetDelete(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	c := NewRedis(redis.NewClient(&redis.Options{Addr: srv.Addr()}))
+	defer c.Close()
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("expected ErrMiss for an empty cache, got %v", err)
+	}
+
+	if err := c.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, err := c.Get(ctx, "a"); err != nil || string(got) != "1" {
+		t.Fatalf("expected 1, got %q, err=%v", got, err)
+	}
+
+	// Keys are namespaced
+	if !srv.Exists("zipit:a") {
+		t.Errorf("expected key zipit:a to exist, got keys %v", srv.Keys())
+	}
+
+	// Empty values are valid entries, not misses
+	_ = c.Set(ctx, "empty", []byte{}, time.Minute)
+	if got, err := c.Get(ctx, "empty"); err != nil || len(got) != 0 {
+		t.Errorf("expected an empty hit, got %q, err=%v", got, err)
+	}
+
+	if err := c.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected ErrMiss after Delete, got %v", err)
+	}
+}
+
+func TestRedis_Add(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	c := NewRedis(redis.NewClient(&redis.Options{Addr: srv.Addr()}))
+	defer c.Close()
+
+	if added, err := c.Add(ctx, "a", []byte("1"), time.Minute); err != nil || !added {
+		t.Fatalf("expected Add to store a new key, got %v, err=%v", added, err)
+	}
+	if added, _ := c.Add(ctx, "a", []byte("2"), time.Minute); added {
+		t.Error("expected Add to keep a cached key")
+	}
+	if got, _ := c.Get(ctx, "a"); string(got) != "1" {
+		t.Errorf("expected 1, got %q", got)
+	}
+
+	srv.FastForward(time.Minute)
+	if added, _ := c.Add(ctx, "a", []byte("3"), time.Minute); !added {
+		t.Error("expected Add to replace an expired key")
+	}
+}
+
+func TestRedis_Expiry(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	c := NewRedis(redis.NewClient(&redis.Options{Addr: srv.Addr()}))
+	defer c.Close()
+
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	srv.FastForward(time.Minute)
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected ErrMiss once the TTL passed, got %v", err)
+	}
+}
+
+func TestRedis_ServerDown(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	c := NewRedis(redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1}))
+	defer c.Close()
+	srv.Close()
+
+	if _, err := c.Get(ctx, "a"); err == nil || errors.Is(err, ErrMiss) {
+		t.Errorf("expected a connection error, got %v", err)
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	srv := miniredis.RunT(t)
+
+	tests := []struct {
+		name    string
+		cfg     *config.CacheConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "None", cfg: &config.CacheConfig{Backend: config.CacheNone}, wantNil: true},
+		{name: "Memory", cfg: &config.CacheConfig{Backend: config.CacheMemory, Size: 10}},
+		{name: "Redis", cfg: &config.CacheConfig{Backend: config.CacheRedis, RedisAddr: srv.Addr()}},
+		{name: "Unreachable redis", cfg: &config.CacheConfig{Backend: config.CacheRedis, RedisAddr: "127.0.0.1:1"}, wantErr: true},
+		{name: "Unknown", cfg: &config.CacheConfig{Backend: "memcached"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCache(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (c == nil) != tt.wantNil {
+				t.Errorf("NewCache() = %v, wantNil %v", c, tt.wantNil)
+			}
+		})
+	}
+}
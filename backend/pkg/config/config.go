@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 /*Defines a Struct to hold DB connection params*/
@@ -66,3 +69,383 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// Supported short code generators.
+const (
+	ShortenerBase62  = "base62"
+	ShortenerFeistel = "feistel"
+	ShortenerRandom  = "random"
+)
+
+/*Defines a Struct to hold short code generator settings*/
+type ShortenerConfig struct {
+	Kind   string
+	Key    string
+	Length int
+}
+
+func NewShortenerConfig() (*ShortenerConfig, error) {
+	kind := getEnvOrDefault("SHORTENER", ShortenerBase62)
+	cfg := &ShortenerConfig{Kind: kind}
+
+	switch kind {
+	case ShortenerBase62:
+	case ShortenerFeistel:
+		cfg.Key = os.Getenv("SHORTENER_KEY")
+		if len(cfg.Key) < 16 {
+			return nil, fmt.Errorf("SHORTENER_KEY of at least 16 characters is required for the feistel shortener")
+		}
+	case ShortenerRandom:
+		length, err := strconv.Atoi(getEnvOrDefault("SHORTENER_LENGTH", "8"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHORTENER_LENGTH: %v", err)
+		}
+		// Codes must fit the 12 character limit enforced by the gateway.
+		if length < 4 || length > 12 {
+			return nil, fmt.Errorf("SHORTENER_LENGTH must be between 4 and 12, got %d", length)
+		}
+		cfg.Length = length
+	default:
+		return nil, fmt.Errorf("unknown SHORTENER %q", kind)
+	}
+	return cfg, nil
+}
+
+// Supported cache backends for resolved links.
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+/*Defines a Struct to hold link cache settings*/
+type CacheConfig struct {
+	Backend       string
+	Size          int
+	TTL           time.Duration
+	NegativeTTL   time.Duration
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+}
+
+func NewCacheConfig() (*CacheConfig, error) {
+	backend := getEnvOrDefault("CACHE", CacheMemory)
+	cfg := &CacheConfig{Backend: backend}
+
+	// Writes only evict a record from the cache of the instance that made
+	// them, so records in memory are kept briefly to bound how long other
+	// instances serve a changed or deleted link.
+	defaultTTL := "10m"
+	switch backend {
+	case CacheNone:
+		return cfg, nil
+	case CacheMemory:
+		size, err := strconv.Atoi(getEnvOrDefault("CACHE_SIZE", "10000"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CACHE_SIZE: %v", err)
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("CACHE_SIZE must be positive, got %d", size)
+		}
+		cfg.Size = size
+		defaultTTL = "10s"
+	case CacheRedis:
+		db, err := strconv.Atoi(getEnvOrDefault("REDIS_DB", "0"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_DB: %v", err)
+		}
+		cfg.RedisAddr = getEnvOrDefault("REDIS_ADDR", "localhost:6379")
+		cfg.RedisPassword = os.Getenv("REDIS_PASSWORD")
+		cfg.RedisDB = db
+	default:
+		return nil, fmt.Errorf("unknown CACHE %q", backend)
+	}
+
+	var err error
+	if cfg.TTL, err = time.ParseDuration(getEnvOrDefault("CACHE_TTL", defaultTTL)); err != nil || cfg.TTL <= 0 {
+		return nil, fmt.Errorf("invalid CACHE_TTL: must be a positive duration")
+	}
+	// Unknown codes are cached briefly so a new alias becomes visible quickly
+	// on other instances.
+	if cfg.NegativeTTL, err = time.ParseDuration(getEnvOrDefault("CACHE_NEGATIVE_TTL", "30s")); err != nil || cfg.NegativeTTL < 0 {
+		return nil, fmt.Errorf("invalid CACHE_NEGATIVE_TTL: must be a duration of at least 0")
+	}
+	return cfg, nil
+}
+
+// Supported rate limit stores.
+const (
+	RateLimitNone   = "none"
+	RateLimitMemory = "memory"
+	RateLimitRedis  = "redis"
+)
+
+/*Defines a Struct to hold gateway rate limit settings. Rates are in requests per second.*/
+type RateLimitConfig struct {
+	Store         string
+	ShortenRate   float64
+	ShortenBurst  int
+	ResolveRate   float64
+	ResolveBurst  int
+	AuthRate      float64
+	AuthBurst     int
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+}
+
+func NewRateLimitConfig() (*RateLimitConfig, error) {
+	store := getEnvOrDefault("RATE_LIMIT", RateLimitMemory)
+	cfg := &RateLimitConfig{Store: store}
+
+	switch store {
+	case RateLimitNone:
+		return cfg, nil
+	case RateLimitMemory:
+	case RateLimitRedis:
+		db, err := strconv.Atoi(getEnvOrDefault("REDIS_DB", "0"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_DB: %v", err)
+		}
+		cfg.RedisAddr = getEnvOrDefault("REDIS_ADDR", "localhost:6379")
+		cfg.RedisPassword = os.Getenv("REDIS_PASSWORD")
+		cfg.RedisDB = db
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT %q", store)
+	}
+
+	var err error
+	if cfg.ShortenRate, cfg.ShortenBurst, err = rateLimit("SHORTEN", "30", "10"); err != nil {
+		return nil, err
+	}
+	if cfg.ResolveRate, cfg.ResolveBurst, err = rateLimit("RESOLVE", "600", "100"); err != nil {
+		return nil, err
+	}
+	if cfg.AuthRate, cfg.AuthBurst, err = rateLimit("AUTH", "120", "30"); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// rateLimit reads RATE_LIMIT_<name>_PER_MINUTE and RATE_LIMIT_<name>_BURST,
+// returning the rate in requests per second.
+func rateLimit(name, defaultPerMinute, defaultBurst string) (float64, int, error) {
+	perMinute, err := strconv.ParseFloat(getEnvOrDefault("RATE_LIMIT_"+name+"_PER_MINUTE", defaultPerMinute), 64)
+	if err != nil || perMinute <= 0 {
+		return 0, 0, fmt.Errorf("invalid RATE_LIMIT_%s_PER_MINUTE: must be a positive number", name)
+	}
+	burst, err := strconv.Atoi(getEnvOrDefault("RATE_LIMIT_"+name+"_BURST", defaultBurst))
+	if err != nil || burst < 1 {
+		return 0, 0, fmt.Errorf("invalid RATE_LIMIT_%s_BURST: must be at least 1", name)
+	}
+	return perMinute / 60, burst, nil
+}
+
+/*Defines a Struct to hold the optional URL normalization rules*/
+type NormalizeConfig struct {
+	SortQuery     bool
+	StripTracking bool
+}
+
+func NewNormalizeConfig() (*NormalizeConfig, error) {
+	sortQuery, err := strconv.ParseBool(getEnvOrDefault("URL_SORT_QUERY", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL_SORT_QUERY: %v", err)
+	}
+	stripTracking, err := strconv.ParseBool(getEnvOrDefault("URL_STRIP_TRACKING", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL_STRIP_TRACKING: %v", err)
+	}
+	return &NormalizeConfig{SortQuery: sortQuery, StripTracking: stripTracking}, nil
+}
+
+/*Defines a Struct to hold URL policy settings*/
+type PolicyConfig struct {
+	BlocklistFile     string
+	AllowlistFile     string
+	ReloadInterval    time.Duration
+	AllowPrivateHosts bool
+	ShortDomains      []string
+}
+
+func NewPolicyConfig() (*PolicyConfig, error) {
+	cfg := &PolicyConfig{
+		BlocklistFile: os.Getenv("URL_BLOCKLIST_FILE"),
+		AllowlistFile: os.Getenv("URL_ALLOWLIST_FILE"),
+	}
+
+	var err error
+	if cfg.ReloadInterval, err = time.ParseDuration(getEnvOrDefault("URL_LISTS_RELOAD_INTERVAL", "30s")); err != nil || cfg.ReloadInterval <= 0 {
+		return nil, fmt.Errorf("invalid URL_LISTS_RELOAD_INTERVAL: must be a positive duration")
+	}
+	if cfg.AllowPrivateHosts, err = strconv.ParseBool(getEnvOrDefault("URL_ALLOW_PRIVATE_HOSTS", "false")); err != nil {
+		return nil, fmt.Errorf("invalid URL_ALLOW_PRIVATE_HOSTS: %v", err)
+	}
+	// Our own domains, so links cannot point at other short links
+	for _, domain := range strings.Split(os.Getenv("SHORT_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			cfg.ShortDomains = append(cfg.ShortDomains, domain)
+		}
+	}
+	return cfg, nil
+}
+
+/*Defines a Struct to hold settings for fetching the destination pages of new links*/
+type PageFetchConfig struct {
+	Enabled   bool
+	Workers   int
+	QueueSize int
+	Timeout   time.Duration
+	MaxBytes  int64
+	UserAgent string
+}
+
+func NewPageFetchConfig() (*PageFetchConfig, error) {
+	cfg := &PageFetchConfig{UserAgent: getEnvOrDefault("PAGE_FETCH_USER_AGENT", "zipit-link-preview/1.0")}
+
+	var err error
+	if cfg.Enabled, err = strconv.ParseBool(getEnvOrDefault("PAGE_FETCH_ENABLED", "true")); err != nil {
+		return nil, fmt.Errorf("invalid PAGE_FETCH_ENABLED: %v", err)
+	}
+	if cfg.Workers, err = strconv.Atoi(getEnvOrDefault("PAGE_FETCH_WORKERS", "4")); err != nil || cfg.Workers < 1 {
+		return nil, fmt.Errorf("invalid PAGE_FETCH_WORKERS: must be at least 1")
+	}
+	if cfg.QueueSize, err = strconv.Atoi(getEnvOrDefault("PAGE_FETCH_QUEUE_SIZE", "1000")); err != nil || cfg.QueueSize < 1 {
+		return nil, fmt.Errorf("invalid PAGE_FETCH_QUEUE_SIZE: must be at least 1")
+	}
+	if cfg.Timeout, err = time.ParseDuration(getEnvOrDefault("PAGE_FETCH_TIMEOUT", "10s")); err != nil || cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid PAGE_FETCH_TIMEOUT: must be a positive duration")
+	}
+	if cfg.MaxBytes, err = strconv.ParseInt(getEnvOrDefault("PAGE_FETCH_MAX_BYTES", "1048576"), 10, 64); err != nil || cfg.MaxBytes < 1 {
+		return nil, fmt.Errorf("invalid PAGE_FETCH_MAX_BYTES: must be at least 1")
+	}
+	return cfg, nil
+}
+
+/*Defines a Struct to hold settings for the periodic checks of link destinations*/
+type HealthCheckConfig struct {
+	Enabled      bool
+	Interval     time.Duration
+	RecheckAfter time.Duration
+	BatchSize    int
+	Workers      int
+	Timeout      time.Duration
+	// DisableAfter disables links after this many failed checks in a row; 0 never does.
+	DisableAfter int
+}
+
+func NewHealthCheckConfig() (*HealthCheckConfig, error) {
+	cfg := &HealthCheckConfig{}
+
+	var err error
+	if cfg.Enabled, err = strconv.ParseBool(getEnvOrDefault("HEALTH_CHECK_ENABLED", "true")); err != nil {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_ENABLED: %v", err)
+	}
+	if cfg.Interval, err = time.ParseDuration(getEnvOrDefault("HEALTH_CHECK_INTERVAL", "1m")); err != nil || cfg.Interval <= 0 {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_INTERVAL: must be a positive duration")
+	}
+	if cfg.RecheckAfter, err = time.ParseDuration(getEnvOrDefault("HEALTH_CHECK_RECHECK_AFTER", "24h")); err != nil || cfg.RecheckAfter <= 0 {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_RECHECK_AFTER: must be a positive duration")
+	}
+	if cfg.BatchSize, err = strconv.Atoi(getEnvOrDefault("HEALTH_CHECK_BATCH_SIZE", "100")); err != nil || cfg.BatchSize < 1 {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_BATCH_SIZE: must be at least 1")
+	}
+	if cfg.Workers, err = strconv.Atoi(getEnvOrDefault("HEALTH_CHECK_WORKERS", "4")); err != nil || cfg.Workers < 1 {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_WORKERS: must be at least 1")
+	}
+	if cfg.Timeout, err = time.ParseDuration(getEnvOrDefault("HEALTH_CHECK_TIMEOUT", "10s")); err != nil || cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_TIMEOUT: must be a positive duration")
+	}
+	if cfg.DisableAfter, err = strconv.Atoi(getEnvOrDefault("HEALTH_CHECK_DISABLE_AFTER", "0")); err != nil || cfg.DisableAfter < 0 {
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_DISABLE_AFTER: must be at least 0")
+	}
+	return cfg, nil
+}
+
+/*Defines a Struct to hold the offline IP database used to route links by country*/
+type GeoIPConfig struct {
+	DatabaseFile   string
+	ReloadInterval time.Duration
+}
+
+func NewGeoIPConfig() (*GeoIPConfig, error) {
+	cfg := &GeoIPConfig{DatabaseFile: os.Getenv("GEOIP_DATABASE_FILE")}
+
+	var err error
+	if cfg.ReloadInterval, err = time.ParseDuration(getEnvOrDefault("GEOIP_RELOAD_INTERVAL", "1h")); err != nil || cfg.ReloadInterval <= 0 {
+		return nil, fmt.Errorf("invalid GEOIP_RELOAD_INTERVAL: must be a positive duration")
+	}
+	return cfg, nil
+}
+
+/*Defines a Struct to hold the Prometheus metrics settings*/
+type MetricsConfig struct {
+	Enabled bool
+	// AdminPort is where url-service serves /metrics, apart from its gRPC
+	// port, and GatewayAdminPort where the gateway does, apart from its
+	// public one.
+	AdminPort        string
+	GatewayAdminPort string
+}
+
+func NewMetricsConfig() (*MetricsConfig, error) {
+	cfg := &MetricsConfig{
+		AdminPort:        getEnvOrDefault("URL_SERVICE_ADMIN_PORT", "9091"),
+		GatewayAdminPort: getEnvOrDefault("GATEWAY_ADMIN_PORT", "9090"),
+	}
+
+	var err error
+	if cfg.Enabled, err = strconv.ParseBool(getEnvOrDefault("METRICS_ENABLED", "true")); err != nil {
+		return nil, fmt.Errorf("invalid METRICS_ENABLED: %v", err)
+	}
+	if port, err := strconv.Atoi(cfg.AdminPort); err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid URL_SERVICE_ADMIN_PORT: must be a port number")
+	}
+	if port, err := strconv.Atoi(cfg.GatewayAdminPort); err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid GATEWAY_ADMIN_PORT: must be a port number")
+	}
+	return cfg, nil
+}
+
+/*Defines a Struct to hold the reverse proxies the gateway takes client addresses from*/
+type ProxyConfig struct {
+	// TrustedProxies are the addresses and networks of the proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed.
+	TrustedProxies []netip.Prefix
+}
+
+func NewProxyConfig() (*ProxyConfig, error) {
+	cfg := &ProxyConfig{}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, prefix.Masked())
+	}
+	return cfg, nil
+}
+
+/*Defines a Struct to hold access settings of the url service*/
+type AccessConfig struct {
+	// AdminOwners may list the links of every owner.
+	AdminOwners []string
+}
+
+func NewAccessConfig() (*AccessConfig, error) {
+	cfg := &AccessConfig{}
+	for _, owner := range strings.Split(os.Getenv("ADMIN_OWNERS"), ",") {
+		if owner = strings.TrimSpace(owner); owner != "" {
+			cfg.AdminOwners = append(cfg.AdminOwners, owner)
+		}
+	}
+	return cfg, nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestNewDBConfig(t *testing.T) {
@@ -113,3 +114,583 @@ func TestGetEnvOrDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestNewShortenerConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		wantErr  bool
+		validate func(*testing.T, *ShortenerConfig)
+	}{
+		{
+			name:    "Defaults to base62",
+			envVars: map[string]string{},
+			validate: func(t *testing.T, cfg *ShortenerConfig) {
+				if cfg.Kind != ShortenerBase62 {
+					t.Errorf("expected Kind base62, got %s", cfg.Kind)
+				}
+			},
+		},
+		{
+			name: "Feistel with key",
+			envVars: map[string]string{
+				"SHORTENER":     "feistel",
+				"SHORTENER_KEY": "0123456789abcdef",
+			},
+			validate: func(t *testing.T, cfg *ShortenerConfig) {
+				if cfg.Key != "0123456789abcdef" {
+					t.Errorf("expected Key to be loaded, got %q", cfg.Key)
+				}
+			},
+		},
+		{
+			name:    "Feistel without key",
+			envVars: map[string]string{"SHORTENER": "feistel"},
+			wantErr: true,
+		},
+		{
+			name:    "Feistel with short key",
+			envVars: map[string]string{"SHORTENER": "feistel", "SHORTENER_KEY": "short"},
+			wantErr: true,
+		},
+		{
+			name:    "Random with default length",
+			envVars: map[string]string{"SHORTENER": "random"},
+			validate: func(t *testing.T, cfg *ShortenerConfig) {
+				if cfg.Length != 8 {
+					t.Errorf("expected Length 8, got %d", cfg.Length)
+				}
+			},
+		},
+		{
+			name:    "Random with length over the limit",
+			envVars: map[string]string{"SHORTENER": "random", "SHORTENER_LENGTH": "13"},
+			wantErr: true,
+		},
+		{
+			name:    "Random with invalid length",
+			envVars: map[string]string{"SHORTENER": "random", "SHORTENER_LENGTH": "eight"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown kind",
+			envVars: map[string]string{"SHORTENER": "md5"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := NewShortenerConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewShortenerConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && tt.validate != nil {
+				tt.validate(t, cfg)
+			}
+		})
+	}
+}
+
+func TestNewCacheConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		wantErr  bool
+		validate func(t *testing.T, cfg *CacheConfig)
+	}{
+		{
+			name:    "Default memory cache",
+			envVars: map[string]string{},
+			validate: func(t *testing.T, cfg *CacheConfig) {
+				if cfg.Backend != CacheMemory {
+					t.Errorf("expected Backend memory, got %q", cfg.Backend)
+				}
+				if cfg.Size != 10000 || cfg.TTL != 10*time.Second || cfg.NegativeTTL != 30*time.Second {
+					t.Errorf("unexpected defaults %+v", cfg)
+				}
+			},
+		},
+		{
+			name:    "Cache disabled",
+			envVars: map[string]string{"CACHE": "none"},
+			validate: func(t *testing.T, cfg *CacheConfig) {
+				if cfg.Backend != CacheNone {
+					t.Errorf("expected Backend none, got %q", cfg.Backend)
+				}
+			},
+		},
+		{
+			name: "Redis cache",
+			envVars: map[string]string{
+				"CACHE":              "redis",
+				"REDIS_ADDR":         "redis:6379",
+				"REDIS_PASSWORD":     "secret",
+				"REDIS_DB":           "2",
+				"CACHE_TTL":          "1h",
+				"CACHE_NEGATIVE_TTL": "0s",
+			},
+			validate: func(t *testing.T, cfg *CacheConfig) {
+				if cfg.RedisAddr != "redis:6379" || cfg.RedisPassword != "secret" || cfg.RedisDB != 2 {
+					t.Errorf("unexpected redis settings %+v", cfg)
+				}
+				if cfg.TTL != time.Hour || cfg.NegativeTTL != 0 {
+					t.Errorf("unexpected ttls %+v", cfg)
+				}
+			},
+		},
+		{
+			name:    "Default redis ttl",
+			envVars: map[string]string{"CACHE": "redis"},
+			validate: func(t *testing.T, cfg *CacheConfig) {
+				if cfg.TTL != 10*time.Minute {
+					t.Errorf("expected TTL 10m, got %v", cfg.TTL)
+				}
+			},
+		},
+		{
+			name:    "Invalid size",
+			envVars: map[string]string{"CACHE_SIZE": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid ttl",
+			envVars: map[string]string{"CACHE_TTL": "ten minutes"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid redis db",
+			envVars: map[string]string{"CACHE": "redis", "REDIS_DB": "one"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown backend",
+			envVars: map[string]string{"CACHE": "memcached"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := NewCacheConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCacheConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && tt.validate != nil {
+				tt.validate(t, cfg)
+			}
+		})
+	}
+}
+
+func TestNewRateLimitConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		wantErr  bool
+		validate func(t *testing.T, cfg *RateLimitConfig)
+	}{
+		{
+			name:    "Default memory store",
+			envVars: map[string]string{},
+			validate: func(t *testing.T, cfg *RateLimitConfig) {
+				if cfg.Store != RateLimitMemory {
+					t.Errorf("expected Store memory, got %q", cfg.Store)
+				}
+				if cfg.ShortenRate != 0.5 || cfg.ShortenBurst != 10 || cfg.ResolveRate != 10 || cfg.ResolveBurst != 100 || cfg.AuthRate != 2 || cfg.AuthBurst != 30 {
+					t.Errorf("unexpected defaults %+v", cfg)
+				}
+			},
+		},
+		{
+			name:    "Rate limiting disabled",
+			envVars: map[string]string{"RATE_LIMIT": "none"},
+			validate: func(t *testing.T, cfg *RateLimitConfig) {
+				if cfg.Store != RateLimitNone {
+					t.Errorf("expected Store none, got %q", cfg.Store)
+				}
+			},
+		},
+		{
+			name: "Redis store",
+			envVars: map[string]string{
+				"RATE_LIMIT":                    "redis",
+				"REDIS_ADDR":                    "redis:6379",
+				"REDIS_DB":                      "1",
+				"RATE_LIMIT_SHORTEN_PER_MINUTE": "6",
+				"RATE_LIMIT_SHORTEN_BURST":      "2",
+			},
+			validate: func(t *testing.T, cfg *RateLimitConfig) {
+				if cfg.RedisAddr != "redis:6379" || cfg.RedisDB != 1 {
+					t.Errorf("unexpected redis settings %+v", cfg)
+				}
+				if cfg.ShortenRate != 0.1 || cfg.ShortenBurst != 2 {
+					t.Errorf("unexpected shorten limit %+v", cfg)
+				}
+			},
+		},
+		{
+			name:    "Invalid rate",
+			envVars: map[string]string{"RATE_LIMIT_RESOLVE_PER_MINUTE": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid burst",
+			envVars: map[string]string{"RATE_LIMIT_SHORTEN_BURST": "lots"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid auth rate",
+			envVars: map[string]string{"RATE_LIMIT_AUTH_PER_MINUTE": "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown store",
+			envVars: map[string]string{"RATE_LIMIT": "memcached"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := NewRateLimitConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRateLimitConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && tt.validate != nil {
+				tt.validate(t, cfg)
+			}
+		})
+	}
+}
+
+func TestNewNormalizeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    NormalizeConfig
+		wantErr bool
+	}{
+		{name: "Defaults", envVars: map[string]string{}, want: NormalizeConfig{}},
+		{name: "All rules", envVars: map[string]string{"URL_SORT_QUERY": "true", "URL_STRIP_TRACKING": "1"}, want: NormalizeConfig{SortQuery: true, StripTracking: true}},
+		{name: "Invalid flag", envVars: map[string]string{"URL_STRIP_TRACKING": "yes please"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := NewNormalizeConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewNormalizeConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && *cfg != tt.want {
+				t.Errorf("NewNormalizeConfig() = %+v, want %+v", *cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicyConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		wantErr  bool
+		validate func(t *testing.T, cfg *PolicyConfig)
+	}{
+		{
+			name:    "Defaults",
+			envVars: map[string]string{},
+			validate: func(t *testing.T, cfg *PolicyConfig) {
+				if cfg.BlocklistFile != "" || cfg.AllowlistFile != "" || cfg.AllowPrivateHosts || len(cfg.ShortDomains) != 0 {
+					t.Errorf("unexpected defaults %+v", cfg)
+				}
+				if cfg.ReloadInterval != 30*time.Second {
+					t.Errorf("expected ReloadInterval 30s, got %v", cfg.ReloadInterval)
+				}
+			},
+		},
+		{
+			name: "All settings",
+			envVars: map[string]string{
+				"URL_BLOCKLIST_FILE":        "/etc/zipit/blocked.txt",
+				"URL_ALLOWLIST_FILE":        "/etc/zipit/allowed.txt",
+				"URL_LISTS_RELOAD_INTERVAL": "5m",
+				"URL_ALLOW_PRIVATE_HOSTS":   "true",
+				"SHORT_DOMAINS":             "zip.it, www.zip.it,,",
+			},
+			validate: func(t *testing.T, cfg *PolicyConfig) {
+				if cfg.BlocklistFile != "/etc/zipit/blocked.txt" || cfg.AllowlistFile != "/etc/zipit/allowed.txt" {
+					t.Errorf("unexpected list files %+v", cfg)
+				}
+				if cfg.ReloadInterval != 5*time.Minute || !cfg.AllowPrivateHosts {
+					t.Errorf("unexpected settings %+v", cfg)
+				}
+				if len(cfg.ShortDomains) != 2 || cfg.ShortDomains[0] != "zip.it" || cfg.ShortDomains[1] != "www.zip.it" {
+					t.Errorf("unexpected short domains %q", cfg.ShortDomains)
+				}
+			},
+		},
+		{
+			name:    "Invalid reload interval",
+			envVars: map[string]string{"URL_LISTS_RELOAD_INTERVAL": "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid private hosts flag",
+			envVars: map[string]string{"URL_ALLOW_PRIVATE_HOSTS": "sometimes"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := NewPolicyConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPolicyConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && tt.validate != nil {
+				tt.validate(t, cfg)
+			}
+		})
+	}
+}
+
+func TestNewPageFetchConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		wantErr  bool
+		validate func(t *testing.T, cfg *PageFetchConfig)
+	}{
+		{
+			name:    "Defaults",
+			envVars: map[string]string{},
+			validate: func(t *testing.T, cfg *PageFetchConfig) {
+				if !cfg.Enabled || cfg.Workers != 4 || cfg.QueueSize != 1000 || cfg.Timeout != 10*time.Second || cfg.MaxBytes != 1<<20 || cfg.UserAgent == "" {
+					t.Errorf("unexpected defaults %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "All settings",
+			envVars: map[string]string{
+				"PAGE_FETCH_ENABLED":    "false",
+				"PAGE_FETCH_WORKERS":    "8",
+				"PAGE_FETCH_QUEUE_SIZE": "50",
+				"PAGE_FETCH_TIMEOUT":    "3s",
+				"PAGE_FETCH_MAX_BYTES":  "65536",
+				"PAGE_FETCH_USER_AGENT": "zipit-test",
+			},
+			validate: func(t *testing.T, cfg *PageFetchConfig) {
+				if cfg.Enabled || cfg.Workers != 8 || cfg.QueueSize != 50 || cfg.Timeout != 3*time.Second || cfg.MaxBytes != 65536 || cfg.UserAgent != "zipit-test" {
+					t.Errorf("unexpected settings %+v", cfg)
+				}
+			},
+		},
+		{name: "Invalid enabled flag", envVars: map[string]string{"PAGE_FETCH_ENABLED": "maybe"}, wantErr: true},
+		{name: "No workers", envVars: map[string]string{"PAGE_FETCH_WORKERS": "0"}, wantErr: true},
+		{name: "Invalid queue size", envVars: map[string]string{"PAGE_FETCH_QUEUE_SIZE": "many"}, wantErr: true},
+		{name: "Invalid timeout", envVars: map[string]string{"PAGE_FETCH_TIMEOUT": "-1s"}, wantErr: true},
+		{name: "Invalid max bytes", envVars: map[string]string{"PAGE_FETCH_MAX_BYTES": "0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := NewPageFetchConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPageFetchConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && tt.validate != nil {
+				tt.validate(t, cfg)
+			}
+		})
+	}
+}
+
+func TestNewHealthCheckConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		wantErr  bool
+		validate func(t *testing.T, cfg *HealthCheckConfig)
+	}{
+		{
+			name:    "Defaults",
+			envVars: map[string]string{},
+			validate: func(t *testing.T, cfg *HealthCheckConfig) {
+				if !cfg.Enabled || cfg.Interval != time.Minute || cfg.RecheckAfter != 24*time.Hour || cfg.BatchSize != 100 ||
+					cfg.Workers != 4 || cfg.Timeout != 10*time.Second || cfg.DisableAfter != 0 {
+					t.Errorf("unexpected defaults %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "All settings",
+			envVars: map[string]string{
+				"HEALTH_CHECK_ENABLED":       "false",
+				"HEALTH_CHECK_INTERVAL":      "30s",
+				"HEALTH_CHECK_RECHECK_AFTER": "6h",
+				"HEALTH_CHECK_BATCH_SIZE":    "20",
+				"HEALTH_CHECK_WORKERS":       "2",
+				"HEALTH_CHECK_TIMEOUT":       "5s",
+				"HEALTH_CHECK_DISABLE_AFTER": "7",
+			},
+			validate: func(t *testing.T, cfg *HealthCheckConfig) {
+				if cfg.Enabled || cfg.Interval != 30*time.Second || cfg.RecheckAfter != 6*time.Hour || cfg.BatchSize != 20 ||
+					cfg.Workers != 2 || cfg.Timeout != 5*time.Second || cfg.DisableAfter != 7 {
+					t.Errorf("unexpected settings %+v", cfg)
+				}
+			},
+		},
+		{name: "Invalid enabled flag", envVars: map[string]string{"HEALTH_CHECK_ENABLED": "often"}, wantErr: true},
+		{name: "Invalid interval", envVars: map[string]string{"HEALTH_CHECK_INTERVAL": "0s"}, wantErr: true},
+		{name: "Invalid recheck period", envVars: map[string]string{"HEALTH_CHECK_RECHECK_AFTER": "daily"}, wantErr: true},
+		{name: "Invalid batch size", envVars: map[string]string{"HEALTH_CHECK_BATCH_SIZE": "0"}, wantErr: true},
+		{name: "Invalid workers", envVars: map[string]string{"HEALTH_CHECK_WORKERS": "-1"}, wantErr: true},
+		{name: "Invalid timeout", envVars: map[string]string{"HEALTH_CHECK_TIMEOUT": "forever"}, wantErr: true},
+		{name: "Negative disable after", envVars: map[string]string{"HEALTH_CHECK_DISABLE_AFTER": "-3"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := NewHealthCheckConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewHealthCheckConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && tt.validate != nil {
+				tt.validate(t, cfg)
+			}
+		})
+	}
+}
+
+func TestNewGeoIPConfig(t *testing.T) {
+	os.Clearenv()
+	cfg, err := NewGeoIPConfig()
+	if err != nil || cfg.DatabaseFile != "" || cfg.ReloadInterval != time.Hour {
+		t.Errorf("unexpected defaults %+v, err=%v", cfg, err)
+	}
+
+	os.Setenv("GEOIP_DATABASE_FILE", "/var/lib/zipit/dbip-country-lite.csv")
+	os.Setenv("GEOIP_RELOAD_INTERVAL", "24h")
+	cfg, err = NewGeoIPConfig()
+	if err != nil || cfg.DatabaseFile != "/var/lib/zipit/dbip-country-lite.csv" || cfg.ReloadInterval != 24*time.Hour {
+		t.Errorf("unexpected settings %+v, err=%v", cfg, err)
+	}
+
+	os.Setenv("GEOIP_RELOAD_INTERVAL", "0s")
+	if _, err := NewGeoIPConfig(); err == nil {
+		t.Error("expected an error for a zero reload interval")
+	}
+}
+
+func TestNewMetricsConfig(t *testing.T) {
+	os.Clearenv()
+	cfg, err := NewMetricsConfig()
+	if err != nil || !cfg.Enabled || cfg.AdminPort != "9091" || cfg.GatewayAdminPort != "9090" {
+		t.Errorf("unexpected defaults %+v, err=%v", cfg, err)
+	}
+
+	os.Setenv("METRICS_ENABLED", "false")
+	os.Setenv("URL_SERVICE_ADMIN_PORT", "9100")
+	os.Setenv("GATEWAY_ADMIN_PORT", "9101")
+	cfg, err = NewMetricsConfig()
+	if err != nil || cfg.Enabled || cfg.AdminPort != "9100" || cfg.GatewayAdminPort != "9101" {
+		t.Errorf("unexpected settings %+v, err=%v", cfg, err)
+	}
+
+	os.Setenv("METRICS_ENABLED", "sometimes")
+	if _, err := NewMetricsConfig(); err == nil {
+		t.Error("expected an error for an invalid METRICS_ENABLED")
+	}
+	os.Setenv("METRICS_ENABLED", "true")
+	os.Setenv("URL_SERVICE_ADMIN_PORT", "http")
+	if _, err := NewMetricsConfig(); err == nil {
+		t.Error("expected an error for an invalid admin port")
+	}
+	os.Setenv("URL_SERVICE_ADMIN_PORT", "9100")
+	os.Setenv("GATEWAY_ADMIN_PORT", "70000")
+	if _, err := NewMetricsConfig(); err == nil {
+		t.Error("expected an error for an invalid gateway admin port")
+	}
+}
+
+func TestNewProxyConfig(t *testing.T) {
+	os.Clearenv()
+	cfg, err := NewProxyConfig()
+	if err != nil || len(cfg.TrustedProxies) != 0 {
+		t.Errorf("expected no trusted proxies by default, got %+v, err=%v", cfg, err)
+	}
+
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.7,,2001:db8::1/64")
+	cfg, err = NewProxyConfig()
+	want := []string{"10.0.0.0/8", "192.0.2.7/32", "2001:db8::/64"}
+	if err != nil || len(cfg.TrustedProxies) != len(want) {
+		t.Fatalf("unexpected trusted proxies %+v, err=%v", cfg, err)
+	}
+	for i, prefix := range cfg.TrustedProxies {
+		if prefix.String() != want[i] {
+			t.Errorf("expected trusted proxy %s, got %s", want[i], prefix)
+		}
+	}
+
+	os.Setenv("TRUSTED_PROXIES", "proxy.internal")
+	if _, err := NewProxyConfig(); err == nil {
+		t.Error("expected an error for a host name")
+	}
+}
+
+func TestNewAccessConfig(t *testing.T) {
+	os.Clearenv()
+	cfg, err := NewAccessConfig()
+	if err != nil || len(cfg.AdminOwners) != 0 {
+		t.Errorf("expected no admins by default, got %+v, err=%v", cfg, err)
+	}
+
+	os.Setenv("ADMIN_OWNERS", "ops, support,,")
+	cfg, err = NewAccessConfig()
+	if err != nil || len(cfg.AdminOwners) != 2 || cfg.AdminOwners[0] != "ops" || cfg.AdminOwners[1] != "support" {
+		t.Errorf("unexpected admins %+v, err=%v", cfg, err)
+	}
+}
@@ -79,3 +79,20 @@ func (d *Database) Health(ctx context.Context) error {
 func (d *Database) Stats() sql.DBStats {
 	return d.Conn.Stats()
 }
+
+// WithTx runs fn inside a transaction. The transaction is committed if fn
+// returns nil and rolled back otherwise.
+func (d *Database) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
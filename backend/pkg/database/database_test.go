@@ -2,6 +2,8 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -69,4 +71,40 @@ func TestDatabaseIntegration(t *testing.T) {
 			t.Errorf("expected 25 max open connections in stats, got %d", stats.MaxOpenConnections)
 		}
 	})
+	// Test transactions
+	t.Run("WithTx", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		if _, err := db.Conn.ExecContext(ctx, "CREATE TEMP TABLE tx_test (n INT)"); err != nil {
+			t.Fatalf("failed to create table: %v", err)
+		}
+		db.Conn.SetMaxOpenConns(1) // temp tables are per connection
+		defer db.Conn.SetMaxOpenConns(25)
+
+		rollback := errors.New("rollback")
+		err := db.WithTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO tx_test VALUES (1)"); err != nil {
+				return err
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Errorf("expected the error from fn, got %v", err)
+		}
+		if err := db.WithTx(ctx, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO tx_test VALUES (2)")
+			return err
+		}); err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+
+		var sum int
+		if err := db.Conn.QueryRowContext(ctx, "SELECT COALESCE(SUM(n), 0) FROM tx_test").Scan(&sum); err != nil {
+			t.Fatalf("failed to read table: %v", err)
+		}
+		if sum != 2 {
+			t.Errorf("expected only the committed row, got sum %d", sum)
+		}
+	})
 }
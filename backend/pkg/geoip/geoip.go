@@ -0,0 +1,239 @@
+package geoip
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Locator finds the country an IP address is located in. Country returns
+// the address's ISO 3166-1 alpha-2 country code in upper case, or "" if it
+// is unknown. Implementations must be safe for concurrent use.
+type Locator interface {
+	Country(addr netip.Addr) string
+}
+
+// ipRange is a range of addresses in one country, from first to last
+// inclusive. IPv4 ranges hold unmapped IPv4 addresses.
+type ipRange struct {
+	first   netip.Addr
+	last    netip.Addr
+	country string
+}
+
+// Database is a Locator backed by an offline database file in CSV format.
+// Each line holds either the first and last address of a range and its
+// country, as in the free DB-IP "IP to Country Lite" database, or a network
+// in CIDR notation and its country:
+//
+//	1.0.0.0,1.0.0.255,AU
+//	2001:db8::/32,NL
+//
+// Further columns are ignored, as are lines starting with #. Ranges must not
+// overlap. The file can be replaced while the service runs: Reload rereads
+// it, and Watch does so whenever it changes.
+type Database struct {
+	path string
+
+	mu     sync.RWMutex
+	ranges []ipRange // sorted by first address, IPv4 before IPv6
+	stamp  fileStamp
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Open loads the database file at path.
+func Open(path string) (*Database, error) {
+	db := &Database{path: path}
+	if err := db.Reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Country implements [Locator].
+func (db *Database) Country(addr netip.Addr) string {
+	addr = addr.Unmap()
+	if !addr.IsValid() {
+		return ""
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	// The range that could hold addr is the last one starting at or before it.
+	i, found := slices.BinarySearchFunc(db.ranges, addr, func(r ipRange, addr netip.Addr) int {
+		return r.first.Compare(addr)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 || db.ranges[i].last.Less(addr) {
+		return ""
+	}
+	return db.ranges[i].country
+}
+
+// Reload rereads the database file. If it cannot be read, the current
+// ranges are kept and an error is returned.
+func (db *Database) Reload() error {
+	f, err := os.Open(db.path)
+	if err != nil {
+		return fmt.Errorf("failed to open ip database: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read ip database %s: %w", db.path, err)
+	}
+	ranges, err := readRanges(f)
+	if err != nil {
+		return fmt.Errorf("failed to read ip database %s: %w", db.path, err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.ranges = ranges
+	db.stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	return nil
+}
+
+// Watch checks the file every interval and reloads it when it changed, until
+// ctx is done. Failed reloads are logged and the previous ranges stay in
+// effect.
+func (db *Database) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !db.changed() {
+				continue
+			}
+			if err := db.Reload(); err != nil {
+				slog.Error("failed to reload ip database", "error", err)
+				continue
+			}
+			slog.Info("reloaded ip database", "path", db.path)
+		}
+	}
+}
+
+// changed reports whether the file differs from the version last loaded.
+func (db *Database) changed() bool {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return true // let Reload report the error
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return !db.stamp.modTime.Equal(info.ModTime()) || db.stamp.size != info.Size()
+}
+
+// readRanges parses the ranges of a database file and sorts them.
+func readRanges(r io.Reader) ([]ipRange, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	var ranges []ipRange
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rng, err := parseRange(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranges = append(ranges, rng)
+	}
+
+	slices.SortFunc(ranges, func(a, b ipRange) int {
+		return a.first.Compare(b.first)
+	})
+	for i := 1; i < len(ranges); i++ {
+		if !ranges[i-1].last.Less(ranges[i].first) {
+			return nil, fmt.Errorf("ranges starting at %s and %s overlap", ranges[i-1].first, ranges[i].first)
+		}
+	}
+	return ranges, nil
+}
+
+// parseRange parses one line of a database file.
+func parseRange(record []string) (ipRange, error) {
+	var rng ipRange
+	var country string
+	if len(record) >= 2 && strings.Contains(record[0], "/") {
+		prefix, err := netip.ParsePrefix(record[0])
+		if err != nil {
+			return rng, err
+		}
+		prefix = prefix.Masked()
+		rng.first, rng.last = prefix.Addr().Unmap(), lastAddr(prefix)
+		country = record[1]
+	} else if len(record) >= 3 {
+		first, err := netip.ParseAddr(record[0])
+		if err != nil {
+			return rng, err
+		}
+		last, err := netip.ParseAddr(record[1])
+		if err != nil {
+			return rng, err
+		}
+		rng.first, rng.last = first.Unmap(), last.Unmap()
+		country = record[2]
+	} else {
+		return rng, errors.New("expected a range or network and a country")
+	}
+
+	if rng.first.Is4() != rng.last.Is4() || rng.last.Less(rng.first) {
+		return rng, fmt.Errorf("invalid range %s to %s", rng.first, rng.last)
+	}
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+		return rng, fmt.Errorf("invalid country code %q", country)
+	}
+	rng.country = country
+	return rng, nil
+}
+
+// lastAddr returns the last address of the masked network prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr().Unmap()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4In6() {
+		bits -= 96
+	}
+	b := addr.AsSlice()
+	for i := range b {
+		hostBits := min(max((i+1)*8-bits, 0), 8)
+		b[i] |= byte(1<<hostBits - 1)
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
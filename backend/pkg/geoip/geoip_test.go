@@ -0,0 +1,116 @@
+package geoip
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDatabase(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestDatabase_Country(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "countries.csv")
+	writeDatabase(t, path, `# first,last,country
+1.0.0.0,1.0.0.255,AU
+"1.0.4.0","1.0.7.255","au"
+8.8.8.0/24,US
+2001:db8::/32,NL
+2a00:1450::,2a00:1450:ffff:ffff:ffff:ffff:ffff:ffff,IE,extra
+`)
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"1.0.0.0", "AU"},
+		{"1.0.0.255", "AU"},
+		{"1.0.1.0", ""},
+		{"1.0.5.9", "AU"},
+		{"8.8.8.8", "US"},
+		{"8.8.9.0", ""},
+		{"::ffff:8.8.8.8", "US"},
+		{"2001:db8:1::1", "NL"},
+		{"2a00:1450:4001::1", "IE"},
+		{"2a01::1", ""},
+		{"0.0.0.1", ""},
+		{"255.255.255.255", ""},
+	}
+	for _, tt := range tests {
+		if got := db.Country(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("Country(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+	if got := db.Country(netip.Addr{}); got != "" {
+		t.Errorf("expected no country for an invalid address, got %q", got)
+	}
+}
+
+func TestDatabase_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"Bad Address", "1.0.0.x,1.0.0.255,AU\n", "line 1"},
+		{"Reversed Range", "1.0.0.255,1.0.0.0,AU\n", "invalid range"},
+		{"Mixed Families", "1.0.0.0,2001:db8::,AU\n", "invalid range"},
+		{"Bad Country", "1.0.0.0,1.0.0.255,Australia\n", "invalid country code"},
+		{"Missing Country", "1.0.0.0\n", "expected a range or network"},
+		{"Overlap", "1.0.0.0/16,AU\n1.0.4.0,1.0.4.255,CN\n", "overlap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "countries.csv")
+			writeDatabase(t, path, tt.content)
+			if _, err := Open(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestDatabase_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "countries.csv")
+	writeDatabase(t, path, "1.0.0.0/24,AU\n")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	addr := netip.MustParseAddr("1.0.0.1")
+
+	// A broken file keeps the ranges in effect
+	writeDatabase(t, path, "not,a,range\n")
+	if !db.changed() {
+		t.Error("expected the file to be reported as changed")
+	}
+	if err := db.Reload(); err == nil {
+		t.Error("expected Reload to fail")
+	}
+	if got := db.Country(addr); got != "AU" {
+		t.Errorf("expected the previous ranges after a failed reload, got %q", got)
+	}
+
+	writeDatabase(t, path, "1.0.0.0/24,JP\n")
+	if err := db.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if got := db.Country(addr); got != "JP" {
+		t.Errorf("expected the reloaded ranges, got %q", got)
+	}
+}
@@ -0,0 +1,36 @@
+package identity
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataKey is the gRPC metadata key carrying the authenticated owner from
+// the gateway to the backend services. The services trust it as-is, so they
+// must only be reachable through the gateway.
+const MetadataKey = "x-zipit-owner"
+
+type ownerKey struct{}
+
+// WithOwner returns a copy of ctx carrying owner, both for local use via
+// Owner and as outgoing gRPC metadata for calls made with the context.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	ctx = context.WithValue(ctx, ownerKey{}, owner)
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, owner)
+}
+
+// Owner returns the owner stored by WithOwner, or "" for anonymous callers.
+func Owner(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// FromIncomingContext returns the owner sent by the gateway with a gRPC
+// request, or "" for anonymous callers.
+func FromIncomingContext(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, MetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package identity
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWithOwner(t *testing.T) {
+	ctx := WithOwner(context.Background(), "acme")
+
+	if got := Owner(ctx); got != "acme" {
+		t.Errorf("expected owner acme, got %q", got)
+	}
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok || len(md.Get(MetadataKey)) != 1 || md.Get(MetadataKey)[0] != "acme" {
+		t.Errorf("expected outgoing metadata %s=acme, got %v", MetadataKey, md)
+	}
+}
+
+func TestOwner_Anonymous(t *testing.T) {
+	if got := Owner(context.Background()); got != "" {
+		t.Errorf("expected no owner, got %q", got)
+	}
+	if got := FromIncomingContext(context.Background()); got != "" {
+		t.Errorf("expected no owner, got %q", got)
+	}
+}
+
+func TestFromIncomingContext(t *testing.T) {
+	// Simulate the server side of a call made with WithOwner
+	out, _ := metadata.FromOutgoingContext(WithOwner(context.Background(), "acme"))
+	ctx := metadata.NewIncomingContext(context.Background(), out)
+
+	if got := FromIncomingContext(ctx); got != "acme" {
+		t.Errorf("expected owner acme, got %q", got)
+	}
+}
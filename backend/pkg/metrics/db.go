@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatsSource reports the statistics of a connection pool, as
+// database.Database does.
+type StatsSource interface {
+	Stats() sql.DBStats
+}
+
+// dbStatsCollector exports the statistics of a connection pool, read afresh
+// on every scrape.
+type dbStatsCollector struct {
+	source StatsSource
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewDBStatsCollector creates a collector exporting the sql.DBStats of source
+// as gauges. Register it with a Registry to export them.
+func NewDBStatsCollector(source StatsSource) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, nil, nil)
+	}
+	return &dbStatsCollector{
+		source:            source,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("open_connections", "Established connections, both in use and idle."),
+		inUse:             desc("in_use_connections", "Connections currently in use."),
+		idle:              desc("idle_connections", "Idle connections."),
+		waitCount:         desc("wait_count", "Connections waited for since the pool was opened."),
+		waitDuration:      desc("wait_duration_seconds", "Time blocked waiting for a connection since the pool was opened."),
+		maxIdleClosed:     desc("max_idle_closed", "Connections closed because of the maximum number of idle connections."),
+		maxIdleTimeClosed: desc("max_idle_time_closed", "Connections closed because they were idle too long."),
+		maxLifetimeClosed: desc("max_lifetime_closed", "Connections closed because they reached their maximum lifetime."),
+	}
+}
+
+// Describe implements [prometheus.Collector].
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements [prometheus.Collector].
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	gauge(c.maxOpen, float64(stats.MaxOpenConnections))
+	gauge(c.open, float64(stats.OpenConnections))
+	gauge(c.inUse, float64(stats.InUse))
+	gauge(c.idle, float64(stats.Idle))
+	gauge(c.waitCount, float64(stats.WaitCount))
+	gauge(c.waitDuration, stats.WaitDuration.Seconds())
+	gauge(c.maxIdleClosed, float64(stats.MaxIdleClosed))
+	gauge(c.maxIdleTimeClosed, float64(stats.MaxIdleTimeClosed))
+	gauge(c.maxLifetimeClosed, float64(stats.MaxLifetimeClosed))
+}
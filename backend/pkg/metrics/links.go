@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LinkCounters counts links created and followed.
+type LinkCounters struct {
+	shortened prometheus.Counter
+	resolved  *prometheus.CounterVec
+}
+
+// NewLinkCounters creates the link counters and registers them with reg.
+func NewLinkCounters(reg prometheus.Registerer) *LinkCounters {
+	c := &LinkCounters{
+		shortened: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "links_shortened_total",
+			Help:      "Links handed out by shortening, including batches.",
+		}),
+		resolved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "links_resolved_total",
+			Help:      "Attempts to follow a link, by result.",
+		}, []string{"result"}),
+	}
+	reg.MustRegister(c.shortened, c.resolved)
+	return c
+}
+
+// Shortened counts n links handed out.
+func (c *LinkCounters) Shortened(n int) {
+	c.shortened.Add(float64(n))
+}
+
+// Resolved counts an attempt to follow a link that ended with result.
+func (c *LinkCounters) Resolved(result string) {
+	c.resolved.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	reg := NewRegistry()
+	m := NewHTTPMetrics(reg)
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/{code}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "code") == "miss" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	for _, target := range []string{"/abc", "/xyz", "/miss", "/abc/history"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/abc", nil))
+
+	tests := []struct {
+		labels []string
+		want   float64
+	}{
+		{[]string{"GET", "/{code}", "200"}, 2},
+		{[]string{"GET", "/{code}", "404"}, 1},
+		{[]string{"GET", "unmatched", "404"}, 1},
+		{[]string{"other", "unmatched", "405"}, 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(tt.labels...)); got != tt.want {
+			t.Errorf("requests%v = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+	if got := testutil.CollectAndCount(m.duration); got != 3 {
+		t.Errorf("expected 3 latency histograms, got %d", got)
+	}
+}
+
+func TestGRPCMetrics(t *testing.T) {
+	reg := NewRegistry()
+	m := NewGRPCMetrics(reg)
+	unary := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/url.URLService/GetLongURL"}
+
+	ok := func(ctx context.Context, req any) (any, error) { return "resp", nil }
+	notFound := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "url not found")
+	}
+	if resp, err := unary(context.Background(), nil, info, ok); resp != "resp" || err != nil {
+		t.Fatalf("expected the handler's response, got %v, %v", resp, err)
+	}
+	if _, err := unary(context.Background(), nil, info, notFound); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected the handler's error, got %v", err)
+	}
+
+	stream := m.StreamServerInterceptor()
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/url.URLService/BatchPostURLStream", IsClientStream: true}
+	_ = stream(nil, nil, streamInfo, func(srv any, ss grpc.ServerStream) error { return nil })
+
+	tests := []struct {
+		labels []string
+		want   float64
+	}{
+		{[]string{"/url.URLService/GetLongURL", "OK"}, 1},
+		{[]string{"/url.URLService/GetLongURL", "NotFound"}, 1},
+		{[]string{"/url.URLService/BatchPostURLStream", "OK"}, 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(tt.labels...)); got != tt.want {
+			t.Errorf("requests%v = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+}
+
+// statsFunc adapts a function to [StatsSource].
+type statsFunc func() sql.DBStats
+
+func (f statsFunc) Stats() sql.DBStats { return f() }
+
+func TestDBStatsCollector(t *testing.T) {
+	stats := sql.DBStats{MaxOpenConnections: 25, OpenConnections: 7, InUse: 3, Idle: 4, WaitCount: 12, WaitDuration: 1500 * time.Millisecond}
+	reg := NewRegistry()
+	reg.MustRegister(NewDBStatsCollector(statsFunc(func() sql.DBStats { return stats })))
+
+	want := `
+# HELP zipit_db_in_use_connections Connections currently in use.
+# TYPE zipit_db_in_use_connections gauge
+zipit_db_in_use_connections 3
+# HELP zipit_db_open_connections Established connections, both in use and idle.
+# TYPE zipit_db_open_connections gauge
+zipit_db_open_connections 7
+# HELP zipit_db_wait_duration_seconds Time blocked waiting for a connection since the pool was opened.
+# TYPE zipit_db_wait_duration_seconds gauge
+zipit_db_wait_duration_seconds 1.5
+`
+	names := []string{"zipit_db_in_use_connections", "zipit_db_open_connections", "zipit_db_wait_duration_seconds"}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), names...); err != nil {
+		t.Error(err)
+	}
+
+	// Stats are read on every scrape
+	stats.InUse = 5
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(strings.Replace(want, "connections 3", "connections 5", 1)), names...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLinkCounters(t *testing.T) {
+	reg := NewRegistry()
+	c := NewLinkCounters(reg)
+	c.Shortened(1)
+	c.Shortened(3)
+	c.Resolved("ok")
+	c.Resolved("ok")
+	c.Resolved("not_found")
+
+	if got := testutil.ToFloat64(c.shortened); got != 4 {
+		t.Errorf("expected 4 shortened links, got %v", got)
+	}
+	if got := testutil.ToFloat64(c.resolved.WithLabelValues("ok")); got != 2 {
+		t.Errorf("expected 2 resolved links, got %v", got)
+	}
+	if got := testutil.ToFloat64(c.resolved.WithLabelValues("not_found")); got != 1 {
+		t.Errorf("expected 1 missing link, got %v", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	NewLinkCounters(reg).Shortened(2)
+
+	rr := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	for _, want := range []string{"zipit_links_shortened_total 2", "go_goroutines", "process_"} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected %q in the exposition, got:\n%s", want, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	"zipit/pkg/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Result describes the outcome of a call to Allow.
+type Result struct {
+	// Allowed reports whether the request may proceed.
+	Allowed bool
+	// Limit is the bucket size, i.e. the largest burst a client can send.
+	Limit int
+	// Remaining is the number of whole tokens left after this request.
+	Remaining int
+	// RetryAfter is how long to wait for the next token when not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Limiter is a token bucket per key: each key may burst up to Burst requests
+// and then gets Rate requests per second. Implementations must be safe for
+// concurrent use.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Limit is the size and refill rate of a token bucket.
+type Limit struct {
+	Rate  float64 // tokens added per second
+	Burst int     // bucket capacity
+}
+
+// take refills a bucket holding tokens, last refilled at last, up to now and
+// takes one token if there is one. It returns the new token count and the result.
+func (l Limit) take(tokens float64, last, now time.Time) (float64, Result) {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(l.Burst), tokens+elapsed*l.Rate)
+	}
+
+	res := Result{Limit: l.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.duration(1 - tokens)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = l.duration(float64(l.Burst) - tokens)
+	return tokens, res
+}
+
+// duration returns how long it takes to refill the given number of tokens.
+func (l Limit) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.Rate * float64(time.Second))
+}
+
+// NewLimiter builds the limiter for one route class (e.g. "shorten") from cfg.
+// It returns nil if rate limiting is disabled. The memory store keeps a budget
+// per gateway replica; the redis store shares one budget between replicas.
+func NewLimiter(cfg *config.RateLimitConfig, name string, limit Limit) (Limiter, error) {
+	switch cfg.Store {
+	case config.RateLimitNone:
+		return nil, nil
+	case config.RateLimitMemory:
+		return NewMemoryLimiter(limit), nil
+	case config.RateLimitRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("no response received from redis: %w", err)
+		}
+		return NewRedisLimiter(client, name, limit), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+}
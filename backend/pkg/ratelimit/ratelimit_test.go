@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+	"zipit/pkg/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLimiters(t *testing.T) {
+	srv := miniredis.RunT(t)
+	limit := Limit{Rate: 1, Burst: 3}
+
+	// Each constructor returns a fresh limiter driven by the clock at *now.
+	limiters := map[string]func(now *time.Time) Limiter{
+		"Memory": func(now *time.Time) Limiter {
+			l := NewMemoryLimiter(limit)
+			l.now = func() time.Time { return *now }
+			return l
+		},
+		"Redis": func(now *time.Time) Limiter {
+			srv.FlushAll()
+			l := NewRedisLimiter(redis.NewClient(&redis.Options{Addr: srv.Addr()}), "test", limit)
+			l.now = func() time.Time { return *now }
+			return l
+		},
+	}
+
+	for name, newLimiter := range limiters {
+		t.Run(name+"/Burst", func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			l := newLimiter(&now)
+
+			for i := 0; i < 3; i++ {
+				res, err := l.Allow(ctx, "ip:1.2.3.4")
+				if err != nil {
+					t.Fatalf("Allow failed: %v", err)
+				}
+				if !res.Allowed || res.Limit != 3 || res.Remaining != 2-i {
+					t.Errorf("request %d: unexpected result %+v", i, res)
+				}
+			}
+
+			res, _ := l.Allow(ctx, "ip:1.2.3.4")
+			if res.Allowed || res.Remaining != 0 {
+				t.Errorf("expected the 4th request to be denied, got %+v", res)
+			}
+			if res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+				t.Errorf("expected RetryAfter 1s and Reset 3s, got %+v", res)
+			}
+
+			// Other keys have their own bucket
+			if res, _ := l.Allow(ctx, "ip:5.6.7.8"); !res.Allowed {
+				t.Errorf("expected another key to be allowed, got %+v", res)
+			}
+		})
+
+		t.Run(name+"/Refill", func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			l := newLimiter(&now)
+
+			for i := 0; i < 3; i++ {
+				_, _ = l.Allow(ctx, "key:acme")
+			}
+
+			now = now.Add(500 * time.Millisecond)
+			if res, _ := l.Allow(ctx, "key:acme"); res.Allowed || res.RetryAfter != 500*time.Millisecond {
+				t.Errorf("expected a denial with RetryAfter 500ms, got %+v", res)
+			}
+
+			now = now.Add(500 * time.Millisecond)
+			if res, _ := l.Allow(ctx, "key:acme"); !res.Allowed {
+				t.Errorf("expected a token after 1s, got %+v", res)
+			}
+
+			// The bucket never holds more than Burst tokens
+			now = now.Add(time.Hour)
+			if res, _ := l.Allow(ctx, "key:acme"); !res.Allowed || res.Remaining != 2 {
+				t.Errorf("expected a full bucket, got %+v", res)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiter_SweepsIdleBuckets(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	l := NewMemoryLimiter(Limit{Rate: 1, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	_, _ = l.Allow(ctx, "a")
+	_, _ = l.Allow(ctx, "b")
+	now = now.Add(2 * sweepInterval)
+	_, _ = l.Allow(ctx, "c")
+
+	if l.Len() != 1 {
+		t.Errorf("expected only the active bucket to be kept, got %d", l.Len())
+	}
+}
+
+func TestRedisLimiter_ExpiresIdleBuckets(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	l := NewRedisLimiter(redis.NewClient(&redis.Options{Addr: srv.Addr()}), "shorten", Limit{Rate: 1, Burst: 3})
+	defer l.Close()
+
+	if _, err := l.Allow(ctx, "ip:1.2.3.4"); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if !srv.Exists("zipit:ratelimit:shorten:ip:1.2.3.4") {
+		t.Fatalf("expected a namespaced bucket, got keys %v", srv.Keys())
+	}
+
+	srv.FastForward(time.Minute)
+	if len(srv.Keys()) != 0 {
+		t.Errorf("expected the idle bucket to expire, got keys %v", srv.Keys())
+	}
+}
+
+func TestRedisLimiter_ServerDown(t *testing.T) {
+	srv := miniredis.RunT(t)
+	l := NewRedisLimiter(redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1}), "shorten", Limit{Rate: 1, Burst: 3})
+	defer l.Close()
+	srv.Close()
+
+	if _, err := l.Allow(context.Background(), "ip:1.2.3.4"); err == nil {
+		t.Error("expected a connection error")
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	srv := miniredis.RunT(t)
+	limit := Limit{Rate: 1, Burst: 1}
+
+	tests := []struct {
+		name    string
+		cfg     *config.RateLimitConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "None", cfg: &config.RateLimitConfig{Store: config.RateLimitNone}, wantNil: true},
+		{name: "Memory", cfg: &config.RateLimitConfig{Store: config.RateLimitMemory}},
+		{name: "Redis", cfg: &config.RateLimitConfig{Store: config.RateLimitRedis, RedisAddr: srv.Addr()}},
+		{name: "Unreachable redis", cfg: &config.RateLimitConfig{Store: config.RateLimitRedis, RedisAddr: "127.0.0.1:1"}, wantErr: true},
+		{name: "Unknown", cfg: &config.RateLimitConfig{Store: "memcached"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLimiter(tt.cfg, "test", limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLimiter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (l == nil) != tt.wantNil {
+				t.Errorf("NewLimiter() = %v, wantNil %v", l, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from a bucket stored as a hash of its token
+// count and last refill time in microseconds, atomically on the server.
+// Idle buckets expire once they would be full again.
+//
+// KEYS[1] bucket key; ARGV: rate per second, burst, now in microseconds.
+// Returns the token count after the call and 1 if a token was taken.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+
+if now > last then
+  tokens = math.min(burst, tokens + (now - last) / 1e6 * rate)
+  last = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(last))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {tostring(tokens), allowed}
+`)
+
+// RedisLimiter keeps its buckets in Redis, so every gateway replica using the
+// same server shares one budget per key.
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+	limit  Limit
+	now    func() time.Time
+}
+
+// NewRedisLimiter creates a limiter enforcing limit per key through client.
+// name keeps the buckets of different limiters apart.
+func NewRedisLimiter(client *redis.Client, name string, limit Limit) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: "zipit:ratelimit:" + name + ":",
+		limit:  limit,
+		now:    time.Now,
+	}
+}
+
+// Allow implements [Limiter].
+func (l *RedisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	args := []any{l.limit.Rate, l.limit.Burst, l.now().UnixMicro()}
+	values, err := takeScript.Run(ctx, l.client, []string{l.prefix + key}, args...).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take token from redis: %w", err)
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply from rate limit script: %v", values)
+	}
+
+	tokens, err := strconv.ParseFloat(fmt.Sprint(values[0]), 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected token count from rate limit script: %w", err)
+	}
+	res := Result{Allowed: values[1] == int64(1), Limit: l.limit.Burst, Remaining: int(tokens)}
+	if !res.Allowed {
+		res.RetryAfter = l.limit.duration(1 - tokens)
+	}
+	res.Reset = l.limit.duration(float64(l.limit.Burst) - tokens)
+	return res, nil
+}
+
+// Close releases the connections to the Redis server.
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
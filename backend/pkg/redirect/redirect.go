@@ -0,0 +1,21 @@
+// Package redirect defines the HTTP redirects short links may use. The url
+// service checks them when links are stored, and the gateway before
+// redirecting visitors.
+package redirect
+
+import "net/http"
+
+// Default is the redirect status of links that do not set one.
+const Default = http.StatusFound
+
+// Allowed lists the redirect statuses Valid accepts, for error messages.
+const Allowed = "301, 302, 307 or 308"
+
+// Valid reports whether code is a redirect status links may use.
+func Valid(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
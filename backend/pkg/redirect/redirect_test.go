@@ -0,0 +1,31 @@
+package redirect
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		code int
+		want bool
+	}{
+		{http.StatusMovedPermanently, true},
+		{http.StatusFound, true},
+		{http.StatusTemporaryRedirect, true},
+		{http.StatusPermanentRedirect, true},
+		{http.StatusSeeOther, false},
+		{http.StatusNotModified, false},
+		{http.StatusOK, false},
+		{0, false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.code); got != tt.want {
+			t.Errorf("Valid(%d) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+	if !Valid(Default) {
+		t.Errorf("expected the default %d to be valid", Default)
+	}
+}
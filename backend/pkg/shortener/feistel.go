@@ -0,0 +1,76 @@
+package shortener
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const (
+	// feistelBits is the size of the permuted domain. 2^40 ids fit in at most
+	// 7 base62 characters; bits above it are passed through unchanged.
+	feistelBits   = 40
+	feistelHalf   = feistelBits / 2
+	feistelRounds = 4
+
+	feistelMask     = int64(1)<<feistelBits - 1
+	feistelHalfMask = uint32(1)<<feistelHalf - 1
+)
+
+// feistelShortener scrambles ids with a keyed Feistel network before base62
+// encoding them. The mapping is a bijection, so codes stay unique and
+// reversible, but consecutive ids produce unrelated codes.
+type feistelShortener struct {
+	base62 Shortener
+	key    []byte
+}
+
+// NewFeistelShortener returns a Shortener whose codes cannot be enumerated
+// without knowing key. Changing the key changes every code, so it must stay
+// fixed for the lifetime of the data.
+func NewFeistelShortener(key []byte) Shortener {
+	return &feistelShortener{
+		base62: NewBase62Shortener(),
+		key:    key,
+	}
+}
+
+func (fsh *feistelShortener) Encode(id int64) string {
+	return fsh.base62.Encode(id&^feistelMask | fsh.permute(id&feistelMask))
+}
+
+func (fsh *feistelShortener) Decode(shortCode string) (int64, error) {
+	value, err := fsh.base62.Decode(shortCode)
+	if err != nil {
+		return -1, err
+	}
+	return value&^feistelMask | fsh.unpermute(value&feistelMask), nil
+}
+
+func (fsh *feistelShortener) permute(value int64) int64 {
+	left, right := uint32(value>>feistelHalf), uint32(value)&feistelHalfMask
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^fsh.round(round, right)
+	}
+	return int64(left)<<feistelHalf | int64(right)
+}
+
+func (fsh *feistelShortener) unpermute(value int64) int64 {
+	left, right := uint32(value>>feistelHalf), uint32(value)&feistelHalfMask
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^fsh.round(round, left), left
+	}
+	return int64(left)<<feistelHalf | int64(right)
+}
+
+// round is the Feistel round function: a keyed hash of the round number and
+// one half of the block, truncated to the half width.
+func (fsh *feistelShortener) round(round int, half uint32) uint32 {
+	var block [5]byte
+	block[0] = byte(round)
+	binary.BigEndian.PutUint32(block[1:], half)
+
+	mac := hmac.New(sha256.New, fsh.key)
+	mac.Write(block[:])
+	return binary.BigEndian.Uint32(mac.Sum(nil)) & feistelHalfMask
+}
@@ -0,0 +1,78 @@
+package shortener
+
+import "testing"
+
+var testKey = []byte("0123456789abcdef")
+
+func TestFeistelShortener_RoundTrip(t *testing.T) {
+	s := NewFeistelShortener(testKey)
+
+	tests := []struct {
+		name string
+		id   int64
+	}{
+		{"zero case", 0},
+		{"id one", 1},
+		{"random id", 12345},
+		{"top of permuted domain", 1<<40 - 1},
+		{"above permuted domain", 1 << 40},
+		{"max int64", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := s.Encode(tt.id)
+			back, err := s.Decode(code)
+			if err != nil {
+				t.Fatalf("Decode(%q) error = %v", code, err)
+			}
+			if back != tt.id {
+				t.Errorf("Decode(Encode(%d)) = %d", tt.id, back)
+			}
+		})
+	}
+}
+
+func TestFeistelShortener_Unique(t *testing.T) {
+	s := NewFeistelShortener(testKey)
+
+	seen := make(map[string]int64)
+	for id := int64(1); id <= 10000; id++ {
+		code := s.Encode(id)
+		if prev, ok := seen[code]; ok {
+			t.Fatalf("Encode(%d) and Encode(%d) both produced %q", prev, id, code)
+		}
+		if len(code) > 7 {
+			t.Errorf("Encode(%d) = %q, want at most 7 characters", id, code)
+		}
+		seen[code] = id
+	}
+}
+
+func TestFeistelShortener_NotSequential(t *testing.T) {
+	s := NewFeistelShortener(testKey)
+	base62 := NewBase62Shortener()
+
+	for id := int64(1); id <= 10; id++ {
+		if s.Encode(id) == base62.Encode(id) {
+			t.Errorf("Encode(%d) leaks the raw id", id)
+		}
+	}
+}
+
+func TestFeistelShortener_KeyDependent(t *testing.T) {
+	a := NewFeistelShortener(testKey)
+	b := NewFeistelShortener([]byte("fedcba9876543210"))
+
+	if a.Encode(42) == b.Encode(42) {
+		t.Error("expected different keys to produce different codes")
+	}
+}
+
+func TestFeistelShortener_DecodeErrors(t *testing.T) {
+	s := NewFeistelShortener(testKey)
+
+	if _, err := s.Decode("abc#123"); err == nil {
+		t.Error("Decode() expected error for invalid character, got nil")
+	}
+}
@@ -0,0 +1,43 @@
+package shortener
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// ErrNotReversible is returned by Decode for codes that do not encode an id.
+var ErrNotReversible = errors.New("short code does not encode an id")
+
+const base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// randomShortener ignores the id and returns a fresh random code on every
+// call. Callers must retry on collisions.
+type randomShortener struct {
+	length int
+}
+
+// NewRandomShortener returns a Shortener that produces random base62 codes of
+// the given length.
+func NewRandomShortener(length int) Shortener {
+	return &randomShortener{length: length}
+}
+
+func (rsh *randomShortener) Encode(_ int64) string {
+	code := make([]byte, 0, rsh.length)
+	buf := make([]byte, rsh.length)
+	for len(code) < rsh.length {
+		_, _ = rand.Read(buf) // crypto/rand never returns an error since Go 1.24
+		for _, b := range buf {
+			// Discard bytes above the largest multiple of 62 to avoid modulo bias.
+			if b >= 248 || len(code) == rsh.length {
+				continue
+			}
+			code = append(code, base62Alphabet[b%62])
+		}
+	}
+	return string(code)
+}
+
+func (rsh *randomShortener) Decode(_ string) (int64, error) {
+	return -1, ErrNotReversible
+}
@@ -0,0 +1,36 @@
+package shortener
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRandomShortener_Encode(t *testing.T) {
+	s := NewRandomShortener(8)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code := s.Encode(1)
+		if len(code) != 8 {
+			t.Fatalf("Encode() = %q, want 8 characters", code)
+		}
+		for _, char := range code {
+			if !strings.ContainsRune(base62Alphabet, char) {
+				t.Fatalf("Encode() = %q contains non-base62 character %c", code, char)
+			}
+		}
+		if seen[code] {
+			t.Fatalf("Encode() repeated code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestRandomShortener_Decode(t *testing.T) {
+	s := NewRandomShortener(8)
+
+	if _, err := s.Decode("abcdefgh"); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("Decode() error = %v, want ErrNotReversible", err)
+	}
+}
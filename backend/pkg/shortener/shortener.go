@@ -1,6 +1,9 @@
 package shortener
 
-import "fmt"
+import (
+	"fmt"
+	"zipit/pkg/config"
+)
 
 // Shortener defines methods for encoding and decoding short URLs.
 type Shortener interface {
@@ -8,6 +11,20 @@ type Shortener interface {
 	Decode(shortCode string) (int64, error)
 }
 
+// NewShortener builds the Shortener selected by cfg.
+func NewShortener(cfg *config.ShortenerConfig) (Shortener, error) {
+	switch cfg.Kind {
+	case config.ShortenerBase62:
+		return NewBase62Shortener(), nil
+	case config.ShortenerFeistel:
+		return NewFeistelShortener([]byte(cfg.Key)), nil
+	case config.ShortenerRandom:
+		return NewRandomShortener(cfg.Length), nil
+	default:
+		return nil, fmt.Errorf("unknown shortener %q", cfg.Kind)
+	}
+}
+
 type base62shortener struct {
 	alphabet string
 	base     int64
@@ -15,7 +32,7 @@ type base62shortener struct {
 }
 
 func NewBase62Shortener() Shortener {
-	alphabet := "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	alphabet := base62Alphabet
 	mapKey := make(map[rune]int)
 	for i, char := range alphabet {
 		mapKey[char] = i
@@ -1,6 +1,9 @@
 package shortener
 
-import "testing"
+import (
+	"testing"
+	"zipit/pkg/config"
+)
 
 func TestBase62Shortener(t *testing.T) {
 	s := NewBase62Shortener()
@@ -60,3 +63,28 @@ func TestBase62Shortener_DecodeErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestNewShortener(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.ShortenerConfig
+		wantErr bool
+	}{
+		{"base62", &config.ShortenerConfig{Kind: config.ShortenerBase62}, false},
+		{"feistel", &config.ShortenerConfig{Kind: config.ShortenerFeistel, Key: "0123456789abcdef"}, false},
+		{"random", &config.ShortenerConfig{Kind: config.ShortenerRandom, Length: 8}, false},
+		{"unknown", &config.ShortenerConfig{Kind: "md5"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewShortener(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewShortener() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && s == nil {
+				t.Error("NewShortener() returned nil shortener")
+			}
+		})
+	}
+}